@@ -39,6 +39,11 @@ const (
 type SPIAccessCheckSpec struct {
 	RepoUrl     string      `json:"repoUrl"`
 	Permissions Permissions `json:"permissions,omitempty"`
+	// RefreshInterval turns on the long-lived mode in which the check is not deleted after its lifetime. Instead, it is
+	// periodically re-evaluated with this interval and also whenever a SPIAccessToken or RemoteSecret for the same
+	// host changes.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
 }
 
 // SPIAccessCheckStatus defines the observed state of SPIAccessCheck
@@ -49,8 +54,73 @@ type SPIAccessCheckStatus struct {
 	ServiceProvider ServiceProviderType         `json:"serviceProvider"`
 	ErrorReason     SPIAccessCheckErrorReason   `json:"errorReason,omitempty"`
 	ErrorMessage    string                      `json:"errorMessage,omitempty"`
+	// Permissions contains the result of the check for each of the required permissions from the spec.
+	// +optional
+	Permissions []SPIAccessCheckPermissionResult `json:"permissions,omitempty"`
+	// GrantedRole is the role on the repository the service provider reports for the credentials used in the check,
+	// e.g. "push" on GitHub, "Developer" on GitLab or "write" on Quay. It is empty if the repository was accessed
+	// without credentials.
+	// +optional
+	GrantedRole string `json:"grantedRole,omitempty"`
+	// GrantedScopes are the scopes of the credentials used in the check, if the service provider reports them.
+	// +optional
+	GrantedScopes []string `json:"grantedScopes,omitempty"`
+	// CredentialsSource identifies the object the credentials used in the check were taken from. It is empty if no
+	// credentials were found.
+	// +optional
+	CredentialsSource *CredentialsSource `json:"credentialsSource,omitempty"`
+	// LastCheckTime is the last time the accessibility of the repository was evaluated.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	// LastTransitionTime is the last time the repository changed from accessible to inaccessible or vice versa.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Conditions is the list of conditions describing the state of the access check. The types of the conditions
+	// are listed in the SPIAccessCheckConditionType enumeration.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// SPIAccessCheckPermissionResult is the result of the check of a single required permission.
+type SPIAccessCheckPermissionResult struct {
+	Permission `json:",inline"`
+	// Granted is true if the permission is satisfied on the repository.
+	Granted bool `json:"granted"`
+}
+
+// CredentialsSourceKind is the kind of the object that provides credentials to the service provider.
+type CredentialsSourceKind string
+
+const (
+	CredentialsSourceKindSPIAccessToken CredentialsSourceKind = "SPIAccessToken"
+	CredentialsSourceKindRemoteSecret   CredentialsSourceKind = "RemoteSecret"
+)
+
+// CredentialsSource is a reference to the object in the namespace of the referring object that provided the credentials.
+type CredentialsSource struct {
+	// +kubebuilder:validation:Enum=SPIAccessToken;RemoteSecret
+	Kind CredentialsSourceKind `json:"kind"`
+	Name string                `json:"name"`
+}
+
+// SPIAccessCheckConditionType lists the types of conditions we track in the access check status
+type SPIAccessCheckConditionType string
+
+const (
+	SPIAccessCheckConditionTypeAccessible SPIAccessCheckConditionType = "Accessible"
+)
+
+// SPIAccessCheckReason is the reason of the state of a condition if there was no error performing the check. The
+// errors are described using the SPIAccessCheckErrorReason.
+type SPIAccessCheckReason string
+
+const (
+	SPIAccessCheckReasonAccessible    SPIAccessCheckReason = "RepositoryAccessible"
+	SPIAccessCheckReasonNotAccessible SPIAccessCheckReason = "RepositoryNotAccessible"
+)
+
 type SPIRepoType string
 
 const (
@@ -103,6 +173,12 @@ func init() {
 	SchemeBuilder.Register(&SPIAccessCheck{}, &SPIAccessCheckList{})
 }
 
+// IsLongLived returns true if the access check should be periodically re-evaluated instead of being deleted after its
+// lifetime.
+func (spec *SPIAccessCheckSpec) IsLongLived() bool {
+	return spec.RefreshInterval != nil
+}
+
 func (c *SPIAccessCheck) RepoUrl() string {
 	return c.Spec.RepoUrl
 }
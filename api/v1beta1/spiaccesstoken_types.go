@@ -136,8 +136,30 @@ type SPIAccessTokenStatus struct {
 	OAuthUrl      string                    `json:"oAuthUrl"`
 	UploadUrl     string                    `json:"uploadUrl,omitempty"`
 	TokenMetadata *TokenMetadata            `json:"tokenMetadata,omitempty"`
+	// Conditions is the list of conditions describing the state of the token. The types of the conditions
+	// are listed in the SPIAccessTokenConditionType enumeration.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// SPIAccessTokenConditionType lists the types of conditions we track in the token status
+type SPIAccessTokenConditionType string
+
+const (
+	SPIAccessTokenConditionTypeMetadataFetched SPIAccessTokenConditionType = "MetadataFetched"
+)
+
+// SPIAccessTokenReason is the reason of a successful or awaiting state of a condition. The failures are described
+// using the SPIAccessTokenErrorReason.
+type SPIAccessTokenReason string
+
+const (
+	SPIAccessTokenReasonMetadataFetched   SPIAccessTokenReason = "Fetched"
+	SPIAccessTokenReasonAwaitingTokenData SPIAccessTokenReason = "AwaitingTokenData"
+)
+
 // SPIAccessTokenPhase is the reconciliation phase of the SPIAccessToken object
 type SPIAccessTokenPhase string
 
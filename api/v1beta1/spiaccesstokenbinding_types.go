@@ -25,6 +25,17 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// BindingLifetimeRenewedAtAnnotation can be put on an SPIAccessTokenBinding by its consumers to extend its lifetime.
+// The value is an RFC3339 timestamp and the binding expires after its lifetime elapses counted from that time instead
+// of from its creation. Consumers are supposed to bump the timestamp for as long as they need the binding. Timestamps
+// in the future, beyond a small allowance for the clock skew, are ignored.
+const BindingLifetimeRenewedAtAnnotation = "spi.appstudio.redhat.com/lifetime-renewed-at"
+
+// TokenSelectionPriorityLabel can be put on an SPIAccessToken to express its priority when several tokens match
+// a binding that uses the TokenSelectionStrategyLabelPriority. The value is an integer, the tokens with the higher
+// values are preferred. Tokens without the label have the priority 0.
+const TokenSelectionPriorityLabel = "spi.appstudio.redhat.com/selection-priority"
+
 // SPIAccessTokenBindingSpec defines the desired state of SPIAccessTokenBinding
 type SPIAccessTokenBindingSpec struct {
 	// RepoUrl is just the URL of the repository for which the access token is requested.
@@ -39,6 +50,42 @@ type SPIAccessTokenBindingSpec struct {
 	// This is specified as time with a unit (30m, 2h). A special value of "-1" means
 	// infinite lifetime.
 	Lifetime string `json:"lifetime,omitempty"`
+	// ExtendWhileMounted, if true, makes the binding live for as long as there is a running pod in the namespace that
+	// uses the secret synced by the binding. The lifetime of the binding is repeatedly extended by the value of
+	// Lifetime while such a pod exists.
+	// +optional
+	ExtendWhileMounted bool `json:"extendWhileMounted,omitempty"`
+	// TokenSelectionStrategy specifies how to choose the token to link to the binding if there are several tokens
+	// matching it. If not specified, the default strategy configured in the operator is used.
+	// +optional
+	TokenSelectionStrategy TokenSelectionStrategy `json:"tokenSelectionStrategy,omitempty"`
+}
+
+// TokenSelectionStrategy specifies how to choose a token for a binding when there are several matching tokens.
+// +kubebuilder:validation:Enum=PreferExactScope;PreferMostRecentlyRefreshed;PreferLabelPriority;PreferLongestRemainingValidity
+type TokenSelectionStrategy string
+
+const (
+	// TokenSelectionStrategyExactScope prefers the token with the fewest scopes, i.e. the one with the permissions
+	// closest to the permissions required by the binding.
+	TokenSelectionStrategyExactScope TokenSelectionStrategy = "PreferExactScope"
+	// TokenSelectionStrategyMostRecentlyRefreshed prefers the token with the most recently refreshed metadata.
+	TokenSelectionStrategyMostRecentlyRefreshed TokenSelectionStrategy = "PreferMostRecentlyRefreshed"
+	// TokenSelectionStrategyLabelPriority prefers the token with the highest value of the TokenSelectionPriorityLabel.
+	TokenSelectionStrategyLabelPriority TokenSelectionStrategy = "PreferLabelPriority"
+	// TokenSelectionStrategyLongestValidity prefers the token that expires last. Tokens without expiry are preferred
+	// over any expiring token.
+	TokenSelectionStrategyLongestValidity TokenSelectionStrategy = "PreferLongestRemainingValidity"
+)
+
+// IsValid returns true if the strategy is one of the known strategies.
+func (s TokenSelectionStrategy) IsValid() bool {
+	switch s {
+	case TokenSelectionStrategyExactScope, TokenSelectionStrategyMostRecentlyRefreshed, TokenSelectionStrategyLabelPriority, TokenSelectionStrategyLongestValidity:
+		return true
+	default:
+		return false
+	}
 }
 
 // SPIAccessTokenBindingStatus defines the observed state of SPIAccessTokenBinding
@@ -51,6 +98,30 @@ type SPIAccessTokenBindingStatus struct {
 	UploadUrl             string                           `json:"uploadUrl,omitempty"`
 	SyncedObjectRef       TargetObjectRef                  `json:"syncedObjectRef"`
 	ServiceAccountNames   []string                         `json:"serviceAccountNames,omitempty"`
+	// UpgradeUrl is the URL of the OAuth flow that upgrades the linked token with the scopes required by the binding
+	// in addition to the scopes the token already has. It is only set when the linked token is ready but doesn't have
+	// the permissions required by the binding. The upgrade must be authorized by the same service provider user that
+	// authorized the token, so that the other bindings linked to the token are not affected.
+	// +optional
+	UpgradeUrl string `json:"upgradeUrl,omitempty"`
+	// LinkedAccessTokenNamespace is the namespace of the linked SPIAccessToken if it is shared with the binding from
+	// another namespace using an SPIAccessTokenSharingPolicy. It is empty if the token lives in the namespace of
+	// the binding.
+	// +optional
+	LinkedAccessTokenNamespace string `json:"linkedAccessTokenNamespace,omitempty"`
+	// TokenSelectionReason describes why the linked token has been chosen for the binding.
+	// +optional
+	TokenSelectionReason string `json:"tokenSelectionReason,omitempty"`
+	// ExpirationTime is the time after which the binding is going to be deleted. It takes into account the renewals
+	// of the lifetime of the binding. It is empty if the binding has unlimited lifetime.
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// Conditions is the list of conditions describing the state of the binding. The types of the conditions
+	// are listed in the SPIAccessTokenBindingConditionType enumeration.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 type SPIAccessTokenBindingPhase string
@@ -61,6 +132,25 @@ const (
 	SPIAccessTokenBindingPhaseError             SPIAccessTokenBindingPhase = "Error"
 )
 
+// SPIAccessTokenBindingConditionType lists the types of conditions we track in the binding status
+type SPIAccessTokenBindingConditionType string
+
+const (
+	SPIAccessTokenBindingConditionTypeTokenLinked           SPIAccessTokenBindingConditionType = "TokenLinked"
+	SPIAccessTokenBindingConditionTypeSecretSynced          SPIAccessTokenBindingConditionType = "SecretSynced"
+	SPIAccessTokenBindingConditionTypeServiceAccountsLinked SPIAccessTokenBindingConditionType = "ServiceAccountsLinked"
+)
+
+// SPIAccessTokenBindingReason is the reason of a successful or awaiting state of a condition. The failures are
+// described using the SPIAccessTokenBindingErrorReason.
+type SPIAccessTokenBindingReason string
+
+const (
+	SPIAccessTokenBindingReasonLinked            SPIAccessTokenBindingReason = "Linked"
+	SPIAccessTokenBindingReasonInjected          SPIAccessTokenBindingReason = "Injected"
+	SPIAccessTokenBindingReasonAwaitingTokenData SPIAccessTokenBindingReason = "AwaitingTokenData"
+)
+
 type SPIAccessTokenBindingErrorReason string
 
 const (
@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"path"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SPIAccessTokenSharingPolicySpec defines which token is shared with which namespaces and under what restrictions.
+type SPIAccessTokenSharingPolicySpec struct {
+	// Token is the reference to the shared SPIAccessToken.
+	Token SharedTokenReference `json:"token"`
+	// TargetNamespaces is the list of namespaces in which the token can be used by bindings, access checks and file
+	// content requests.
+	TargetNamespaces []string `json:"targetNamespaces"`
+	// AllowedRepositories restricts the repositories for which the token can be used in the target namespaces. Each
+	// item is a pattern matched against the path of the repository URL (e.g. "redhat-appstudio/*"). The patterns
+	// use the syntax of the Go path.Match function. If empty, the token can be used for any repository.
+	// +optional
+	AllowedRepositories []string `json:"allowedRepositories,omitempty"`
+	// AllowedPermissions restricts the permissions that can be requested from the token in the target namespaces.
+	// A required permission is allowed if there is an allowed permission in the same area that includes its type.
+	// If empty, any permission can be requested.
+	// +optional
+	AllowedPermissions []Permission `json:"allowedPermissions,omitempty"`
+}
+
+// SharedTokenReference points to an SPIAccessToken in some namespace.
+type SharedTokenReference struct {
+	// Namespace is the namespace of the shared token.
+	Namespace string `json:"namespace"`
+	// Name is the name of the shared token.
+	Name string `json:"name"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// SPIAccessTokenSharingPolicy is a cluster-scoped object with which the cluster admins can make an SPIAccessToken
+// available in other namespaces than the one it lives in. The shared token is considered in the token lookup in
+// the target namespaces only if there is no matching token in the namespace itself.
+type SPIAccessTokenSharingPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SPIAccessTokenSharingPolicySpec `json:"spec"`
+}
+
+//+kubebuilder:object:root=true
+
+// SPIAccessTokenSharingPolicyList contains a list of SPIAccessTokenSharingPolicy
+type SPIAccessTokenSharingPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SPIAccessTokenSharingPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SPIAccessTokenSharingPolicy{}, &SPIAccessTokenSharingPolicyList{})
+}
+
+// AppliesToNamespace returns true if the policy shares the token with the provided namespace.
+func (p *SPIAccessTokenSharingPolicy) AppliesToNamespace(namespace string) bool {
+	if namespace == p.Spec.Token.Namespace {
+		return false
+	}
+	for _, ns := range p.Spec.TargetNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsRepository returns true if the policy allows using the token for the repository with the provided path
+// (without the host).
+func (p *SPIAccessTokenSharingPolicy) AllowsRepository(repoPath string) bool {
+	if len(p.Spec.AllowedRepositories) == 0 {
+		return true
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	for _, pattern := range p.Spec.AllowedRepositories {
+		if matches, err := path.Match(strings.Trim(pattern, "/"), repoPath); err == nil && matches {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsPermissions returns true if all the required permissions are allowed by the policy.
+func (p *SPIAccessTokenSharingPolicy) AllowsPermissions(permissions *Permissions) bool {
+	if len(p.Spec.AllowedPermissions) == 0 || permissions == nil {
+		return true
+	}
+
+	for _, required := range permissions.Required {
+		allowed := false
+		for _, candidate := range p.Spec.AllowedPermissions {
+			if candidate.Area == required.Area &&
+				(!required.Type.IsRead() || candidate.Type.IsRead()) &&
+				(!required.Type.IsWrite() || candidate.Type.IsWrite()) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
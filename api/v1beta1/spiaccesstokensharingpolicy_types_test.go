@@ -0,0 +1,55 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSPIAccessTokenSharingPolicy_AppliesToNamespace(t *testing.T) {
+	p := &SPIAccessTokenSharingPolicy{Spec: SPIAccessTokenSharingPolicySpec{
+		Token:            SharedTokenReference{Namespace: "source", Name: "bot"},
+		TargetNamespaces: []string{"source", "tenant"},
+	}}
+
+	assert.True(t, p.AppliesToNamespace("tenant"))
+	assert.False(t, p.AppliesToNamespace("other"))
+	assert.False(t, p.AppliesToNamespace("source"))
+}
+
+func TestSPIAccessTokenSharingPolicy_AllowsRepository(t *testing.T) {
+	p := &SPIAccessTokenSharingPolicy{}
+	assert.True(t, p.AllowsRepository("/acme/anything"))
+
+	p.Spec.AllowedRepositories = []string{"acme/*", "/other/repo/"}
+	assert.True(t, p.AllowsRepository("/acme/repo"))
+	assert.True(t, p.AllowsRepository("/acme/repo.git"))
+	assert.True(t, p.AllowsRepository("/other/repo"))
+	assert.False(t, p.AllowsRepository("/other/different"))
+	assert.False(t, p.AllowsRepository("/acme/repo/subgroup"))
+}
+
+func TestSPIAccessTokenSharingPolicy_AllowsPermissions(t *testing.T) {
+	p := &SPIAccessTokenSharingPolicy{}
+	assert.True(t, p.AllowsPermissions(&Permissions{Required: []Permission{{Type: PermissionTypeReadWrite, Area: PermissionAreaRepository}}}))
+
+	p.Spec.AllowedPermissions = []Permission{{Type: PermissionTypeRead, Area: PermissionAreaRepository}, {Type: PermissionTypeReadWrite, Area: PermissionAreaWebhooks}}
+	assert.True(t, p.AllowsPermissions(&Permissions{Required: []Permission{{Type: PermissionTypeRead, Area: PermissionAreaRepository}}}))
+	assert.True(t, p.AllowsPermissions(&Permissions{Required: []Permission{{Type: PermissionTypeWrite, Area: PermissionAreaWebhooks}}}))
+	assert.False(t, p.AllowsPermissions(&Permissions{Required: []Permission{{Type: PermissionTypeWrite, Area: PermissionAreaRepository}}}))
+	assert.False(t, p.AllowsPermissions(&Permissions{Required: []Permission{{Type: PermissionTypeRead, Area: PermissionAreaUser}}}))
+}
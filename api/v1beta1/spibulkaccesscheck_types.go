@@ -0,0 +1,146 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SPIBulkAccessCheckSpec defines the desired state of SPIBulkAccessCheck
+type SPIBulkAccessCheckSpec struct {
+	// Repositories is the list of the repositories to check the accessibility of.
+	// +kubebuilder:validation:MinItems=1
+	Repositories []SPIBulkAccessCheckRepository `json:"repositories"`
+}
+
+// SPIBulkAccessCheckRepository is a single repository to check within the bulk access check.
+type SPIBulkAccessCheckRepository struct {
+	RepoUrl     string      `json:"repoUrl"`
+	Permissions Permissions `json:"permissions,omitempty"`
+}
+
+// SPIBulkAccessCheckStatus defines the observed state of SPIBulkAccessCheck
+type SPIBulkAccessCheckStatus struct {
+	// Repositories contains the result of the check of each repository in the same order as in the spec.
+	// +optional
+	Repositories []SPIBulkAccessCheckRepositoryStatus `json:"repositories,omitempty"`
+	// AccessibleCount is the number of the accessible repositories.
+	AccessibleCount int `json:"accessibleCount"`
+	// LastCheckTime is the last time the accessibility of the repositories was evaluated.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	// Conditions is the list of conditions describing the state of the bulk access check. The types of the conditions
+	// are listed in the SPIBulkAccessCheckConditionType enumeration.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// SPIBulkAccessCheckRepositoryStatus is the result of the check of a single repository. The fields have the same
+// meaning as in the status of SPIAccessCheck.
+type SPIBulkAccessCheckRepositoryStatus struct {
+	RepoUrl         string                      `json:"repoUrl"`
+	Accessible      bool                        `json:"accessible"`
+	Accessibility   SPIAccessCheckAccessibility `json:"accessibility"`
+	Type            SPIRepoType                 `json:"repoType,omitempty"`
+	ServiceProvider ServiceProviderType         `json:"serviceProvider,omitempty"`
+	ErrorReason     SPIAccessCheckErrorReason   `json:"errorReason,omitempty"`
+	ErrorMessage    string                      `json:"errorMessage,omitempty"`
+	// +optional
+	Permissions []SPIAccessCheckPermissionResult `json:"permissions,omitempty"`
+	// +optional
+	GrantedRole string `json:"grantedRole,omitempty"`
+	// +optional
+	GrantedScopes []string `json:"grantedScopes,omitempty"`
+	// +optional
+	CredentialsSource *CredentialsSource `json:"credentialsSource,omitempty"`
+}
+
+// SPIBulkAccessCheckConditionType lists the types of conditions we track in the bulk access check status
+type SPIBulkAccessCheckConditionType string
+
+const (
+	SPIBulkAccessCheckConditionTypeChecked SPIBulkAccessCheckConditionType = "Checked"
+)
+
+// SPIBulkAccessCheckReason is the reason of the state of a condition of the bulk access check.
+type SPIBulkAccessCheckReason string
+
+const (
+	SPIBulkAccessCheckReasonChecked     SPIBulkAccessCheckReason = "RepositoriesChecked"
+	SPIBulkAccessCheckReasonCheckFailed SPIBulkAccessCheckReason = "CheckFailed"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// SPIBulkAccessCheck is the Schema for the spibulkaccesschecks API. It checks the accessibility of many repositories
+// at once.
+type SPIBulkAccessCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SPIBulkAccessCheckSpec   `json:"spec,omitempty"`
+	Status SPIBulkAccessCheckStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SPIBulkAccessCheckList contains a list of SPIBulkAccessCheck
+type SPIBulkAccessCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SPIBulkAccessCheck `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SPIBulkAccessCheck{}, &SPIBulkAccessCheckList{})
+}
+
+// AccessCheck returns a transient SPIAccessCheck for the repository on the provided index. It is used to look up
+// the credentials and to check the repository using the service providers.
+func (c *SPIBulkAccessCheck) AccessCheck(index int) *SPIAccessCheck {
+	repo := c.Spec.Repositories[index]
+	return &SPIAccessCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.Name,
+			Namespace: c.Namespace,
+		},
+		Spec: SPIAccessCheckSpec{
+			RepoUrl:     repo.RepoUrl,
+			Permissions: repo.Permissions,
+		},
+	}
+}
+
+// BulkRepositoryStatus converts the status of a single access check to the status of a repository within a bulk check.
+func (s *SPIAccessCheckStatus) BulkRepositoryStatus(repoUrl string) SPIBulkAccessCheckRepositoryStatus {
+	return SPIBulkAccessCheckRepositoryStatus{
+		RepoUrl:           repoUrl,
+		Accessible:        s.Accessible,
+		Accessibility:     s.Accessibility,
+		Type:              s.Type,
+		ServiceProvider:   s.ServiceProvider,
+		ErrorReason:       s.ErrorReason,
+		ErrorMessage:      s.ErrorMessage,
+		Permissions:       s.Permissions,
+		GrantedRole:       s.GrantedRole,
+		GrantedScopes:     s.GrantedScopes,
+		CredentialsSource: s.CredentialsSource,
+	}
+}
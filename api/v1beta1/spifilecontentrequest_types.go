@@ -24,22 +24,141 @@ type SPIFileContentRequestSpec struct {
 	// Ref defines target git reference (tag/branch/commit)
 	// +optional
 	Ref string `json:"ref,omitempty"`
+	// PinRef makes the controller use the commit SHA to which the Ref was resolved during the first successful download
+	// (reported in the status) when the content is downloaded again, so that the content always comes from the same
+	// commit even if the Ref is a branch that moves on. The delivered content is only downloaded again if the target
+	// object is deleted, because the requests with PinRef never follow the Ref (see RefreshInterval).
+	// +optional
+	PinRef bool `json:"pinRef,omitempty"`
+	// RefreshInterval turns on the follow-ref mode in which the controller periodically checks whether the ref moved
+	// and updates the delivered content if the file changed. It is only supported in the File mode without PinRef.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+	// Mode specifies whether FilePath refers to a single file or to a directory whose files should be downloaded.
+	// Defaults to File.
+	// +kubebuilder:validation:Enum=File;Directory
+	// +optional
+	Mode SPIFileContentRequestMode `json:"mode,omitempty"`
+	// Include is the list of patterns of the files to download in the Directory mode. The patterns use the syntax of
+	// the Go path.Match function and are matched against both the path of the file relative to the directory and its
+	// base name. If empty, all the files in the directory are included.
+	// +optional
+	Include []string `json:"include,omitempty"`
+	// Exclude is the list of patterns of the files not to download in the Directory mode. The syntax is the same as
+	// for Include. Exclusion takes precedence over inclusion.
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+	// Target specifies the object into which the file content should be delivered. If not specified, the content is
+	// delivered in the status of the request.
+	// +optional
+	Target *SPIFileContentRequestTarget `json:"target,omitempty"`
 }
 
+// SPIFileContentRequestTarget specifies the ConfigMap or Secret to deliver the file content into. The object is created
+// in the namespace of the request and is owned by it, so it is deleted together with the request.
+type SPIFileContentRequestTarget struct {
+	// Kind is the kind of the target object.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind SPIFileContentRequestTargetKind `json:"kind"`
+	// Name is the name of the target object. If not specified, the name of the request is used.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Key is the key in the data of the target object under which the file content is stored. If not specified,
+	// the base name of the file is used. In the Directory mode, the files are stored as a gzipped tar archive under
+	// this key if it is specified. Otherwise, each file is stored under its own key which is the path of the file
+	// relative to the directory with the slashes replaced by underscores.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// SPIFileContentMetadata describes which version of the file was delivered. Only the information the service provider
+// was able to determine is filled in.
+type SPIFileContentMetadata struct {
+	// CommitSha is the SHA of the commit to which the ref of the request was resolved. For container registries,
+	// this is the digest of the manifest of the artifact.
+	// +optional
+	CommitSha string `json:"commitSha,omitempty"`
+	// BlobSha is the SHA of the git blob with the content of the file.
+	// +optional
+	BlobSha string `json:"blobSha,omitempty"`
+	// Size is the size of the file in bytes.
+	// +optional
+	Size int `json:"size,omitempty"`
+	// LastModified is the time of the last commit that modified the file. Where determining it costs an additional
+	// API call, it is only reported if enabled in the operator configuration.
+	// +optional
+	LastModified *metav1.Time `json:"lastModified,omitempty"`
+	// FilePath is the actual path of the delivered file in the repository. This can differ from the requested path if
+	// the service provider followed a symlink or redirect.
+	// +optional
+	FilePath string `json:"filePath,omitempty"`
+}
+
+// SPIFileContentRequestMode specifies what the file path of the request refers to.
+type SPIFileContentRequestMode string
+
+const (
+	SPIFileContentRequestModeFile      SPIFileContentRequestMode = "File"
+	SPIFileContentRequestModeDirectory SPIFileContentRequestMode = "Directory"
+)
+
+type SPIFileContentRequestTargetKind string
+
+const (
+	SPIFileContentRequestTargetKindConfigMap SPIFileContentRequestTargetKind = "ConfigMap"
+	SPIFileContentRequestTargetKindSecret    SPIFileContentRequestTargetKind = "Secret"
+)
+
 type SPIFileContentRequestStatus struct {
 	// Phase of the current file request
 	Phase SPIFileContentRequestPhase `json:"phase"`
 	// ErrorMessage defines error message if file request failed
 	// + optional
 	ErrorMessage string `json:"errorMessage,omitempty"`
-	// Content encoded target file content
+	// Content encoded target file content. In the Directory mode, this is the encoded gzipped tar archive of the files.
 	// +optional
 	Content string `json:"content,omitempty"`
 	// ContentEncoding encoding used for file content
 	// +optional
 	ContentEncoding string `json:"contentEncoding,omitempty"`
+	// SyncedObjectRef is the reference to the ConfigMap or Secret with the file content if the request specifies
+	// a target.
+	// +optional
+	SyncedObjectRef *TargetObjectRef `json:"syncedObjectRef,omitempty"`
+	// FileMetadata describes the version of the delivered file. It is only reported in the File mode.
+	// +optional
+	FileMetadata *SPIFileContentMetadata `json:"fileMetadata,omitempty"`
+	// LastRefreshTime is the last time the content was downloaded or checked for changes.
+	// +optional
+	LastRefreshTime *metav1.Time `json:"lastRefreshTime,omitempty"`
+	// Conditions is the list of conditions describing the state of the file request. The types of the conditions
+	// are listed in the SPIFileContentRequestConditionType enumeration.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// SPIFileContentRequestConditionType lists the types of conditions we track in the file request status
+type SPIFileContentRequestConditionType string
+
+const (
+	SPIFileContentRequestConditionTypeContentDelivered SPIFileContentRequestConditionType = "ContentDelivered"
+	// SPIFileContentRequestConditionTypeContentRefreshed is only reported once the content of a delivered request is
+	// downloaded again. It is false if the last download failed and the previously delivered content is kept.
+	SPIFileContentRequestConditionTypeContentRefreshed SPIFileContentRequestConditionType = "ContentRefreshed"
+)
+
+// SPIFileContentRequestReason is the reason of the state of a condition.
+type SPIFileContentRequestReason string
+
+const (
+	SPIFileContentRequestReasonDelivered     SPIFileContentRequestReason = "Delivered"
+	SPIFileContentRequestReasonError         SPIFileContentRequestReason = "Error"
+	SPIFileContentRequestReasonRefreshed     SPIFileContentRequestReason = "Refreshed"
+	SPIFileContentRequestReasonRefreshFailed SPIFileContentRequestReason = "RefreshFailed"
+)
+
 type SPIFileContentRequestPhase string
 
 const (
@@ -71,6 +190,16 @@ func init() {
 	SchemeBuilder.Register(&SPIFileContentRequest{}, &SPIFileContentRequestList{})
 }
 
+// IsDirectory returns true if the request asks for the files of a directory instead of a single file.
+func (req *SPIFileContentRequestSpec) IsDirectory() bool {
+	return req.Mode == SPIFileContentRequestModeDirectory
+}
+
+// FollowsRef returns true if the content of the request should be periodically refreshed when the ref moves.
+func (req *SPIFileContentRequestSpec) FollowsRef() bool {
+	return req.RefreshInterval != nil && !req.IsDirectory() && !req.PinRef
+}
+
 func (req *SPIFileContentRequest) RepoUrl() string {
 	return req.Spec.RepoUrl
 }
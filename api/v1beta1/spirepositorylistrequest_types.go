@@ -0,0 +1,113 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SPIRepositoryListRequestSpec defines the desired state of SPIRepositoryListRequest
+type SPIRepositoryListRequestSpec struct {
+	// AccessTokenName is the name of the SPIAccessToken in the same namespace whose accessible repositories should
+	// be listed.
+	AccessTokenName string `json:"accessTokenName"`
+	// NamePrefix limits the listed repositories to those whose full name (e.g. "owner/repository") starts with
+	// the prefix. The comparison is case-insensitive.
+	// +optional
+	NamePrefix string `json:"namePrefix,omitempty"`
+	// Page is the 1-based number of the page of the repositories to return.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	Page int `json:"page,omitempty"`
+	// PageSize is the maximum number of the repositories returned on a single page.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=30
+	// +optional
+	PageSize int `json:"pageSize,omitempty"`
+}
+
+// SPIRepositoryListRequestStatus defines the observed state of SPIRepositoryListRequest
+type SPIRepositoryListRequestStatus struct {
+	Phase SPIRepositoryListRequestPhase `json:"phase,omitempty"`
+	// ErrorMessage describes the reason why the repositories could not be listed.
+	// +optional
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	// Repositories is the requested page of the repositories accessible using the token sorted by their full names.
+	// +optional
+	Repositories []SPIRepositoryListItem `json:"repositories,omitempty"`
+	// TotalCount is the number of all the repositories matching the name prefix.
+	TotalCount int `json:"totalCount"`
+	// NextPage is the number of the next page of the repositories or 0 if this is the last page.
+	// +optional
+	NextPage int `json:"nextPage,omitempty"`
+	// LastListTime is the last time the repositories were listed.
+	// +optional
+	LastListTime *metav1.Time `json:"lastListTime,omitempty"`
+}
+
+// SPIRepositoryListItem is a single repository accessible using the token.
+type SPIRepositoryListItem struct {
+	// RepoUrl is the URL of the repository.
+	RepoUrl string `json:"repoUrl"`
+	// FullName is the name of the repository including its owner, e.g. "owner/repository".
+	FullName string `json:"fullName"`
+	// Private is true if the repository is not publicly accessible.
+	Private bool `json:"private"`
+	// Role is the role of the user on the repository as reported by the service provider, using the same names as
+	// the GrantedRole in the status of SPIAccessCheck. It is empty if the service provider doesn't report it.
+	// +optional
+	Role string `json:"role,omitempty"`
+}
+
+// SPIRepositoryListRequestPhase is the phase of the repository list request.
+type SPIRepositoryListRequestPhase string
+
+const (
+	SPIRepositoryListRequestPhaseReady SPIRepositoryListRequestPhase = "Ready"
+	SPIRepositoryListRequestPhaseError SPIRepositoryListRequestPhase = "Error"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Token",type="string",JSONPath=".spec.accessTokenName"
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="Total",type="integer",JSONPath=".status.totalCount"
+
+// SPIRepositoryListRequest is the Schema for the spirepositorylistrequests API. It lists the repositories accessible
+// using an SPIAccessToken.
+type SPIRepositoryListRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SPIRepositoryListRequestSpec   `json:"spec,omitempty"`
+	Status SPIRepositoryListRequestStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SPIRepositoryListRequestList contains a list of SPIRepositoryListRequest
+type SPIRepositoryListRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SPIRepositoryListRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SPIRepositoryListRequest{}, &SPIRepositoryListRequestList{})
+}
@@ -22,9 +22,25 @@ limitations under the License.
 package v1beta1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsSource) DeepCopyInto(out *CredentialsSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsSource.
+func (in *CredentialsSource) DeepCopy() *CredentialsSource {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Permission) DeepCopyInto(out *Permission) {
 	*out = *in
@@ -71,7 +87,7 @@ func (in *SPIAccessCheck) DeepCopyInto(out *SPIAccessCheck) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIAccessCheck.
@@ -124,10 +140,31 @@ func (in *SPIAccessCheckList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIAccessCheckPermissionResult) DeepCopyInto(out *SPIAccessCheckPermissionResult) {
+	*out = *in
+	out.Permission = in.Permission
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIAccessCheckPermissionResult.
+func (in *SPIAccessCheckPermissionResult) DeepCopy() *SPIAccessCheckPermissionResult {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIAccessCheckPermissionResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SPIAccessCheckSpec) DeepCopyInto(out *SPIAccessCheckSpec) {
 	*out = *in
 	in.Permissions.DeepCopyInto(&out.Permissions)
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIAccessCheckSpec.
@@ -143,6 +180,36 @@ func (in *SPIAccessCheckSpec) DeepCopy() *SPIAccessCheckSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SPIAccessCheckStatus) DeepCopyInto(out *SPIAccessCheckStatus) {
 	*out = *in
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]SPIAccessCheckPermissionResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.GrantedScopes != nil {
+		in, out := &in.GrantedScopes, &out.GrantedScopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CredentialsSource != nil {
+		in, out := &in.CredentialsSource, &out.CredentialsSource
+		*out = new(CredentialsSource)
+		**out = **in
+	}
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIAccessCheckStatus.
@@ -267,6 +334,17 @@ func (in *SPIAccessTokenBindingStatus) DeepCopyInto(out *SPIAccessTokenBindingSt
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExpirationTime != nil {
+		in, out := &in.ExpirationTime, &out.ExpirationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIAccessTokenBindingStatus.
@@ -405,6 +483,95 @@ func (in *SPIAccessTokenList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIAccessTokenSharingPolicy) DeepCopyInto(out *SPIAccessTokenSharingPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIAccessTokenSharingPolicy.
+func (in *SPIAccessTokenSharingPolicy) DeepCopy() *SPIAccessTokenSharingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIAccessTokenSharingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SPIAccessTokenSharingPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIAccessTokenSharingPolicyList) DeepCopyInto(out *SPIAccessTokenSharingPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SPIAccessTokenSharingPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIAccessTokenSharingPolicyList.
+func (in *SPIAccessTokenSharingPolicyList) DeepCopy() *SPIAccessTokenSharingPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIAccessTokenSharingPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SPIAccessTokenSharingPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIAccessTokenSharingPolicySpec) DeepCopyInto(out *SPIAccessTokenSharingPolicySpec) {
+	*out = *in
+	out.Token = in.Token
+	if in.TargetNamespaces != nil {
+		in, out := &in.TargetNamespaces, &out.TargetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedRepositories != nil {
+		in, out := &in.AllowedRepositories, &out.AllowedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedPermissions != nil {
+		in, out := &in.AllowedPermissions, &out.AllowedPermissions
+		*out = make([]Permission, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIAccessTokenSharingPolicySpec.
+func (in *SPIAccessTokenSharingPolicySpec) DeepCopy() *SPIAccessTokenSharingPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIAccessTokenSharingPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SPIAccessTokenSpec) DeepCopyInto(out *SPIAccessTokenSpec) {
 	*out = *in
@@ -429,6 +596,13 @@ func (in *SPIAccessTokenStatus) DeepCopyInto(out *SPIAccessTokenStatus) {
 		*out = new(TokenMetadata)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIAccessTokenStatus.
@@ -441,13 +615,192 @@ func (in *SPIAccessTokenStatus) DeepCopy() *SPIAccessTokenStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIBulkAccessCheck) DeepCopyInto(out *SPIBulkAccessCheck) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIBulkAccessCheck.
+func (in *SPIBulkAccessCheck) DeepCopy() *SPIBulkAccessCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIBulkAccessCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SPIBulkAccessCheck) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIBulkAccessCheckList) DeepCopyInto(out *SPIBulkAccessCheckList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SPIBulkAccessCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIBulkAccessCheckList.
+func (in *SPIBulkAccessCheckList) DeepCopy() *SPIBulkAccessCheckList {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIBulkAccessCheckList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SPIBulkAccessCheckList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIBulkAccessCheckRepository) DeepCopyInto(out *SPIBulkAccessCheckRepository) {
+	*out = *in
+	in.Permissions.DeepCopyInto(&out.Permissions)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIBulkAccessCheckRepository.
+func (in *SPIBulkAccessCheckRepository) DeepCopy() *SPIBulkAccessCheckRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIBulkAccessCheckRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIBulkAccessCheckRepositoryStatus) DeepCopyInto(out *SPIBulkAccessCheckRepositoryStatus) {
+	*out = *in
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]SPIAccessCheckPermissionResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.GrantedScopes != nil {
+		in, out := &in.GrantedScopes, &out.GrantedScopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CredentialsSource != nil {
+		in, out := &in.CredentialsSource, &out.CredentialsSource
+		*out = new(CredentialsSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIBulkAccessCheckRepositoryStatus.
+func (in *SPIBulkAccessCheckRepositoryStatus) DeepCopy() *SPIBulkAccessCheckRepositoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIBulkAccessCheckRepositoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIBulkAccessCheckSpec) DeepCopyInto(out *SPIBulkAccessCheckSpec) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]SPIBulkAccessCheckRepository, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIBulkAccessCheckSpec.
+func (in *SPIBulkAccessCheckSpec) DeepCopy() *SPIBulkAccessCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIBulkAccessCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIBulkAccessCheckStatus) DeepCopyInto(out *SPIBulkAccessCheckStatus) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]SPIBulkAccessCheckRepositoryStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIBulkAccessCheckStatus.
+func (in *SPIBulkAccessCheckStatus) DeepCopy() *SPIBulkAccessCheckStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIBulkAccessCheckStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIFileContentMetadata) DeepCopyInto(out *SPIFileContentMetadata) {
+	*out = *in
+	if in.LastModified != nil {
+		in, out := &in.LastModified, &out.LastModified
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIFileContentMetadata.
+func (in *SPIFileContentMetadata) DeepCopy() *SPIFileContentMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIFileContentMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SPIFileContentRequest) DeepCopyInto(out *SPIFileContentRequest) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIFileContentRequest.
@@ -503,6 +856,26 @@ func (in *SPIFileContentRequestList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SPIFileContentRequestSpec) DeepCopyInto(out *SPIFileContentRequestSpec) {
 	*out = *in
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(SPIFileContentRequestTarget)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIFileContentRequestSpec.
@@ -518,6 +891,27 @@ func (in *SPIFileContentRequestSpec) DeepCopy() *SPIFileContentRequestSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SPIFileContentRequestStatus) DeepCopyInto(out *SPIFileContentRequestStatus) {
 	*out = *in
+	if in.SyncedObjectRef != nil {
+		in, out := &in.SyncedObjectRef, &out.SyncedObjectRef
+		*out = new(TargetObjectRef)
+		**out = **in
+	}
+	if in.FileMetadata != nil {
+		in, out := &in.FileMetadata, &out.FileMetadata
+		*out = new(SPIFileContentMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastRefreshTime != nil {
+		in, out := &in.LastRefreshTime, &out.LastRefreshTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIFileContentRequestStatus.
@@ -530,6 +924,134 @@ func (in *SPIFileContentRequestStatus) DeepCopy() *SPIFileContentRequestStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIFileContentRequestTarget) DeepCopyInto(out *SPIFileContentRequestTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIFileContentRequestTarget.
+func (in *SPIFileContentRequestTarget) DeepCopy() *SPIFileContentRequestTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIFileContentRequestTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIRepositoryListItem) DeepCopyInto(out *SPIRepositoryListItem) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIRepositoryListItem.
+func (in *SPIRepositoryListItem) DeepCopy() *SPIRepositoryListItem {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIRepositoryListItem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIRepositoryListRequest) DeepCopyInto(out *SPIRepositoryListRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIRepositoryListRequest.
+func (in *SPIRepositoryListRequest) DeepCopy() *SPIRepositoryListRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIRepositoryListRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SPIRepositoryListRequest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIRepositoryListRequestList) DeepCopyInto(out *SPIRepositoryListRequestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SPIRepositoryListRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIRepositoryListRequestList.
+func (in *SPIRepositoryListRequestList) DeepCopy() *SPIRepositoryListRequestList {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIRepositoryListRequestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SPIRepositoryListRequestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIRepositoryListRequestSpec) DeepCopyInto(out *SPIRepositoryListRequestSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIRepositoryListRequestSpec.
+func (in *SPIRepositoryListRequestSpec) DeepCopy() *SPIRepositoryListRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIRepositoryListRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPIRepositoryListRequestStatus) DeepCopyInto(out *SPIRepositoryListRequestStatus) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]SPIRepositoryListItem, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastListTime != nil {
+		in, out := &in.LastListTime, &out.LastListTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPIRepositoryListRequestStatus.
+func (in *SPIRepositoryListRequestStatus) DeepCopy() *SPIRepositoryListRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SPIRepositoryListRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretSpec) DeepCopyInto(out *SecretSpec) {
 	*out = *in
@@ -547,6 +1069,21 @@ func (in *SecretSpec) DeepCopy() *SecretSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedTokenReference) DeepCopyInto(out *SharedTokenReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedTokenReference.
+func (in *SharedTokenReference) DeepCopy() *SharedTokenReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedTokenReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetObjectRef) DeepCopyInto(out *TargetObjectRef) {
 	*out = *in
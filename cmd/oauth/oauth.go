@@ -14,7 +14,9 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -43,9 +45,15 @@ import (
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/tokenstorage"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+var (
+	invalidSessionStoreError   = errors.New("invalid session store configuration")
+	invalidPagesConfigMapError = errors.New("the pages ConfigMap must be in the form 'namespace/name'")
+)
+
 func main() {
 	args := cli.OAuthServiceCliArgs{}
 	arg.MustParse(&args)
@@ -72,6 +80,9 @@ func main() {
 		setupLog.Error(err, "failed to initialize the configuration")
 		os.Exit(1)
 	}
+	if !cfg.OAuthState.Signed() {
+		setupLog.Info("WARNING: no OAuth state keys are configured, the OAuth state is neither signed nor verified. The unsigned state is only supported for backward compatibility, configure the keys in the 'oauthState' section of the configuration")
+	}
 
 	go metrics.ServeMetrics(ctx, args.MetricsAddr)
 	router := mux.NewRouter()
@@ -120,15 +131,30 @@ func main() {
 	}
 
 	// the session has 15 minutes timeout and stale sessions are cleaned every 5 minutes
+	sessionStore, err := createSessionStore(ctx, args, inClusterK8sClient)
+	if err != nil {
+		setupLog.Error(err, "failed to create the session store")
+		os.Exit(1)
+	}
 	sessionManager := scs.New()
-	sessionManager.Store = memstore.NewWithCleanupInterval(5 * time.Minute)
+	sessionManager.Store = sessionStore
 	sessionManager.IdleTimeout = 15 * time.Minute
 	sessionManager.Lifetime = time.Hour
 	sessionManager.Cookie.Persist = false
 	sessionManager.Cookie.Name = "appstudio_spi_session"
 	sessionManager.Cookie.SameSite = http.SameSiteNoneMode
 	sessionManager.Cookie.Secure = true
-	authenticator := oauth.NewAuthenticator(sessionManager, userAuthK8sClientFactory)
+	// the tokens are always reviewed using the service account of the OAuth service, even if the requests of the users
+	// go through the workspace proxy. The positive reviews are cached for a minute.
+	tokenReviewer := oauth.NewKubernetesTokenReviewer(inClusterK8sClientFactory, time.Minute)
+	authenticator := oauth.NewAuthenticator(sessionManager, userAuthK8sClientFactory, tokenReviewer)
+	if args.OIDCIssuerUrl != "" {
+		authenticator.OIDC, err = oauth.NewOIDCLogin(ctx, args.OIDCIssuerUrl, args.OIDCClientId, args.OIDCClientSecret, strings.Split(args.OIDCScopes, ","), cfg.BaseUrl)
+		if err != nil {
+			setupLog.Error(err, "failed to initialize the OIDC login")
+			os.Exit(1)
+		}
+	}
 	stateStorage := oauth.NewStateStorage(sessionManager)
 
 	// service state routes
@@ -138,6 +164,10 @@ func main() {
 	// auth
 	router.HandleFunc("/login", authenticator.Login).Methods("POST")
 	router.HandleFunc("/logout", authenticator.Logout).Methods("POST")
+	if authenticator.OIDC != nil {
+		router.HandleFunc(oauth.OIDCLoginRoutePath, authenticator.OIDCLogin).Methods("GET")
+		router.HandleFunc(oauth.OIDCCallbackRoutePath, authenticator.OIDCCallback).Methods("GET")
+	}
 
 	// token upload
 	router.NewRoute().Path("/token/{namespace}/{name}").HandlerFunc(oauth.HandleUpload(&tokenUploader)).Methods("POST")
@@ -148,6 +178,11 @@ func main() {
 		setupLog.Error(templateErr, "failed to parse the redirect notice HTML template")
 		os.Exit(1)
 	}
+	pages, pagesErr := loadPages(ctx, args, inClusterK8sClient)
+	if pagesErr != nil {
+		setupLog.Error(pagesErr, "failed to load the templates of the OAuth flow result pages")
+		os.Exit(1)
+	}
 	routerCfg := oauth.RouterConfiguration{
 		OAuthServiceConfiguration: cfg,
 		Authenticator:             authenticator,
@@ -156,17 +191,42 @@ func main() {
 		InClusterK8sClient:        inClusterK8sClient,
 		TokenStorage:              tokenStorage,
 		RedirectTemplate:          redirectTpl,
+		Pages:                     pages,
 	}
-	oauthRouter, routerErr := oauth.NewRouter(ctx, routerCfg, config.SupportedServiceProviderTypes)
+	// the background work of the router, like the device flow polling, is cancelled when the server shuts down
+	routerCtx, cancelRouter := context.WithCancel(ctx)
+	defer cancelRouter()
+	oauthRouter, routerErr := oauth.NewRouter(routerCtx, routerCfg, config.SupportedServiceProviderTypes)
 	if routerErr != nil {
 		setupLog.Error(routerErr, "failed to initialize oauth router")
 		os.Exit(1)
 	}
 
-	router.Handle("/callback_success", oauth.CSPHandler(oauth.CallbackSuccessHandler())).Methods("GET")
-	router.NewRoute().Path(oauth2.CallBackRoutePath).Queries("error", "", "error_description", "").Handler(oauth.CSPHandler(oauth.CallbackErrorHandler()))
+	router.Handle("/callback_success", oauth.CSPHandler(oauth.CallbackSuccessHandler(pages))).Methods("GET")
+	router.NewRoute().Path(oauth2.CallBackRoutePath).Queries("error", "", "error_description", "").Handler(oauth.CSPHandler(oauthRouter.CallbackError()))
 	router.NewRoute().Path(oauth2.CallBackRoutePath).Handler(oauthRouter.Callback())
 	router.NewRoute().Path(oauth2.AuthenticateRoutePath).Handler(oauth.CSPHandler(oauthRouter.Authenticate()))
+	router.NewRoute().Path("/device/{namespace}/{name}").Handler(oauthRouter.DeviceAuthorization()).Methods("POST")
+
+	// JSON API for web clients
+	router.NewRoute().Path("/api/v1/tokens/{namespace}/{name}").Handler(oauthRouter.TokenStatus()).Methods("GET")
+	router.NewRoute().Path("/api/v1/tokens/{namespace}/{name}/authorize").Handler(oauthRouter.AuthorizationUrl()).Methods("POST")
+	router.NewRoute().Path("/api/v1/flows/{stateId}").Handler(oauthRouter.FlowStatus()).Methods("GET")
+
+	var rateLimiter *oauth.RateLimiter
+	if args.RateLimits != "" {
+		rateLimits, rateLimitsErr := oauth.ParseRateLimits(args.RateLimits)
+		if rateLimitsErr != nil {
+			setupLog.Error(rateLimitsErr, "failed to parse the rate limits")
+			os.Exit(1)
+		}
+		trustedProxies, trustedProxiesErr := oauth.ParseTrustedProxies(args.RateLimitTrustedProxies)
+		if trustedProxiesErr != nil {
+			setupLog.Error(trustedProxiesErr, "failed to parse the trusted proxies")
+			os.Exit(1)
+		}
+		rateLimiter = oauth.NewRateLimiter(rateLimits, sessionManager, trustedProxies)
+	}
 
 	setupLog.Info("Starting the server", "Addr", args.ServiceAddr)
 	server := &http.Server{
@@ -176,7 +236,7 @@ func main() {
 		ReadTimeout:       time.Second * 15,
 		ReadHeaderTimeout: time.Second * 15,
 		IdleTimeout:       time.Second * 60,
-		Handler:           sessionManager.LoadAndSave(oauth.MiddlewareHandler(metrics.Registry, strings.Split(args.AllowedOrigins, ","), router)),
+		Handler:           sessionManager.LoadAndSave(oauth.MiddlewareHandler(metrics.Registry, strings.Split(args.AllowedOrigins, ","), rateLimiter, router)),
 	}
 
 	if args.DisableHTTP2 {
@@ -198,6 +258,7 @@ func main() {
 	// Waiting for SIGINT (kill -2)
 	<-stop
 	setupLog.Info("Server got interrupt signal, going to gracefully shutdown the server", "signal", stop)
+	cancelRouter()
 	// Create a deadline to wait for.
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -214,15 +275,65 @@ func main() {
 	os.Exit(0)
 }
 
+// createSessionStore creates the store of the user sessions according to the configured backend.
+func createSessionStore(ctx context.Context, args cli.OAuthServiceCliArgs, cl client.Client) (scs.Store, error) {
+	switch args.SessionStore {
+	case cli.SessionStoreMemory:
+		return memstore.NewWithCleanupInterval(5 * time.Minute), nil
+	case cli.SessionStoreKubernetes:
+		if args.SessionStoreNamespace == "" {
+			return nil, fmt.Errorf("%w: the namespace of the kubernetes session store must be configured", invalidSessionStoreError)
+		}
+		if args.SessionStoreKeyFile == "" {
+			return nil, fmt.Errorf("%w: the key file of the kubernetes session store must be configured", invalidSessionStoreError)
+		}
+		key, err := os.ReadFile(args.SessionStoreKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the key of the kubernetes session store: %w", err)
+		}
+		store, err := oauth.NewKubernetesSessionStore(ctx, cl, args.SessionStoreNamespace, bytes.TrimSpace(key), 5*time.Minute)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the kubernetes session store: %w", err)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown session store '%s'", invalidSessionStoreError, args.SessionStore)
+	}
+}
+
 func loadOAuthServiceConfiguration(args cli.OAuthServiceCliArgs) (oauth.OAuthServiceConfiguration, error) {
 	baseCfg, err := config.LoadFrom(args.ConfigFile, args.BaseUrl)
 	if err != nil {
 		return oauth.OAuthServiceConfiguration{}, fmt.Errorf("failed to load the configuration from file %s: %w", args.ConfigFile, err)
 	}
 	cfg := oauth.OAuthServiceConfiguration{SharedConfiguration: baseCfg, RedirectProxyUrl: args.OAuthRedirectProxyUrl}
+	if args.RedirectAfterUrls != "" {
+		cfg.AllowedRedirectAfterUrls = strings.Split(args.RedirectAfterUrls, ",")
+	}
 	err = rconfig.ValidateStruct(cfg)
 	if err != nil {
 		return oauth.OAuthServiceConfiguration{}, fmt.Errorf("oauth service configuration validation failed: %w", err)
 	}
 	return cfg, nil
 }
+
+// loadPages loads the templates of the OAuth flow result pages either from the configured ConfigMap or directory.
+func loadPages(ctx context.Context, args cli.OAuthServiceCliArgs, cl client.Client) (*oauth.Pages, error) {
+	if args.PagesConfigMap == "" {
+		pages, err := oauth.LoadPagesFromDir(args.PagesDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the pages from directory %s: %w", args.PagesDir, err)
+		}
+		return pages, nil
+	}
+
+	namespace, name, found := strings.Cut(args.PagesConfigMap, "/")
+	if !found {
+		return nil, fmt.Errorf("%w: %s", invalidPagesConfigMapError, args.PagesConfigMap)
+	}
+	pages, err := oauth.LoadPagesFromConfigMap(ctx, cl, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the pages from ConfigMap: %w", err)
+	}
+	return pages, nil
+}
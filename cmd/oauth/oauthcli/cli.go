@@ -18,13 +18,32 @@ import (
 	"github.com/redhat-appstudio/service-provider-integration-operator/cmd"
 )
 
+const (
+	// SessionStoreMemory keeps the sessions in the memory of each replica of the OAuth service.
+	SessionStoreMemory = "memory"
+	// SessionStoreKubernetes keeps the sessions in Secrets, so that they are shared by all the replicas.
+	SessionStoreKubernetes = "kubernetes"
+)
+
 type OAuthServiceCliArgs struct {
 	cmd.CommonCliArgs
-	ServiceAddr           string `arg:"--service-addr, env" default:"0.0.0.0:8000" help:"Service address to listen on"`
-	AllowedOrigins        string `arg:"--allowed-origins, env" default:"https://console.redhat.com,https://console.stage.redhat.com,https://console.dev.redhat.com,https://prod.foo.redhat.com:1337" help:"Comma-separated list of domains allowed for cross-domain requests"`
-	KubeConfig            string `arg:"--kubeconfig, env" default:"" help:""`
-	KubeInsecureTLS       bool   `arg:"--kube-insecure-tls, env" default:"false" help:"Whether is allowed or not insecure kubernetes tls connection."`
-	ApiServer             string `arg:"--api-server, env:API_SERVER" default:"" help:"host:port of the Kubernetes API server to use when handling HTTP requests"`
-	ApiServerCAPath       string `arg:"--ca-path, env:API_SERVER_CA_PATH" default:"" help:"the path to the CA certificate to use when connecting to the Kubernetes API server"`
-	OAuthRedirectProxyUrl string `arg:"--oauth-redirect-proxy-url, env:OAUTH_REDIRECT_PROXY_URL" default:"" help:"the URL of OAuth redirection proxy used in the tests to maintain predictable callback URL"`
+	ServiceAddr             string `arg:"--service-addr, env" default:"0.0.0.0:8000" help:"Service address to listen on"`
+	AllowedOrigins          string `arg:"--allowed-origins, env" default:"https://console.redhat.com,https://console.stage.redhat.com,https://console.dev.redhat.com,https://prod.foo.redhat.com:1337" help:"Comma-separated list of domains allowed for cross-domain requests"`
+	KubeConfig              string `arg:"--kubeconfig, env" default:"" help:""`
+	KubeInsecureTLS         bool   `arg:"--kube-insecure-tls, env" default:"false" help:"Whether is allowed or not insecure kubernetes tls connection."`
+	ApiServer               string `arg:"--api-server, env:API_SERVER" default:"" help:"host:port of the Kubernetes API server to use when handling HTTP requests"`
+	ApiServerCAPath         string `arg:"--ca-path, env:API_SERVER_CA_PATH" default:"" help:"the path to the CA certificate to use when connecting to the Kubernetes API server"`
+	OAuthRedirectProxyUrl   string `arg:"--oauth-redirect-proxy-url, env:OAUTH_REDIRECT_PROXY_URL" default:"" help:"the URL of OAuth redirection proxy used in the tests to maintain predictable callback URL"`
+	SessionStore            string `arg:"--session-store, env:SESSION_STORE" default:"memory" help:"the backend of the user sessions and OAuth states. Either 'memory' (not shared between replicas) or 'kubernetes' (stored in Secrets shared by all replicas)"`
+	SessionStoreNamespace   string `arg:"--session-store-namespace, env:SESSION_STORE_NAMESPACE" default:"" help:"the namespace of the Secrets holding the sessions when the 'kubernetes' session store is used"`
+	SessionStoreKeyFile     string `arg:"--session-store-key-file, env:SESSION_STORE_KEY_FILE" default:"" help:"the file with the key used to encrypt the sessions when the 'kubernetes' session store is used"`
+	OIDCIssuerUrl           string `arg:"--oidc-issuer-url, env:OIDC_ISSUER_URL" default:"" help:"the URL of the OIDC issuer used to log in the users. The OIDC login is disabled if empty"`
+	OIDCClientId            string `arg:"--oidc-client-id, env:OIDC_CLIENT_ID" default:"" help:"the client ID of the OAuth service in the OIDC issuer"`
+	OIDCClientSecret        string `arg:"--oidc-client-secret, env:OIDC_CLIENT_SECRET" default:"" help:"the client secret of the OAuth service in the OIDC issuer"`
+	OIDCScopes              string `arg:"--oidc-scopes, env:OIDC_SCOPES" default:"openid,email,profile" help:"comma-separated list of the scopes requested from the OIDC issuer"`
+	PagesDir                string `arg:"--pages-dir, env:PAGES_DIR" default:"static" help:"the directory with the templates of the pages shown at the end of the OAuth flow"`
+	PagesConfigMap          string `arg:"--pages-configmap, env:PAGES_CONFIGMAP" default:"" help:"the ConfigMap in the form 'namespace/name' with the templates of the pages shown at the end of the OAuth flow. Takes precedence over --pages-dir if set"`
+	RedirectAfterUrls       string `arg:"--redirect-after-urls, env:REDIRECT_AFTER_URLS" default:"" help:"comma-separated list of URLs to which the users can be redirected at the end of the OAuth flow using the redirect_after parameter"`
+	RateLimits              string `arg:"--rate-limits, env:RATE_LIMITS" default:"login.ip=20/1m,authenticate.ip=60/1m,authenticate.identity=30/1m,callback.ip=60/1m,upload.ip=60/1m,upload.identity=30/1m,device.ip=20/1m,device.identity=10/1m,api.ip=120/1m,api.identity=60/1m" help:"comma-separated list of the rate limits of the routes in the form '<route>.<ip|identity>=<requests>/<period>'. The rate limiting is disabled if empty"`
+	RateLimitTrustedProxies string `arg:"--rate-limit-trusted-proxies, env:RATE_LIMIT_TRUSTED_PROXIES" default:"" help:"comma-separated list of the IP addresses or CIDRs of the proxies in front of the service whose X-Forwarded-For header is used to determine the IP address of the client. Only list the proxies that overwrite or append to the header"`
 }
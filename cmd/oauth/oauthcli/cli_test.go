@@ -100,3 +100,22 @@ func parseWithEnv(cmdline string, env []string, dest interface{}) (*arg.Parser,
 	// execute the parser
 	return p, p.Parse(parts)
 }
+
+func TestSessionStoreParse(t *testing.T) {
+	//given
+	cmd := ""
+	env := []string{"SESSION_STORE=kubernetes", "SESSION_STORE_NAMESPACE=spi-system"}
+	//then
+	args := OAuthServiceCliArgs{}
+	_, err := parseWithEnv(cmd, env, &args)
+	//when
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.SessionStore != SessionStoreKubernetes {
+		t.Fatal("Unable to parse session store")
+	}
+	if args.SessionStoreNamespace != "spi-system" {
+		t.Fatal("Unable to parse session store namespace")
+	}
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"os"
 
@@ -62,6 +63,8 @@ import (
 )
 
 var (
+	errInvalidTokenSelectionStrategy = errors.New("invalid token selection strategy")
+
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
 
@@ -116,6 +119,9 @@ func main() {
 		setupLog.Error(err, "Failed to load the configuration")
 		os.Exit(1)
 	}
+	if !cfg.OAuthState.Signed() {
+		setupLog.Info("WARNING: no OAuth state keys are configured, the OAuth state is neither signed nor verified. The unsigned state is only supported for backward compatibility, configure the keys in the 'oauthState' section of the configuration")
+	}
 
 	secretStorage, err := rcmd.CreateInitializedSecretStorage(ctx, &args.CommonCliArgs.CommonCliArgs)
 	if err != nil {
@@ -159,9 +165,16 @@ func LoadFrom(args *cli.OperatorCliArgs) (opconfig.OperatorConfiguration, error)
 	ret.AccessTokenBindingTtl = args.BindingLifetimeDuration
 	ret.FileContentRequestTtl = args.FileRequestLifetimeDuration
 	ret.TokenMatchPolicy = args.TokenMatchPolicy
+	if !args.TokenSelectionStrategy.IsValid() {
+		return opconfig.OperatorConfiguration{}, fmt.Errorf("%w: %s", errInvalidTokenSelectionStrategy, args.TokenSelectionStrategy)
+	}
+	ret.TokenSelectionStrategy = args.TokenSelectionStrategy
 	ret.DeletionGracePeriod = args.DeletionGracePeriod
 	ret.MaxFileDownloadSize = args.MaxFileDownloadSize
+	ret.MaxTargetFileDownloadSize = args.MaxTargetFileDownloadSize
+	ret.LookupFileLastModified = args.LookupFileLastModified
 	ret.EnableTokenUpload = args.EnableTokenUpload
+	ret.EnableWebhooks = args.EnableWebhooks
 
 	return ret, nil
 }
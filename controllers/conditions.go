@@ -0,0 +1,42 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// conditionReasonUnknownError is used as the reason of failed conditions for which we don't have any more specific
+// reason. Kubernetes requires the reason of a condition to be non-empty.
+const conditionReasonUnknownError = "Error"
+
+// setCondition sets the condition of the given type in the provided list of conditions, recording the current
+// generation of the object as the observed generation of the condition. The last transition time is only updated
+// if the status of the condition changes.
+func setCondition[T ~string, R ~string](conditions *[]metav1.Condition, obj client.Object, conditionType T, status metav1.ConditionStatus, reason R, message string) {
+	r := string(reason)
+	if r == "" {
+		r = conditionReasonUnknownError
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               string(conditionType),
+		Status:             status,
+		ObservedGeneration: obj.GetGeneration(),
+		Reason:             r,
+		Message:            message,
+	})
+}
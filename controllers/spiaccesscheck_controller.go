@@ -18,21 +18,29 @@ package controllers
 
 import (
 	"context"
-	stderrors "errors"
 	"fmt"
 	"time"
 
-	"github.com/go-playground/validator/v10"
-
 	"github.com/go-logr/logr"
 
 	opconfig "github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
 
 	"github.com/redhat-appstudio/remote-secret/pkg/logs"
 
+	"github.com/redhat-appstudio/remote-secret/api/v1beta1"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -41,17 +49,28 @@ import (
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 )
 
+const (
+	// minimalAccessCheckRefreshInterval is the lower bound of the refresh interval of the long-lived access checks, so
+	// that we don't exhaust the rate limits of the service providers.
+	minimalAccessCheckRefreshInterval = 30 * time.Second
+
+	accessibilityChangedEventReason = "AccessibilityChanged"
+)
+
 // SPIAccessCheckReconciler reconciles a SPIAccessCheck object
 type SPIAccessCheckReconciler struct {
 	client.Client
 	Scheme                 *runtime.Scheme
 	ServiceProviderFactory serviceprovider.Factory
 	Configuration          *opconfig.OperatorConfiguration
+	Recorder               record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spiaccesschecks,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spiaccesschecks/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spiaccesschecks/finalizers,verbs=update
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=remotesecrets,verbs=list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *SPIAccessCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	lg := log.FromContext(ctx)
@@ -66,7 +85,7 @@ func (r *SPIAccessCheckReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, fmt.Errorf("failed to load the SPIAccessCheck from the cluster: %w", err)
 	}
 
-	if time.Now().After(ac.ObjectMeta.CreationTimestamp.Add(r.Configuration.AccessCheckTtl)) {
+	if !ac.Spec.IsLongLived() && time.Now().After(ac.ObjectMeta.CreationTimestamp.Add(r.Configuration.AccessCheckTtl)) {
 		lg.Info("SPIAccessCheck is after ttl, deleting ...")
 		if deleteError := r.Delete(ctx, &ac); deleteError != nil {
 			return ctrl.Result{Requeue: true}, fmt.Errorf("error while deleting accesscheck: %w", deleteError)
@@ -76,10 +95,13 @@ func (r *SPIAccessCheckReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 	}
 
+	previousStatus := ac.Status.DeepCopy()
+
 	if sp, spErr := r.ServiceProviderFactory.FromRepoUrl(ctx, ac.Spec.RepoUrl, req.Namespace); spErr == nil {
 		auditLog := log.FromContext(ctx, "audit", "true", "namespace", ac.Namespace, "token", ac.Name, "repository", ac.Spec.RepoUrl)
 		auditLog.Info("performing repository access check", "action", "UPDATE")
 		if status, repoCheckErr := sp.CheckRepositoryAccess(ctx, r.Client, &ac); repoCheckErr == nil {
+			status.Conditions = ac.Status.Conditions
 			ac.Status = *status
 			auditLog.Info("repository access check succeeded")
 		} else {
@@ -87,30 +109,81 @@ func (r *SPIAccessCheckReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			return ctrl.Result{}, fmt.Errorf("failed to check repository access: %w", repoCheckErr)
 		}
 	} else {
-		var validationErr validator.ValidationErrors
-		if stderrors.As(spErr, &validationErr) {
-			lg.Error(spErr, "failed to validate service provider for SPIAccessCheck")
-			ac.Status.ErrorReason = api.SPIAccessCheckErrorUnsupportedServiceProviderConfiguration
-			ac.Status.ErrorMessage = spErr.Error()
-		} else {
-			lg.Error(spErr, "failed to determine service provider for SPIAccessCheck")
-			ac.Status.ErrorReason = api.SPIAccessCheckErrorUnknownServiceProvider
-			ac.Status.ErrorMessage = spErr.Error()
-		}
+		lg.Error(spErr, "failed to determine service provider for SPIAccessCheck")
+		ac.Status.ErrorReason = serviceProviderErrorReason(spErr)
+		ac.Status.ErrorMessage = spErr.Error()
 	}
 
+	transitioned := recordCheckTime(&ac, previousStatus)
+	setAccessibleCondition(&ac)
+
 	if updateErr := r.Client.Status().Update(ctx, &ac); updateErr != nil {
 		lg.Error(updateErr, "Failed to update status")
 		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", updateErr)
-	} else {
-		return ctrl.Result{RequeueAfter: r.Configuration.AccessCheckTtl}, nil
+	}
+
+	if transitioned {
+		if ac.Status.Accessible {
+			r.Recorder.Eventf(&ac, corev1.EventTypeNormal, accessibilityChangedEventReason, "the repository is now accessible, accessibility: %s", ac.Status.Accessibility)
+		} else {
+			r.Recorder.Event(&ac, corev1.EventTypeNormal, accessibilityChangedEventReason, "the repository is no longer accessible")
+		}
+	}
+
+	if ac.Spec.IsLongLived() {
+		return ctrl.Result{RequeueAfter: accessCheckRefreshInterval(&ac)}, nil
+	}
+	return ctrl.Result{RequeueAfter: r.Configuration.AccessCheckTtl}, nil
+}
+
+// recordCheckTime records the time of the evaluation into the status of the access check. It returns true if the
+// accessibility of the repository changed since the previous evaluation.
+func recordCheckTime(ac *api.SPIAccessCheck, previous *api.SPIAccessCheckStatus) bool {
+	now := metav1.Now()
+	ac.Status.LastCheckTime = &now
+	ac.Status.LastTransitionTime = previous.LastTransitionTime
+
+	checkedBefore := previous.LastCheckTime != nil
+	if checkedBefore && previous.Accessible == ac.Status.Accessible {
+		return false
+	}
+	ac.Status.LastTransitionTime = &now
+	return checkedBefore
+}
+
+func accessCheckRefreshInterval(ac *api.SPIAccessCheck) time.Duration {
+	if ac.Spec.RefreshInterval == nil || ac.Spec.RefreshInterval.Duration < minimalAccessCheckRefreshInterval {
+		return minimalAccessCheckRefreshInterval
+	}
+	return ac.Spec.RefreshInterval.Duration
+}
+
+// setAccessibleCondition sets the Accessible condition of the access check according to the results of the check
+// stored in its status.
+func setAccessibleCondition(ac *api.SPIAccessCheck) {
+	switch {
+	case ac.Status.ErrorReason != "":
+		setCondition(&ac.Status.Conditions, ac, api.SPIAccessCheckConditionTypeAccessible, metav1.ConditionFalse, ac.Status.ErrorReason, ac.Status.ErrorMessage)
+	case ac.Status.Accessible:
+		setCondition(&ac.Status.Conditions, ac, api.SPIAccessCheckConditionTypeAccessible, metav1.ConditionTrue, api.SPIAccessCheckReasonAccessible,
+			fmt.Sprintf("the repository is accessible, accessibility: %s", ac.Status.Accessibility))
+	default:
+		setCondition(&ac.Status.Conditions, ac, api.SPIAccessCheckConditionTypeAccessible, metav1.ConditionFalse, api.SPIAccessCheckReasonNotAccessible,
+			"the repository is not accessible, it either doesn't exist or no suitable credentials have been found")
 	}
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SPIAccessCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	err := ctrl.NewControllerManagedBy(mgr).
-		For(&api.SPIAccessCheck{}).
+		// the status updates must not trigger another evaluation, otherwise the checks would be evaluated in a loop
+		For(&api.SPIAccessCheck{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &api.SPIAccessToken{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			return r.longLivedChecksForHost(context.Background(), o, o.GetLabels()[api.ServiceProviderHostLabel], "SPIAccessToken")
+		}), builder.WithPredicates(tokenReadinessChangedPredicate)).
+		Watches(&source.Kind{Type: &v1beta1.RemoteSecret{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			return r.longLivedChecksForHost(context.Background(), o, o.GetLabels()[api.RSServiceProviderHostLabel], "RemoteSecret")
+		})).
 		Complete(r)
 	if err != nil {
 		err = fmt.Errorf("failed to build the controller manager: %w", err)
@@ -118,3 +191,54 @@ func (r *SPIAccessCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	return err
 }
+
+// tokenReadinessChangedPredicate filters out the updates of the tokens that cannot change the accessibility of
+// the repositories, like the updates of the token metadata that happen during the access checks themselves.
+var tokenReadinessChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldToken, oldOk := e.ObjectOld.(*api.SPIAccessToken)
+		newToken, newOk := e.ObjectNew.(*api.SPIAccessToken)
+		if !oldOk || !newOk {
+			return true
+		}
+		return oldToken.Status.Phase != newToken.Status.Phase ||
+			oldToken.Generation != newToken.Generation ||
+			oldToken.Labels[api.ServiceProviderHostLabel] != newToken.Labels[api.ServiceProviderHostLabel]
+	},
+}
+
+// longLivedChecksForHost returns the reconcile requests for the long-lived access checks in the namespace of the object
+// that check a repository on the provided host.
+func (r *SPIAccessCheckReconciler) longLivedChecksForHost(ctx context.Context, o client.Object, host string, objectKind string) []reconcile.Request {
+	if host == "" {
+		return []reconcile.Request{}
+	}
+
+	checks := &api.SPIAccessCheckList{}
+	if err := r.Client.List(ctx, checks, client.InNamespace(o.GetNamespace())); err != nil {
+		enqueueLog.Error(err, "failed to list SPIAccessChecks while determining the ones affected by "+objectKind,
+			"name", o.GetName(), "namespace", o.GetNamespace())
+		return []reconcile.Request{}
+	}
+
+	requests := []reconcile.Request{}
+	for _, ac := range checks.Items {
+		if !ac.Spec.IsLongLived() {
+			continue
+		}
+		repoUrl, err := serviceprovider.RepoUrlFromSchemalessString(ac.Spec.RepoUrl)
+		if err != nil || repoUrl.Host != host {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      ac.Name,
+				Namespace: ac.Namespace,
+			},
+		})
+	}
+
+	logReconciliationRequests(requests, "SPIAccessCheck", o, objectKind)
+
+	return requests
+}
@@ -0,0 +1,115 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRecordCheckTime(t *testing.T) {
+	t.Run("first check", func(t *testing.T) {
+		ac := &api.SPIAccessCheck{Status: api.SPIAccessCheckStatus{Accessible: true}}
+
+		assert.False(t, recordCheckTime(ac, &api.SPIAccessCheckStatus{}))
+		assert.NotNil(t, ac.Status.LastCheckTime)
+		assert.Equal(t, ac.Status.LastCheckTime, ac.Status.LastTransitionTime)
+	})
+
+	t.Run("no change", func(t *testing.T) {
+		lastCheck := metav1.NewTime(time.Now().Add(-time.Hour))
+		ac := &api.SPIAccessCheck{Status: api.SPIAccessCheckStatus{Accessible: true}}
+
+		assert.False(t, recordCheckTime(ac, &api.SPIAccessCheckStatus{Accessible: true, LastCheckTime: &lastCheck, LastTransitionTime: &lastCheck}))
+		assert.True(t, ac.Status.LastCheckTime.After(lastCheck.Time))
+		assert.Equal(t, &lastCheck, ac.Status.LastTransitionTime)
+	})
+
+	t.Run("accessibility flipped", func(t *testing.T) {
+		lastCheck := metav1.NewTime(time.Now().Add(-time.Hour))
+		ac := &api.SPIAccessCheck{Status: api.SPIAccessCheckStatus{Accessible: false}}
+
+		assert.True(t, recordCheckTime(ac, &api.SPIAccessCheckStatus{Accessible: true, LastCheckTime: &lastCheck, LastTransitionTime: &lastCheck}))
+		assert.Equal(t, ac.Status.LastCheckTime, ac.Status.LastTransitionTime)
+	})
+}
+
+func TestAccessCheckRefreshInterval(t *testing.T) {
+	ac := func(interval time.Duration) *api.SPIAccessCheck {
+		return &api.SPIAccessCheck{Spec: api.SPIAccessCheckSpec{RefreshInterval: &metav1.Duration{Duration: interval}}}
+	}
+
+	assert.Equal(t, 5*time.Minute, accessCheckRefreshInterval(ac(5*time.Minute)))
+	assert.Equal(t, minimalAccessCheckRefreshInterval, accessCheckRefreshInterval(ac(time.Second)))
+}
+
+func TestLongLivedChecksForHost(t *testing.T) {
+	check := func(name, repoUrl string, longLived bool) *api.SPIAccessCheck {
+		ac := &api.SPIAccessCheck{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       api.SPIAccessCheckSpec{RepoUrl: repoUrl},
+		}
+		if longLived {
+			ac.Spec.RefreshInterval = &metav1.Duration{Duration: time.Minute}
+		}
+		return ac
+	}
+	cl := mockK8sClient(
+		check("github", "https://github.com/acme/repo", true),
+		check("github-short-lived", "https://github.com/acme/repo", false),
+		check("quay", "quay.io/acme/repo", true),
+	)
+	r := &SPIAccessCheckReconciler{Client: cl}
+	token := &api.SPIAccessToken{ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "default"}}
+
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "github", Namespace: "default"}}},
+		r.longLivedChecksForHost(context.TODO(), token, "github.com", "SPIAccessToken"))
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "quay", Namespace: "default"}}},
+		r.longLivedChecksForHost(context.TODO(), token, "quay.io", "SPIAccessToken"))
+	assert.Empty(t, r.longLivedChecksForHost(context.TODO(), token, "", "SPIAccessToken"))
+	assert.Empty(t, r.longLivedChecksForHost(context.TODO(), &api.SPIAccessToken{ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "other"}}, "github.com", "SPIAccessToken"))
+}
+
+func TestTokenReadinessChangedPredicate(t *testing.T) {
+	token := func(phase api.SPIAccessTokenPhase, host string) *api.SPIAccessToken {
+		return &api.SPIAccessToken{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{api.ServiceProviderHostLabel: host}},
+			Status:     api.SPIAccessTokenStatus{Phase: phase},
+		}
+	}
+
+	assert.False(t, tokenReadinessChangedPredicate.Update(event.UpdateEvent{
+		ObjectOld: token(api.SPIAccessTokenPhaseReady, "github.com"),
+		ObjectNew: token(api.SPIAccessTokenPhaseReady, "github.com"),
+	}))
+	assert.True(t, tokenReadinessChangedPredicate.Update(event.UpdateEvent{
+		ObjectOld: token(api.SPIAccessTokenPhaseAwaitingTokenData, "github.com"),
+		ObjectNew: token(api.SPIAccessTokenPhaseReady, "github.com"),
+	}))
+	assert.True(t, tokenReadinessChangedPredicate.Update(event.UpdateEvent{
+		ObjectOld: token(api.SPIAccessTokenPhaseReady, "github.com"),
+		ObjectNew: token(api.SPIAccessTokenPhaseReady, "gitlab.com"),
+	}))
+	assert.True(t, tokenReadinessChangedPredicate.Create(event.CreateEvent{Object: token(api.SPIAccessTokenPhaseReady, "github.com")}))
+	assert.True(t, tokenReadinessChangedPredicate.Delete(event.DeleteEvent{Object: token(api.SPIAccessTokenPhaseReady, "github.com")}))
+}
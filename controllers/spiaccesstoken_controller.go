@@ -21,6 +21,8 @@ import (
 	stderrors "errors"
 	"fmt"
 	"net/url"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/redhat-appstudio/remote-secret/pkg/rerror"
@@ -46,6 +48,7 @@ import (
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -92,6 +95,12 @@ func (r *SPIAccessTokenReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&api.SPIAccessToken{}).
 		// We're watching the bindings so that we can remove abandoned tokens without data
 		Watches(&source.Kind{Type: &api.SPIAccessTokenBinding{}}, handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+			if binding, ok := object.(*api.SPIAccessTokenBinding); ok && binding.Status.LinkedAccessTokenNamespace != "" {
+				// the binding uses a token shared from another namespace
+				reqs := []reconcile.Request{{NamespacedName: linkedTokenKey(binding)}}
+				logReconciliationRequests(reqs, "SPIAccessToken", object, "SPIAccessTokenBinding")
+				return reqs
+			}
 			return requestsForTokenInObjectNamespace(object, "SPIAccessTokenBinding", func() string {
 				return object.GetLabels()[SPIAccessTokenLinkLabel]
 			})
@@ -286,13 +295,26 @@ func (r *SPIAccessTokenReconciler) reconcileTokenData(ctx context.Context, at *a
 }
 
 func (r *SPIAccessTokenReconciler) durationUntilNextReconcile(at *api.SPIAccessToken) time.Duration {
-	return time.Until(at.CreationTimestamp.Add(r.Configuration.AccessTokenTtl).Add(r.Configuration.DeletionGracePeriod))
+	untilDeletion := time.Until(at.CreationTimestamp.Add(r.Configuration.AccessTokenTtl).Add(r.Configuration.DeletionGracePeriod))
+
+	// the OAuth URL with a signed state needs to be refreshed before the state expires
+	if at.Status.OAuthUrl != "" {
+		oauthBaseUrl := at.Status.OAuthUrl[:strings.Index(at.Status.OAuthUrl+"?", "?")]
+		if _, refreshAt, ok := parseOAuthUrl(oauthstate.NewCodec(r.Configuration.OAuthState), at.Status.OAuthUrl, oauthBaseUrl); ok && !refreshAt.IsZero() {
+			if untilRefresh := time.Until(refreshAt); untilRefresh < untilDeletion {
+				return untilRefresh
+			}
+		}
+	}
+
+	return untilDeletion
 }
 
 func (r *SPIAccessTokenReconciler) flipToExceptionalPhase(ctx context.Context, at *api.SPIAccessToken, phase api.SPIAccessTokenPhase, reason api.SPIAccessTokenErrorReason, err error) error {
 	at.Status.Phase = phase
 	at.Status.ErrorMessage = err.Error()
 	at.Status.ErrorReason = reason
+	setCondition(&at.Status.Conditions, at, api.SPIAccessTokenConditionTypeMetadataFetched, metav1.ConditionFalse, reason, err.Error())
 	if uerr := r.Client.Status().Update(ctx, at); uerr != nil {
 		log.FromContext(ctx).Error(uerr, "failed to update the status with error", "reason", reason, "token_error", err)
 		return fmt.Errorf("failed to update the status with error: %w", uerr)
@@ -323,10 +345,14 @@ func (r *SPIAccessTokenReconciler) fillInStatus(ctx context.Context, at *api.SPI
 
 		at.Status.OAuthUrl = oauthUrl
 		at.Status.Phase = api.SPIAccessTokenPhaseAwaitingTokenData
+		setCondition(&at.Status.Conditions, at, api.SPIAccessTokenConditionTypeMetadataFetched, metav1.ConditionFalse,
+			api.SPIAccessTokenReasonAwaitingTokenData, "the token data has not been provided yet")
 	} else {
 		changed := at.Status.Phase != api.SPIAccessTokenPhaseReady || at.Status.OAuthUrl != ""
 		at.Status.Phase = api.SPIAccessTokenPhaseReady
 		at.Status.OAuthUrl = ""
+		setCondition(&at.Status.Conditions, at, api.SPIAccessTokenConditionTypeMetadataFetched, metav1.ConditionTrue,
+			api.SPIAccessTokenReasonMetadataFetched, "the metadata of the token has been fetched from the service provider")
 		if changed {
 			log.FromContext(ctx).V(logs.DebugLevel).Info("Flipping token to ready state because of metadata presence", "metadata", at.Status.TokenMetadata)
 		}
@@ -359,13 +385,22 @@ func (r *SPIAccessTokenReconciler) oAuthUrlFor(ctx context.Context, at *api.SPIA
 		return "", nil
 	}
 
-	state, err := oauthstate.Encode(&oauthstate.OAuthInfo{
+	info := oauthstate.OAuthInfo{
 		TokenName:           at.Name,
 		TokenNamespace:      at.Namespace,
 		Scopes:              oauthCapability.OAuthScopesFor(&at.Spec.Permissions),
 		ServiceProviderName: sp.GetType().Name,
 		ServiceProviderUrl:  sp.GetBaseUrl(),
-	})
+	}
+
+	// The signed states differ each time they are encoded. Let's keep the current URL for as long as it is usable so
+	// that the status update doesn't trigger another reconciliation.
+	codec := oauthstate.NewCodec(r.Configuration.OAuthState)
+	if current, refreshAt, ok := parseOAuthUrl(codec, at.Status.OAuthUrl, oauthBaseUrl); ok && reflect.DeepEqual(current, info) && (refreshAt.IsZero() || time.Now().Before(refreshAt)) {
+		return at.Status.OAuthUrl, nil
+	}
+
+	state, err := codec.Encode(&info)
 	if err != nil {
 		return "", fmt.Errorf("failed to encode the OAuth state: %w", err)
 	}
@@ -373,6 +408,30 @@ func (r *SPIAccessTokenReconciler) oAuthUrlFor(ctx context.Context, at *api.SPIA
 	return oauthBaseUrl + "?state=" + state, nil
 }
 
+// parseOAuthUrl parses the state from the OAuth URL with the given base URL. It also returns the time after which
+// the URL should be replaced with a new one, because its state is about to expire. This time is zero for unsigned
+// states that never expire.
+func parseOAuthUrl(codec *oauthstate.Codec, oauthUrl string, oauthBaseUrl string) (oauthstate.OAuthInfo, time.Time, bool) {
+	if !strings.HasPrefix(oauthUrl, oauthBaseUrl+"?") {
+		return oauthstate.OAuthInfo{}, time.Time{}, false
+	}
+
+	parsedUrl, err := url.Parse(oauthUrl)
+	if err != nil {
+		return oauthstate.OAuthInfo{}, time.Time{}, false
+	}
+
+	info, expiresAt, err := codec.ParseOAuthInfo(parsedUrl.Query().Get("state"))
+	if err != nil {
+		return oauthstate.OAuthInfo{}, time.Time{}, false
+	}
+
+	if expiresAt.IsZero() {
+		return info, time.Time{}, true
+	}
+	return info, expiresAt.Add(-codec.Lifetime() / 2), true
+}
+
 func (r *SPIAccessTokenReconciler) refreshToken(ctx context.Context, at *api.SPIAccessToken, sp serviceprovider.ServiceProvider) error {
 	lg := logs.AuditLog(ctx)
 	lg.Info("initiated token refresh", "action", "UPDATE")
@@ -453,15 +512,26 @@ func (f *tokenStorageFinalizer) Finalize(ctx context.Context, obj client.Object)
 	return finalizer.Result{}, err
 }
 
+// hasLinkedBindings checks whether there are bindings linked to the token, including the bindings from other namespaces
+// the token is shared with using an SPIAccessTokenSharingPolicy.
 func hasLinkedBindings(ctx context.Context, token *api.SPIAccessToken, k8sClient client.Client) (bool, error) {
 	list := &api.SPIAccessTokenBindingList{}
-	if err := k8sClient.List(ctx, list, client.InNamespace(token.Namespace), client.Limit(1), client.MatchingLabels{
+	if err := k8sClient.List(ctx, list, client.MatchingLabels{
 		SPIAccessTokenLinkLabel: token.Name,
 	}); err != nil {
 		return false, fmt.Errorf("failed to list the linked bindings for %s/%s: %w", token.Namespace, token.Name, err)
 	}
 
-	return len(list.Items) > 0, nil
+	for _, b := range list.Items {
+		if b.Namespace == token.Namespace && (b.Status.LinkedAccessTokenNamespace == "" || b.Status.LinkedAccessTokenNamespace == token.Namespace) {
+			return true, nil
+		}
+		if b.Namespace != token.Namespace && b.Status.LinkedAccessTokenNamespace == token.Namespace {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
 // EnsureLabels makes sure that the object has labels set according to its spec. The labels are used for faster lookup during
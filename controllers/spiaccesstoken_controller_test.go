@@ -17,11 +17,16 @@ package controllers
 import (
 	"context"
 	"testing"
+	"time"
 
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	opconfig "github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/tokenstorage/memorystorage"
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestEnsureLabels(t *testing.T) {
@@ -86,3 +91,94 @@ func TestReconcileData(t *testing.T) {
 		assert.NotNil(t, at.Status.TokenMetadata)
 	})
 }
+
+func TestParseOAuthUrl(t *testing.T) {
+	info := oauthstate.OAuthInfo{TokenName: "token", TokenNamespace: "default", Scopes: []string{"repo"}}
+
+	t.Run("unsigned state", func(t *testing.T) {
+		codec := oauthstate.NewCodec(config.OAuthStateConfiguration{})
+		state, err := codec.Encode(&info)
+		assert.NoError(t, err)
+
+		parsed, refreshAt, ok := parseOAuthUrl(codec, "https://spi/oauth/authenticate?state="+state, "https://spi/oauth/authenticate")
+		assert.True(t, ok)
+		assert.True(t, refreshAt.IsZero())
+		assert.Equal(t, info, parsed)
+	})
+
+	t.Run("signed state", func(t *testing.T) {
+		codec := oauthstate.NewCodec(config.OAuthStateConfiguration{
+			Keys:     []config.OAuthStateKey{{Id: "key", Secret: []byte("0123456789abcdef0123456789abcdef")}},
+			Lifetime: time.Hour,
+		})
+		state, err := codec.Encode(&info)
+		assert.NoError(t, err)
+
+		parsed, refreshAt, ok := parseOAuthUrl(codec, "https://spi/oauth/authenticate?state="+state, "https://spi/oauth/authenticate")
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(30*time.Minute), refreshAt, 5*time.Second)
+		assert.Equal(t, info, parsed)
+	})
+
+	t.Run("different base url", func(t *testing.T) {
+		codec := oauthstate.NewCodec(config.OAuthStateConfiguration{})
+		state, err := codec.Encode(&info)
+		assert.NoError(t, err)
+
+		_, _, ok := parseOAuthUrl(codec, "https://spi/oauth/authenticate?state="+state, "https://other/oauth/authenticate")
+		assert.False(t, ok)
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		codec := oauthstate.NewCodec(config.OAuthStateConfiguration{
+			Keys: []config.OAuthStateKey{{Id: "key", Secret: []byte("0123456789abcdef0123456789abcdef")}},
+		})
+
+		_, _, ok := parseOAuthUrl(codec, "https://spi/oauth/authenticate?state=blah", "https://spi/oauth/authenticate")
+		assert.False(t, ok)
+	})
+}
+
+func TestDurationUntilNextReconcileRefreshesOAuthUrl(t *testing.T) {
+	oauthStateCfg := config.OAuthStateConfiguration{
+		Keys:     []config.OAuthStateKey{{Id: "key", Secret: []byte("0123456789abcdef0123456789abcdef")}},
+		Lifetime: time.Hour,
+	}
+	r := &SPIAccessTokenReconciler{Configuration: &opconfig.OperatorConfiguration{
+		SharedConfiguration: config.SharedConfiguration{OAuthState: oauthStateCfg},
+		AccessTokenTtl:      24 * time.Hour,
+	}}
+	at := &api.SPIAccessToken{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()}}
+
+	assert.InDelta(t, (24 * time.Hour).Seconds(), r.durationUntilNextReconcile(at).Seconds(), 5)
+
+	state, err := oauthstate.NewCodec(oauthStateCfg).Encode(&oauthstate.OAuthInfo{TokenName: "token"})
+	assert.NoError(t, err)
+	at.Status.OAuthUrl = "https://spi/oauth/authenticate?state=" + state
+
+	assert.InDelta(t, (30 * time.Minute).Seconds(), r.durationUntilNextReconcile(at).Seconds(), 5)
+}
+
+func TestHasLinkedBindings(t *testing.T) {
+	token := &api.SPIAccessToken{ObjectMeta: metav1.ObjectMeta{Name: "bot", Namespace: "platform"}}
+	binding := func(namespace, linkedNamespace string) *api.SPIAccessTokenBinding {
+		return &api.SPIAccessTokenBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: namespace, Labels: map[string]string{SPIAccessTokenLinkLabel: "bot"}},
+			Status:     api.SPIAccessTokenBindingStatus{LinkedAccessTokenName: "bot", LinkedAccessTokenNamespace: linkedNamespace},
+		}
+	}
+
+	test := func(name string, expected bool, objects ...client.Object) {
+		t.Run(name, func(t *testing.T) {
+			has, err := hasLinkedBindings(context.TODO(), token, mockK8sClient(objects...))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, has)
+		})
+	}
+
+	test("no bindings", false)
+	test("binding in the same namespace", true, binding("platform", ""))
+	test("binding in another namespace sharing the token", true, binding("tenant", "platform"))
+	test("binding in another namespace with a token of the same name", false, binding("tenant", ""))
+	test("binding in the same namespace using a shared token of the same name", false, binding("platform", "other"))
+}
@@ -21,6 +21,7 @@ import (
 	stderrors "errors"
 	"fmt"
 	"net/url"
+	"reflect"
 	"time"
 
 	"github.com/redhat-appstudio/remote-secret/pkg/rerror"
@@ -38,6 +39,7 @@ import (
 	"github.com/redhat-appstudio/remote-secret/pkg/logs"
 
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/tokenstorage"
 
 	"github.com/redhat-appstudio/remote-secret/pkg/sync"
@@ -47,9 +49,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
@@ -63,6 +68,10 @@ import (
 const deprecatedLinkedSecretsFinalizerName = "spi.appstudio.redhat.com/linked-secrets" //#nosec G101 -- false positive, this is not a private data
 const linkedObjectsFinalizerName = "spi.appstudio.redhat.com/linked-objects"
 
+// maxLifetimeRenewalClockSkew is how far in the future the lifetime renewal timestamp can lie to still be accepted,
+// because the clocks of the consumers setting it and of the operator may differ a little.
+const maxLifetimeRenewalClockSkew = time.Minute
+
 var (
 	linkedTokenDoesntMatchError     = stderrors.New("linked token doesn't match the criteria")
 	invalidServiceProviderHostError = stderrors.New("the host of service provider url, determined from repoUrl, is not a valid DNS1123 subdomain")
@@ -87,6 +96,7 @@ type SPIAccessTokenBindingReconciler struct {
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spiaccesstokenbindings/finalizers,verbs=update
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;watch;create;update;list;delete
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SPIAccessTokenBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -105,6 +115,13 @@ func (r *SPIAccessTokenBindingReconciler) SetupWithManager(mgr ctrl.Manager) err
 					"SPIAccessTokenName", o.GetName(), "SPIAccessTokenNamespace", o.GetNamespace())
 				return []reconcile.Request{}
 			}
+			sharedRequests, err := r.sharedTokenBindingsAsRequests(context.Background(), client.ObjectKeyFromObject(o))
+			if err != nil {
+				enqueueLog.Error(err, "failed to list SPIAccessTokenBindings while determining the ones linked to SPIAccessToken from other namespaces",
+					"SPIAccessTokenName", o.GetName(), "SPIAccessTokenNamespace", o.GetNamespace())
+				return []reconcile.Request{}
+			}
+			requests = append(requests, sharedRequests...)
 
 			logReconciliationRequests(requests, "SPIAccessTokenBinding", o, "SPIAccessToken")
 
@@ -135,18 +152,54 @@ func (r *SPIAccessTokenBindingReconciler) SetupWithManager(mgr ctrl.Manager) err
 			tokenRequest := tmpRequests[0]
 
 			requests, err := r.filteredBindingsAsRequests(context.Background(), o.GetNamespace(), func(binding api.SPIAccessTokenBinding) bool {
-				return tokenRequest.Name == binding.Status.LinkedAccessTokenName
+				return tokenRequest.Name == binding.Status.LinkedAccessTokenName && binding.Status.LinkedAccessTokenNamespace == ""
 			})
 			if err != nil {
 				enqueueLog.Error(err, "failed to list SPIAccessTokenBindings while determining the ones linked to DataUpdate",
 					"SecretName", o.GetName(), "SecretNamespace", o.GetNamespace())
 				return []reconcile.Request{}
 			}
+			sharedRequests, err := r.sharedTokenBindingsAsRequests(context.Background(), tokenRequest.NamespacedName)
+			if err != nil {
+				enqueueLog.Error(err, "failed to list SPIAccessTokenBindings in other namespaces while determining the ones linked to DataUpdate",
+					"SecretName", o.GetName(), "SecretNamespace", o.GetNamespace())
+				return []reconcile.Request{}
+			}
+			requests = append(requests, sharedRequests...)
 
 			logReconciliationRequests(requests, "SPIAccessTokenBinding", o, "SPIAccessTokenDataUpdate")
 
 			return requests
 		})).
+		Watches(&source.Kind{Type: &api.SPIAccessTokenSharingPolicy{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			policy, ok := o.(*api.SPIAccessTokenSharingPolicy)
+			if !ok {
+				return []reconcile.Request{}
+			}
+			// the bindings using the shared token need to check whether they are still allowed to do so, and the bindings
+			// in the target namespaces might now be able to use the token.
+			requests, err := r.sharedTokenBindingsAsRequests(context.Background(), client.ObjectKey{Namespace: policy.Spec.Token.Namespace, Name: policy.Spec.Token.Name})
+			if err != nil {
+				enqueueLog.Error(err, "failed to list SPIAccessTokenBindings while determining the ones linked to the token of SPIAccessTokenSharingPolicy",
+					"SPIAccessTokenSharingPolicyName", o.GetName())
+				return []reconcile.Request{}
+			}
+			for _, ns := range policy.Spec.TargetNamespaces {
+				nsRequests, err := r.filteredBindingsAsRequests(context.Background(), ns, func(binding api.SPIAccessTokenBinding) bool {
+					return binding.Status.LinkedAccessTokenNamespace == ""
+				})
+				if err != nil {
+					enqueueLog.Error(err, "failed to list SPIAccessTokenBindings in the target namespace of SPIAccessTokenSharingPolicy",
+						"SPIAccessTokenSharingPolicyName", o.GetName(), "TargetNamespace", ns)
+					return []reconcile.Request{}
+				}
+				requests = append(requests, nsRequests...)
+			}
+
+			logReconciliationRequests(requests, "SPIAccessTokenBinding", o, "SPIAccessTokenSharingPolicy")
+
+			return requests
+		})).
 		Watches(&source.Kind{Type: &corev1.ServiceAccount{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
 			requests, err := r.filteredBindingsAsRequests(context.Background(), o.GetNamespace(), func(binding api.SPIAccessTokenBinding) bool {
 				marker := bindingtarget.BindingTargetObjectMarker{}
@@ -166,6 +219,24 @@ func (r *SPIAccessTokenBindingReconciler) SetupWithManager(mgr ctrl.Manager) err
 
 			return requests
 		})).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			pod, ok := o.(*corev1.Pod)
+			if !ok {
+				return []reconcile.Request{}
+			}
+			requests, err := r.filteredBindingsAsRequests(context.Background(), o.GetNamespace(), func(binding api.SPIAccessTokenBinding) bool {
+				return binding.Spec.ExtendWhileMounted && binding.Status.SyncedObjectRef.Kind == "Secret" && podUsesSecret(pod, binding.Status.SyncedObjectRef.Name)
+			})
+			if err != nil {
+				enqueueLog.Error(err, "failed to list SPIAccessTokenBindings while determining the ones with secret used by a Pod",
+					"PodName", o.GetName(), "PodNamespace", o.GetNamespace())
+				return []reconcile.Request{}
+			}
+
+			logReconciliationRequests(requests, "SPIAccessTokenBinding", o, "Pod")
+
+			return requests
+		}), builder.WithPredicates(podUsingSecretsPredicate)).
 		Complete(r)
 
 	if err != nil {
@@ -197,6 +268,27 @@ func (r *SPIAccessTokenBindingReconciler) filteredBindingsAsRequests(ctx context
 	return ret, nil
 }
 
+// sharedTokenBindingsAsRequests creates reconcile requests for the bindings from other namespaces that are linked to
+// the token with the provided key shared with them by an SPIAccessTokenSharingPolicy.
+func (r *SPIAccessTokenBindingReconciler) sharedTokenBindingsAsRequests(ctx context.Context, tokenKey client.ObjectKey) ([]reconcile.Request, error) {
+	bindings := &api.SPIAccessTokenBindingList{}
+	if err := r.Client.List(ctx, bindings, client.MatchingLabels{SPIAccessTokenLinkLabel: tokenKey.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list bindings linked to the token %s, error: %w", tokenKey, err)
+	}
+	ret := make([]reconcile.Request, 0, len(bindings.Items))
+	for _, b := range bindings.Items {
+		if b.Status.LinkedAccessTokenNamespace == tokenKey.Namespace && b.Status.LinkedAccessTokenName == tokenKey.Name {
+			ret = append(ret, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      b.Name,
+					Namespace: b.Namespace,
+				},
+			})
+		}
+	}
+	return ret, nil
+}
+
 func (r *SPIAccessTokenBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	lg := log.FromContext(ctx)
 	defer logs.TimeTrackWithLazyLogger(func() logr.Logger { return lg }, time.Now(), "Reconcile SPIAccessTokenBinding")
@@ -271,15 +363,8 @@ func (r *SPIAccessTokenBindingReconciler) Reconcile(ctx context.Context, req ctr
 	}
 
 	// cleanup bindings by lifetime
-	bindingLifetime := time.Since(binding.CreationTimestamp.Time).Seconds()
-	if expectedLifetimeDuration != nil && bindingLifetime > expectedLifetimeDuration.Seconds() {
-		err := r.Client.Delete(ctx, &binding)
-		if err != nil {
-			lg.Error(err, "failed to cleanup binding on reaching the max lifetime", "error", err)
-			return ctrl.Result{}, fmt.Errorf("failed to cleanup binding on reaching the max lifetime: %w", err)
-		}
-		lg.V(logs.DebugLevel).Info("binding being cleaned up on reaching the max lifetime", "binding", binding.ObjectMeta.Name, "bindingLifetime", bindingLifetime, "bindingttl", r.Configuration.AccessTokenBindingTtl.Seconds())
-		return ctrl.Result{}, nil
+	if expired, err := r.deleteExpiredBinding(ctx, &binding, expectedLifetimeDuration); err != nil || expired {
+		return ctrl.Result{}, err
 	}
 
 	if binding.Status.Phase == "" {
@@ -318,12 +403,23 @@ func (r *SPIAccessTokenBindingReconciler) Reconcile(ctx context.Context, req ctr
 		return ctrl.Result{}, fmt.Errorf("failed to link the token: %w", err)
 	}
 	lg = lg.WithValues("linked_to", token.Name)
+	setCondition(&binding.Status.Conditions, &binding, api.SPIAccessTokenBindingConditionTypeTokenLinked, metav1.ConditionTrue,
+		api.SPIAccessTokenBindingReasonLinked, fmt.Sprintf("linked to the SPIAccessToken %s", token.Name))
 	if !matching && token.Status.Phase == api.SPIAccessTokenPhaseReady {
 		// the token that we are linked to is ready but doesn't match the criteria of the binding.
 		// We can't do much here - the user granted the token the access we requested, but we still don't match
+		// Let's at least offer the upgrade of the token to the scopes the binding requires.
 		binding.Status.Phase = api.SPIAccessTokenBindingPhaseError
 		binding.Status.OAuthUrl = ""
+		upgradeUrl, refreshAt, err := r.upgradeUrlFor(ctx, sp, &binding, token)
+		if err != nil {
+			lg.Error(err, "failed to determine the upgrade URL of the linked token")
+		}
+		binding.Status.UpgradeUrl = upgradeUrl
 		r.updateBindingStatusError(ctx, &binding, api.SPIAccessTokenBindingErrorReasonLinkedToken, linkedTokenDoesntMatchError)
+		if !refreshAt.IsZero() {
+			return ctrl.Result{RequeueAfter: time.Until(refreshAt)}, nil
+		}
 		return ctrl.Result{}, nil
 	}
 
@@ -342,6 +438,7 @@ func (r *SPIAccessTokenBindingReconciler) Reconcile(ctx context.Context, req ctr
 
 	binding.Status.OAuthUrl = token.Status.OAuthUrl
 	binding.Status.UploadUrl = token.Status.UploadUrl
+	binding.Status.UpgradeUrl = ""
 
 	// remember the state that we need to revert to if updates to the binding fail after we've made changes to the cluster
 	depCheckpoint, err := dependentsHandler.CheckPoint(ctx)
@@ -358,6 +455,7 @@ func (r *SPIAccessTokenBindingReconciler) Reconcile(ctx context.Context, req ctr
 			binding.Status.Phase = api.SPIAccessTokenBindingPhaseAwaitingTokenData
 			binding.Status.SyncedObjectRef = api.TargetObjectRef{}
 			binding.Status.ServiceAccountNames = []string{}
+			setAwaitingTokenDataConditions(&binding)
 		} else if err != nil {
 			binding.Status.Phase = api.SPIAccessTokenBindingPhaseError
 			// TODO: add the translation layer between the string errors from the depdendentsHandler to the typed reasons
@@ -385,11 +483,16 @@ func (r *SPIAccessTokenBindingReconciler) Reconcile(ctx context.Context, req ctr
 			binding.Status.Phase = api.SPIAccessTokenBindingPhaseInjected
 			binding.Status.SyncedObjectRef = toObjectRef(deps.Secret)
 			binding.Status.ServiceAccountNames = sas
+			setCondition(&binding.Status.Conditions, &binding, api.SPIAccessTokenBindingConditionTypeSecretSynced, metav1.ConditionTrue,
+				api.SPIAccessTokenBindingReasonInjected, fmt.Sprintf("the token data is synced to the secret %s", deps.Secret.GetName()))
+			setCondition(&binding.Status.Conditions, &binding, api.SPIAccessTokenBindingConditionTypeServiceAccountsLinked, metav1.ConditionTrue,
+				api.SPIAccessTokenBindingReasonLinked, fmt.Sprintf("the secret is linked to %d service accounts", len(sas)))
 		}
 	} else {
 		binding.Status.Phase = api.SPIAccessTokenBindingPhaseAwaitingTokenData
 		binding.Status.SyncedObjectRef = api.TargetObjectRef{}
 		binding.Status.ServiceAccountNames = []string{}
+		setAwaitingTokenDataConditions(&binding)
 	}
 
 	if err := r.updateBindingStatusSuccess(ctx, &binding); err != nil {
@@ -417,7 +520,13 @@ func (r *SPIAccessTokenBindingReconciler) Reconcile(ctx context.Context, req ctr
 		return ctrl.Result{}, nil
 	}
 
-	delay := time.Until(binding.CreationTimestamp.Add(*expectedLifetimeDuration).Add(r.Configuration.DeletionGracePeriod))
+	delay := time.Until(binding.Status.ExpirationTime.Add(r.Configuration.DeletionGracePeriod))
+	if binding.Spec.ExtendWhileMounted {
+		// we need to check whether the secret is still used by some pod at the time the lifetime can be extended.
+		if renewalDelay := time.Until(binding.Status.ExpirationTime.Add(-*expectedLifetimeDuration / 2)); renewalDelay > 0 && renewalDelay < delay {
+			delay = renewalDelay
+		}
+	}
 	lg.V(logs.DebugLevel).Info("binding with limited lifetime", "requeueIn", delay)
 
 	return ctrl.Result{RequeueAfter: delay}, nil
@@ -465,6 +574,179 @@ func bindingLifetime(r *SPIAccessTokenBindingReconciler, binding api.SPIAccessTo
 	}
 }
 
+// deleteExpiredBinding deletes the binding if its lifetime elapsed and returns true in that case. Otherwise, the expiration
+// time is recorded in the status of the binding. The lifetime is nil for the bindings that never expire.
+func (r *SPIAccessTokenBindingReconciler) deleteExpiredBinding(ctx context.Context, binding *api.SPIAccessTokenBinding, lifetime *time.Duration) (bool, error) {
+	lg := log.FromContext(ctx)
+
+	if lifetime == nil {
+		binding.Status.ExpirationTime = nil
+		return false, nil
+	}
+
+	expiration, err := r.bindingExpiration(ctx, binding, *lifetime)
+	if err != nil {
+		lg.Error(err, "failed to determine the expiration time of the binding")
+		return false, fmt.Errorf("failed to determine the expiration time of the binding: %w", err)
+	}
+	if time.Now().After(expiration) {
+		if err := r.Client.Delete(ctx, binding); err != nil {
+			lg.Error(err, "failed to cleanup binding on reaching the max lifetime", "error", err)
+			return false, fmt.Errorf("failed to cleanup binding on reaching the max lifetime: %w", err)
+		}
+		lg.V(logs.DebugLevel).Info("binding being cleaned up on reaching the max lifetime", "binding", binding.ObjectMeta.Name, "expirationTime", expiration, "bindingttl", lifetime.Seconds())
+		return true, nil
+	}
+	binding.Status.ExpirationTime = &metav1.Time{Time: expiration}
+	return false, nil
+}
+
+// bindingExpiration computes the time at which the binding expires given its lifetime. By default, this is the creation
+// time plus the lifetime. The lifetime can be renewed by the consumers of the binding using the
+// api.BindingLifetimeRenewedAtAnnotation, whose value is ignored if it lies in the future, or, if the binding's spec requires it,
+// by a running pod using the synced secret. The renewal by a pod is recorded in the annotation too, so that it persists
+// after the pod finishes. It is only performed once less than half of the lifetime remains, so that the binding
+// doesn't need to be updated on every reconciliation.
+func (r *SPIAccessTokenBindingReconciler) bindingExpiration(ctx context.Context, binding *api.SPIAccessTokenBinding, lifetime time.Duration) (time.Time, error) {
+	expiration := binding.CreationTimestamp.Add(lifetime)
+
+	if renewedAt, ok := binding.Annotations[api.BindingLifetimeRenewedAtAnnotation]; ok {
+		renewedAtTime, err := time.Parse(time.RFC3339, renewedAt)
+		if err != nil {
+			// we don't want to fail the whole binding just because a consumer didn't format the annotation correctly
+			log.FromContext(ctx).Info("ignoring invalid value of the lifetime renewal annotation", "annotation", api.BindingLifetimeRenewedAtAnnotation, "value", renewedAt, "error", err.Error())
+		} else if now := time.Now(); renewedAtTime.After(now.Add(maxLifetimeRenewalClockSkew)) {
+			// the renewal cannot be used to extend the lifetime in advance. Just capping the timestamp at the current
+			// time wouldn't help, because the binding would then never expire until the timestamp passes.
+			log.FromContext(ctx).Info("ignoring the lifetime renewal in the future", "annotation", api.BindingLifetimeRenewedAtAnnotation, "value", renewedAt)
+		} else {
+			if renewedAtTime.After(now) {
+				renewedAtTime = now
+			}
+			if renewedAtTime.Add(lifetime).After(expiration) {
+				expiration = renewedAtTime.Add(lifetime)
+			}
+		}
+	}
+
+	if binding.Spec.ExtendWhileMounted && time.Until(expiration) < lifetime/2 {
+		mounted, err := r.isSyncedSecretUsedByPod(ctx, binding)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if mounted {
+			renewedAt := time.Now().Truncate(time.Second)
+			if binding.Annotations == nil {
+				binding.Annotations = map[string]string{}
+			}
+			binding.Annotations[api.BindingLifetimeRenewedAtAnnotation] = renewedAt.Format(time.RFC3339)
+			if err := r.Client.Update(ctx, binding); err != nil {
+				return time.Time{}, fmt.Errorf("failed to record the renewal of the binding lifetime: %w", err)
+			}
+			expiration = renewedAt.Add(lifetime)
+		}
+	}
+
+	return expiration, nil
+}
+
+// isSyncedSecretUsedByPod checks whether there is a running pod in the namespace of the binding that uses the secret
+// synced by the binding.
+func (r *SPIAccessTokenBindingReconciler) isSyncedSecretUsedByPod(ctx context.Context, binding *api.SPIAccessTokenBinding) (bool, error) {
+	if binding.Status.SyncedObjectRef.Kind != "Secret" || binding.Status.SyncedObjectRef.Name == "" {
+		return false, nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.Client.List(ctx, pods, client.InNamespace(binding.Namespace)); err != nil {
+		return false, fmt.Errorf("failed to list the pods in the namespace %s: %w", binding.Namespace, err)
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if podUsesSecret(pod, binding.Status.SyncedObjectRef.Name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// podUsingSecretsPredicate filters out the pods that don't use any secrets and therefore cannot extend the lifetime of
+// any binding. Of the updates, only the changes of the phase are interesting, because they start or stop the extension.
+var podUsingSecretsPredicate = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		pod, ok := e.Object.(*corev1.Pod)
+		return ok && podUsesAnySecret(pod)
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, oldOk := e.ObjectOld.(*corev1.Pod)
+		newPod, newOk := e.ObjectNew.(*corev1.Pod)
+		return oldOk && newOk && oldPod.Status.Phase != newPod.Status.Phase && podUsesAnySecret(newPod)
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return false
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		pod, ok := e.Object.(*corev1.Pod)
+		return ok && podUsesAnySecret(pod)
+	},
+}
+
+// podUsesSecret checks whether the pod mounts the secret with the provided name as a volume, uses it as an image pull
+// secret or references it in the environment of some of its containers.
+func podUsesSecret(pod *corev1.Pod, secretName string) bool {
+	return anyPodSecret(pod, func(name string) bool { return name == secretName })
+}
+
+// podUsesAnySecret checks whether the pod uses some secret in any of the ways checked by podUsesSecret.
+func podUsesAnySecret(pod *corev1.Pod) bool {
+	return anyPodSecret(pod, func(string) bool { return true })
+}
+
+// anyPodSecret returns true if the match function returns true for the name of any secret used by the pod.
+func anyPodSecret(pod *corev1.Pod, match func(secretName string) bool) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.Secret != nil && match(v.Secret.SecretName) {
+			return true
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.Secret != nil && match(src.Secret.Name) {
+					return true
+				}
+			}
+		}
+	}
+
+	for _, ref := range pod.Spec.ImagePullSecrets {
+		if match(ref.Name) {
+			return true
+		}
+	}
+
+	containers := make([]corev1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	containers = append(containers, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+	for _, c := range containers {
+		for _, envFrom := range c.EnvFrom {
+			if envFrom.SecretRef != nil && match(envFrom.SecretRef.Name) {
+				return true
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && match(env.ValueFrom.SecretKeyRef.Name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func checkQuayPermissionAreasMigration(binding *api.SPIAccessTokenBinding, spName config.ServiceProviderName) bool {
 	permissionChange := false
 	if spName == config.ServiceProviderTypeQuay.Name {
@@ -482,9 +764,20 @@ func checkQuayPermissionAreasMigration(binding *api.SPIAccessTokenBinding, spNam
 	return permissionChange
 }
 
+// linkedTokenKey returns the key of the token linked to the binding. The token usually lives in the namespace of
+// the binding but can also be shared with it from another namespace.
+func linkedTokenKey(binding *api.SPIAccessTokenBinding) client.ObjectKey {
+	namespace := binding.Status.LinkedAccessTokenNamespace
+	if namespace == "" {
+		namespace = binding.Namespace
+	}
+	return client.ObjectKey{Name: binding.Status.LinkedAccessTokenName, Namespace: namespace}
+}
+
 func getLinkedTokenFromList(s *api.SPIAccessTokenBinding, tokens []api.SPIAccessToken) *api.SPIAccessToken {
+	key := linkedTokenKey(s)
 	for _, t := range tokens {
-		if t.Name == s.Status.LinkedAccessTokenName {
+		if t.Name == key.Name && (t.Namespace == key.Namespace || t.Namespace == "") {
 			return &t
 		}
 	}
@@ -528,10 +821,14 @@ func (r *SPIAccessTokenBindingReconciler) linkToken(ctx context.Context, sp serv
 		matching = false
 		if binding.Status.LinkedAccessTokenName != "" {
 			// ok, there are no matching tokens, but we're already linked to one. So let's just load that token here...
-			token = &api.SPIAccessToken{}
-			if err = r.Client.Get(ctx, client.ObjectKey{Name: binding.Status.LinkedAccessTokenName, Namespace: binding.Namespace}, token); err != nil {
-				return nil, false, fmt.Errorf("failed to get the linked token: %w", err)
+			token, err = r.getPreviouslyLinkedToken(ctx, binding)
+			if err != nil {
+				return nil, false, err
 			}
+		}
+
+		if token != nil {
+			binding.Status.TokenSelectionReason = "no token matches the binding, keeping the previously linked token"
 		} else {
 			lg.V(logs.DebugLevel).Info("creating a new token because none found for binding")
 
@@ -559,16 +856,24 @@ func (r *SPIAccessTokenBindingReconciler) linkToken(ctx context.Context, sp serv
 				return nil, false, fmt.Errorf("failed to create the token: %w", err)
 			}
 			newTokenCreated = true
+			binding.Status.TokenSelectionReason = "no token matched the binding, a new token has been created"
 			// we've just created a new token. It technically doesn't match, but we want to give it a chance at least
 			// until the next reconciliation.
 			matching = true
 		}
 	} else {
 		// if the binding is already linked to a matching token, no change needed. Otherwise, we need to link it to one
-		// of the results (we randomly choose the first one ;) ).
+		// of the results chosen using the token selection strategy.
 		token = getLinkedTokenFromList(binding, tokens)
 		if token == nil {
-			token = &tokens[0]
+			selector := tokenSelector{Strategy: r.tokenSelectionStrategy(binding), TokenStorage: r.TokenStorage}
+			var reason string
+			token, reason, err = selector.Select(ctx, tokens)
+			if err != nil {
+				r.updateBindingStatusError(ctx, binding, api.SPIAccessTokenBindingErrorReasonTokenLookup, err)
+				return nil, false, fmt.Errorf("failed to select the token to link: %w", err)
+			}
+			binding.Status.TokenSelectionReason = reason
 		}
 		matching = true
 	}
@@ -590,6 +895,99 @@ func (r *SPIAccessTokenBindingReconciler) linkToken(ctx context.Context, sp serv
 	return
 }
 
+// getPreviouslyLinkedToken loads the token the binding is linked to. If the token is shared with the binding from
+// another namespace and no SPIAccessTokenSharingPolicy shares it anymore, the binding is unlinked from it and nil is
+// returned.
+func (r *SPIAccessTokenBindingReconciler) getPreviouslyLinkedToken(ctx context.Context, binding *api.SPIAccessTokenBinding) (*api.SPIAccessToken, error) {
+	token := &api.SPIAccessToken{}
+	if err := r.Client.Get(ctx, linkedTokenKey(binding), token); err != nil {
+		return nil, fmt.Errorf("failed to get the linked token: %w", err)
+	}
+
+	if token.Namespace == binding.Namespace {
+		return token, nil
+	}
+
+	shared, err := serviceprovider.IsTokenSharedWith(ctx, r.Client, binding, token)
+	if err != nil {
+		r.updateBindingStatusError(ctx, binding, api.SPIAccessTokenBindingErrorReasonTokenLookup, err)
+		return nil, fmt.Errorf("failed to check the sharing of the linked token: %w", err)
+	}
+	if shared {
+		return token, nil
+	}
+
+	auditLog := log.FromContext(ctx, "audit", "true", "namespace", binding.Namespace, "token", token.Namespace+"/"+token.Name,
+		"repository", binding.Spec.RepoUrl)
+	auditLog.Info("token no longer shared from another namespace, unlinking", "action", "DELETE")
+
+	// the binding is linked to a new token below, after which the dependent objects with the data of the shared token
+	// are cleaned up, because the new token has no data yet.
+	binding.Status.LinkedAccessTokenName = ""
+	binding.Status.LinkedAccessTokenNamespace = ""
+
+	return nil, nil
+}
+
+// upgradeUrlFor returns the URL of the OAuth flow that upgrades the linked token to the union of its current scopes and
+// the scopes required by the binding, together with the time after which the URL should be refreshed. No URL is
+// returned if the service provider doesn't support OAuth, the service provider user of the token is not known or
+// the token is shared with the binding from another namespace.
+func (r *SPIAccessTokenBindingReconciler) upgradeUrlFor(ctx context.Context, sp serviceprovider.ServiceProvider, binding *api.SPIAccessTokenBinding, token *api.SPIAccessToken) (string, time.Time, error) {
+	oauthCapability := sp.GetOAuthCapability()
+	if oauthCapability == nil || token.Status.TokenMetadata == nil || token.Status.TokenMetadata.UserId == "" {
+		return "", time.Time{}, nil
+	}
+
+	if token.Namespace != binding.Namespace {
+		// the token is shared from another namespace. Its scopes are under the control of its owners, not the users of
+		// the binding.
+		return "", time.Time{}, nil
+	}
+
+	oauthBaseUrl := oauthCapability.GetOAuthEndpoint()
+	if len(oauthBaseUrl) == 0 {
+		return "", time.Time{}, nil
+	}
+
+	scopes := append([]string{}, token.Status.TokenMetadata.Scopes...)
+	known := map[string]bool{}
+	for _, scope := range scopes {
+		known[scope] = true
+	}
+	for _, scope := range oauthCapability.OAuthScopesFor(&binding.Spec.Permissions) {
+		if !known[scope] {
+			known[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	info := oauthstate.OAuthInfo{
+		TokenName:           token.Name,
+		TokenNamespace:      token.Namespace,
+		Scopes:              scopes,
+		ServiceProviderName: sp.GetType().Name,
+		ServiceProviderUrl:  sp.GetBaseUrl(),
+		ExpectedUserId:      token.Status.TokenMetadata.UserId,
+	}
+
+	// the same as with the OAuth URL of the token, let's keep the current URL for as long as it is usable so that
+	// the status update doesn't trigger another reconciliation
+	codec := oauthstate.NewCodec(r.Configuration.OAuthState)
+	if current, refreshAt, ok := parseOAuthUrl(codec, binding.Status.UpgradeUrl, oauthBaseUrl); ok && reflect.DeepEqual(current, info) && (refreshAt.IsZero() || time.Now().Before(refreshAt)) {
+		return binding.Status.UpgradeUrl, refreshAt, nil
+	}
+
+	state, err := codec.Encode(&info)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to encode the OAuth state: %w", err)
+	}
+
+	upgradeUrl := oauthBaseUrl + "?state=" + state
+	_, refreshAt, _ := parseOAuthUrl(codec, upgradeUrl, oauthBaseUrl)
+	return upgradeUrl, refreshAt, nil
+}
+
 func validateServiceProviderUrl(serviceProviderUrl string) error {
 	parse, err := url.Parse(serviceProviderUrl)
 	if err != nil {
@@ -614,8 +1012,15 @@ func (r *SPIAccessTokenBindingReconciler) persistWithMatchingLabels(ctx context.
 		}
 	}
 
-	if binding.Status.LinkedAccessTokenName != token.Name {
+	linkedNamespace := ""
+	if token.Namespace != binding.Namespace {
+		// the token is shared from another namespace using a sharing policy
+		linkedNamespace = token.Namespace
+	}
+
+	if binding.Status.LinkedAccessTokenName != token.Name || binding.Status.LinkedAccessTokenNamespace != linkedNamespace {
 		binding.Status.LinkedAccessTokenName = token.Name
+		binding.Status.LinkedAccessTokenNamespace = linkedNamespace
 		binding.Status.OAuthUrl = token.Status.OAuthUrl
 		binding.Status.UploadUrl = token.Status.UploadUrl
 		if err := r.updateBindingStatusSuccess(ctx, binding); err != nil {
@@ -631,11 +1036,38 @@ func (r *SPIAccessTokenBindingReconciler) persistWithMatchingLabels(ctx context.
 func (r *SPIAccessTokenBindingReconciler) updateBindingStatusError(ctx context.Context, binding *api.SPIAccessTokenBinding, reason api.SPIAccessTokenBindingErrorReason, err error) {
 	binding.Status.ErrorMessage = err.Error()
 	binding.Status.ErrorReason = reason
+	setCondition(&binding.Status.Conditions, binding, conditionTypeForBindingError(reason), metav1.ConditionFalse, reason, err.Error())
 	if err := r.Client.Status().Update(ctx, binding); err != nil {
 		log.FromContext(ctx).Error(err, "failed to update the status with error", "status_to_update", binding.Status, "reason", reason, "error", err)
 	}
 }
 
+// setAwaitingTokenDataConditions marks the conditions of the binding related to the token data as waiting for the data
+// to become available.
+func setAwaitingTokenDataConditions(binding *api.SPIAccessTokenBinding) {
+	setCondition(&binding.Status.Conditions, binding, api.SPIAccessTokenBindingConditionTypeSecretSynced, metav1.ConditionFalse,
+		api.SPIAccessTokenBindingReasonAwaitingTokenData, "the linked token has no data yet")
+	setCondition(&binding.Status.Conditions, binding, api.SPIAccessTokenBindingConditionTypeServiceAccountsLinked, metav1.ConditionFalse,
+		api.SPIAccessTokenBindingReasonAwaitingTokenData, "the linked token has no data yet")
+}
+
+// conditionTypeForBindingError determines which of the binding conditions is affected by an error with the provided
+// reason.
+func conditionTypeForBindingError(reason api.SPIAccessTokenBindingErrorReason) api.SPIAccessTokenBindingConditionType {
+	switch reason {
+	case api.SPIAccessTokenBindingErrorReasonTokenSync,
+		api.SPIAccessTokenBindingErrorReasonTokenRetrieval,
+		api.SPIAccessTokenBindingErrorReasonTokenAnalysis,
+		api.SPIAccessTokenBindingErrorReason(bindings.ErrorReasonSecretUpdate):
+		return api.SPIAccessTokenBindingConditionTypeSecretSynced
+	case api.SPIAccessTokenBindingErrorReasonServiceAccountUnavailable,
+		api.SPIAccessTokenBindingErrorReasonServiceAccountUpdate:
+		return api.SPIAccessTokenBindingConditionTypeServiceAccountsLinked
+	default:
+		return api.SPIAccessTokenBindingConditionTypeTokenLinked
+	}
+}
+
 // updateBindingStatusSuccess updates the status of the binding as successful, clearing any previous error state.
 func (r *SPIAccessTokenBindingReconciler) updateBindingStatusSuccess(ctx context.Context, binding *api.SPIAccessTokenBinding) error {
 	binding.Status.ErrorMessage = ""
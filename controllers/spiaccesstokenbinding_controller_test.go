@@ -17,18 +17,24 @@ package controllers
 import (
 	"context"
 	"testing"
+	"time"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 
+	opconfig "github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -116,9 +122,387 @@ func TestAssureProperValuesInBinding(t *testing.T) {
 	})
 }
 
+func TestPodUsesSecret(t *testing.T) {
+	test := func(name string, expected bool, spec corev1.PodSpec) {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, podUsesSecret(&corev1.Pod{Spec: spec}, "secret"))
+		})
+	}
+
+	test("no usage", false, corev1.PodSpec{
+		Volumes:          []corev1.Volume{{Name: "v", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "other"}}}},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "other"}},
+		Containers:       []corev1.Container{{EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "other"}}}}}},
+	})
+	test("volume", true, corev1.PodSpec{
+		Volumes: []corev1.Volume{{Name: "v", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "secret"}}}},
+	})
+	test("projected volume", true, corev1.PodSpec{
+		Volumes: []corev1.Volume{{Name: "v", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+			Sources: []corev1.VolumeProjection{{Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "secret"}}}},
+		}}}},
+	})
+	test("image pull secret", true, corev1.PodSpec{
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "secret"}},
+	})
+	test("env from", true, corev1.PodSpec{
+		InitContainers: []corev1.Container{{EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "secret"}}}}}},
+	})
+	test("env value", true, corev1.PodSpec{
+		Containers: []corev1.Container{{Env: []corev1.EnvVar{{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "secret"}, Key: "token"},
+		}}}}},
+	})
+}
+
+func TestBindingExpiration(t *testing.T) {
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	newBinding := func() *api.SPIAccessTokenBinding {
+		return &api.SPIAccessTokenBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "binding",
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Status: api.SPIAccessTokenBindingStatus{
+				SyncedObjectRef: api.TargetObjectRef{Name: "secret", Kind: "Secret", ApiVersion: "v1"},
+			},
+		}
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec:       corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "secret"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+
+	t.Run("from creation time", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient()}
+		expiration, err := r.bindingExpiration(context.TODO(), newBinding(), 30*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, created.Add(30*time.Minute), expiration)
+	})
+
+	t.Run("renewed by annotation", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient()}
+		renewed := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+		binding := newBinding()
+		binding.Annotations = map[string]string{api.BindingLifetimeRenewedAtAnnotation: renewed.Format(time.RFC3339)}
+		expiration, err := r.bindingExpiration(context.TODO(), binding, 30*time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, renewed.Add(30*time.Minute).Equal(expiration))
+	})
+
+	t.Run("invalid annotation ignored", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient()}
+		binding := newBinding()
+		binding.Annotations = map[string]string{api.BindingLifetimeRenewedAtAnnotation: "yesterday"}
+		expiration, err := r.bindingExpiration(context.TODO(), binding, 30*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, created.Add(30*time.Minute), expiration)
+	})
+
+	t.Run("renewal in the future ignored", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient()}
+		binding := newBinding()
+		binding.Annotations = map[string]string{api.BindingLifetimeRenewedAtAnnotation: time.Now().Add(24 * time.Hour).Format(time.RFC3339)}
+		expiration, err := r.bindingExpiration(context.TODO(), binding, 30*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, created.Add(30*time.Minute), expiration)
+	})
+
+	t.Run("renewal within clock skew clamped", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient()}
+		binding := newBinding()
+		binding.Annotations = map[string]string{api.BindingLifetimeRenewedAtAnnotation: time.Now().Add(30 * time.Second).Format(time.RFC3339)}
+		expiration, err := r.bindingExpiration(context.TODO(), binding, 30*time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, expiration.After(time.Now().Add(29*time.Minute)))
+		assert.False(t, expiration.After(time.Now().Add(30*time.Minute)))
+	})
+
+	t.Run("status not used as lower bound", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient()}
+		binding := newBinding()
+		binding.Status.ExpirationTime = &metav1.Time{Time: time.Now().Add(24 * time.Hour)}
+		expiration, err := r.bindingExpiration(context.TODO(), binding, 30*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, created.Add(30*time.Minute), expiration)
+	})
+
+	t.Run("extended while mounted", func(t *testing.T) {
+		binding := newBinding()
+		binding.Spec.ExtendWhileMounted = true
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient(pod.DeepCopy(), binding)}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(binding), binding))
+		expiration, err := r.bindingExpiration(context.TODO(), binding, 30*time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, expiration.After(time.Now().Add(29*time.Minute)))
+
+		// the renewal is recorded so that it outlives the pod
+		stored := &api.SPIAccessTokenBinding{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(binding), stored))
+		assert.NotEmpty(t, stored.Annotations[api.BindingLifetimeRenewedAtAnnotation])
+
+		r.Client = mockK8sClient()
+		renewed, err := r.bindingExpiration(context.TODO(), stored, 30*time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, expiration.Equal(renewed))
+	})
+
+	t.Run("not extended by finished pod", func(t *testing.T) {
+		finished := pod.DeepCopy()
+		finished.Status.Phase = corev1.PodSucceeded
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient(finished)}
+		binding := newBinding()
+		binding.Spec.ExtendWhileMounted = true
+		expiration, err := r.bindingExpiration(context.TODO(), binding, 30*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, created.Add(30*time.Minute), expiration)
+	})
+
+	t.Run("not extended when not requested", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient(pod.DeepCopy())}
+		expiration, err := r.bindingExpiration(context.TODO(), newBinding(), 30*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, created.Add(30*time.Minute), expiration)
+	})
+}
+
+func TestDeleteExpiredBinding(t *testing.T) {
+	lifetime := 30 * time.Minute
+	binding := &api.SPIAccessTokenBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "binding",
+			Namespace: "default",
+			// the binding was created long enough ago for its lifetime to elapse
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * lifetime)),
+			Annotations: map[string]string{
+				api.BindingLifetimeRenewedAtAnnotation: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339),
+			},
+		},
+	}
+
+	t.Run("expired despite renewal in the future", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient(binding.DeepCopy())}
+		b := binding.DeepCopy()
+
+		expired, err := r.deleteExpiredBinding(context.TODO(), b, &lifetime)
+		assert.NoError(t, err)
+		assert.True(t, expired)
+		assert.True(t, k8serrors.IsNotFound(r.Client.Get(context.TODO(), client.ObjectKeyFromObject(binding), &api.SPIAccessTokenBinding{})))
+	})
+
+	t.Run("not expired", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient(binding.DeepCopy())}
+		b := binding.DeepCopy()
+		b.Annotations[api.BindingLifetimeRenewedAtAnnotation] = time.Now().Format(time.RFC3339)
+
+		expired, err := r.deleteExpiredBinding(context.TODO(), b, &lifetime)
+		assert.NoError(t, err)
+		assert.False(t, expired)
+		assert.NotNil(t, b.Status.ExpirationTime)
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(binding), &api.SPIAccessTokenBinding{}))
+	})
+
+	t.Run("infinite lifetime", func(t *testing.T) {
+		r := SPIAccessTokenBindingReconciler{Client: mockK8sClient(binding.DeepCopy())}
+		b := binding.DeepCopy()
+
+		expired, err := r.deleteExpiredBinding(context.TODO(), b, nil)
+		assert.NoError(t, err)
+		assert.False(t, expired)
+		assert.Nil(t, b.Status.ExpirationTime)
+	})
+}
+
+func TestPodUsingSecretsPredicate(t *testing.T) {
+	withSecret := &corev1.Pod{Spec: corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "secret"}}}}
+	withoutSecret := &corev1.Pod{}
+
+	assert.True(t, podUsingSecretsPredicate.Create(event.CreateEvent{Object: withSecret}))
+	assert.False(t, podUsingSecretsPredicate.Create(event.CreateEvent{Object: withoutSecret}))
+
+	running := withSecret.DeepCopy()
+	running.Status.Phase = corev1.PodRunning
+	assert.True(t, podUsingSecretsPredicate.Update(event.UpdateEvent{ObjectOld: withSecret, ObjectNew: running}))
+	assert.False(t, podUsingSecretsPredicate.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: running.DeepCopy()}))
+
+	assert.False(t, podUsingSecretsPredicate.Delete(event.DeleteEvent{Object: withSecret}))
+}
+
+func TestConditionTypeForBindingError(t *testing.T) {
+	assert.Equal(t, api.SPIAccessTokenBindingConditionTypeTokenLinked, conditionTypeForBindingError(api.SPIAccessTokenBindingErrorReasonTokenLookup))
+	assert.Equal(t, api.SPIAccessTokenBindingConditionTypeTokenLinked, conditionTypeForBindingError(api.SPIAccessTokenBindingErrorReasonInvalidLifetime))
+	assert.Equal(t, api.SPIAccessTokenBindingConditionTypeSecretSynced, conditionTypeForBindingError(api.SPIAccessTokenBindingErrorReasonTokenSync))
+	assert.Equal(t, api.SPIAccessTokenBindingConditionTypeServiceAccountsLinked, conditionTypeForBindingError(api.SPIAccessTokenBindingErrorReasonServiceAccountUpdate))
+}
+
+func TestSetCondition(t *testing.T) {
+	binding := &api.SPIAccessTokenBinding{ObjectMeta: metav1.ObjectMeta{Generation: 3}}
+
+	setCondition(&binding.Status.Conditions, binding, api.SPIAccessTokenBindingConditionTypeTokenLinked, metav1.ConditionFalse, api.SPIAccessTokenBindingErrorReasonNoError, "failed")
+	assert.Len(t, binding.Status.Conditions, 1)
+	assert.Equal(t, "TokenLinked", binding.Status.Conditions[0].Type)
+	assert.Equal(t, conditionReasonUnknownError, binding.Status.Conditions[0].Reason)
+	assert.Equal(t, int64(3), binding.Status.Conditions[0].ObservedGeneration)
+
+	setCondition(&binding.Status.Conditions, binding, api.SPIAccessTokenBindingConditionTypeTokenLinked, metav1.ConditionTrue, api.SPIAccessTokenBindingReasonLinked, "linked")
+	assert.Len(t, binding.Status.Conditions, 1)
+	assert.Equal(t, metav1.ConditionTrue, binding.Status.Conditions[0].Status)
+	assert.Equal(t, "Linked", binding.Status.Conditions[0].Reason)
+}
+
 func mockK8sClient(objects ...client.Object) client.WithWatch {
 	sch := runtime.NewScheme()
 	utilruntime.Must(corev1.AddToScheme(sch))
 	utilruntime.Must(api.AddToScheme(sch))
 	return fake.NewClientBuilder().WithScheme(sch).WithObjects(objects...).Build()
 }
+
+func TestUpgradeUrlFor(t *testing.T) {
+	r := &SPIAccessTokenBindingReconciler{Configuration: &opconfig.OperatorConfiguration{}}
+	codec := oauthstate.NewCodec(config.OAuthStateConfiguration{})
+	capability := &serviceprovider.TestCapabilities{
+		GetOAuthEndpointImpl: func() string { return "https://spi/github/authenticate" },
+		OAuthScopesForImpl: func(_ *api.Permissions) []string {
+			return []string{"repo", "workflow"}
+		},
+	}
+	sp := serviceprovider.TestServiceProvider{
+		GetTypeImpl:     func() config.ServiceProviderType { return config.ServiceProviderTypeGitHub },
+		GetBaseUrlImpl:  func() string { return "https://github.com" },
+		OAuthCapability: func() serviceprovider.OAuthCapability { return capability },
+	}
+	token := &api.SPIAccessToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "default"},
+		Status: api.SPIAccessTokenStatus{
+			Phase:         api.SPIAccessTokenPhaseReady,
+			TokenMetadata: &api.TokenMetadata{UserId: "42", Scopes: []string{"read:org", "repo"}},
+		},
+	}
+
+	t.Run("union of scopes", func(t *testing.T) {
+		binding := &api.SPIAccessTokenBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}}
+		upgradeUrl, refreshAt, err := r.upgradeUrlFor(context.TODO(), sp, binding, token)
+		assert.NoError(t, err)
+		assert.True(t, refreshAt.IsZero())
+
+		info, _, ok := parseOAuthUrl(codec, upgradeUrl, "https://spi/github/authenticate")
+		assert.True(t, ok)
+		assert.Equal(t, oauthstate.OAuthInfo{
+			TokenName:           "token",
+			TokenNamespace:      "default",
+			Scopes:              []string{"read:org", "repo", "workflow"},
+			ServiceProviderName: config.ServiceProviderTypeGitHub.Name,
+			ServiceProviderUrl:  "https://github.com",
+			ExpectedUserId:      "42",
+		}, info)
+
+		binding.Status.UpgradeUrl = upgradeUrl
+		reusedUrl, _, err := r.upgradeUrlFor(context.TODO(), sp, binding, token)
+		assert.NoError(t, err)
+		assert.Equal(t, upgradeUrl, reusedUrl)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		anonymous := token.DeepCopy()
+		anonymous.Status.TokenMetadata.UserId = ""
+		upgradeUrl, _, err := r.upgradeUrlFor(context.TODO(), sp, &api.SPIAccessTokenBinding{}, anonymous)
+		assert.NoError(t, err)
+		assert.Empty(t, upgradeUrl)
+	})
+
+	t.Run("shared token", func(t *testing.T) {
+		binding := &api.SPIAccessTokenBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant"}}
+		upgradeUrl, _, err := r.upgradeUrlFor(context.TODO(), sp, binding, token)
+		assert.NoError(t, err)
+		assert.Empty(t, upgradeUrl)
+	})
+
+	t.Run("no OAuth capability", func(t *testing.T) {
+		noOAuth := sp
+		noOAuth.OAuthCapability = nil
+		upgradeUrl, _, err := r.upgradeUrlFor(context.TODO(), noOAuth, &api.SPIAccessTokenBinding{}, token)
+		assert.NoError(t, err)
+		assert.Empty(t, upgradeUrl)
+	})
+}
+
+func TestLinkTokenRechecksSharing(t *testing.T) {
+	sharedToken := &api.SPIAccessToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "bot", Namespace: "platform"},
+		Status:     api.SPIAccessTokenStatus{Phase: api.SPIAccessTokenPhaseReady},
+	}
+	policy := &api.SPIAccessTokenSharingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "share-bot"},
+		Spec: api.SPIAccessTokenSharingPolicySpec{
+			Token:            api.SharedTokenReference{Namespace: "platform", Name: "bot"},
+			TargetNamespaces: []string{"tenant"},
+		},
+	}
+	binding := &api.SPIAccessTokenBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "binding",
+			Namespace: "tenant",
+			Labels:    map[string]string{SPIAccessTokenLinkLabel: "bot"},
+		},
+		Spec: api.SPIAccessTokenBindingSpec{RepoUrl: "https://github.com/acme/repo"},
+		Status: api.SPIAccessTokenBindingStatus{
+			LinkedAccessTokenName:      "bot",
+			LinkedAccessTokenNamespace: "platform",
+		},
+	}
+	sp := serviceprovider.TestServiceProvider{
+		GetBaseUrlImpl: func() string { return "https://github.com" },
+		LookupTokensImpl: func(_ context.Context, _ client.Client, _ *api.SPIAccessTokenBinding) ([]api.SPIAccessToken, error) {
+			return []api.SPIAccessToken{}, nil
+		},
+	}
+
+	t.Run("still shared", func(t *testing.T) {
+		cl := mockK8sClient(sharedToken.DeepCopy(), policy.DeepCopy(), binding.DeepCopy())
+		r := &SPIAccessTokenBindingReconciler{Client: cl}
+		b := &api.SPIAccessTokenBinding{}
+		assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(binding), b))
+
+		token, matching, err := r.linkToken(context.TODO(), sp, b)
+		assert.NoError(t, err)
+		assert.False(t, matching)
+		assert.Equal(t, client.ObjectKeyFromObject(sharedToken), client.ObjectKeyFromObject(token))
+		assert.Equal(t, "platform", b.Status.LinkedAccessTokenNamespace)
+	})
+
+	t.Run("sharing revoked", func(t *testing.T) {
+		cl := mockK8sClient(sharedToken.DeepCopy(), binding.DeepCopy())
+		r := &SPIAccessTokenBindingReconciler{Client: cl}
+		b := &api.SPIAccessTokenBinding{}
+		assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(binding), b))
+
+		token, _, err := r.linkToken(context.TODO(), sp, b)
+		assert.NoError(t, err)
+		assert.Equal(t, "tenant", token.Namespace)
+		assert.NotEqual(t, "bot", token.Name)
+
+		assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(binding), b))
+		assert.Equal(t, token.Name, b.Status.LinkedAccessTokenName)
+		assert.Empty(t, b.Status.LinkedAccessTokenNamespace)
+		assert.Equal(t, token.Name, b.Labels[SPIAccessTokenLinkLabel])
+	})
+}
+
+func TestSharedTokenBindingsAsRequests(t *testing.T) {
+	linked := &api.SPIAccessTokenBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "linked", Namespace: "tenant", Labels: map[string]string{SPIAccessTokenLinkLabel: "bot"}},
+		Status:     api.SPIAccessTokenBindingStatus{LinkedAccessTokenName: "bot", LinkedAccessTokenNamespace: "platform"},
+	}
+	local := &api.SPIAccessTokenBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "tenant", Labels: map[string]string{SPIAccessTokenLinkLabel: "bot"}},
+		Status:     api.SPIAccessTokenBindingStatus{LinkedAccessTokenName: "bot"},
+	}
+	r := &SPIAccessTokenBindingReconciler{Client: mockK8sClient(linked, local)}
+
+	requests, err := r.sharedTokenBindingsAsRequests(context.TODO(), client.ObjectKey{Namespace: "platform", Name: "bot"})
+	assert.NoError(t, err)
+	assert.Len(t, requests, 1)
+	assert.Equal(t, client.ObjectKeyFromObject(linked), requests[0].NamespacedName)
+}
@@ -0,0 +1,234 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-playground/validator/v10"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	opconfig "github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// SPIBulkAccessCheckReconciler reconciles a SPIBulkAccessCheck object
+type SPIBulkAccessCheckReconciler struct {
+	client.Client
+	Scheme                 *runtime.Scheme
+	ServiceProviderFactory serviceprovider.Factory
+	Configuration          *opconfig.OperatorConfiguration
+}
+
+// providerRepositories are the repositories of the bulk access check handled by the same service provider.
+type providerRepositories struct {
+	sp      serviceprovider.ServiceProvider
+	indices []int
+}
+
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spibulkaccesschecks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spibulkaccesschecks/status,verbs=get;update;patch
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SPIBulkAccessCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewControllerManagedBy(mgr).
+		// the status updates must not trigger another evaluation of all the repositories
+		For(&api.SPIBulkAccessCheck{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+	if err != nil {
+		err = fmt.Errorf("failed to build the controller manager: %w", err)
+	}
+
+	return err
+}
+
+func (r *SPIBulkAccessCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	lg := log.FromContext(ctx)
+	defer logs.TimeTrackWithLazyLogger(func() logr.Logger { return lg }, time.Now(), "Reconcile SPIBulkAccessCheck")
+
+	bulk := api.SPIBulkAccessCheck{}
+	if err := r.Get(ctx, req.NamespacedName, &bulk); err != nil {
+		if errors.IsNotFound(err) {
+			lg.Info("SPIBulkAccessCheck not found on cluster")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to load the SPIBulkAccessCheck from the cluster: %w", err)
+	}
+
+	if time.Now().After(bulk.CreationTimestamp.Add(r.Configuration.AccessCheckTtl)) {
+		lg.Info("SPIBulkAccessCheck is after ttl, deleting ...")
+		if err := r.Delete(ctx, &bulk); err != nil {
+			return ctrl.Result{}, fmt.Errorf("error while deleting the bulk access check: %w", err)
+		}
+		lg.Info("SPIBulkAccessCheck deleted")
+		return ctrl.Result{}, nil
+	}
+
+	auditLog := log.FromContext(ctx, "audit", "true", "namespace", bulk.Namespace, "bulkAccessCheck", bulk.Name)
+	auditLog.Info("performing bulk repository access check", "action", "UPDATE", "repositories", len(bulk.Spec.Repositories))
+
+	statuses, checkErr := r.checkRepositories(ctx, &bulk)
+	if checkErr != nil {
+		auditLog.Error(checkErr, "failed to check the access to some of the repositories")
+	} else {
+		auditLog.Info("bulk repository access check succeeded")
+	}
+
+	bulk.Status.Repositories = make([]api.SPIBulkAccessCheckRepositoryStatus, len(statuses))
+	bulk.Status.AccessibleCount = 0
+	for i, status := range statuses {
+		bulk.Status.Repositories[i] = status.BulkRepositoryStatus(bulk.Spec.Repositories[i].RepoUrl)
+		if status.Accessible {
+			bulk.Status.AccessibleCount++
+		}
+	}
+	now := metav1.Now()
+	bulk.Status.LastCheckTime = &now
+
+	if checkErr != nil {
+		setCondition(&bulk.Status.Conditions, &bulk, api.SPIBulkAccessCheckConditionTypeChecked, metav1.ConditionFalse, api.SPIBulkAccessCheckReasonCheckFailed, checkErr.Error())
+	} else {
+		setCondition(&bulk.Status.Conditions, &bulk, api.SPIBulkAccessCheckConditionTypeChecked, metav1.ConditionTrue, api.SPIBulkAccessCheckReasonChecked,
+			fmt.Sprintf("%d of %d repositories are accessible", bulk.Status.AccessibleCount, len(statuses)))
+	}
+
+	if err := r.Client.Status().Update(ctx, &bulk); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update the status of the bulk access check: %w", err)
+	}
+
+	// The failures are recorded in the status of the affected repositories. We don't retry the check with backoff,
+	// because that would check all the other repositories again. The repositories are checked again on spec changes.
+	return ctrl.Result{RequeueAfter: r.Configuration.AccessCheckTtl}, nil
+}
+
+// checkRepositories checks the access to all the repositories of the bulk access check. The repositories are grouped by
+// the service provider and each group is checked at once, if the service provider supports it. The returned statuses
+// are in the same order as the repositories in the spec. The failures to check a group are recorded in the statuses of
+// the repositories in the group and also returned as the error, so that the failure of one group doesn't prevent
+// checking the others.
+func (r *SPIBulkAccessCheckReconciler) checkRepositories(ctx context.Context, bulk *api.SPIBulkAccessCheck) ([]*api.SPIAccessCheckStatus, error) {
+	statuses := make([]*api.SPIAccessCheckStatus, len(bulk.Spec.Repositories))
+	accessChecks := make([]*api.SPIAccessCheck, len(bulk.Spec.Repositories))
+
+	// the service providers are determined once per base URL, because the determination can be expensive
+	providersByBaseUrl := map[string]*providerRepositories{}
+	var providers []*providerRepositories
+
+	for i := range bulk.Spec.Repositories {
+		accessChecks[i] = bulk.AccessCheck(i)
+		repoUrl := accessChecks[i].Spec.RepoUrl
+
+		baseUrl := ""
+		if parsed, err := url.Parse(repoUrl); err == nil {
+			baseUrl = config.GetBaseUrl(parsed)
+		}
+		if group, ok := providersByBaseUrl[baseUrl]; ok && baseUrl != "" {
+			group.indices = append(group.indices, i)
+			continue
+		}
+
+		sp, err := r.ServiceProviderFactory.FromRepoUrl(ctx, repoUrl, bulk.Namespace)
+		if err != nil {
+			statuses[i] = &api.SPIAccessCheckStatus{
+				Accessibility: api.SPIAccessCheckAccessibilityUnknown,
+				ErrorReason:   serviceProviderErrorReason(err),
+				ErrorMessage:  err.Error(),
+			}
+			continue
+		}
+		group := &providerRepositories{sp: sp, indices: []int{i}}
+		providers = append(providers, group)
+		if baseUrl != "" {
+			providersByBaseUrl[baseUrl] = group
+		}
+	}
+
+	var errs []error
+	for _, group := range providers {
+		groupChecks := make([]*api.SPIAccessCheck, len(group.indices))
+		for j, i := range group.indices {
+			groupChecks[j] = accessChecks[i]
+		}
+
+		groupStatuses, err := checkRepositoriesAccess(ctx, r.Client, group.sp, groupChecks)
+		for j, i := range group.indices {
+			if err != nil {
+				statuses[i] = &api.SPIAccessCheckStatus{
+					ServiceProvider: api.ServiceProviderType(group.sp.GetType().Name),
+					Accessibility:   api.SPIAccessCheckAccessibilityUnknown,
+					ErrorReason:     api.SPIAccessCheckErrorUnknownError,
+					ErrorMessage:    err.Error(),
+				}
+			} else {
+				statuses[i] = groupStatuses[j]
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return statuses, fmt.Errorf("failed to check the repositories: %w", kubeerrors.NewAggregate(errs))
+	}
+	return statuses, nil
+}
+
+// checkRepositoriesAccess checks the repositories using the bulk capability of the service provider, if it has one.
+// Otherwise, the repositories are checked one by one.
+func checkRepositoriesAccess(ctx context.Context, cl client.Client, sp serviceprovider.ServiceProvider, accessChecks []*api.SPIAccessCheck) ([]*api.SPIAccessCheckStatus, error) {
+	if bulk, ok := sp.(serviceprovider.BulkAccessCheckCapability); ok {
+		statuses, err := bulk.CheckRepositoriesAccess(ctx, cl, accessChecks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the repositories of %s: %w", sp.GetBaseUrl(), err)
+		}
+		return statuses, nil
+	}
+
+	statuses := make([]*api.SPIAccessCheckStatus, len(accessChecks))
+	indices := make([]int, len(accessChecks))
+	for i := range indices {
+		indices[i] = i
+	}
+	if err := serviceprovider.CheckRepositoriesAccessOneByOne(ctx, cl, sp, accessChecks, indices, statuses); err != nil {
+		return nil, fmt.Errorf("failed to check the repositories of %s: %w", sp.GetBaseUrl(), err)
+	}
+	return statuses, nil
+}
+
+// serviceProviderErrorReason returns the reason of the access check failure caused by the inability to determine
+// the service provider.
+func serviceProviderErrorReason(err error) api.SPIAccessCheckErrorReason {
+	var validationErr validator.ValidationErrors
+	if stderrors.As(err, &validationErr) {
+		return api.SPIAccessCheckErrorUnsupportedServiceProviderConfiguration
+	}
+	return api.SPIAccessCheckErrorUnknownServiceProvider
+}
@@ -0,0 +1,156 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	rconfig "github.com/redhat-appstudio/remote-secret/pkg/config"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	opconfig "github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type bulkTestServiceProvider struct {
+	serviceprovider.TestServiceProvider
+	checkRepositoriesAccessImpl func(context.Context, client.Client, []*api.SPIAccessCheck) ([]*api.SPIAccessCheckStatus, error)
+}
+
+func (b bulkTestServiceProvider) CheckRepositoriesAccess(ctx context.Context, cl client.Client, accessChecks []*api.SPIAccessCheck) ([]*api.SPIAccessCheckStatus, error) {
+	return b.checkRepositoriesAccessImpl(ctx, cl, accessChecks)
+}
+
+func TestCheckRepositoriesAccess(t *testing.T) {
+	accessChecks := []*api.SPIAccessCheck{
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/a"}},
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/b"}},
+	}
+	singleCheck := func(_ context.Context, _ client.Client, ac *api.SPIAccessCheck) (*api.SPIAccessCheckStatus, error) {
+		return &api.SPIAccessCheckStatus{Accessible: ac.Spec.RepoUrl == "https://test.sp/a"}, nil
+	}
+
+	t.Run("one by one", func(t *testing.T) {
+		sp := serviceprovider.TestServiceProvider{CheckRepositoryAccessImpl: singleCheck}
+
+		statuses, err := checkRepositoriesAccess(context.TODO(), nil, sp, accessChecks)
+		assert.NoError(t, err)
+		assert.Len(t, statuses, 2)
+		assert.True(t, statuses[0].Accessible)
+		assert.False(t, statuses[1].Accessible)
+	})
+
+	t.Run("bulk capability", func(t *testing.T) {
+		sp := bulkTestServiceProvider{
+			TestServiceProvider: serviceprovider.TestServiceProvider{
+				CheckRepositoryAccessImpl: func(_ context.Context, _ client.Client, _ *api.SPIAccessCheck) (*api.SPIAccessCheckStatus, error) {
+					assert.Fail(t, "the repositories should not be checked one by one")
+					return nil, nil
+				},
+			},
+			checkRepositoriesAccessImpl: func(_ context.Context, _ client.Client, acs []*api.SPIAccessCheck) ([]*api.SPIAccessCheckStatus, error) {
+				return []*api.SPIAccessCheckStatus{{Accessible: true}, {Accessible: true}}, nil
+			},
+		}
+
+		statuses, err := checkRepositoriesAccess(context.TODO(), nil, sp, accessChecks)
+		assert.NoError(t, err)
+		assert.Len(t, statuses, 2)
+		assert.True(t, statuses[1].Accessible)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		sp := serviceprovider.TestServiceProvider{
+			CheckRepositoryAccessImpl: func(_ context.Context, _ client.Client, _ *api.SPIAccessCheck) (*api.SPIAccessCheckStatus, error) {
+				return nil, errors.New("intentional failure")
+			},
+			GetBaseUrlImpl: func() string { return "https://test.sp" },
+		}
+
+		_, err := checkRepositoriesAccess(context.TODO(), nil, sp, accessChecks)
+		assert.ErrorContains(t, err, "https://test.sp")
+	})
+}
+
+func TestReconcileBulkAccessCheckGroupFailure(t *testing.T) {
+	bulk := &api.SPIBulkAccessCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "bulk", Namespace: "default", CreationTimestamp: metav1.Now()},
+		Spec: api.SPIBulkAccessCheckSpec{Repositories: []api.SPIBulkAccessCheckRepository{
+			{RepoUrl: "https://ok.sp/acme/repo"},
+			{RepoUrl: "https://failing.sp/acme/repo"},
+		}},
+	}
+	cl := mockK8sClient(bulk)
+
+	assert.NoError(t, rconfig.SetupCustomValidations(rconfig.CustomValidationOptions{AllowInsecureURLs: false}))
+	initializer := serviceprovider.Initializer{
+		Constructor: serviceprovider.ConstructorFunc(func(_ *serviceprovider.Factory, spConfig *config.ServiceProviderConfiguration) (serviceprovider.ServiceProvider, error) {
+			baseUrl := spConfig.ServiceProviderBaseUrl
+			return serviceprovider.TestServiceProvider{
+				CheckRepositoryAccessImpl: func(_ context.Context, _ client.Client, _ *api.SPIAccessCheck) (*api.SPIAccessCheckStatus, error) {
+					if baseUrl == "https://failing.sp" {
+						return nil, errors.New("intentional failure")
+					}
+					return &api.SPIAccessCheckStatus{Accessible: true, Accessibility: api.SPIAccessCheckAccessibilityPublic}, nil
+				},
+				GetBaseUrlImpl: func() string { return baseUrl },
+				GetTypeImpl:    func() config.ServiceProviderType { return config.ServiceProviderTypeHostCredentials },
+			}, nil
+		}),
+	}
+	initializers := serviceprovider.NewInitializers().AddKnownInitializer(config.ServiceProviderTypeHostCredentials, initializer)
+	for _, spType := range config.SupportedServiceProviderTypes {
+		initializers.AddKnownInitializer(spType, initializer)
+	}
+
+	r := &SPIBulkAccessCheckReconciler{
+		Client:        cl,
+		Configuration: &opconfig.OperatorConfiguration{AccessCheckTtl: time.Hour},
+		ServiceProviderFactory: serviceprovider.Factory{
+			Configuration:    &opconfig.OperatorConfiguration{},
+			KubernetesClient: cl,
+			Initializers:     initializers,
+		},
+	}
+
+	res, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bulk)})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, res.RequeueAfter)
+
+	updated := &api.SPIBulkAccessCheck{}
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(bulk), updated))
+	assert.Len(t, updated.Status.Repositories, 2)
+	assert.True(t, updated.Status.Repositories[0].Accessible)
+	assert.Empty(t, updated.Status.Repositories[0].ErrorReason)
+	assert.False(t, updated.Status.Repositories[1].Accessible)
+	assert.Equal(t, api.SPIAccessCheckErrorUnknownError, updated.Status.Repositories[1].ErrorReason)
+	assert.Contains(t, updated.Status.Repositories[1].ErrorMessage, "intentional failure")
+	assert.Equal(t, 1, updated.Status.AccessibleCount)
+	assert.True(t, meta.IsStatusConditionFalse(updated.Status.Conditions, string(api.SPIBulkAccessCheckConditionTypeChecked)))
+}
+
+func TestServiceProviderErrorReason(t *testing.T) {
+	assert.Equal(t, api.SPIAccessCheckErrorUnknownServiceProvider, serviceProviderErrorReason(errors.New("unknown")))
+	assert.Equal(t, api.SPIAccessCheckErrorUnsupportedServiceProviderConfiguration, serviceProviderErrorReason(validator.ValidationErrors{}))
+}
@@ -15,12 +15,19 @@
 package controllers
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
 	stderrors "errors"
 	"fmt"
 	"net/http"
+	"path"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -31,38 +38,63 @@ import (
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	opconfig "github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-const linkedFileRequestBindingsFinalizerName = "spi.appstudio.redhat.com/file-linked-bindings"
+const (
+	linkedFileRequestBindingsFinalizerName = "spi.appstudio.redhat.com/file-linked-bindings"
+
+	// minimalFileRefreshInterval is the lower bound of the refresh interval of the file content requests following
+	// their ref, so that we don't exhaust the rate limits of the service providers.
+	minimalFileRefreshInterval = 30 * time.Second
+
+	fileContentUpdatedEventReason = "ContentUpdated"
+	fileRefreshFailedEventReason  = "RefreshFailed"
+)
 
 var (
 	noSuitableServiceProviderFound  = stderrors.New("unable to find a matching service provider for the given URL")
 	unableToValidateServiceProvider = stderrors.New("unable to validate service provider for the given URL")
 	noCredentialsFoundError         = stderrors.New("no suitable credentials found, please create SPIAccessToken or RemoteSecret")
+	unsupportedTargetKindError      = stderrors.New("unsupported kind of the file content target")
+	targetNotOwnedError             = stderrors.New("the target object already exists and is not owned by the file content request")
+	targetKeyConflictError          = stderrors.New("several files of the directory map to the same key in the target object")
+	targetTooLargeError             = stderrors.New("the file content exceeds the maximum size of the data of the target object")
 )
 
+// maxTargetDataSize is the maximum size of the data of a ConfigMap or Secret accepted by Kubernetes.
+const maxTargetDataSize = 1024 * 1024
+
 type SPIFileContentRequestReconciler struct {
 	Configuration          *opconfig.OperatorConfiguration
 	K8sClient              client.Client
 	Scheme                 *runtime.Scheme
 	HttpClient             *http.Client
 	ServiceProviderFactory serviceprovider.Factory
+	Recorder               record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spifilecontentrequests,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spifilecontentrequests/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spifilecontentrequests/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SPIFileContentRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	err := ctrl.NewControllerManagedBy(mgr).
 		For(&api.SPIFileContentRequest{}).
+		// the delivered content is restored if the target object is modified or deleted
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.Secret{}).
 		Complete(r)
 	if err != nil {
 		err = fmt.Errorf("failed to build the controller manager: %w", err)
@@ -111,8 +143,24 @@ func (r *SPIFileContentRequestReconciler) Reconcile(ctx context.Context, req ctr
 
 	// Controller logic usually should not be dependent on the object's status, but this helps us save some
 	// service provider API calls which lessens the chance we hit a rate limit on the API.
+	refreshing := false
 	if request.Status.Phase == api.SPIFileContentRequestPhaseDelivered {
-		return ctrl.Result{}, nil
+		missing, err := r.targetMissing(ctx, &request)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if missing {
+			// the target object has been deleted, let's deliver the content again
+			lg.Info("the target object of the delivered file content is missing, delivering again")
+		} else {
+			if !request.Spec.FollowsRef() {
+				return ctrl.Result{}, nil
+			}
+			if r.durationUntilNextRefresh(&request) > 0 {
+				return ctrl.Result{RequeueAfter: r.nextRequeue(&request)}, nil
+			}
+			refreshing = true
+		}
 	}
 
 	sp, err := r.ServiceProviderFactory.FromRepoUrl(ctx, request.Spec.RepoUrl, request.Namespace)
@@ -128,7 +176,11 @@ func (r *SPIFileContentRequestReconciler) Reconcile(ctx context.Context, req ctr
 		return r.updateFileRequestStatusError(ctx, &request, noSuitableServiceProviderFound)
 	}
 
-	if sp.GetDownloadFileCapability() == nil {
+	if request.Spec.IsDirectory() {
+		if sp.GetDownloadTreeCapability() == nil {
+			return r.updateFileRequestStatusError(ctx, &request, serviceprovider.TreeDownloadNotSupportedError{})
+		}
+	} else if sp.GetDownloadFileCapability() == nil {
 		return r.updateFileRequestStatusError(ctx, &request, serviceprovider.FileDownloadNotSupportedError{})
 	}
 	credentials, err := sp.LookupCredentials(ctx, r.K8sClient, &request)
@@ -140,21 +192,360 @@ func (r *SPIFileContentRequestReconciler) Reconcile(ctx context.Context, req ctr
 		return r.updateFileRequestStatusError(ctx, &request, noCredentialsFoundError)
 	}
 
-	contents, err := sp.GetDownloadFileCapability().DownloadFile(ctx, request.Spec, *credentials, r.Configuration.MaxFileDownloadSize)
-	if err != nil {
-		// We might fix the download error by retrying the reconciliation
-		return r.requeueErrorWithStatusUpdate(ctx, &request, fmt.Errorf("error fetching file content: %w", err))
+	if refreshing {
+		unchanged, err := r.refUnchanged(ctx, sp, &request, *credentials)
+		if err != nil {
+			return r.refreshFailed(ctx, &request, err)
+		}
+		if unchanged {
+			return r.finishRefresh(ctx, &request)
+		}
+	}
+
+	maxFileSize := r.Configuration.MaxFileDownloadSize
+	if request.Spec.Target != nil && r.Configuration.MaxTargetFileDownloadSize > 0 {
+		maxFileSize = r.Configuration.MaxTargetFileDownloadSize
+	}
+
+	spec := downloadSpec(&request)
+	// the content is downloaded again either because the ref moved or because the target object was deleted. Let's
+	// not throw away the previously delivered content if that fails.
+	redelivering := request.Status.Phase == api.SPIFileContentRequestPhaseDelivered
+
+	var files map[string]string
+	var metadata *api.SPIFileContentMetadata
+	if spec.IsDirectory() {
+		files, err = sp.GetDownloadTreeCapability().DownloadTree(ctx, spec, *credentials, maxFileSize)
+		if err != nil {
+			if redelivering {
+				return r.refreshFailed(ctx, &request, err)
+			}
+			// We might fix the download error by retrying the reconciliation
+			return r.requeueErrorWithStatusUpdate(ctx, &request, fmt.Errorf("error fetching directory content: %w", err))
+		}
+	} else {
+		file, err := sp.GetDownloadFileCapability().DownloadFile(ctx, spec, *credentials, maxFileSize)
+		if err != nil {
+			if redelivering {
+				return r.refreshFailed(ctx, &request, err)
+			}
+			// We might fix the download error by retrying the reconciliation
+			return r.requeueErrorWithStatusUpdate(ctx, &request, fmt.Errorf("error fetching file content: %w", err))
+		}
+		if refreshing && !fileChanged(request.Status.FileMetadata, &file.Metadata) {
+			// the ref moved, but the file stayed the same
+			request.Status.FileMetadata = &file.Metadata
+			return r.finishRefresh(ctx, &request)
+		}
+		files = map[string]string{path.Base(request.Spec.FilePath): file.Content}
+		metadata = &file.Metadata
 	}
 
 	request.Status.ErrorMessage = ""
-	request.Status.ContentEncoding = "base64"
-	request.Status.Content = base64.StdEncoding.EncodeToString([]byte(contents))
+	request.Status.FileMetadata = metadata
+	if request.Spec.Target == nil {
+		contents, err := fileRequestContents(&request.Spec, files)
+		if err != nil {
+			return r.updateFileRequestStatusError(ctx, &request, err)
+		}
+		request.Status.ContentEncoding = "base64"
+		request.Status.Content = base64.StdEncoding.EncodeToString(contents)
+		request.Status.SyncedObjectRef = nil
+	} else {
+		data, err := targetData(&request, files)
+		if err != nil {
+			return r.updateFileRequestStatusError(ctx, &request, err)
+		}
+		ref, err := r.deliverToTarget(ctx, &request, data)
+		if err != nil {
+			return r.requeueErrorWithStatusUpdate(ctx, &request, fmt.Errorf("failed to deliver the file content to the target: %w", err))
+		}
+		request.Status.ContentEncoding = ""
+		request.Status.Content = ""
+		request.Status.SyncedObjectRef = &ref
+	}
+	now := metav1.Now()
+	request.Status.LastRefreshTime = &now
 	request.Status.Phase = api.SPIFileContentRequestPhaseDelivered
+	setCondition(&request.Status.Conditions, &request, api.SPIFileContentRequestConditionTypeContentDelivered, metav1.ConditionTrue,
+		api.SPIFileContentRequestReasonDelivered, "the file content has been delivered")
+	if redelivering {
+		setRefreshedCondition(&request)
+	}
 
 	if err := r.K8sClient.Status().Update(ctx, &request); err != nil {
 		return reconcile.Result{}, fmt.Errorf("failed to update the file request status: %w", err)
 	}
-	return ctrl.Result{RequeueAfter: r.durationUntilNextReconcile(&request)}, nil
+	if refreshing {
+		r.Recorder.Eventf(&request, corev1.EventTypeNormal, fileContentUpdatedEventReason, "the file content has been updated from commit %s", request.Status.FileMetadata.CommitSha)
+	}
+	return ctrl.Result{RequeueAfter: r.nextRequeue(&request)}, nil
+}
+
+// refUnchanged cheaply checks whether the ref of the delivered request still points to the commit the content was
+// downloaded from. It returns false if the service provider is not able to resolve the refs.
+func (r *SPIFileContentRequestReconciler) refUnchanged(ctx context.Context, sp serviceprovider.ServiceProvider, request *api.SPIFileContentRequest, credentials serviceprovider.Credentials) (bool, error) {
+	resolver, ok := sp.GetDownloadFileCapability().(serviceprovider.RefResolvingCapability)
+	if !ok || request.Status.FileMetadata == nil || request.Status.FileMetadata.CommitSha == "" {
+		return false, nil
+	}
+
+	lastCommitSha := request.Status.FileMetadata.CommitSha
+	commitSha, err := resolver.ResolveRef(ctx, request.Spec, credentials, lastCommitSha)
+	if err != nil {
+		return false, fmt.Errorf("failed to check the ref for changes: %w", err)
+	}
+	log.FromContext(ctx).V(logs.DebugLevel).Info("checked the ref of the file content request", "lastCommit", lastCommitSha, "commit", commitSha)
+	return commitSha == lastCommitSha, nil
+}
+
+// fileChanged returns true if the newly downloaded file differs from the previously delivered one. If we can't tell, we
+// consider the file changed.
+func fileChanged(previous *api.SPIFileContentMetadata, current *api.SPIFileContentMetadata) bool {
+	if previous == nil || previous.BlobSha == "" || current.BlobSha == "" {
+		return true
+	}
+	return previous.BlobSha != current.BlobSha || previous.FilePath != current.FilePath
+}
+
+// finishRefresh records the time of the refresh that didn't find any change of the file and schedules the next one.
+func (r *SPIFileContentRequestReconciler) finishRefresh(ctx context.Context, request *api.SPIFileContentRequest) (ctrl.Result, error) {
+	now := metav1.Now()
+	request.Status.LastRefreshTime = &now
+	setRefreshedCondition(request)
+	if err := r.K8sClient.Status().Update(ctx, request); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to update the file request status: %w", err)
+	}
+	return ctrl.Result{RequeueAfter: r.nextRequeue(request)}, nil
+}
+
+// refreshFailed handles an error during the refresh of the delivered content. The previously delivered content is kept,
+// the failure is recorded in the ContentRefreshed condition and the refresh is retried after the refresh interval or
+// after the reset of the rate limit if it was exceeded.
+func (r *SPIFileContentRequestReconciler) refreshFailed(ctx context.Context, request *api.SPIFileContentRequest, err error) (ctrl.Result, error) {
+	log.FromContext(ctx).Error(err, "failed to refresh the file content")
+	r.Recorder.Event(request, corev1.EventTypeWarning, fileRefreshFailedEventReason, err.Error())
+
+	setCondition(&request.Status.Conditions, request, api.SPIFileContentRequestConditionTypeContentRefreshed, metav1.ConditionFalse,
+		api.SPIFileContentRequestReasonRefreshFailed, err.Error())
+	if uerr := r.K8sClient.Status().Update(ctx, request); uerr != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update the file request status after a failed refresh: %w", uerr)
+	}
+
+	retryAfter := refreshInterval(request)
+	var rateLimitedErr *serviceprovider.RateLimitedError
+	if stderrors.As(err, &rateLimitedErr) {
+		if untilReset := time.Until(rateLimitedErr.ResetTime); untilReset > retryAfter {
+			retryAfter = untilReset
+		}
+	}
+	if untilDeletion := r.durationUntilNextReconcile(request); untilDeletion < retryAfter {
+		retryAfter = untilDeletion
+	}
+	return ctrl.Result{RequeueAfter: retryAfter}, nil
+}
+
+// setRefreshedCondition marks the content of the request as successfully refreshed.
+func setRefreshedCondition(request *api.SPIFileContentRequest) {
+	setCondition(&request.Status.Conditions, request, api.SPIFileContentRequestConditionTypeContentRefreshed, metav1.ConditionTrue,
+		api.SPIFileContentRequestReasonRefreshed, "the file content is up to date with the ref")
+}
+
+// nextRequeue returns the duration until the next reconciliation of the delivered request. That is either the next
+// refresh if the request follows its ref or the end of its lifetime.
+func (r *SPIFileContentRequestReconciler) nextRequeue(request *api.SPIFileContentRequest) time.Duration {
+	untilDeletion := r.durationUntilNextReconcile(request)
+	if request.Spec.FollowsRef() {
+		if untilRefresh := r.durationUntilNextRefresh(request); untilRefresh < untilDeletion {
+			return untilRefresh
+		}
+	}
+	return untilDeletion
+}
+
+func (r *SPIFileContentRequestReconciler) durationUntilNextRefresh(request *api.SPIFileContentRequest) time.Duration {
+	if request.Status.LastRefreshTime == nil {
+		return 0
+	}
+	return time.Until(request.Status.LastRefreshTime.Add(refreshInterval(request)))
+}
+
+func refreshInterval(request *api.SPIFileContentRequest) time.Duration {
+	if request.Spec.RefreshInterval == nil || request.Spec.RefreshInterval.Duration < minimalFileRefreshInterval {
+		return minimalFileRefreshInterval
+	}
+	return request.Spec.RefreshInterval.Duration
+}
+
+// downloadSpec returns the spec to download the content with. If the request pins its ref and the ref has already been
+// resolved to a commit during a previous download, the commit is used instead of the ref.
+func downloadSpec(request *api.SPIFileContentRequest) api.SPIFileContentRequestSpec {
+	spec := request.Spec
+	if spec.PinRef && request.Status.FileMetadata != nil && request.Status.FileMetadata.CommitSha != "" {
+		spec.Ref = request.Status.FileMetadata.CommitSha
+	}
+	return spec
+}
+
+// fileRequestContents returns the content to deliver for the request. That is the content of the single downloaded file
+// or the gzipped tar archive of the files in the Directory mode.
+func fileRequestContents(spec *api.SPIFileContentRequestSpec, files map[string]string) ([]byte, error) {
+	if spec.IsDirectory() {
+		return archiveFiles(files)
+	}
+	for _, contents := range files {
+		return []byte(contents), nil
+	}
+	return []byte{}, nil
+}
+
+// targetData returns the data to store in the target object of the request. That is the content of the request under
+// the key specified in the target or, in the Directory mode without the key, each file under its own key.
+func targetData(request *api.SPIFileContentRequest, files map[string]string) (map[string][]byte, error) {
+	var data map[string][]byte
+	key := request.Spec.Target.Key
+	if request.Spec.IsDirectory() && key == "" {
+		data = make(map[string][]byte, len(files))
+		for relPath, contents := range files {
+			fileKey := strings.ReplaceAll(relPath, "/", "_")
+			if _, ok := data[fileKey]; ok {
+				return nil, fmt.Errorf("%w: %s", targetKeyConflictError, fileKey)
+			}
+			data[fileKey] = []byte(contents)
+		}
+	} else {
+		if key == "" {
+			key = path.Base(request.Spec.FilePath)
+		}
+		contents, err := fileRequestContents(&request.Spec, files)
+		if err != nil {
+			return nil, err
+		}
+		data = map[string][]byte{key: contents}
+	}
+
+	// Kubernetes would reject the object anyway, but we want a clear error message in the status of the request instead
+	// of repeated failed attempts to create the object.
+	size := 0
+	for key, contents := range data {
+		size += len(key) + len(contents)
+	}
+	if size > maxTargetDataSize {
+		return nil, fmt.Errorf("%w: %d bytes", targetTooLargeError, size)
+	}
+
+	return data, nil
+}
+
+// archiveFiles creates a gzipped tar archive of the provided files. The files are stored in the archive sorted by
+// their paths so that the archive is the same for the same set of files.
+func archiveFiles(files map[string]string) ([]byte, error) {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, p := range paths {
+		contents := files[p]
+		if err := tw.WriteHeader(&tar.Header{Name: p, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}); err != nil {
+			return nil, fmt.Errorf("failed to write the archive header of %s: %w", p, err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			return nil, fmt.Errorf("failed to write %s to the archive: %w", p, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish the archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress the archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// targetMissing checks whether the target object the content of the request has been delivered to no longer exists.
+func (r *SPIFileContentRequestReconciler) targetMissing(ctx context.Context, request *api.SPIFileContentRequest) (bool, error) {
+	ref := request.Status.SyncedObjectRef
+	if request.Spec.Target == nil || ref == nil {
+		return false, nil
+	}
+
+	var obj client.Object
+	switch ref.Kind {
+	case string(api.SPIFileContentRequestTargetKindConfigMap):
+		obj = &corev1.ConfigMap{}
+	case string(api.SPIFileContentRequestTargetKindSecret):
+		obj = &corev1.Secret{}
+	default:
+		return false, nil
+	}
+
+	if err := r.K8sClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: request.Namespace}, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get the target object %s %s: %w", ref.Kind, ref.Name, err)
+	}
+	return false, nil
+}
+
+// deliverToTarget writes the data into the ConfigMap or Secret specified in the target of the request. The target
+// object is owned by the request so that it is deleted together with it. An existing object not owned by the request
+// is never overwritten.
+func (r *SPIFileContentRequestReconciler) deliverToTarget(ctx context.Context, request *api.SPIFileContentRequest, data map[string][]byte) (api.TargetObjectRef, error) {
+	target := request.Spec.Target
+	name := target.Name
+	if name == "" {
+		name = request.Name
+	}
+
+	var obj client.Object
+	var fillData func()
+	switch target.Kind {
+	case api.SPIFileContentRequestTargetKindConfigMap:
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: request.Namespace}}
+		fillData = func() {
+			cm.Data = map[string]string{}
+			cm.BinaryData = map[string][]byte{}
+			for key, contents := range data {
+				if utf8.Valid(contents) {
+					cm.Data[key] = string(contents)
+				} else {
+					cm.BinaryData[key] = contents
+				}
+			}
+		}
+		obj = cm
+	case api.SPIFileContentRequestTargetKindSecret:
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: request.Namespace}}
+		fillData = func() {
+			secret.Data = data
+		}
+		obj = secret
+	default:
+		return api.TargetObjectRef{}, fmt.Errorf("%w: %s", unsupportedTargetKindError, target.Kind)
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.K8sClient, obj, func() error {
+		if obj.GetResourceVersion() != "" && !metav1.IsControlledBy(obj, request) {
+			return fmt.Errorf("%w: %s %s", targetNotOwnedError, target.Kind, name)
+		}
+		fillData()
+		if err := controllerutil.SetControllerReference(request, obj, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set the owner of the target object: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return api.TargetObjectRef{}, fmt.Errorf("failed to create or update the %s %s: %w", target.Kind, name, err)
+	}
+	log.FromContext(ctx).V(logs.DebugLevel).Info("file content delivered to the target", "kind", target.Kind, "name", name, "keys", len(data), "result", result)
+
+	return api.TargetObjectRef{Name: name, Kind: string(target.Kind), ApiVersion: corev1.SchemeGroupVersion.String()}, nil
 }
 
 func (r *SPIFileContentRequestReconciler) durationUntilNextReconcile(req *api.SPIFileContentRequest) time.Duration {
@@ -177,6 +568,8 @@ func (r *SPIFileContentRequestReconciler) updateFileRequestStatusError(ctx conte
 	request.Status.ContentEncoding = ""
 	request.Status.ErrorMessage = err.Error()
 	request.Status.Phase = api.SPIFileContentRequestPhaseError
+	setCondition(&request.Status.Conditions, request, api.SPIFileContentRequestConditionTypeContentDelivered, metav1.ConditionFalse,
+		api.SPIFileContentRequestReasonError, err.Error())
 	if err := r.K8sClient.Status().Update(ctx, request); err != nil {
 		// we might consider using `errors.IsConflict(err)` here
 		return ctrl.Result{}, fmt.Errorf("failed to update SPIFileContentRequest status with error: %w", err)
@@ -0,0 +1,334 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDeliverToTarget(t *testing.T) {
+	request := func(target *api.SPIFileContentRequestTarget) *api.SPIFileContentRequest {
+		return &api.SPIFileContentRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "request", Namespace: "default", UID: "request-uid"},
+			Spec: api.SPIFileContentRequestSpec{
+				RepoUrl:  "https://github.com/acme/repo",
+				FilePath: "config/settings.yaml",
+				Target:   target,
+			},
+		}
+	}
+
+	t.Run("config map with defaults", func(t *testing.T) {
+		cl := mockK8sClient()
+		r := &SPIFileContentRequestReconciler{K8sClient: cl, Scheme: cl.Scheme()}
+		req := request(&api.SPIFileContentRequestTarget{Kind: api.SPIFileContentRequestTargetKindConfigMap})
+
+		ref, err := r.deliverToTarget(context.TODO(), req, map[string][]byte{"settings.yaml": []byte("content")})
+		assert.NoError(t, err)
+		assert.Equal(t, api.TargetObjectRef{Name: "request", Kind: "ConfigMap", ApiVersion: "v1"}, ref)
+
+		cm := &corev1.ConfigMap{}
+		assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "request", Namespace: "default"}, cm))
+		assert.Equal(t, "content", cm.Data["settings.yaml"])
+		assert.True(t, metav1.IsControlledBy(cm, req))
+	})
+
+	t.Run("config map with binary content", func(t *testing.T) {
+		cl := mockK8sClient()
+		r := &SPIFileContentRequestReconciler{K8sClient: cl, Scheme: cl.Scheme()}
+		req := request(&api.SPIFileContentRequestTarget{Kind: api.SPIFileContentRequestTargetKindConfigMap, Name: "cm", Key: "data"})
+
+		_, err := r.deliverToTarget(context.TODO(), req, map[string][]byte{"data": []byte("\xff\xfe")})
+		assert.NoError(t, err)
+
+		cm := &corev1.ConfigMap{}
+		assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "cm", Namespace: "default"}, cm))
+		assert.Empty(t, cm.Data)
+		assert.Equal(t, []byte("\xff\xfe"), cm.BinaryData["data"])
+	})
+
+	t.Run("secret is updated", func(t *testing.T) {
+		cl := mockK8sClient()
+		r := &SPIFileContentRequestReconciler{K8sClient: cl, Scheme: cl.Scheme()}
+		req := request(&api.SPIFileContentRequestTarget{Kind: api.SPIFileContentRequestTargetKindSecret, Name: "secret"})
+
+		_, err := r.deliverToTarget(context.TODO(), req, map[string][]byte{"settings.yaml": []byte("old")})
+		assert.NoError(t, err)
+		ref, err := r.deliverToTarget(context.TODO(), req, map[string][]byte{"settings.yaml": []byte("new")})
+		assert.NoError(t, err)
+		assert.Equal(t, "Secret", ref.Kind)
+
+		secret := &corev1.Secret{}
+		assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "secret", Namespace: "default"}, secret))
+		assert.Equal(t, []byte("new"), secret.Data["settings.yaml"])
+	})
+
+	t.Run("foreign object is not overwritten", func(t *testing.T) {
+		cl := mockK8sClient(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "foreign", Namespace: "default"},
+			Data:       map[string]string{"a": "b"},
+		})
+		r := &SPIFileContentRequestReconciler{K8sClient: cl, Scheme: cl.Scheme()}
+		req := request(&api.SPIFileContentRequestTarget{Kind: api.SPIFileContentRequestTargetKindConfigMap, Name: "foreign"})
+
+		_, err := r.deliverToTarget(context.TODO(), req, map[string][]byte{"settings.yaml": []byte("content")})
+		assert.ErrorIs(t, err, targetNotOwnedError)
+
+		cm := &corev1.ConfigMap{}
+		assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "foreign", Namespace: "default"}, cm))
+		assert.Equal(t, map[string]string{"a": "b"}, cm.Data)
+	})
+}
+
+func TestTargetMissing(t *testing.T) {
+	request := &api.SPIFileContentRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "request", Namespace: "default"},
+		Spec: api.SPIFileContentRequestSpec{
+			Target: &api.SPIFileContentRequestTarget{Kind: api.SPIFileContentRequestTargetKindSecret},
+		},
+		Status: api.SPIFileContentRequestStatus{
+			SyncedObjectRef: &api.TargetObjectRef{Name: "request", Kind: "Secret", ApiVersion: "v1"},
+		},
+	}
+
+	t.Run("present", func(t *testing.T) {
+		r := &SPIFileContentRequestReconciler{K8sClient: mockK8sClient(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "request", Namespace: "default"}})}
+		missing, err := r.targetMissing(context.TODO(), request)
+		assert.NoError(t, err)
+		assert.False(t, missing)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		r := &SPIFileContentRequestReconciler{K8sClient: mockK8sClient()}
+		missing, err := r.targetMissing(context.TODO(), request)
+		assert.NoError(t, err)
+		assert.True(t, missing)
+	})
+
+	t.Run("no target", func(t *testing.T) {
+		r := &SPIFileContentRequestReconciler{K8sClient: mockK8sClient()}
+		missing, err := r.targetMissing(context.TODO(), &api.SPIFileContentRequest{})
+		assert.NoError(t, err)
+		assert.False(t, missing)
+	})
+}
+
+func TestTargetData(t *testing.T) {
+	files := map[string]string{"pipeline.yaml": "a", "tasks/build.yaml": "b"}
+
+	t.Run("single file", func(t *testing.T) {
+		req := &api.SPIFileContentRequest{Spec: api.SPIFileContentRequestSpec{
+			FilePath: "config/settings.yaml",
+			Target:   &api.SPIFileContentRequestTarget{Kind: api.SPIFileContentRequestTargetKindConfigMap},
+		}}
+		data, err := targetData(req, map[string]string{"settings.yaml": "content"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"settings.yaml": []byte("content")}, data)
+	})
+
+	t.Run("directory with multiple keys", func(t *testing.T) {
+		req := &api.SPIFileContentRequest{Spec: api.SPIFileContentRequestSpec{
+			FilePath: ".tekton",
+			Mode:     api.SPIFileContentRequestModeDirectory,
+			Target:   &api.SPIFileContentRequestTarget{Kind: api.SPIFileContentRequestTargetKindConfigMap},
+		}}
+		data, err := targetData(req, files)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"pipeline.yaml": []byte("a"), "tasks_build.yaml": []byte("b")}, data)
+
+		_, err = targetData(req, map[string]string{"a/b": "1", "a_b": "2"})
+		assert.ErrorIs(t, err, targetKeyConflictError)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		req := &api.SPIFileContentRequest{Spec: api.SPIFileContentRequestSpec{
+			FilePath: ".tekton",
+			Mode:     api.SPIFileContentRequestModeDirectory,
+			Target:   &api.SPIFileContentRequestTarget{Kind: api.SPIFileContentRequestTargetKindConfigMap},
+		}}
+		half := strings.Repeat("a", maxTargetDataSize/2)
+		_, err := targetData(req, map[string]string{"a.yaml": half, "b.yaml": half})
+		assert.ErrorIs(t, err, targetTooLargeError)
+	})
+
+	t.Run("directory as archive", func(t *testing.T) {
+		req := &api.SPIFileContentRequest{Spec: api.SPIFileContentRequestSpec{
+			FilePath: ".tekton",
+			Mode:     api.SPIFileContentRequestModeDirectory,
+			Target:   &api.SPIFileContentRequestTarget{Kind: api.SPIFileContentRequestTargetKindSecret, Key: "tekton.tar.gz"},
+		}}
+		data, err := targetData(req, files)
+		assert.NoError(t, err)
+		assert.Len(t, data, 1)
+		assert.Equal(t, files, readArchive(t, data["tekton.tar.gz"]))
+	})
+}
+
+func TestArchiveFiles(t *testing.T) {
+	files := map[string]string{"b.txt": "bbb", "a/a.txt": "aaa"}
+
+	archive, err := archiveFiles(files)
+	assert.NoError(t, err)
+	assert.Equal(t, files, readArchive(t, archive))
+
+	again, err := archiveFiles(files)
+	assert.NoError(t, err)
+	assert.Equal(t, archive, again)
+}
+
+func readArchive(t *testing.T, archive []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	assert.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		contents, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		files[hdr.Name] = string(contents)
+	}
+	return files
+}
+
+func TestDownloadSpec(t *testing.T) {
+	request := &api.SPIFileContentRequest{
+		Spec: api.SPIFileContentRequestSpec{RepoUrl: "https://github.com/acme/repo", FilePath: "README.md", Ref: "main"},
+	}
+	assert.Equal(t, "main", downloadSpec(request).Ref)
+
+	request.Status.FileMetadata = &api.SPIFileContentMetadata{CommitSha: "abcd"}
+	assert.Equal(t, "main", downloadSpec(request).Ref)
+
+	request.Spec.PinRef = true
+	assert.Equal(t, "abcd", downloadSpec(request).Ref)
+	assert.Equal(t, "main", request.Spec.Ref)
+}
+
+type refResolvingDownloadCapability struct {
+	serviceprovider.DownloadFileFunc
+	commitSha string
+	err       error
+}
+
+func (c refResolvingDownloadCapability) ResolveRef(_ context.Context, _ api.SPIFileContentRequestSpec, _ serviceprovider.Credentials, _ string) (string, error) {
+	return c.commitSha, c.err
+}
+
+func TestRefUnchanged(t *testing.T) {
+	r := &SPIFileContentRequestReconciler{}
+	request := &api.SPIFileContentRequest{
+		Status: api.SPIFileContentRequestStatus{FileMetadata: &api.SPIFileContentMetadata{CommitSha: "abcd"}},
+	}
+	spWith := func(capability serviceprovider.DownloadFileCapability) serviceprovider.ServiceProvider {
+		return serviceprovider.TestServiceProvider{DownloadFileCapability: func() serviceprovider.DownloadFileCapability { return capability }}
+	}
+
+	unchanged, err := r.refUnchanged(context.TODO(), spWith(refResolvingDownloadCapability{commitSha: "abcd"}), request, serviceprovider.Credentials{})
+	assert.NoError(t, err)
+	assert.True(t, unchanged)
+
+	unchanged, err = r.refUnchanged(context.TODO(), spWith(refResolvingDownloadCapability{commitSha: "efgh"}), request, serviceprovider.Credentials{})
+	assert.NoError(t, err)
+	assert.False(t, unchanged)
+
+	_, err = r.refUnchanged(context.TODO(), spWith(refResolvingDownloadCapability{err: &serviceprovider.RateLimitedError{}}), request, serviceprovider.Credentials{})
+	assert.Error(t, err)
+
+	// providers not able to resolve refs always download the file
+	unchanged, err = r.refUnchanged(context.TODO(), spWith(serviceprovider.DownloadFileFunc(nil)), request, serviceprovider.Credentials{})
+	assert.NoError(t, err)
+	assert.False(t, unchanged)
+}
+
+func TestFileChanged(t *testing.T) {
+	assert.True(t, fileChanged(nil, &api.SPIFileContentMetadata{BlobSha: "a"}))
+	assert.True(t, fileChanged(&api.SPIFileContentMetadata{BlobSha: "a"}, &api.SPIFileContentMetadata{BlobSha: "b"}))
+	assert.True(t, fileChanged(&api.SPIFileContentMetadata{BlobSha: "a", FilePath: "x"}, &api.SPIFileContentMetadata{BlobSha: "a", FilePath: "y"}))
+	assert.True(t, fileChanged(&api.SPIFileContentMetadata{}, &api.SPIFileContentMetadata{}))
+	assert.False(t, fileChanged(&api.SPIFileContentMetadata{BlobSha: "a", CommitSha: "1"}, &api.SPIFileContentMetadata{BlobSha: "a", CommitSha: "2"}))
+}
+
+func TestNextRequeue(t *testing.T) {
+	r := &SPIFileContentRequestReconciler{Configuration: &config.OperatorConfiguration{FileContentRequestTtl: time.Hour}}
+	request := &api.SPIFileContentRequest{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()}}
+
+	assert.InDelta(t, time.Hour, r.nextRequeue(request), float64(time.Second))
+
+	request.Spec.RefreshInterval = &metav1.Duration{Duration: 5 * time.Minute}
+	assert.Equal(t, time.Duration(0), r.durationUntilNextRefresh(request))
+	request.Status.LastRefreshTime = &request.CreationTimestamp
+	assert.InDelta(t, 5*time.Minute, r.nextRequeue(request), float64(time.Second))
+
+	// too short intervals are prolonged
+	request.Spec.RefreshInterval = &metav1.Duration{Duration: time.Second}
+	assert.InDelta(t, minimalFileRefreshInterval, r.nextRequeue(request), float64(time.Second))
+}
+
+func TestRefreshFailed(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	request := &api.SPIFileContentRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "request", Namespace: "default", CreationTimestamp: metav1.Now()},
+		Spec:       api.SPIFileContentRequestSpec{RefreshInterval: &metav1.Duration{Duration: time.Minute}},
+		Status: api.SPIFileContentRequestStatus{
+			Phase:   api.SPIFileContentRequestPhaseDelivered,
+			Content: "Y29udGVudA==",
+		},
+	}
+	cl := mockK8sClient(request.DeepCopy())
+	r := &SPIFileContentRequestReconciler{Configuration: &config.OperatorConfiguration{FileContentRequestTtl: 24 * time.Hour}, Recorder: recorder, K8sClient: cl}
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(request), request))
+
+	result, err := r.refreshFailed(context.TODO(), request, fmt.Errorf("intentional"))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, result.RequeueAfter)
+	assert.Contains(t, <-recorder.Events, fileRefreshFailedEventReason)
+
+	stored := &api.SPIFileContentRequest{}
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(request), stored))
+	assert.Equal(t, "Y29udGVudA==", stored.Status.Content)
+	condition := meta.FindStatusCondition(stored.Status.Conditions, string(api.SPIFileContentRequestConditionTypeContentRefreshed))
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, "intentional", condition.Message)
+	}
+
+	result, err = r.refreshFailed(context.TODO(), request, fmt.Errorf("wrapped: %w", &serviceprovider.RateLimitedError{ResetTime: time.Now().Add(time.Hour)}))
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour, result.RequeueAfter, float64(time.Second))
+	assert.Equal(t, api.SPIFileContentRequestPhaseDelivered, request.Status.Phase)
+}
@@ -0,0 +1,278 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	opconfig "github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/tokenstorage"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// repositoryListingCacheTtl is how long the full listing of the repositories is reused for a repository list request.
+const repositoryListingCacheTtl = 5 * time.Minute
+
+var (
+	tokenNotReadyError     = stderrors.New("the SPIAccessToken is not ready")
+	tokenDataNotFoundError = stderrors.New("the SPIAccessToken has no data in the token storage")
+)
+
+// SPIRepositoryListRequestReconciler reconciles a SPIRepositoryListRequest object
+type SPIRepositoryListRequestReconciler struct {
+	client.Client
+	Scheme                 *runtime.Scheme
+	ServiceProviderFactory serviceprovider.Factory
+	TokenStorage           tokenstorage.TokenStorage
+	Configuration          *opconfig.OperatorConfiguration
+	listings               repositoryListingCache
+}
+
+// repositoryListingCache caches the full listings of the repositories of the repository list requests, so that changing
+// the page or the name prefix of a request doesn't list all the repositories from the service provider again.
+type repositoryListingCache struct {
+	lock     sync.Mutex
+	listings map[types.NamespacedName]repositoryListing
+}
+
+// repositoryListing is the full listing of the repositories of a single repository list request.
+type repositoryListing struct {
+	// requestUID distinguishes the listing of a request from the listing of an older request with the same name
+	requestUID types.UID
+	// tokenVersion is the resource version of the token the repositories were listed with
+	tokenVersion string
+	listedAt     time.Time
+	repositories []api.SPIRepositoryListItem
+}
+
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spirepositorylistrequests,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spirepositorylistrequests/status,verbs=get;update;patch
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SPIRepositoryListRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewControllerManagedBy(mgr).
+		// the status updates must not trigger listing the repositories again
+		For(&api.SPIRepositoryListRequest{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &api.SPIAccessToken{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			return r.listRequestsForToken(context.Background(), o)
+		}), builder.WithPredicates(tokenReadinessChangedPredicate)).
+		Complete(r)
+	if err != nil {
+		err = fmt.Errorf("failed to build the controller manager: %w", err)
+	}
+
+	return err
+}
+
+// listRequestsForToken returns the reconcile requests for the repository list requests referring to the token.
+func (r *SPIRepositoryListRequestReconciler) listRequestsForToken(ctx context.Context, o client.Object) []reconcile.Request {
+	listRequests := &api.SPIRepositoryListRequestList{}
+	if err := r.Client.List(ctx, listRequests, client.InNamespace(o.GetNamespace())); err != nil {
+		enqueueLog.Error(err, "failed to list SPIRepositoryListRequests while determining the ones affected by SPIAccessToken",
+			"name", o.GetName(), "namespace", o.GetNamespace())
+		return []reconcile.Request{}
+	}
+
+	requests := []reconcile.Request{}
+	for _, lr := range listRequests.Items {
+		if lr.Spec.AccessTokenName == o.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      lr.Name,
+					Namespace: lr.Namespace,
+				},
+			})
+		}
+	}
+
+	logReconciliationRequests(requests, "SPIRepositoryListRequest", o, "SPIAccessToken")
+
+	return requests
+}
+
+func (r *SPIRepositoryListRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	lg := log.FromContext(ctx)
+	defer logs.TimeTrackWithLazyLogger(func() logr.Logger { return lg }, time.Now(), "Reconcile SPIRepositoryListRequest")
+
+	listRequest := api.SPIRepositoryListRequest{}
+	if err := r.Get(ctx, req.NamespacedName, &listRequest); err != nil {
+		if errors.IsNotFound(err) {
+			lg.Info("SPIRepositoryListRequest not found on cluster")
+			r.listings.remove(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to load the SPIRepositoryListRequest from the cluster: %w", err)
+	}
+
+	if time.Now().After(listRequest.CreationTimestamp.Add(r.Configuration.AccessCheckTtl)) {
+		lg.Info("SPIRepositoryListRequest is after ttl, deleting ...")
+		r.listings.remove(req.NamespacedName)
+		if err := r.Delete(ctx, &listRequest); err != nil {
+			return ctrl.Result{}, fmt.Errorf("error while deleting the repository list request: %w", err)
+		}
+		lg.Info("SPIRepositoryListRequest deleted")
+		return ctrl.Result{}, nil
+	}
+
+	auditLog := log.FromContext(ctx, "audit", "true", "namespace", listRequest.Namespace, "repositoryListRequest", listRequest.Name,
+		"token", listRequest.Spec.AccessTokenName)
+	auditLog.Info("listing repositories accessible using the token", "action", "UPDATE")
+
+	repositories, retry, err := r.listRepositories(ctx, &listRequest)
+	now := metav1.Now()
+	listRequest.Status.LastListTime = &now
+	if err != nil {
+		auditLog.Error(err, "failed to list the repositories")
+		listRequest.Status.Phase = api.SPIRepositoryListRequestPhaseError
+		listRequest.Status.ErrorMessage = err.Error()
+		listRequest.Status.Repositories = nil
+		listRequest.Status.TotalCount = 0
+		listRequest.Status.NextPage = 0
+	} else {
+		auditLog.Info("repositories listed", "count", len(repositories))
+		listRequest.Status.Phase = api.SPIRepositoryListRequestPhaseReady
+		listRequest.Status.ErrorMessage = ""
+		listRequest.Status.Repositories, listRequest.Status.TotalCount, listRequest.Status.NextPage =
+			serviceprovider.FilterAndPaginateRepositories(repositories, listRequest.Spec.NamePrefix, listRequest.Spec.Page, listRequest.Spec.PageSize)
+	}
+
+	if uerr := r.Client.Status().Update(ctx, &listRequest); uerr != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update the status of the repository list request: %w", uerr)
+	}
+
+	if err != nil && retry {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.Configuration.AccessCheckTtl}, nil
+}
+
+// listRepositories lists all the repositories accessible using the token referenced by the request. The listing is
+// reused for the same request until the token changes or repositoryListingCacheTtl passes. The returned bool
+// is true if the returned error is worth retrying the request later, e.g. a failure to communicate with the service
+// provider. The errors caused by the state of the token or the service provider not supporting the listing are
+// not retried, the request is reconciled again once the token changes.
+func (r *SPIRepositoryListRequestReconciler) listRepositories(ctx context.Context, listRequest *api.SPIRepositoryListRequest) ([]api.SPIRepositoryListItem, bool, error) {
+	key := client.ObjectKeyFromObject(listRequest)
+	token := &api.SPIAccessToken{}
+	if err := r.Get(ctx, client.ObjectKey{Name: listRequest.Spec.AccessTokenName, Namespace: listRequest.Namespace}, token); err != nil {
+		r.listings.remove(key)
+		if errors.IsNotFound(err) {
+			return nil, false, fmt.Errorf("failed to find the SPIAccessToken %s: %w", listRequest.Spec.AccessTokenName, err)
+		}
+		return nil, true, fmt.Errorf("failed to get the SPIAccessToken %s: %w", listRequest.Spec.AccessTokenName, err)
+	}
+	if token.Status.Phase != api.SPIAccessTokenPhaseReady {
+		r.listings.remove(key)
+		return nil, false, fmt.Errorf("%w: the phase is %s", tokenNotReadyError, token.Status.Phase)
+	}
+
+	if repositories, ok := r.listings.get(key, listRequest.UID, token.ResourceVersion); ok {
+		log.FromContext(ctx).V(logs.DebugLevel).Info("reusing the cached listing of the repositories", "count", len(repositories))
+		return repositories, false, nil
+	}
+
+	repositories, retry, err := r.listRepositoriesUsingToken(ctx, token)
+	if err != nil {
+		r.listings.remove(key)
+		return nil, retry, err
+	}
+	r.listings.put(key, repositoryListing{
+		requestUID:   listRequest.UID,
+		tokenVersion: token.ResourceVersion,
+		listedAt:     time.Now(),
+		repositories: repositories,
+	})
+	return repositories, false, nil
+}
+
+// listRepositoriesUsingToken lists all the repositories accessible using the ready token from the service provider.
+func (r *SPIRepositoryListRequestReconciler) listRepositoriesUsingToken(ctx context.Context, token *api.SPIAccessToken) ([]api.SPIRepositoryListItem, bool, error) {
+
+	sp, err := r.ServiceProviderFactory.FromRepoUrl(ctx, token.Spec.ServiceProviderUrl, token.Namespace)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to determine the service provider of the SPIAccessToken: %w", err)
+	}
+	listing, ok := sp.(serviceprovider.RepositoryListingCapability)
+	if !ok {
+		return nil, false, fmt.Errorf("%w by %s", serviceprovider.RepositoryListingNotSupportedError, sp.GetType().Name)
+	}
+
+	data, err := r.TokenStorage.Get(ctx, token)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to get the token data from the token storage: %w", err)
+	}
+	if data == nil {
+		return nil, false, tokenDataNotFoundError
+	}
+
+	repositories, err := listing.ListRepositories(ctx, serviceprovider.Credentials{
+		Username: data.Username,
+		Token:    data.AccessToken,
+		Source:   &api.CredentialsSource{Kind: api.CredentialsSourceKindSPIAccessToken, Name: token.Name},
+	})
+	if err != nil {
+		return nil, !stderrors.Is(err, serviceprovider.RepositoryListingNotSupportedError), fmt.Errorf("failed to list the repositories using the SPIAccessToken: %w", err)
+	}
+	return repositories, false, nil
+}
+
+// get returns the cached listing of the repositories of the request, if it was made for the same request using
+// the same version of the token and didn't expire yet.
+func (c *repositoryListingCache) get(key types.NamespacedName, requestUID types.UID, tokenVersion string) ([]api.SPIRepositoryListItem, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	listing, ok := c.listings[key]
+	if !ok || listing.requestUID != requestUID || listing.tokenVersion != tokenVersion || time.Since(listing.listedAt) > repositoryListingCacheTtl {
+		return nil, false
+	}
+	return listing.repositories, true
+}
+
+func (c *repositoryListingCache) put(key types.NamespacedName, listing repositoryListing) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.listings == nil {
+		c.listings = map[types.NamespacedName]repositoryListing{}
+	}
+	c.listings[key] = listing
+}
+
+func (c *repositoryListingCache) remove(key types.NamespacedName) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.listings, key)
+}
@@ -0,0 +1,136 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestListRequestsForToken(t *testing.T) {
+	listRequest := func(name, namespace, token string) *api.SPIRepositoryListRequest {
+		return &api.SPIRepositoryListRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       api.SPIRepositoryListRequestSpec{AccessTokenName: token},
+		}
+	}
+	r := &SPIRepositoryListRequestReconciler{
+		Client: mockK8sClient(
+			listRequest("matching", "default", "token"),
+			listRequest("other-token", "default", "different"),
+			listRequest("other-namespace", "other", "token"),
+		),
+	}
+	token := &api.SPIAccessToken{ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "default"}}
+
+	requests := r.listRequestsForToken(context.TODO(), token)
+
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "matching", Namespace: "default"}}}, requests)
+}
+
+func TestListRepositoriesTokenState(t *testing.T) {
+	listRequest := &api.SPIRepositoryListRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "list", Namespace: "default"},
+		Spec:       api.SPIRepositoryListRequestSpec{AccessTokenName: "token"},
+	}
+
+	t.Run("token not found", func(t *testing.T) {
+		r := &SPIRepositoryListRequestReconciler{Client: mockK8sClient()}
+
+		_, retry, err := r.listRepositories(context.TODO(), listRequest)
+		assert.ErrorContains(t, err, "failed to find the SPIAccessToken token")
+		assert.False(t, retry)
+	})
+
+	t.Run("token not ready", func(t *testing.T) {
+		r := &SPIRepositoryListRequestReconciler{Client: mockK8sClient(&api.SPIAccessToken{
+			ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "default"},
+			Status:     api.SPIAccessTokenStatus{Phase: api.SPIAccessTokenPhaseAwaitingTokenData},
+		})}
+
+		_, retry, err := r.listRepositories(context.TODO(), listRequest)
+		assert.ErrorIs(t, err, tokenNotReadyError)
+		assert.False(t, retry)
+	})
+}
+
+func TestListRepositoriesCache(t *testing.T) {
+	listRequest := &api.SPIRepositoryListRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "list", Namespace: "default", UID: "list-uid"},
+		Spec:       api.SPIRepositoryListRequestSpec{AccessTokenName: "token", Page: 2},
+	}
+	token := &api.SPIAccessToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "default"},
+		Status:     api.SPIAccessTokenStatus{Phase: api.SPIAccessTokenPhaseReady},
+	}
+	cl := mockK8sClient(token)
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(token), token))
+	cached := []api.SPIRepositoryListItem{{FullName: "acme/repo"}}
+
+	// the reconciler has no service provider factory, so only the cached listing can be returned
+	r := &SPIRepositoryListRequestReconciler{Client: cl}
+	r.listings.put(client.ObjectKeyFromObject(listRequest), repositoryListing{
+		requestUID:   listRequest.UID,
+		tokenVersion: token.ResourceVersion,
+		listedAt:     time.Now(),
+		repositories: cached,
+	})
+
+	repositories, _, err := r.listRepositories(context.TODO(), listRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, cached, repositories)
+
+	t.Run("cache validity", func(t *testing.T) {
+		key := types.NamespacedName{Name: "list", Namespace: "default"}
+		c := repositoryListingCache{}
+		_, ok := c.get(key, "uid", "1")
+		assert.False(t, ok)
+
+		c.put(key, repositoryListing{requestUID: "uid", tokenVersion: "1", listedAt: time.Now(), repositories: cached})
+		_, ok = c.get(key, "uid", "1")
+		assert.True(t, ok)
+		_, ok = c.get(key, "other-uid", "1")
+		assert.False(t, ok)
+		_, ok = c.get(key, "uid", "2")
+		assert.False(t, ok)
+
+		c.put(key, repositoryListing{requestUID: "uid", tokenVersion: "1", listedAt: time.Now().Add(-repositoryListingCacheTtl - time.Second), repositories: cached})
+		_, ok = c.get(key, "uid", "1")
+		assert.False(t, ok)
+
+		c.put(key, repositoryListing{requestUID: "uid", tokenVersion: "1", listedAt: time.Now(), repositories: cached})
+		c.remove(key)
+		_, ok = c.get(key, "uid", "1")
+		assert.False(t, ok)
+	})
+
+	t.Run("token not ready evicts the listing", func(t *testing.T) {
+		token.Status.Phase = api.SPIAccessTokenPhaseInvalid
+		assert.NoError(t, cl.Update(context.TODO(), token))
+
+		_, _, err := r.listRepositories(context.TODO(), listRequest)
+		assert.ErrorIs(t, err, tokenNotReadyError)
+		_, ok := r.listings.get(client.ObjectKeyFromObject(listRequest), listRequest.UID, token.ResourceVersion)
+		assert.False(t, ok)
+	})
+}
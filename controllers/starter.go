@@ -99,6 +99,26 @@ func SetupAllReconcilers(mgr controllerruntime.Manager, cfg *config.OperatorConf
 		Scheme:                 mgr.GetScheme(),
 		ServiceProviderFactory: spf,
 		Configuration:          cfg,
+		Recorder:               mgr.GetEventRecorderFor("spiaccesscheck-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		return err
+	}
+
+	if err = (&SPIBulkAccessCheckReconciler{
+		Client:                 mgr.GetClient(),
+		Scheme:                 mgr.GetScheme(),
+		ServiceProviderFactory: spf,
+		Configuration:          cfg,
+	}).SetupWithManager(mgr); err != nil {
+		return err
+	}
+
+	if err = (&SPIRepositoryListRequestReconciler{
+		Client:                 mgr.GetClient(),
+		Scheme:                 mgr.GetScheme(),
+		ServiceProviderFactory: spf,
+		TokenStorage:           tokenStorage,
+		Configuration:          cfg,
 	}).SetupWithManager(mgr); err != nil {
 		return err
 	}
@@ -109,6 +129,7 @@ func SetupAllReconcilers(mgr controllerruntime.Manager, cfg *config.OperatorConf
 		HttpClient:             spf.HttpClient,
 		ServiceProviderFactory: spf,
 		Configuration:          cfg,
+		Recorder:               mgr.GetEventRecorderFor("spifilecontentrequest-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		return err
 	}
@@ -133,6 +154,12 @@ func SetupAllReconcilers(mgr controllerruntime.Manager, cfg *config.OperatorConf
 		return err
 	}
 
+	if cfg.EnableWebhooks {
+		if err = SetupAllWebhooks(mgr, cfg, spf); err != nil {
+			return err
+		}
+	}
+
 	if cfg.EnableTokenUpload {
 		// Setup tokenUpload controller if configured
 		// Important: need NotifyingTokenStorage to reconcile related SPIAccessToken and RemoteSecret
@@ -0,0 +1,140 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/tokenstorage"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// tokenSelector chooses one of the tokens matching a binding according to a selection strategy. The selection is
+// deterministic - the tokens that are equal according to the strategy are ordered by their namespace and name.
+type tokenSelector struct {
+	Strategy     api.TokenSelectionStrategy
+	TokenStorage tokenstorage.TokenStorage
+}
+
+// tokenSelectionStrategy returns the strategy to use for the binding - either the one specified in the binding or
+// the default one from the configuration.
+func (r *SPIAccessTokenBindingReconciler) tokenSelectionStrategy(binding *api.SPIAccessTokenBinding) api.TokenSelectionStrategy {
+	if binding.Spec.TokenSelectionStrategy != "" {
+		return binding.Spec.TokenSelectionStrategy
+	}
+	if r.Configuration != nil && r.Configuration.TokenSelectionStrategy != "" {
+		return r.Configuration.TokenSelectionStrategy
+	}
+	return api.TokenSelectionStrategyExactScope
+}
+
+// Select returns the preferred token from the provided non-empty list of tokens together with the human-readable
+// reason why it was chosen.
+func (s *tokenSelector) Select(ctx context.Context, tokens []api.SPIAccessToken) (*api.SPIAccessToken, string, error) {
+	if len(tokens) == 1 {
+		return &tokens[0], "the token is the only one matching the binding", nil
+	}
+
+	scores := make([]int64, len(tokens))
+	for i := range tokens {
+		score, err := s.score(ctx, &tokens[i])
+		if err != nil {
+			return nil, "", err
+		}
+		scores[i] = score
+	}
+
+	indices := make([]int, len(tokens))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		ta, tb := &tokens[indices[a]], &tokens[indices[b]]
+		if scores[indices[a]] != scores[indices[b]] {
+			return scores[indices[a]] > scores[indices[b]]
+		}
+		if ta.Namespace != tb.Namespace {
+			return ta.Namespace < tb.Namespace
+		}
+		return ta.Name < tb.Name
+	})
+
+	best := indices[0]
+	reason := fmt.Sprintf("the token is preferred by the %s strategy among %d matching tokens (%s)", s.Strategy, len(tokens), s.describe(&tokens[best], scores[best]))
+	log.FromContext(ctx).V(logs.DebugLevel).Info("selected token for binding", "token", tokens[best].Name, "reason", reason)
+
+	return &tokens[best], reason, nil
+}
+
+// score computes the score of the token according to the strategy. Higher scores are preferred.
+func (s *tokenSelector) score(ctx context.Context, token *api.SPIAccessToken) (int64, error) {
+	switch s.Strategy {
+	case api.TokenSelectionStrategyMostRecentlyRefreshed:
+		if token.Status.TokenMetadata == nil {
+			return 0, nil
+		}
+		return token.Status.TokenMetadata.LastRefreshTime, nil
+	case api.TokenSelectionStrategyLabelPriority:
+		priority, err := strconv.ParseInt(token.Labels[api.TokenSelectionPriorityLabel], 10, 64)
+		if err != nil {
+			// missing or invalid priority
+			return 0, nil
+		}
+		return priority, nil
+	case api.TokenSelectionStrategyLongestValidity:
+		data, err := s.TokenStorage.Get(ctx, token)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get the token data to determine its expiry: %w", err)
+		}
+		if data == nil {
+			return 0, nil
+		}
+		if data.Expiry == 0 || data.Expiry > math.MaxInt64 {
+			return math.MaxInt64, nil
+		}
+		return int64(data.Expiry), nil
+	default:
+		// api.TokenSelectionStrategyExactScope - the fewer scopes, the closer the token is to the required permissions
+		if token.Status.TokenMetadata == nil {
+			return math.MinInt64, nil
+		}
+		return -int64(len(token.Status.TokenMetadata.Scopes)), nil
+	}
+}
+
+func (s *tokenSelector) describe(token *api.SPIAccessToken, score int64) string {
+	switch s.Strategy {
+	case api.TokenSelectionStrategyMostRecentlyRefreshed:
+		return fmt.Sprintf("last refreshed at %d", score)
+	case api.TokenSelectionStrategyLabelPriority:
+		return fmt.Sprintf("priority %d", score)
+	case api.TokenSelectionStrategyLongestValidity:
+		if score == math.MaxInt64 {
+			return "does not expire"
+		}
+		return fmt.Sprintf("expires at %d", score)
+	default:
+		if token.Status.TokenMetadata == nil {
+			return "no scopes known"
+		}
+		return fmt.Sprintf("%d scopes", len(token.Status.TokenMetadata.Scopes))
+	}
+}
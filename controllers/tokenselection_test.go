@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	opconfig "github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/tokenstorage"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTokenSelector_Select(t *testing.T) {
+	newToken := func(name string, labels map[string]string, metadata *api.TokenMetadata) api.SPIAccessToken {
+		return api.SPIAccessToken{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Status:     api.SPIAccessTokenStatus{TokenMetadata: metadata},
+		}
+	}
+	tokens := func() []api.SPIAccessToken {
+		return []api.SPIAccessToken{
+			newToken("c", map[string]string{api.TokenSelectionPriorityLabel: "5"}, &api.TokenMetadata{Scopes: []string{"repo", "user"}, LastRefreshTime: 100}),
+			newToken("b", map[string]string{api.TokenSelectionPriorityLabel: "10"}, &api.TokenMetadata{Scopes: []string{"repo"}, LastRefreshTime: 50}),
+			newToken("a", nil, &api.TokenMetadata{Scopes: []string{"repo"}, LastRefreshTime: 200}),
+		}
+	}
+
+	test := func(selector tokenSelector, expected string) {
+		t.Run(string(selector.Strategy), func(t *testing.T) {
+			token, reason, err := selector.Select(context.TODO(), tokens())
+			assert.NoError(t, err)
+			assert.Equal(t, expected, token.Name)
+			assert.Contains(t, reason, string(selector.Strategy))
+		})
+	}
+
+	// "a" and "b" have the same number of scopes, "a" wins by name
+	test(tokenSelector{Strategy: api.TokenSelectionStrategyExactScope}, "a")
+	test(tokenSelector{Strategy: api.TokenSelectionStrategyMostRecentlyRefreshed}, "a")
+	test(tokenSelector{Strategy: api.TokenSelectionStrategyLabelPriority}, "b")
+	test(tokenSelector{Strategy: api.TokenSelectionStrategyLongestValidity, TokenStorage: tokenstorage.TestTokenStorage{
+		GetImpl: func(_ context.Context, token *api.SPIAccessToken) (*api.Token, error) {
+			switch token.Name {
+			case "a":
+				return &api.Token{Expiry: 1000}, nil
+			case "b":
+				return &api.Token{Expiry: 2000}, nil
+			default:
+				return &api.Token{}, nil
+			}
+		},
+	}}, "c")
+
+	t.Run("single token", func(t *testing.T) {
+		token, reason, err := (&tokenSelector{}).Select(context.TODO(), tokens()[:1])
+		assert.NoError(t, err)
+		assert.Equal(t, "c", token.Name)
+		assert.Equal(t, "the token is the only one matching the binding", reason)
+	})
+}
+
+func TestTokenSelectionStrategy(t *testing.T) {
+	r := &SPIAccessTokenBindingReconciler{Configuration: &opconfig.OperatorConfiguration{TokenSelectionStrategy: api.TokenSelectionStrategyLabelPriority}}
+	binding := &api.SPIAccessTokenBinding{}
+	assert.Equal(t, api.TokenSelectionStrategyLabelPriority, r.tokenSelectionStrategy(binding))
+
+	binding.Spec.TokenSelectionStrategy = api.TokenSelectionStrategyMostRecentlyRefreshed
+	assert.Equal(t, api.TokenSelectionStrategyMostRecentlyRefreshed, r.tokenSelectionStrategy(binding))
+
+	assert.Equal(t, api.TokenSelectionStrategyExactScope, (&SPIAccessTokenBindingReconciler{}).tokenSelectionStrategy(&api.SPIAccessTokenBinding{}))
+}
@@ -0,0 +1,241 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+
+	"github.com/redhat-appstudio/remote-secret/pkg/rerror"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/mutate-appstudio-redhat-com-v1beta1-spiaccesstokenbinding,mutating=true,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=spiaccesstokenbindings,verbs=create;update,versions=v1beta1,name=mspiaccesstokenbinding.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-appstudio-redhat-com-v1beta1-spiaccesstokenbinding,mutating=false,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=spiaccesstokenbindings,verbs=create;update,versions=v1beta1,name=vspiaccesstokenbinding.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-appstudio-redhat-com-v1beta1-spiaccesstoken,mutating=false,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=spiaccesstokens,verbs=create;update,versions=v1beta1,name=vspiaccesstoken.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/mutate-appstudio-redhat-com-v1beta1-spiaccesscheck,mutating=true,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=spiaccesschecks,verbs=create;update,versions=v1beta1,name=mspiaccesscheck.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-appstudio-redhat-com-v1beta1-spiaccesscheck,mutating=false,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=spiaccesschecks,verbs=create;update,versions=v1beta1,name=vspiaccesscheck.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/mutate-appstudio-redhat-com-v1beta1-spibulkaccesscheck,mutating=true,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=spibulkaccesschecks,verbs=create;update,versions=v1beta1,name=mspibulkaccesscheck.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-appstudio-redhat-com-v1beta1-spibulkaccesscheck,mutating=false,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=spibulkaccesschecks,verbs=create;update,versions=v1beta1,name=vspibulkaccesscheck.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/mutate-appstudio-redhat-com-v1beta1-spifilecontentrequest,mutating=true,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=spifilecontentrequests,verbs=create;update,versions=v1beta1,name=mspifilecontentrequest.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-appstudio-redhat-com-v1beta1-spifilecontentrequest,mutating=false,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=spifilecontentrequests,verbs=create;update,versions=v1beta1,name=vspifilecontentrequest.kb.io,admissionReviewVersions=v1
+
+// SetupAllWebhooks registers the defaulting and validating webhooks of all the SPI CRDs with the webhook server of
+// the manager. The webhooks perform the same checks as the controllers, so that invalid objects are rejected
+// on admission instead of ending up in the error phase after the reconciliation.
+func SetupAllWebhooks(mgr ctrl.Manager, cfg *config.OperatorConfiguration, spf serviceprovider.Factory) error {
+	w := &spiWebhook{
+		Configuration:          cfg,
+		ServiceProviderFactory: spf,
+	}
+
+	for _, obj := range []runtime.Object{&api.SPIAccessTokenBinding{}, &api.SPIAccessToken{}, &api.SPIAccessCheck{}, &api.SPIBulkAccessCheck{}, &api.SPIFileContentRequest{}} {
+		if err := ctrl.NewWebhookManagedBy(mgr).For(obj).WithDefaulter(w).WithValidator(w).Complete(); err != nil {
+			return fmt.Errorf("failed to set up the webhook for %T: %w", obj, err)
+		}
+	}
+
+	return nil
+}
+
+// spiWebhook implements the defaulting and validation of all the SPI CRDs. It reuses the validation logic of
+// the controllers.
+type spiWebhook struct {
+	Configuration          *config.OperatorConfiguration
+	ServiceProviderFactory serviceprovider.Factory
+}
+
+var invalidRefreshIntervalError = stderrors.New("invalid refresh interval")
+
+var (
+	_ admission.CustomDefaulter = (*spiWebhook)(nil)
+	_ admission.CustomValidator = (*spiWebhook)(nil)
+)
+
+// Default implements admission.CustomDefaulter. It normalizes the repository URL of the objects that have one.
+// Invalid URLs are left untouched so that they are reported by the validation.
+func (w *spiWebhook) Default(_ context.Context, obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *api.SPIAccessTokenBinding:
+		defaultRepoUrl(&o.Spec.RepoUrl)
+	case *api.SPIAccessCheck:
+		defaultRepoUrl(&o.Spec.RepoUrl)
+	case *api.SPIBulkAccessCheck:
+		for i := range o.Spec.Repositories {
+			defaultRepoUrl(&o.Spec.Repositories[i].RepoUrl)
+		}
+	case *api.SPIFileContentRequest:
+		defaultRepoUrl(&o.Spec.RepoUrl)
+	}
+	return nil
+}
+
+// ValidateCreate implements admission.CustomValidator.
+func (w *spiWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return w.validate(ctx, obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Only the changes of the spec are validated, so that the objects
+// that became invalid in the meantime (e.g. because the configuration of the service providers changed) can still be
+// updated by the controllers, e.g. to remove their finalizers, and deleted.
+func (w *spiWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	if newMeta, ok := newObj.(metav1.Object); ok && newMeta.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	if specUnchanged(oldObj, newObj) {
+		return nil
+	}
+	return w.validate(ctx, newObj)
+}
+
+// ValidateDelete implements admission.CustomValidator. The deletion is always allowed.
+func (w *spiWebhook) ValidateDelete(_ context.Context, _ runtime.Object) error {
+	return nil
+}
+
+func (w *spiWebhook) validate(ctx context.Context, obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *api.SPIAccessTokenBinding:
+		return w.validateBinding(ctx, o)
+	case *api.SPIAccessToken:
+		return w.validatePermissions(ctx, o.Spec.ServiceProviderUrl, o.Namespace, o)
+	case *api.SPIAccessCheck:
+		if err := validateRepoUrl(o.Spec.RepoUrl); err != nil {
+			return err
+		}
+		if o.Spec.RefreshInterval != nil && o.Spec.RefreshInterval.Duration < minimalAccessCheckRefreshInterval {
+			return fmt.Errorf("%w: refreshInterval must be at least %s", invalidRefreshIntervalError, minimalAccessCheckRefreshInterval)
+		}
+		return w.validatePermissions(ctx, o.Spec.RepoUrl, o.Namespace, o)
+	case *api.SPIBulkAccessCheck:
+		for i := range o.Spec.Repositories {
+			if err := validateRepoUrl(o.Spec.Repositories[i].RepoUrl); err != nil {
+				return fmt.Errorf("repository %d: %w", i, err)
+			}
+			if err := w.validatePermissions(ctx, o.Spec.Repositories[i].RepoUrl, o.Namespace, o.AccessCheck(i)); err != nil {
+				return fmt.Errorf("repository %d: %w", i, err)
+			}
+		}
+		return nil
+	case *api.SPIFileContentRequest:
+		if err := validateRepoUrl(o.Spec.RepoUrl); err != nil {
+			return err
+		}
+		if err := serviceprovider.ValidateTreeFilterPatterns(&o.Spec); err != nil {
+			return fmt.Errorf("invalid file content request: %w", err)
+		}
+		if err := validateRefreshInterval(&o.Spec); err != nil {
+			return err
+		}
+		return w.validatePermissions(ctx, o.Spec.RepoUrl, o.Namespace, o)
+	}
+	return nil
+}
+
+func (w *spiWebhook) validateBinding(ctx context.Context, binding *api.SPIAccessTokenBinding) error {
+	if err := validateRepoUrl(binding.Spec.RepoUrl); err != nil {
+		return err
+	}
+
+	if _, err := bindingLifetime(&SPIAccessTokenBindingReconciler{Configuration: w.Configuration}, *binding); err != nil {
+		return err
+	}
+
+	if val := binding.Validate(); len(val.Consistency) > 0 {
+		validationErrors := rerror.NewAggregatedError()
+		for _, e := range val.Consistency {
+			validationErrors.Add(fmt.Errorf("%w: %s", bindingConsistencyError, e))
+		}
+		return validationErrors
+	}
+
+	return w.validatePermissions(ctx, binding.Spec.RepoUrl, binding.Namespace, binding)
+}
+
+// validatePermissions checks that the service provider for the given URL supports the permissions required by
+// the object.
+func (w *spiWebhook) validatePermissions(ctx context.Context, repoUrl string, namespace string, obj serviceprovider.Validated) error {
+	sp, err := w.ServiceProviderFactory.FromRepoUrl(ctx, repoUrl, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to determine the service provider: %w", err)
+	}
+
+	validation, err := sp.Validate(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("failed to validate the object: %w", err)
+	}
+	if len(validation.ScopeValidation) > 0 {
+		return rerror.NewAggregatedError(validation.ScopeValidation...)
+	}
+
+	return nil
+}
+
+// validateRefreshInterval checks that the follow-ref mode is only requested where it is supported.
+func validateRefreshInterval(spec *api.SPIFileContentRequestSpec) error {
+	if spec.RefreshInterval == nil {
+		return nil
+	}
+	if spec.IsDirectory() || spec.PinRef {
+		return fmt.Errorf("%w: refreshInterval cannot be combined with the Directory mode or pinRef", invalidRefreshIntervalError)
+	}
+	if spec.RefreshInterval.Duration < minimalFileRefreshInterval {
+		return fmt.Errorf("%w: refreshInterval must be at least %s", invalidRefreshIntervalError, minimalFileRefreshInterval)
+	}
+	return nil
+}
+
+// specUnchanged checks whether the spec of the validated object is the same in both versions of it.
+func specUnchanged(oldObj, newObj runtime.Object) bool {
+	switch o := newObj.(type) {
+	case *api.SPIAccessTokenBinding:
+		old, ok := oldObj.(*api.SPIAccessTokenBinding)
+		return ok && reflect.DeepEqual(old.Spec, o.Spec)
+	case *api.SPIAccessToken:
+		old, ok := oldObj.(*api.SPIAccessToken)
+		return ok && reflect.DeepEqual(old.Spec, o.Spec)
+	case *api.SPIAccessCheck:
+		old, ok := oldObj.(*api.SPIAccessCheck)
+		return ok && reflect.DeepEqual(old.Spec, o.Spec)
+	case *api.SPIBulkAccessCheck:
+		old, ok := oldObj.(*api.SPIBulkAccessCheck)
+		return ok && reflect.DeepEqual(old.Spec, o.Spec)
+	case *api.SPIFileContentRequest:
+		old, ok := oldObj.(*api.SPIFileContentRequest)
+		return ok && reflect.DeepEqual(old.Spec, o.Spec)
+	}
+	return false
+}
+
+func defaultRepoUrl(repoUrl *string) {
+	if fixed, err := assureProperRepoUrl(*repoUrl); err == nil {
+		*repoUrl = fixed
+	}
+}
+
+func validateRepoUrl(repoUrl string) error {
+	if _, err := assureProperRepoUrl(repoUrl); err != nil {
+		return fmt.Errorf("invalid repoUrl: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	rapi "github.com/redhat-appstudio/remote-secret/api/v1beta1"
+	rconfig "github.com/redhat-appstudio/remote-secret/pkg/config"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	spiconfig "github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWebhookDefault(t *testing.T) {
+	w := &spiWebhook{}
+
+	binding := &api.SPIAccessTokenBinding{Spec: api.SPIAccessTokenBindingSpec{RepoUrl: "github.com/redhat-appstudio/infra-deployments"}}
+	assert.NoError(t, w.Default(context.TODO(), binding))
+	assert.Equal(t, "https://github.com/redhat-appstudio/infra-deployments", binding.Spec.RepoUrl)
+
+	check := &api.SPIAccessCheck{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://github.com/redhat-appstudio/infra-deployments"}}
+	assert.NoError(t, w.Default(context.TODO(), check))
+	assert.Equal(t, "https://github.com/redhat-appstudio/infra-deployments", check.Spec.RepoUrl)
+
+	request := &api.SPIFileContentRequest{Spec: api.SPIFileContentRequestSpec{RepoUrl: "://"}}
+	assert.NoError(t, w.Default(context.TODO(), request))
+	assert.Equal(t, "://", request.Spec.RepoUrl)
+}
+
+func TestWebhookValidateBinding(t *testing.T) {
+	w := &spiWebhook{Configuration: &config.OperatorConfiguration{}}
+
+	t.Run("malformed repo url", func(t *testing.T) {
+		binding := &api.SPIAccessTokenBinding{Spec: api.SPIAccessTokenBindingSpec{RepoUrl: "://"}}
+		assert.ErrorContains(t, w.ValidateCreate(context.TODO(), binding), "invalid repoUrl")
+	})
+
+	t.Run("invalid lifetime", func(t *testing.T) {
+		binding := &api.SPIAccessTokenBinding{Spec: api.SPIAccessTokenBindingSpec{RepoUrl: "https://github.com/acme/repo", Lifetime: "10s"}}
+		assert.ErrorIs(t, w.ValidateCreate(context.TODO(), binding), minimalBindingLifetimeError)
+		updated := binding.DeepCopy()
+		updated.Spec.Lifetime = "forever"
+		assert.ErrorContains(t, w.ValidateUpdate(context.TODO(), binding, updated), "invalid binding lifetime format")
+	})
+
+	t.Run("update without spec change allowed", func(t *testing.T) {
+		binding := &api.SPIAccessTokenBinding{Spec: api.SPIAccessTokenBindingSpec{RepoUrl: "://"}}
+		updated := binding.DeepCopy()
+		updated.Finalizers = []string{"spi.appstudio.redhat.com/linked-secrets"}
+		assert.NoError(t, w.ValidateUpdate(context.TODO(), binding, updated))
+	})
+
+	t.Run("update of deleted object allowed", func(t *testing.T) {
+		binding := &api.SPIAccessTokenBinding{Spec: api.SPIAccessTokenBindingSpec{RepoUrl: "https://github.com/acme/repo"}}
+		updated := binding.DeepCopy()
+		updated.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		updated.Spec.RepoUrl = "://"
+		assert.NoError(t, w.ValidateUpdate(context.TODO(), binding, updated))
+	})
+
+	t.Run("inconsistent spec", func(t *testing.T) {
+		binding := &api.SPIAccessTokenBinding{Spec: api.SPIAccessTokenBindingSpec{RepoUrl: "https://github.com/acme/repo"}}
+		binding.Spec.Secret.LinkedTo = []rapi.SecretLink{{
+			ServiceAccount: rapi.ServiceAccountLink{
+				As:        rapi.ServiceAccountLinkTypeImagePullSecret,
+				Reference: corev1.LocalObjectReference{Name: "sa"},
+			},
+		}}
+		assert.ErrorContains(t, w.ValidateCreate(context.TODO(), binding), "binding consistency error")
+	})
+
+	t.Run("delete always allowed", func(t *testing.T) {
+		binding := &api.SPIAccessTokenBinding{Spec: api.SPIAccessTokenBindingSpec{RepoUrl: "://"}}
+		assert.NoError(t, w.ValidateDelete(context.TODO(), binding))
+	})
+}
+
+func TestValidateRefreshInterval(t *testing.T) {
+	assert.NoError(t, validateRefreshInterval(&api.SPIFileContentRequestSpec{}))
+	assert.NoError(t, validateRefreshInterval(&api.SPIFileContentRequestSpec{RefreshInterval: &metav1.Duration{Duration: time.Minute}}))
+	assert.ErrorIs(t, validateRefreshInterval(&api.SPIFileContentRequestSpec{RefreshInterval: &metav1.Duration{Duration: time.Second}}), invalidRefreshIntervalError)
+	assert.ErrorIs(t, validateRefreshInterval(&api.SPIFileContentRequestSpec{RefreshInterval: &metav1.Duration{Duration: time.Minute}, PinRef: true}), invalidRefreshIntervalError)
+	assert.ErrorIs(t, validateRefreshInterval(&api.SPIFileContentRequestSpec{RefreshInterval: &metav1.Duration{Duration: time.Minute}, Mode: api.SPIFileContentRequestModeDirectory}), invalidRefreshIntervalError)
+}
+
+func TestWebhookValidateAccessCheckRefreshInterval(t *testing.T) {
+	w := &spiWebhook{Configuration: &config.OperatorConfiguration{}}
+
+	check := &api.SPIAccessCheck{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://github.com/acme/repo", RefreshInterval: &metav1.Duration{Duration: time.Second}}}
+	assert.ErrorIs(t, w.ValidateCreate(context.TODO(), check), invalidRefreshIntervalError)
+}
+
+func TestWebhookBulkAccessCheck(t *testing.T) {
+	sp := serviceprovider.TestServiceProvider{
+		ValidateImpl: func(_ context.Context, obj serviceprovider.Validated) (serviceprovider.ValidationResult, error) {
+			res := serviceprovider.ValidationResult{}
+			for _, p := range obj.Permissions().Required {
+				if p.Area == api.PermissionAreaRegistry {
+					res.ScopeValidation = append(res.ScopeValidation, errors.New("registry permissions not supported"))
+				}
+			}
+			return res, nil
+		},
+	}
+	assert.NoError(t, rconfig.SetupCustomValidations(rconfig.CustomValidationOptions{AllowInsecureURLs: false}))
+	initializer := serviceprovider.Initializer{
+		Constructor: serviceprovider.ConstructorFunc(func(_ *serviceprovider.Factory, _ *spiconfig.ServiceProviderConfiguration) (serviceprovider.ServiceProvider, error) {
+			return sp, nil
+		}),
+	}
+	initializers := serviceprovider.NewInitializers().AddKnownInitializer(spiconfig.ServiceProviderTypeHostCredentials, initializer)
+	for _, spType := range spiconfig.SupportedServiceProviderTypes {
+		initializers.AddKnownInitializer(spType, initializer)
+	}
+	w := &spiWebhook{
+		Configuration: &config.OperatorConfiguration{},
+		ServiceProviderFactory: serviceprovider.Factory{
+			Configuration:    &config.OperatorConfiguration{},
+			KubernetesClient: mockK8sClient(),
+			Initializers:     initializers,
+		},
+	}
+
+	bulk := &api.SPIBulkAccessCheck{Spec: api.SPIBulkAccessCheckSpec{Repositories: []api.SPIBulkAccessCheckRepository{
+		{RepoUrl: "github.com/acme/repo"},
+		{RepoUrl: "https://gitlab.com/acme/repo"},
+	}}}
+	assert.NoError(t, w.Default(context.TODO(), bulk))
+	assert.Equal(t, "https://github.com/acme/repo", bulk.Spec.Repositories[0].RepoUrl)
+	assert.Equal(t, "https://gitlab.com/acme/repo", bulk.Spec.Repositories[1].RepoUrl)
+	assert.NoError(t, w.ValidateCreate(context.TODO(), bulk))
+
+	bulk.Spec.Repositories[1].Permissions.Required = []api.Permission{{Type: api.PermissionTypeRead, Area: api.PermissionAreaRegistry}}
+	assert.ErrorContains(t, w.ValidateCreate(context.TODO(), bulk), "repository 1: registry permissions not supported")
+
+	bulk.Spec.Repositories[1].RepoUrl = "://"
+	assert.ErrorContains(t, w.ValidateCreate(context.TODO(), bulk), "repository 1")
+}
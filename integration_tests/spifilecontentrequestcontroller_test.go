@@ -68,8 +68,8 @@ var _ = Describe("SPIFileContentRequest", func() {
 						return &serviceprovider.Credentials{Token: "abcd"}, nil
 					}
 					ITest.TestServiceProvider.DownloadFileCapability = func() serviceprovider.DownloadFileCapability {
-						return serviceprovider.DownloadFileFunc(func(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxFileSizeLimit int) (string, error) {
-							return "", fmt.Errorf("expected error")
+						return serviceprovider.DownloadFileFunc(func(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxFileSizeLimit int) (serviceprovider.DownloadedFile, error) {
+							return serviceprovider.DownloadedFile{}, fmt.Errorf("expected error")
 						})
 					}
 				},
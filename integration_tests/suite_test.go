@@ -193,8 +193,8 @@ var _ = BeforeSuite(func() {
 
 	ITest.Capabilities = serviceprovider.TestCapabilities{}
 
-	ITest.Capabilities.DownloadFileImpl = func(_ context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, i int) (string, error) {
-		return "abcdefg", nil
+	ITest.Capabilities.DownloadFileImpl = func(_ context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, i int) (serviceprovider.DownloadedFile, error) {
+		return serviceprovider.DownloadedFile{Content: "abcdefg"}, nil
 	}
 
 	ITest.Capabilities.GetOAuthEndpointImpl = func() string {
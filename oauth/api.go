@@ -0,0 +1,252 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/oauth/clientfactory"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// TokenStatusRoute route for /api/v1/tokens/{namespace}/{name} requests
+type TokenStatusRoute struct {
+	router *Router
+}
+
+// AuthorizationUrlRoute route for /api/v1/tokens/{namespace}/{name}/authorize requests
+type AuthorizationUrlRoute struct {
+	router *Router
+}
+
+// FlowStatusRoute route for /api/v1/flows/{stateId} requests
+type FlowStatusRoute struct {
+	router *Router
+}
+
+// TokenStatusResponse is the JSON response of the token status endpoint.
+type TokenStatusResponse struct {
+	Phase        api.SPIAccessTokenPhase       `json:"phase"`
+	ErrorReason  api.SPIAccessTokenErrorReason `json:"errorReason,omitempty"`
+	ErrorMessage string                        `json:"errorMessage,omitempty"`
+	Metadata     *TokenMetadataResponse        `json:"metadata,omitempty"`
+}
+
+// TokenMetadataResponse is the part of the TokenMetadata of the SPIAccessToken that is exposed by the token status
+// endpoint. The opaque service provider state is deliberately left out.
+type TokenMetadataResponse struct {
+	Username        string   `json:"username,omitempty"`
+	UserId          string   `json:"userId,omitempty"`
+	Scopes          []string `json:"scopes,omitempty"`
+	LastRefreshTime int64    `json:"lastRefreshTime,omitempty"`
+}
+
+// AuthorizationUrlResponse is the JSON response of the authorization URL endpoint. The state id identifies the OAuth
+// flow when polling for its completion.
+type AuthorizationUrlResponse struct {
+	AuthorizationUrl string `json:"authorizationUrl"`
+	StateId          string `json:"stateId"`
+}
+
+// FlowStatusResponse is the JSON response of the flow status endpoint.
+type FlowStatusResponse struct {
+	StateId string     `json:"stateId"`
+	Status  FlowStatus `json:"status"`
+}
+
+func (r *Router) TokenStatus() *TokenStatusRoute {
+	return &TokenStatusRoute{router: r}
+}
+
+func (r *Router) AuthorizationUrl() *AuthorizationUrlRoute {
+	return &AuthorizationUrlRoute{router: r}
+}
+
+func (r *Router) FlowStatus() *FlowStatusRoute {
+	return &FlowStatusRoute{router: r}
+}
+
+// CallbackError returns the handler of the OAuth callbacks carrying an error. It marks the OAuth flow as failed and
+// either redirects the user to the redirect_after URI of the flow or renders the error page.
+func (r *Router) CallbackError() http.Handler {
+	return http.HandlerFunc(func(wrt http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		r.stateStorage.StoreFlowStatus(ctx, req.URL.Query().Get("state"), FlowStatusFailed)
+
+		errorMsg := req.URL.Query().Get("error")
+		errorDescription := req.URL.Query().Get("error_description")
+		logs.AuditLog(ctx).Info("OAuth authentication flow failed.", "message", errorMsg, "description", errorDescription)
+
+		// the state is only used to describe the failed flow, so it is fine if it is unknown
+		state := &oauthstate.OAuthInfo{}
+		if stateString, err := r.stateStorage.UnveilState(ctx, req); err == nil && stateString != "" {
+			if _, err := r.stateCodec.ParseInto(stateString, state); err != nil {
+				state = &oauthstate.OAuthInfo{}
+			}
+		}
+
+		if redirectAfter := allowedRedirectAfter(ctx, r.allowedRedirectAfterUrls, state); redirectAfter != "" {
+			http.Redirect(wrt, req, flowResultUrl(redirectAfter, state, FlowOutcomeError, errorMsg), http.StatusFound)
+			return
+		}
+
+		r.pages.Render(wrt, req, callbackErrorPage, PageData{
+			Title:          errorMsg,
+			Message:        errorDescription,
+			Provider:       string(state.ServiceProviderName),
+			TokenName:      state.TokenName,
+			TokenNamespace: state.TokenNamespace,
+			Outcome:        FlowOutcomeError,
+		})
+	})
+}
+
+// ServeHTTP responds with the phase and the metadata of the SPIAccessToken given in the path. The token is read with
+// the permissions of the caller.
+func (r *TokenStatusRoute) ServeHTTP(wrt http.ResponseWriter, req *http.Request) {
+	k8sToken := clientfactory.ExtractTokenFromAuthorizationHeader(req.Header.Get("Authorization"))
+	if k8sToken == "" {
+		LogDebugAndWriteResponse(req.Context(), wrt, http.StatusUnauthorized, "failed extract authorization information from headers")
+		return
+	}
+	ctx := clientfactory.WithAuthIntoContext(k8sToken, req.Context())
+
+	vars := mux.Vars(req)
+	token, err := r.router.getToken(ctx, vars["name"], vars["namespace"])
+	if err != nil {
+		writeStateOfTokenError(ctx, wrt, err)
+		return
+	}
+
+	response := TokenStatusResponse{
+		Phase:        token.Status.Phase,
+		ErrorReason:  token.Status.ErrorReason,
+		ErrorMessage: token.Status.ErrorMessage,
+	}
+	if metadata := token.Status.TokenMetadata; metadata != nil {
+		response.Metadata = &TokenMetadataResponse{
+			Username:        metadata.Username,
+			UserId:          metadata.UserId,
+			Scopes:          metadata.Scopes,
+			LastRefreshTime: metadata.LastRefreshTime,
+		}
+	}
+
+	writeJsonResponse(ctx, wrt, response)
+}
+
+// ServeHTTP starts the OAuth flow for the SPIAccessToken given in the path and responds with the authorization URL of
+// the service provider instead of rendering the redirect notice. The OAuth state is taken from the OAuth URL of the
+// token.
+func (r *AuthorizationUrlRoute) ServeHTTP(wrt http.ResponseWriter, req *http.Request) {
+	k8sToken := clientfactory.ExtractTokenFromAuthorizationHeader(req.Header.Get("Authorization"))
+	if k8sToken == "" {
+		LogDebugAndWriteResponse(req.Context(), wrt, http.StatusUnauthorized, "failed extract authorization information from headers")
+		return
+	}
+	ctx := clientfactory.WithAuthIntoContext(k8sToken, req.Context())
+
+	vars := mux.Vars(req)
+	stateString, state, err := r.router.stateOfToken(ctx, vars["name"], vars["namespace"])
+	if err != nil {
+		writeStateOfTokenError(ctx, wrt, err)
+		return
+	}
+
+	ctrl := r.router.controllers[state.ServiceProviderName]
+	if ctrl == nil {
+		LogErrorAndWriteResponse(ctx, wrt, http.StatusBadRequest, "failed to find the service provider", fmt.Errorf("%w: type '%s', base URL '%s'", errUnknownServiceProviderType, state.ServiceProviderName, state.ServiceProviderUrl))
+		return
+	}
+
+	ctrl.AuthorizationUrl(ctx, wrt, k8sToken, stateString, req.URL.Query().Get("redirect_after"), state)
+}
+
+// ServeHTTP responds with the progress of the OAuth flow started by the authorization URL endpoint. Only the flows
+// started in the same session with the same Kubernetes token can be queried.
+func (r *FlowStatusRoute) ServeHTTP(wrt http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	k8sToken := clientfactory.ExtractTokenFromAuthorizationHeader(req.Header.Get("Authorization"))
+	if k8sToken == "" {
+		LogDebugAndWriteResponse(ctx, wrt, http.StatusUnauthorized, "failed extract authorization information from headers")
+		return
+	}
+
+	if r.router.authenticator.SessionManager.GetString(ctx, sessionK8sTokenKey) != k8sToken {
+		LogDebugAndWriteResponse(ctx, wrt, http.StatusForbidden, "the OAuth flow was not started with the provided authorization token")
+		return
+	}
+
+	stateId := mux.Vars(req)["stateId"]
+	status := r.router.stateStorage.FlowStatus(ctx, stateId)
+	if status == "" {
+		LogDebugAndWriteResponse(ctx, wrt, http.StatusNotFound, "unknown OAuth flow", "stateId", stateId)
+		return
+	}
+
+	writeJsonResponse(ctx, wrt, FlowStatusResponse{StateId: stateId, Status: status})
+}
+
+func (c *commonController) AuthorizationUrl(ctx context.Context, w http.ResponseWriter, k8sToken string, stateString string, redirectAfter string, state *oauthstate.OAuthInfo) {
+	lg := log.FromContext(ctx)
+	defer logs.TimeTrack(lg, time.Now(), "/api/v1/authorize")
+
+	hasAccess, err := c.checkIdentityHasAccess(ctx, state)
+	if err != nil {
+		LogErrorAndWriteResponse(ctx, w, http.StatusInternalServerError, "failed to determine if the authenticated user has access", err)
+		return
+	}
+	if !hasAccess {
+		LogDebugAndWriteResponse(ctx, w, http.StatusForbidden, "the authenticated user is not allowed to update the token data")
+		return
+	}
+
+	// the callback finishes the flow using the Kubernetes token from the session, the same as after /authenticate
+	c.Authenticator.SessionManager.Put(ctx, sessionK8sTokenKey, k8sToken)
+
+	stateString, err = c.withRedirectAfter(state, stateString, redirectAfter)
+	if err != nil {
+		LogErrorAndWriteResponse(ctx, w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	veiledState, err := c.StateStorage.VeilState(ctx, stateString)
+	if err != nil {
+		LogErrorAndWriteResponse(ctx, w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	authUrl, err := c.authCodeUrl(ctx, state, veiledState)
+	if err != nil {
+		LogErrorAndWriteResponse(ctx, w, http.StatusInternalServerError, "failed to create oauth configuration", err)
+		return
+	}
+	AuditLogWithTokenInfo(ctx, "OAuth authentication flow started", state.TokenNamespace, state.TokenName, "scopes", state.Scopes, "providerName", state.ServiceProviderName, "providerUrl", state.ServiceProviderUrl)
+
+	writeJsonResponse(ctx, w, AuthorizationUrlResponse{AuthorizationUrl: authUrl, StateId: veiledState})
+}
+
+// writeJsonResponse writes the provided value as the JSON body of a successful response.
+func writeJsonResponse(ctx context.Context, w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.FromContext(ctx).Error(err, "failed to write the JSON response")
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/gorilla/mux"
+	"github.com/redhat-appstudio/remote-secret/pkg/kubernetesclient"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestJsonApi(t *testing.T) {
+	codec := oauthstate.NewCodec(config.OAuthStateConfiguration{})
+	state, err := codec.Encode(&oauthstate.OAuthInfo{
+		TokenName:           "token",
+		TokenNamespace:      "ns",
+		Scopes:              []string{"repo"},
+		ServiceProviderName: config.ServiceProviderTypeGitHub.Name,
+		ServiceProviderUrl:  "https://github.com",
+	})
+	assert.NoError(t, err)
+	craftedState, err := codec.Encode(&oauthstate.OAuthInfo{
+		TokenName:           "crafted-token",
+		TokenNamespace:      "ns",
+		Scopes:              []string{"repo"},
+		ServiceProviderName: config.ServiceProviderTypeGitHub.Name,
+		ServiceProviderUrl:  "https://github.com",
+		RedirectAfter:       "https://evil.com",
+	})
+	assert.NoError(t, err)
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(api.AddToScheme(scheme))
+	utilruntime.Must(corev1.AddToScheme(scheme))
+
+	newRouter := func(allowed bool) http.Handler {
+		cl := &accessReviewingClient{
+			allowed: allowed,
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&api.SPIAccessToken{
+					ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "ns"},
+					Status: api.SPIAccessTokenStatus{
+						Phase:    api.SPIAccessTokenPhaseAwaitingTokenData,
+						OAuthUrl: "https://spi/github/authenticate?state=" + url.QueryEscape(state),
+					},
+				},
+				&api.SPIAccessToken{
+					ObjectMeta: metav1.ObjectMeta{Name: "crafted-token", Namespace: "ns"},
+					Status: api.SPIAccessTokenStatus{
+						Phase:    api.SPIAccessTokenPhaseAwaitingTokenData,
+						OAuthUrl: "https://spi/github/authenticate?state=" + url.QueryEscape(craftedState),
+					},
+				},
+				&api.SPIAccessToken{
+					ObjectMeta: metav1.ObjectMeta{Name: "ready-token", Namespace: "ns"},
+					Status: api.SPIAccessTokenStatus{
+						Phase: api.SPIAccessTokenPhaseReady,
+						TokenMetadata: &api.TokenMetadata{
+							Username:             "alice",
+							UserId:               "42",
+							Scopes:               []string{"repo"},
+							ServiceProviderState: []byte("opaque"),
+							LastRefreshTime:      1700000000,
+						},
+					},
+				},
+			).Build(),
+		}
+		clientFactory := kubernetesclient.SingleInstanceClientFactory{Client: cl}
+		sessionManager := scs.New()
+		authenticator := NewAuthenticator(sessionManager, clientFactory, nil)
+		stateStorage := NewStateStorage(sessionManager)
+		pages, err := LoadPagesFromDir("../static")
+		assert.NoError(t, err)
+
+		controller := &commonController{
+			OAuthServiceConfiguration: OAuthServiceConfiguration{SharedConfiguration: config.SharedConfiguration{
+				BaseUrl: "https://spi",
+				ServiceProviders: []config.ServiceProviderConfiguration{{
+					ServiceProviderType:    config.ServiceProviderTypeGitHub,
+					ServiceProviderBaseUrl: "https://github.com",
+					OAuth2Config: &oauth2.Config{
+						ClientID:     "client",
+						ClientSecret: "secret",
+						Endpoint:     oauth2.Endpoint{AuthURL: "https://github.com/login/oauth/authorize"},
+					},
+				}},
+			}, AllowedRedirectAfterUrls: []string{"https://console.acme.com/spi"}},
+			ClientFactory:       clientFactory,
+			InClusterK8sClient:  cl,
+			Authenticator:       authenticator,
+			StateStorage:        stateStorage,
+			ServiceProviderType: config.ServiceProviderTypeGitHub,
+		}
+
+		router := &Router{
+			controllers:   map[config.ServiceProviderName]Controller{config.ServiceProviderTypeGitHub.Name: controller},
+			stateStorage:  stateStorage,
+			stateCodec:    codec,
+			clientFactory: clientFactory,
+			authenticator: authenticator,
+			pages:         pages,
+
+			allowedRedirectAfterUrls: []string{"https://console.acme.com/spi"},
+		}
+
+		muxRouter := mux.NewRouter()
+		muxRouter.NewRoute().Path("/oauth/callback").Queries("error", "", "error_description", "").Handler(router.CallbackError())
+		muxRouter.NewRoute().Path("/api/v1/tokens/{namespace}/{name}").Handler(router.TokenStatus()).Methods("GET")
+		muxRouter.NewRoute().Path("/api/v1/tokens/{namespace}/{name}/authorize").Handler(router.AuthorizationUrl()).Methods("POST")
+		muxRouter.NewRoute().Path("/api/v1/flows/{stateId}").Handler(router.FlowStatus()).Methods("GET")
+		return sessionManager.LoadAndSave(muxRouter)
+	}
+
+	request := func(router http.Handler, method string, path string, k8sToken string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if k8sToken != "" {
+			req.Header.Set("Authorization", "Bearer "+k8sToken)
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	authorize := func(t *testing.T, router http.Handler) (AuthorizationUrlResponse, *http.Cookie) {
+		res := request(router, "POST", "/api/v1/tokens/ns/token/authorize", "k8s-token")
+		assert.Equal(t, http.StatusOK, res.Code)
+		response := AuthorizationUrlResponse{}
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Len(t, res.Result().Cookies(), 1)
+		return response, res.Result().Cookies()[0]
+	}
+
+	flowStatus := func(t *testing.T, res *httptest.ResponseRecorder) FlowStatus {
+		assert.Equal(t, http.StatusOK, res.Code)
+		response := FlowStatusResponse{}
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		return response.Status
+	}
+
+	t.Run("token status", func(t *testing.T) {
+		res := request(newRouter(true), "GET", "/api/v1/tokens/ns/ready-token", "k8s-token")
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		response := TokenStatusResponse{}
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, api.SPIAccessTokenPhaseReady, response.Phase)
+		assert.Equal(t, &TokenMetadataResponse{Username: "alice", UserId: "42", Scopes: []string{"repo"}, LastRefreshTime: 1700000000}, response.Metadata)
+	})
+
+	t.Run("token status of unknown token", func(t *testing.T) {
+		res := request(newRouter(true), "GET", "/api/v1/tokens/ns/unknown", "k8s-token")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("authorization URL and failed flow", func(t *testing.T) {
+		router := newRouter(true)
+		response, cookie := authorize(t, router)
+
+		authUrl, err := url.Parse(response.AuthorizationUrl)
+		assert.NoError(t, err)
+		assert.Equal(t, "github.com", authUrl.Host)
+		assert.Equal(t, response.StateId, authUrl.Query().Get("state"))
+		assert.Equal(t, "repo", authUrl.Query().Get("scope"))
+
+		assert.Equal(t, FlowStatusPending, flowStatus(t, request(router, "GET", "/api/v1/flows/"+response.StateId, "k8s-token", cookie)))
+
+		request(router, "GET", "/oauth/callback?state="+response.StateId+"&error=access_denied&error_description=denied", "", cookie)
+		assert.Equal(t, FlowStatusFailed, flowStatus(t, request(router, "GET", "/api/v1/flows/"+response.StateId, "k8s-token", cookie)))
+	})
+
+	t.Run("redirect after the failed flow", func(t *testing.T) {
+		router := newRouter(true)
+		res := request(router, "POST", "/api/v1/tokens/ns/token/authorize?redirect_after="+url.QueryEscape("https://console.acme.com/spi/done"), "k8s-token")
+		assert.Equal(t, http.StatusOK, res.Code)
+		response := AuthorizationUrlResponse{}
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+
+		res = request(router, "GET", "/oauth/callback?state="+response.StateId+"&error=access_denied&error_description=denied", "", res.Result().Cookies()[0])
+
+		assert.Equal(t, http.StatusFound, res.Code)
+		assert.Equal(t, "https://console.acme.com/spi/done?error=access_denied&outcome=error&provider=GitHub&tokenName=token&tokenNamespace=ns", res.Header().Get("Location"))
+	})
+
+	t.Run("redirect after not allowed", func(t *testing.T) {
+		res := request(newRouter(true), "POST", "/api/v1/tokens/ns/token/authorize?redirect_after="+url.QueryEscape("https://evil.com/spi"), "k8s-token")
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("redirect after carried by the state not allowed", func(t *testing.T) {
+		res := request(newRouter(true), "POST", "/api/v1/tokens/ns/crafted-token/authorize", "k8s-token")
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("flow of another kubernetes token", func(t *testing.T) {
+		router := newRouter(true)
+		response, cookie := authorize(t, router)
+
+		res := request(router, "GET", "/api/v1/flows/"+response.StateId, "other-token", cookie)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("unknown flow", func(t *testing.T) {
+		router := newRouter(true)
+		_, cookie := authorize(t, router)
+
+		res := request(router, "GET", "/api/v1/flows/unknown", "k8s-token", cookie)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("not allowed to update the token data", func(t *testing.T) {
+		res := request(newRouter(false), "POST", "/api/v1/tokens/ns/token/authorize", "k8s-token")
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		router := newRouter(true)
+		assert.Equal(t, http.StatusUnauthorized, request(router, "GET", "/api/v1/tokens/ns/token", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, request(router, "POST", "/api/v1/tokens/ns/token/authorize", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, request(router, "GET", "/api/v1/flows/abc", "").Code)
+	})
+}
@@ -16,6 +16,7 @@ package oauth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -32,41 +33,46 @@ import (
 type Authenticator struct {
 	ClientFactory  kubernetesclient.K8sClientFactory
 	SessionManager *scs.SessionManager
+	TokenReviewer  TokenReviewer
+	// OIDC enables the login through an OIDC issuer. The OIDC login endpoints are disabled if nil.
+	OIDC *OIDCLogin
 }
 
+const (
+	sessionK8sTokenKey    = "k8s_token"
+	sessionK8sUsernameKey = "k8s_username"
+)
+
 var (
 	noTokenFoundError = errors.New("no token associated with the given session or provided as a `k8s_token` query parameter")
 )
 
-func (a Authenticator) tokenReview(token string, req *http.Request) (bool, error) {
-	//TODO not working. temporary disabled.
-	//review := v1.TokenReview{
-	//	Spec: v1.TokenReviewSpec{
-	//		Token: token,
-	//	},
-	//}
-	//
-	//ctx := WithAuthIntoContext(token, req.Context())
-	//
-	//if err := a.K8sClient.Create(ctx, &review); err != nil {
-	//	zap.L().Error("token review error", zap.Error(err))
-	//	return false, err
-	//}
-	//
-	//zap.L().Debug("token review result", zap.Stringer("review", &review))
-	//return review.Status.Authenticated, nil
-	return true, nil
-}
+// GetToken returns the Kubernetes token of the user. The token is taken from the `k8s_token` query parameter, in which
+// case it is reviewed and persisted to the session, or from the session. The returned error wraps invalidK8sTokenError,
+// noTokenFoundError or oidcExpiredError if the user is not authenticated, otherwise it signals an infrastructure failure.
 func (a *Authenticator) GetToken(ctx context.Context, r *http.Request) (string, error) {
 	lg := log.FromContext(ctx)
 	defer logs.TimeTrack(lg, time.Now(), "/GetToken")
 
 	token := r.URL.Query().Get("k8s_token")
 	if token == "" {
-		token = a.SessionManager.GetString(ctx, "k8s_token")
+		var err error
+		if token, err = a.refreshOIDCToken(ctx); err != nil {
+			return "", err
+		}
 	} else {
+		// the token is reviewed the same way as in Login so that no unverified token gets into the session
+		username, err := a.TokenReviewer.Review(ctx, token)
+		if err != nil {
+			if errors.Is(err, invalidK8sTokenError) {
+				logs.AuditLog(ctx).Info("unsuccessful authentication with Kubernetes token occurred", "reason", err.Error())
+			}
+			return "", fmt.Errorf("failed to review the token provided by `k8s_token` query parameter: %w", err)
+		}
 		lg.V(logs.DebugLevel).Info("persisting token that was provided by `k8s_token` query parameter to the session")
-		a.SessionManager.Put(ctx, "k8s_token", token)
+		a.removeIdentity(ctx)
+		a.SessionManager.Put(ctx, sessionK8sTokenKey, token)
+		a.SessionManager.Put(ctx, sessionK8sUsernameKey, username)
 	}
 
 	if token == "" {
@@ -89,9 +95,14 @@ func (a Authenticator) Login(w http.ResponseWriter, r *http.Request) {
 		LogDebugAndWriteResponse(r.Context(), w, http.StatusUnauthorized, "failed extract authorization info either from headers or form parameters")
 		return
 	}
-	hasAccess, err := a.tokenReview(token, r)
+	username, err := a.TokenReviewer.Review(r.Context(), token)
 	if err != nil {
-		LogErrorAndWriteResponse(r.Context(), w, http.StatusUnauthorized, "failed to determine if the authenticated user has access", err)
+		if errors.Is(err, invalidK8sTokenError) {
+			LogDebugAndWriteResponse(r.Context(), w, http.StatusUnauthorized, "authenticating the request in Kubernetes unsuccessful")
+			logs.AuditLog(r.Context()).Info("unsuccessful authentication with Kubernetes token occurred", "reason", err.Error())
+			return
+		}
+		LogErrorAndWriteResponse(r.Context(), w, http.StatusInternalServerError, "failed to determine if the authenticated user has access", err)
 		lg.Error(err, "The token is incorrect or the SPI OAuth service is not configured properly "+
 			"and the API_SERVER environment variable points it to the incorrect Kubernetes API server. "+
 			"If SPI is running with Devsandbox Proxy, make sure this env var points to the Kubernetes API proxy,"+
@@ -99,14 +110,10 @@ func (a Authenticator) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !hasAccess {
-		LogDebugAndWriteResponse(r.Context(), w, http.StatusUnauthorized, "authenticating the request in Kubernetes unsuccessful")
-		logs.AuditLog(r.Context()).Info("unsuccessful authentication with Kubernetes token occurred") //more details will be logged after real TokenReview will be in action
-		return
-	}
-
-	a.SessionManager.Put(r.Context(), "k8s_token", token)
-	logs.AuditLog(r.Context()).Info("successful authentication with Kubernetes token", "action", "ADD")
+	a.removeIdentity(r.Context())
+	a.SessionManager.Put(r.Context(), sessionK8sTokenKey, token)
+	a.SessionManager.Put(r.Context(), sessionK8sUsernameKey, username)
+	logs.AuditLog(r.Context()).Info("successful authentication with Kubernetes token", "action", "ADD", "username", username)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -114,19 +121,34 @@ func (a Authenticator) Logout(w http.ResponseWriter, r *http.Request) {
 	lg := log.FromContext(r.Context())
 	defer logs.TimeTrack(lg, time.Now(), "/logout")
 
+	username := a.SessionManager.GetString(r.Context(), sessionK8sUsernameKey)
 	if err := a.SessionManager.Destroy(r.Context()); err != nil {
 		LogErrorAndWriteResponse(r.Context(), w, http.StatusInternalServerError, "failed to destroy the user session", err)
-		logs.AuditLog(r.Context()).Info("unsuccessful attempt to clear the user session")
+		logs.AuditLog(r.Context()).Info("unsuccessful attempt to clear the user session", "username", username)
 		return
 	}
 
-	logs.AuditLog(r.Context()).Info("successfully cleared the user session", "action", "DELETE")
+	logs.AuditLog(r.Context()).Info("successfully cleared the user session", "action", "DELETE", "username", username)
 	w.WriteHeader(http.StatusOK)
 }
 
-func NewAuthenticator(sessionManager *scs.SessionManager, clientFactory kubernetesclient.K8sClientFactory) *Authenticator {
+// isUnauthenticated returns true if the error returned from GetToken means that the user is not authenticated.
+func isUnauthenticated(err error) bool {
+	return errors.Is(err, invalidK8sTokenError) || errors.Is(err, noTokenFoundError) || errors.Is(err, oidcExpiredError)
+}
+
+// removeIdentity removes the token of the user and the data related to it from the session.
+func (a *Authenticator) removeIdentity(ctx context.Context) {
+	a.SessionManager.Remove(ctx, sessionK8sTokenKey)
+	a.SessionManager.Remove(ctx, sessionK8sUsernameKey)
+	a.SessionManager.Remove(ctx, sessionOIDCExpiryKey)
+	a.SessionManager.Remove(ctx, sessionOIDCRefreshTokenKey)
+}
+
+func NewAuthenticator(sessionManager *scs.SessionManager, clientFactory kubernetesclient.K8sClientFactory, tokenReviewer TokenReviewer) *Authenticator {
 	return &Authenticator{
 		ClientFactory:  clientFactory,
 		SessionManager: sessionManager,
+		TokenReviewer:  tokenReviewer,
 	}
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/redhat-appstudio/remote-secret/pkg/httptransport"
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	cli "github.com/redhat-appstudio/service-provider-integration-operator/cmd/oauth/oauthcli"
+	authn "k8s.io/api/authentication/v1"
 	authz "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	certutil "k8s.io/client-go/util/cert"
@@ -45,7 +46,10 @@ type K8sClientFactoryBuilder struct {
 func (r K8sClientFactoryBuilder) CreateInClusterClientFactory() (clientFactory kubernetesclient.K8sClientFactory, err error) {
 	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
 	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+	// the templates of the OAuth flow result pages can be loaded from a ConfigMap
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
 	mapper.Add(api.GroupVersion.WithKind("SPIAccessTokenDataUpdate"), meta.RESTScopeNamespace)
+	mapper.Add(authn.SchemeGroupVersion.WithKind("TokenReview"), meta.RESTScopeRoot)
 	clientOptions, errClientOptions := clientOptions(mapper)
 	if errClientOptions != nil {
 		return nil, errClientOptions
@@ -72,6 +76,7 @@ func (r K8sClientFactoryBuilder) CreateUserAuthClientFactory() (clientFactory ku
 	// client here thus making the mapper not reach out to the target cluster at all.
 	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
 	mapper.Add(authz.SchemeGroupVersion.WithKind("SelfSubjectAccessReview"), meta.RESTScopeRoot)
+	mapper.Add(authn.SchemeGroupVersion.WithKind("TokenReview"), meta.RESTScopeRoot)
 	mapper.Add(api.GroupVersion.WithKind("SPIAccessToken"), meta.RESTScopeNamespace)
 	mapper.Add(api.GroupVersion.WithKind("SPIAccessTokenDataUpdate"), meta.RESTScopeNamespace)
 	clientOptions, errClientOptions := clientOptions(mapper)
@@ -145,6 +150,9 @@ func clientOptions(mapper meta.RESTMapper) (*client.Options, error) {
 	if err := authz.AddToScheme(options.Scheme); err != nil {
 		return nil, fmt.Errorf("failed to add authz to the scheme: %w", err)
 	}
+	if err := authn.AddToScheme(options.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to add authn to the scheme: %w", err)
+	}
 
 	return options, nil
 }
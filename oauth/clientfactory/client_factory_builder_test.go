@@ -15,6 +15,7 @@
 package clientfactory
 
 import (
+	"context"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -23,8 +24,12 @@ import (
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	"github.com/redhat-appstudio/service-provider-integration-operator/cmd/oauth/oauthcli"
 	"github.com/stretchr/testify/assert"
+	authn "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 // minimal kubeconfig for testing
@@ -53,6 +58,7 @@ func TestClientOptions(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, opts.Mapper)
 	assert.NotNil(t, opts.Scheme)
+	assert.True(t, opts.Scheme.Recognizes(authn.SchemeGroupVersion.WithKind("TokenReview")))
 }
 
 func TestCustomizeRestconfig(t *testing.T) {
@@ -90,6 +96,23 @@ func TestCustomizeRestconfig(t *testing.T) {
 
 	})
 
+	t.Run("in-cluster client maps the used kinds", func(t *testing.T) {
+		kubeconfigPath := createFile(t, "kubeconfig", kubeconfigContent)
+		defer os.Remove(kubeconfigPath)
+
+		clientFactory, err := K8sClientFactoryBuilder{Args: oauthcli.OAuthServiceCliArgs{KubeConfig: kubeconfigPath}}.CreateInClusterClientFactory()
+		assert.NoError(t, err)
+		cl, err := clientFactory.CreateClient(context.TODO())
+		assert.NoError(t, err)
+
+		for _, obj := range []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}, &api.SPIAccessTokenDataUpdate{}} {
+			gvk, err := apiutil.GVKForObject(obj, cl.Scheme())
+			assert.NoError(t, err)
+			_, err = cl.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+			assert.NoError(t, err, "%s is not mapped", gvk.Kind)
+		}
+	})
+
 	t.Run("insecure tls", func(t *testing.T) {
 		kubeconfigPath := createFile(t, "kubeconfig", kubeconfigContent)
 		defer os.Remove(kubeconfigPath)
@@ -55,6 +55,7 @@ type commonController struct {
 	Authenticator       *Authenticator
 	StateStorage        StateStorage
 	ServiceProviderType config.ServiceProviderType
+	devicePolls         *devicePolls
 }
 
 // exchangeResult this the result of the OAuth exchange with all the data necessary to store the token into the storage
@@ -80,6 +81,10 @@ func (c *commonController) Authenticate(w http.ResponseWriter, r *http.Request,
 	defer logs.TimeTrack(lg, time.Now(), "/authenticate")
 
 	token, err := c.Authenticator.GetToken(ctx, r)
+	if err != nil && !isUnauthenticated(err) {
+		LogErrorAndWriteResponse(ctx, w, http.StatusInternalServerError, "failed to authenticate the request", err)
+		return
+	}
 	if err != nil {
 		LogErrorAndWriteResponse(ctx, w, http.StatusUnauthorized, "No active session was found. Please use `/login` method to authorize your request and try again. Or provide the token as a `k8s_token` query parameter.", err)
 		return
@@ -100,30 +105,27 @@ func (c *commonController) Authenticate(w http.ResponseWriter, r *http.Request,
 		LogDebugAndWriteResponse(ctx, w, http.StatusUnauthorized, "authenticating the request in Kubernetes unsuccessful")
 		return
 	}
-	AuditLogWithTokenInfo(ctx, "OAuth authentication flow started", state.TokenNamespace, state.TokenName, "scopes", state.Scopes, "providerName", state.ServiceProviderName, "providerUrl", state.ServiceProviderUrl)
-	newStateString, err := c.StateStorage.VeilRealState(r)
+	stateString, err := c.withRedirectAfter(state, r.URL.Query().Get("state"), r.URL.Query().Get("redirect_after"))
 	if err != nil {
 		LogErrorAndWriteResponse(ctx, w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
-	//encode original state and callback to the new state. it allows to make redirection proxy stateless.
-	if c.OAuthServiceConfiguration.RedirectProxyUrl != "" {
-		params := url.Values{}
-		params.Add("state", newStateString)
-		params.Add("callback", strings.TrimSuffix(c.OAuthServiceConfiguration.BaseUrl, "/")+oauth.CallBackRoutePath)
-		newStateString = params.Encode()
+	AuditLogWithTokenInfo(ctx, "OAuth authentication flow started", state.TokenNamespace, state.TokenName, "scopes", state.Scopes, "providerName", state.ServiceProviderName, "providerUrl", state.ServiceProviderUrl)
+	newStateString, err := c.StateStorage.VeilState(ctx, stateString)
+	if err != nil {
+		LogErrorAndWriteResponse(ctx, w, http.StatusBadRequest, err.Error(), err)
+		return
 	}
-	oauthCfg, oauthConfigErr := c.obtainOauthConfig(ctx, state)
-	if oauthConfigErr != nil {
-		LogErrorAndWriteResponse(ctx, w, http.StatusInternalServerError, "failed to create oauth confgiuration", oauthConfigErr)
+	authUrl, err := c.authCodeUrl(ctx, state, newStateString)
+	if err != nil {
+		LogErrorAndWriteResponse(ctx, w, http.StatusInternalServerError, "failed to create oauth confgiuration", err)
 		return
 	}
-	oauthCfg.Scopes = state.Scopes
 
 	templateData := struct {
 		Url string
 	}{
-		Url: oauthCfg.AuthCodeURL(newStateString),
+		Url: authUrl,
 	}
 	lg.V(logs.DebugLevel).Info("Redirecting ", "url", templateData.Url)
 	err = c.RedirectTemplate.Execute(w, templateData)
@@ -133,29 +135,107 @@ func (c *commonController) Authenticate(w http.ResponseWriter, r *http.Request,
 	}
 }
 
+// withRedirectAfter verifies the redirect_after URI requested by the caller and carries it in a newly encoded state, so
+// that it cannot be tampered with until the callback. The original state string is returned if no URI is requested.
+func (c *commonController) withRedirectAfter(state *oauthstate.OAuthInfo, stateString string, redirectAfter string) (string, error) {
+	if redirectAfter == "" {
+		// the state itself may carry the URI if it is not signed, so it must be allowed, too
+		if state.RedirectAfter != "" {
+			if err := checkRedirectAfter(c.AllowedRedirectAfterUrls, state.RedirectAfter); err != nil {
+				return "", err
+			}
+		}
+		return stateString, nil
+	}
+	if err := checkRedirectAfter(c.AllowedRedirectAfterUrls, redirectAfter); err != nil {
+		return "", err
+	}
+
+	state.RedirectAfter = redirectAfter
+	newStateString, err := oauthstate.NewCodec(c.OAuthState).Encode(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the state with the redirect_after URI: %w", err)
+	}
+	return newStateString, nil
+}
+
+// authCodeUrl constructs the URL of the authorization endpoint of the service provider with the veiled state. If
+// the service provider uses PKCE, a new verifier is generated and stored next to the veiled state.
+func (c *commonController) authCodeUrl(ctx context.Context, state *oauthstate.OAuthInfo, veiledState string) (string, error) {
+	spConfig, err := c.obtainServiceProviderConfig(ctx, state)
+	if err != nil {
+		return "", err
+	}
+	oauthCfg := spConfig.OAuth2Config
+	oauthCfg.Scopes = state.Scopes
+
+	var authCodeOptions []oauth2.AuthCodeOption
+	if spConfig.Pkce {
+		verifier := oauth2.GenerateVerifier()
+		c.StateStorage.StorePkceVerifier(ctx, veiledState, verifier)
+		authCodeOptions = append(authCodeOptions, oauth2.S256ChallengeOption(verifier))
+	}
+
+	//encode original state and callback to the new state. it allows to make redirection proxy stateless.
+	if c.OAuthServiceConfiguration.RedirectProxyUrl != "" {
+		params := url.Values{}
+		params.Add("state", veiledState)
+		params.Add("callback", strings.TrimSuffix(c.OAuthServiceConfiguration.BaseUrl, "/")+oauth.CallBackRoutePath)
+		veiledState = params.Encode()
+	}
+
+	return oauthCfg.AuthCodeURL(veiledState, authCodeOptions...), nil
+}
+
 func (c *commonController) Callback(ctx context.Context, w http.ResponseWriter, r *http.Request, state *oauthstate.OAuthInfo) {
 	lg := log.FromContext(ctx)
 	defer logs.TimeTrack(lg, time.Now(), "/callback")
 
+	// record the result of the flow for the clients polling for it
+	flowStatus := FlowStatusFailed
+	defer func() {
+		c.StateStorage.StoreFlowStatus(ctx, r.URL.Query().Get("state"), flowStatus)
+	}()
+
 	exchange, err := c.finishOAuthExchange(ctx, r, state)
 	if err != nil {
-		LogErrorAndWriteResponse(ctx, w, http.StatusBadRequest, "error in Service Provider token exchange", err)
+		if errors.Is(err, errUpgradeUserMismatch) {
+			c.writeCallbackError(ctx, w, r, state, http.StatusForbidden, "the token must be upgraded by the same service provider user that authorized it", err)
+			return
+		}
+		c.writeCallbackError(ctx, w, r, state, http.StatusBadRequest, "error in Service Provider token exchange", err)
 		return
 	}
 
 	if exchange.result == oauthFinishK8sAuthRequired {
-		LogErrorAndWriteResponse(ctx, w, http.StatusUnauthorized, "could not authenticate to Kubernetes", err)
+		c.writeCallbackError(ctx, w, r, state, http.StatusUnauthorized, "could not authenticate to Kubernetes", err)
 		return
 	}
 
 	err = c.syncTokenData(ctx, &exchange)
 	if err != nil {
-		LogErrorAndWriteResponse(ctx, w, http.StatusInternalServerError, "failed to store token data to cluster", err)
+		c.writeCallbackError(ctx, w, r, state, http.StatusInternalServerError, "failed to store token data to cluster", err)
 		return
 	}
-	AuditLogWithTokenInfo(ctx, "OAuth authentication completed successfully", exchange.TokenNamespace, exchange.TokenName, "scopes", exchange.Scopes, "providerName", exchange.ServiceProviderName, "providerUrl", exchange.ServiceProviderUrl)
+	AuditLogWithTokenInfo(ctx, "OAuth authentication completed successfully", exchange.TokenNamespace, exchange.TokenName, "scopes", exchange.Scopes, "providerName", exchange.ServiceProviderName, "providerUrl", exchange.ServiceProviderUrl, "upgrade", exchange.ExpectedUserId != "")
+	flowStatus = FlowStatusCompleted
 	redirectLocation := strings.TrimSuffix(c.SharedConfiguration.BaseUrl, "/") + "/" + "callback_success"
-	http.Redirect(w, r, redirectLocation, http.StatusFound)
+	if redirectAfter := allowedRedirectAfter(ctx, c.AllowedRedirectAfterUrls, &exchange.OAuthInfo); redirectAfter != "" {
+		redirectLocation = redirectAfter
+	}
+	http.Redirect(w, r, flowResultUrl(redirectLocation, &exchange.OAuthInfo, FlowOutcomeSuccess, ""), http.StatusFound)
+}
+
+// writeCallbackError responds to the failed callback. If the flow was started with the redirect_after URI, the user is
+// redirected there with the error, otherwise the error is written to the response.
+func (c *commonController) writeCallbackError(ctx context.Context, w http.ResponseWriter, r *http.Request, state *oauthstate.OAuthInfo, status int, msg string, err error) {
+	redirectAfter := allowedRedirectAfter(ctx, c.AllowedRedirectAfterUrls, state)
+	if redirectAfter == "" {
+		LogErrorAndWriteResponse(ctx, w, status, msg, err)
+		return
+	}
+	log.FromContext(ctx).Error(err, msg)
+	http.Redirect(w, r, flowResultUrl(redirectAfter, state, FlowOutcomeError, msg), http.StatusFound)
 }
 
 // finishOAuthExchange implements the bulk of the Callback function. It returns the token, if obtained, the decoded
@@ -169,14 +249,17 @@ func (c *commonController) finishOAuthExchange(ctx context.Context, r *http.Requ
 		return exchangeResult{result: oauthFinishError}, fmt.Errorf("failed to unveil token state: %w", err)
 	}
 
-	err = oauthstate.ParseInto(stateString, state)
+	_, err = oauthstate.NewCodec(c.OAuthState).ParseInto(stateString, state)
 	if err != nil {
 		return exchangeResult{result: oauthFinishError}, fmt.Errorf("failed to parse JWT state string: %w", err)
 	}
 
 	k8sToken, err := c.Authenticator.GetToken(ctx, r)
 	if err != nil {
-		return exchangeResult{result: oauthFinishK8sAuthRequired}, noActiveSessionError
+		if isUnauthenticated(err) {
+			return exchangeResult{result: oauthFinishK8sAuthRequired}, noActiveSessionError
+		}
+		return exchangeResult{result: oauthFinishError}, fmt.Errorf("failed to authenticate the request: %w", err)
 	}
 	ctx = clientfactory.WithAuthIntoContext(k8sToken, ctx)
 
@@ -190,11 +273,26 @@ func (c *commonController) finishOAuthExchange(ctx context.Context, r *http.Requ
 
 	// adding scopes to code exchange request is little out of spec, but quay wants them,
 	// while other providers will just ignore this parameter
-	scopeOption := oauth2.SetAuthURLParam("scope", r.FormValue("scope"))
-	token, err := oauthCfg.Exchange(ctx, code, scopeOption)
+	exchangeOptions := []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("scope", r.FormValue("scope"))}
+
+	verifier, err := c.StateStorage.PkceVerifier(ctx, r)
+	if err != nil {
+		return exchangeResult{result: oauthFinishError}, fmt.Errorf("failed to recover the PKCE verifier: %w", err)
+	}
+	if verifier != "" {
+		exchangeOptions = append(exchangeOptions, oauth2.VerifierOption(verifier))
+	}
+
+	token, err := oauthCfg.Exchange(ctx, code, exchangeOptions...)
 	if err != nil {
 		return exchangeResult{result: oauthFinishError}, fmt.Errorf("failed to finish the OAuth exchange: %w", err)
 	}
+
+	if state.ExpectedUserId != "" {
+		if err := verifyUpgradedUser(ctx, oauthCfg, token, state); err != nil {
+			return exchangeResult{result: oauthFinishError}, err
+		}
+	}
 	return exchangeResult{
 		OAuthInfo:           *state,
 		result:              oauthFinishAuthenticated,
@@ -40,7 +40,16 @@ import (
 	"strings"
 	"time"
 
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/redhat-appstudio/remote-secret/pkg/kubernetesclient"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 var _ = Describe("Controller", func() {
@@ -94,7 +103,7 @@ var _ = Describe("Controller", func() {
 	}
 
 	prepareAuthenticator := func(g Gomega) *Authenticator {
-		return NewAuthenticator(IT.SessionManager, IT.ClientFactory)
+		return NewAuthenticator(IT.SessionManager, IT.ClientFactory, NewKubernetesTokenReviewer(kubernetesclient.SingleInstanceClientFactory{Client: IT.InClusterClient}, time.Minute))
 	}
 	prepareController := func(g Gomega) *commonController {
 		tmpl, err := template.ParseFiles("../static/redirect_notice.html")
@@ -397,8 +406,107 @@ var _ = Describe("Controller", func() {
 				})).ServeHTTP(res, req)
 
 				g.Expect(res.Code).To(Equal(http.StatusFound))
-				g.Expect(res.Result().Header.Get("Location")).To(Equal("https://spi.on.my.machine/callback_success"))
+				g.Expect(res.Result().Header.Get("Location")).To(Equal("https://spi.on.my.machine/callback_success?outcome=success&provider=GitHub&tokenName=mytoken&tokenNamespace=" + IT.Namespace))
 			}).Should(Succeed())
 		})
 	})
 })
+
+func TestPkceFlow(t *testing.T) {
+	test := func(t *testing.T, pkce bool) {
+		var challenge, verifier string
+		oauthServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/token", r.URL.Path)
+			assert.NoError(t, r.ParseForm())
+			verifier = r.PostForm.Get("code_verifier")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token": "access-token", "token_type": "bearer"}`))
+		}))
+		defer oauthServer.Close()
+
+		sch := runtime.NewScheme()
+		utilruntime.Must(corev1.AddToScheme(sch))
+		cl := fake.NewClientBuilder().WithScheme(sch).Build()
+		sessionManager := scs.New()
+
+		c := &commonController{
+			OAuthServiceConfiguration: OAuthServiceConfiguration{
+				SharedConfiguration: config.SharedConfiguration{
+					ServiceProviders: []config.ServiceProviderConfiguration{{
+						ServiceProviderType:    config.ServiceProviderTypeGitLab,
+						ServiceProviderBaseUrl: "https://gitlab.acme",
+						OAuth2Config: &oauth2.Config{
+							ClientID:     "clientId",
+							ClientSecret: "clientSecret",
+							Endpoint: oauth2.Endpoint{
+								AuthURL:   oauthServer.URL + "/authorize",
+								TokenURL:  oauthServer.URL + "/token",
+								AuthStyle: oauth2.AuthStyleInParams,
+							},
+						},
+						Pkce: pkce,
+					}},
+					BaseUrl: "https://spi.on.my.machine",
+				},
+			},
+			InClusterK8sClient:  cl,
+			Authenticator:       NewAuthenticator(sessionManager, nil, acceptingTokenReviewer{username: "alice"}),
+			StateStorage:        NewStateStorage(sessionManager),
+			ServiceProviderType: config.ServiceProviderTypeGitLab,
+		}
+
+		state := &oauthstate.OAuthInfo{
+			TokenName:           "mytoken",
+			TokenNamespace:      "default",
+			ServiceProviderName: config.ServiceProviderTypeGitLab.Name,
+			ServiceProviderUrl:  "https://gitlab.acme",
+		}
+		encodedState, err := oauthstate.Encode(state)
+		assert.NoError(t, err)
+
+		// the authentication phase of the flow
+		var veiledState string
+		res := httptest.NewRecorder()
+		sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			veiledState, err = c.StateStorage.VeilRealState(r)
+			assert.NoError(t, err)
+			authUrl, err := c.authCodeUrl(r.Context(), state, veiledState)
+			assert.NoError(t, err)
+
+			parsed, err := url.Parse(authUrl)
+			assert.NoError(t, err)
+			challenge = parsed.Query().Get("code_challenge")
+			if pkce {
+				assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+			} else {
+				assert.Empty(t, parsed.Query().Get("code_challenge_method"))
+			}
+		})).ServeHTTP(res, httptest.NewRequest("GET", "/?state="+encodedState, nil))
+
+		// the callback phase of the flow
+		req := httptest.NewRequest("GET", fmt.Sprintf("/?state=%s&code=abcd&k8s_token=k8s-token", veiledState), nil)
+		req.AddCookie(res.Result().Cookies()[0])
+		sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			exchange, err := c.finishOAuthExchange(r.Context(), r, &oauthstate.OAuthInfo{})
+			assert.NoError(t, err)
+			assert.Equal(t, oauthFinishAuthenticated, exchange.result)
+			assert.Equal(t, "access-token", exchange.token.AccessToken)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+
+		if pkce {
+			assert.NotEmpty(t, verifier)
+			assert.Equal(t, oauth2.S256ChallengeFromVerifier(verifier), challenge)
+		} else {
+			assert.Empty(t, challenge)
+			assert.Empty(t, verifier)
+		}
+	}
+
+	t.Run("with PKCE", func(t *testing.T) {
+		test(t, true)
+	})
+
+	t.Run("without PKCE", func(t *testing.T) {
+		test(t, false)
+	})
+}
@@ -20,4 +20,7 @@ import (
 type OAuthServiceConfiguration struct {
 	config.SharedConfiguration `validate:"required"`
 	RedirectProxyUrl           string `validate:"omitempty,https_only"`
+	// AllowedRedirectAfterUrls lists the URLs to which the users can be redirected at the end of the OAuth flow
+	// using the redirect_after parameter. The parameter is rejected if the list is empty.
+	AllowedRedirectAfterUrls []string `validate:"dive,https_only"`
 }
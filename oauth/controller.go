@@ -35,6 +35,17 @@ type Controller interface {
 
 	// Callback finishes the OAuth flow. It handles the final redirect from the OAuth flow of the service provider.
 	Callback(ctx context.Context, w http.ResponseWriter, r *http.Request, state *oauthstate.OAuthInfo)
+
+	// DeviceAuthorization initiates the device authorization grant for the token described by the state and responds
+	// with the user code and the verification URL. The token is obtained from the service provider in the background
+	// using the provided Kubernetes token to store it.
+	DeviceAuthorization(ctx context.Context, w http.ResponseWriter, k8sToken string, state *oauthstate.OAuthInfo)
+
+	// AuthorizationUrl starts the OAuth flow for the token described by the state the same way as Authenticate but
+	// responds with the authorization URL of the service provider and the id of the flow as JSON. The provided
+	// Kubernetes token is stored in the session so that the callback can finish the flow. The optional redirectAfter
+	// URI is where the user is redirected at the end of the flow.
+	AuthorizationUrl(ctx context.Context, w http.ResponseWriter, k8sToken string, stateString string, redirectAfter string, state *oauthstate.OAuthInfo)
 }
 
 // oauthFinishResult is an enum listing the possible results of authentication during the commonController.finishOAuthExchange
@@ -51,6 +62,8 @@ var (
 	errMultipleConfigsForSameHost = errors.New("failed to initialize - multiple configurations for one service provider host")
 )
 
+// InitController creates the controller of the given service provider type. The context bounds the lifetime of the
+// work the controller runs in the background, like polling for the device access tokens.
 func InitController(ctx context.Context, spType config.ServiceProviderType, cfg RouterConfiguration) (Controller, error) {
 	lg := log.FromContext(ctx)
 
@@ -69,6 +82,7 @@ func InitController(ctx context.Context, spType config.ServiceProviderType, cfg
 		StateStorage:              cfg.StateStorage,
 		RedirectTemplate:          cfg.RedirectTemplate,
 		ServiceProviderType:       spType,
+		devicePolls:               newDevicePolls(ctx),
 	}
 
 	initializedServiceProviders := map[string]bool{}
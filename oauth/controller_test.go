@@ -31,3 +31,7 @@ func (n NopController) Authenticate(w http.ResponseWriter, r *http.Request, stat
 }
 func (n NopController) Callback(ctx context.Context, w http.ResponseWriter, r *http.Request, state *oauthstate.OAuthInfo) {
 }
+func (n NopController) DeviceAuthorization(ctx context.Context, w http.ResponseWriter, k8sToken string, state *oauthstate.OAuthInfo) {
+}
+func (n NopController) AuthorizationUrl(ctx context.Context, w http.ResponseWriter, k8sToken string, stateString string, redirectAfter string, state *oauthstate.OAuthInfo) {
+}
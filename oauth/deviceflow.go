@@ -0,0 +1,259 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/oauth/clientfactory"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
+	"golang.org/x/oauth2"
+	kuberrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var (
+	errDeviceFlowNotSupported = errors.New("the service provider doesn't support the device authorization grant")
+	errNoOAuthUrl             = errors.New("the SPIAccessToken has no OAuth URL, it either is already ready or its service provider doesn't support OAuth")
+	errOAuthUrlMismatch       = errors.New("the state in the OAuth URL of the SPIAccessToken doesn't belong to it")
+)
+
+// devicePolls keeps track of the device access token polls running in the background. There is at most one poll per
+// SPIAccessToken. The polls are derived from the context the service runs with so that they are cancelled on shutdown.
+type devicePolls struct {
+	ctx      context.Context
+	lock     sync.Mutex
+	inFlight map[client.ObjectKey]struct{}
+}
+
+func newDevicePolls(ctx context.Context) *devicePolls {
+	return &devicePolls{ctx: ctx, inFlight: map[client.ObjectKey]struct{}{}}
+}
+
+// start registers a poll for the token with the given key. It returns false if a poll for the token is already running.
+func (p *devicePolls) start(key client.ObjectKey) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.inFlight[key]; ok {
+		return false
+	}
+	p.inFlight[key] = struct{}{}
+	return true
+}
+
+// finish unregisters the poll for the token with the given key.
+func (p *devicePolls) finish(key client.ObjectKey) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.inFlight, key)
+}
+
+// DeviceAuthorizationRoute route for /device/{namespace}/{name} requests
+type DeviceAuthorizationRoute struct {
+	router *Router
+}
+
+// DeviceAuthorizationResponse is the JSON response of the device authorization endpoint. It contains the data the user
+// needs to complete the authorization in the browser, possibly on a different device.
+type DeviceAuthorizationResponse struct {
+	UserCode                string `json:"userCode"`
+	VerificationUri         string `json:"verificationUri"`
+	VerificationUriComplete string `json:"verificationUriComplete,omitempty"`
+	ExpiresIn               int64  `json:"expiresIn,omitempty"`
+}
+
+func (r *Router) DeviceAuthorization() *DeviceAuthorizationRoute {
+	return &DeviceAuthorizationRoute{router: r}
+}
+
+// ServeHTTP initiates the device authorization grant for the SPIAccessToken given in the path. The OAuth state is
+// taken from the OAuth URL of the token, so that the same scopes are requested as in the redirect flow.
+func (r *DeviceAuthorizationRoute) ServeHTTP(wrt http.ResponseWriter, req *http.Request) {
+	k8sToken := clientfactory.ExtractTokenFromAuthorizationHeader(req.Header.Get("Authorization"))
+	if k8sToken == "" {
+		LogDebugAndWriteResponse(req.Context(), wrt, http.StatusUnauthorized, "failed extract authorization information from headers")
+		return
+	}
+	ctx := clientfactory.WithAuthIntoContext(k8sToken, req.Context())
+
+	vars := mux.Vars(req)
+	tokenObjectName := vars["name"]
+	tokenObjectNamespace := vars["namespace"]
+
+	_, state, err := r.router.stateOfToken(ctx, tokenObjectName, tokenObjectNamespace)
+	if err != nil {
+		writeStateOfTokenError(ctx, wrt, err)
+		return
+	}
+
+	ctrl := r.router.controllers[state.ServiceProviderName]
+	if ctrl == nil {
+		LogErrorAndWriteResponse(ctx, wrt, http.StatusBadRequest, "failed to find the service provider", fmt.Errorf("%w: type '%s', base URL '%s'", errUnknownServiceProviderType, state.ServiceProviderName, state.ServiceProviderUrl))
+		return
+	}
+
+	ctrl.DeviceAuthorization(ctx, wrt, k8sToken, state)
+}
+
+// getToken reads the SPIAccessToken using the credentials in the context.
+func (r *Router) getToken(ctx context.Context, name, namespace string) (*api.SPIAccessToken, error) {
+	ctx = clientfactory.NamespaceIntoContext(ctx, namespace)
+	cl, err := r.clientFactory.CreateClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create K8S client for namespace %s: %w", namespace, err)
+	}
+
+	token := &api.SPIAccessToken{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, token); err != nil {
+		return nil, fmt.Errorf("failed to get the SPIAccessToken object %s/%s: %w", namespace, name, err)
+	}
+
+	return token, nil
+}
+
+// stateOfToken reads the SPIAccessToken using the credentials in the context and parses the OAuth state from its OAuth
+// URL. The state is signed by the operator, therefore it can be trusted the same way as in the redirect flow. Both
+// the encoded and the parsed state are returned.
+func (r *Router) stateOfToken(ctx context.Context, name, namespace string) (string, *oauthstate.OAuthInfo, error) {
+	token, err := r.getToken(ctx, name, namespace)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if token.Status.OAuthUrl == "" {
+		return "", nil, errNoOAuthUrl
+	}
+
+	oauthUrl, err := url.Parse(token.Status.OAuthUrl)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse the OAuth URL of the SPIAccessToken: %w", err)
+	}
+
+	stateString := oauthUrl.Query().Get("state")
+	state := &oauthstate.OAuthInfo{}
+	if _, err := r.stateCodec.ParseInto(stateString, state); err != nil {
+		return "", nil, fmt.Errorf("failed to parse state string: %w", err)
+	}
+
+	if state.TokenName != name || state.TokenNamespace != namespace {
+		return "", nil, errOAuthUrlMismatch
+	}
+
+	return stateString, state, nil
+}
+
+// writeStateOfTokenError translates the errors of reading the SPIAccessToken and its OAuth state to the HTTP responses.
+func writeStateOfTokenError(ctx context.Context, wrt http.ResponseWriter, err error) {
+	switch {
+	case kuberrors.ReasonForError(err) == metav1.StatusReasonNotFound:
+		LogErrorAndWriteResponse(ctx, wrt, http.StatusNotFound, "specified SPIAccessToken does not exist", err)
+	case kuberrors.ReasonForError(err) == metav1.StatusReasonForbidden:
+		LogErrorAndWriteResponse(ctx, wrt, http.StatusForbidden, "authorization token does not have permission to read the SPIAccessToken", err)
+	case kuberrors.ReasonForError(err) == metav1.StatusReasonUnauthorized:
+		LogErrorAndWriteResponse(ctx, wrt, http.StatusUnauthorized, "invalid authorization token, cannot read the SPIAccessToken", err)
+	case errors.Is(err, errNoOAuthUrl):
+		LogErrorAndWriteResponse(ctx, wrt, http.StatusConflict, err.Error(), err)
+	default:
+		LogErrorAndWriteResponse(ctx, wrt, http.StatusInternalServerError, "failed to determine the OAuth state of the SPIAccessToken", err)
+	}
+}
+
+func (c *commonController) DeviceAuthorization(ctx context.Context, w http.ResponseWriter, k8sToken string, state *oauthstate.OAuthInfo) {
+	lg := log.FromContext(ctx)
+	defer logs.TimeTrack(lg, time.Now(), "/device")
+
+	hasAccess, err := c.checkIdentityHasAccess(ctx, state)
+	if err != nil {
+		LogErrorAndWriteResponse(ctx, w, http.StatusInternalServerError, "failed to determine if the authenticated user has access", err)
+		return
+	}
+	if !hasAccess {
+		LogDebugAndWriteResponse(ctx, w, http.StatusForbidden, "the authenticated user is not allowed to update the token data")
+		return
+	}
+
+	oauthCfg, err := c.obtainOauthConfig(ctx, state)
+	if err != nil {
+		LogErrorAndWriteResponse(ctx, w, http.StatusInternalServerError, "failed to create oauth configuration", err)
+		return
+	}
+	if oauthCfg.Endpoint.DeviceAuthURL == "" {
+		LogDebugAndWriteResponse(ctx, w, http.StatusBadRequest, errDeviceFlowNotSupported.Error(), "providerName", state.ServiceProviderName, "providerUrl", state.ServiceProviderUrl)
+		return
+	}
+	oauthCfg.Scopes = state.Scopes
+
+	tokenKey := client.ObjectKey{Namespace: state.TokenNamespace, Name: state.TokenName}
+	if !c.devicePolls.start(tokenKey) {
+		LogDebugAndWriteResponse(ctx, w, http.StatusConflict, "the device authorization is already in progress for the SPIAccessToken", "namespace", state.TokenNamespace, "token", state.TokenName)
+		return
+	}
+
+	deviceAuth, err := oauthCfg.DeviceAuth(ctx)
+	if err != nil {
+		c.devicePolls.finish(tokenKey)
+		LogErrorAndWriteResponse(ctx, w, http.StatusBadGateway, "failed to initiate the device authorization with the service provider", err)
+		return
+	}
+	AuditLogWithTokenInfo(ctx, "OAuth device authorization flow started", state.TokenNamespace, state.TokenName, "scopes", state.Scopes, "providerName", state.ServiceProviderName, "providerUrl", state.ServiceProviderUrl)
+
+	// the polling must outlive the request, so it runs with the context of the service instead
+	go c.pollDeviceAccessToken(log.IntoContext(c.devicePolls.ctx, lg), oauthCfg, deviceAuth, exchangeResult{
+		OAuthInfo:           *state,
+		result:              oauthFinishAuthenticated,
+		authorizationHeader: k8sToken,
+	})
+
+	response := DeviceAuthorizationResponse{
+		UserCode:                deviceAuth.UserCode,
+		VerificationUri:         deviceAuth.VerificationURI,
+		VerificationUriComplete: deviceAuth.VerificationURIComplete,
+	}
+	if !deviceAuth.Expiry.IsZero() {
+		response.ExpiresIn = int64(time.Until(deviceAuth.Expiry).Seconds())
+	}
+
+	writeJsonResponse(ctx, w, response)
+}
+
+// pollDeviceAccessToken polls the token endpoint of the service provider until the user completes the device
+// authorization, the device code expires or the service shuts down. The obtained token is stored the same way as in the
+// redirect flow.
+func (c *commonController) pollDeviceAccessToken(ctx context.Context, oauthCfg *oauth2.Config, deviceAuth *oauth2.DeviceAuthResponse, exchange exchangeResult) {
+	defer c.devicePolls.finish(client.ObjectKey{Namespace: exchange.TokenNamespace, Name: exchange.TokenName})
+
+	token, err := oauthCfg.DeviceAccessToken(ctx, deviceAuth)
+	if err != nil {
+		AuditLogWithTokenInfo(ctx, "OAuth device authorization flow failed", exchange.TokenNamespace, exchange.TokenName, "reason", err.Error())
+		return
+	}
+	exchange.token = token
+
+	if err := c.syncTokenData(ctx, &exchange); err != nil {
+		log.FromContext(ctx).Error(err, "failed to store token data obtained by the device authorization", "namespace", exchange.TokenNamespace, "token", exchange.TokenName)
+		return
+	}
+	AuditLogWithTokenInfo(ctx, "OAuth device authorization flow completed successfully", exchange.TokenNamespace, exchange.TokenName, "scopes", exchange.Scopes, "providerName", exchange.ServiceProviderName, "providerUrl", exchange.ServiceProviderUrl)
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redhat-appstudio/remote-secret/pkg/kubernetesclient"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/tokenstorage"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	authzv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// accessReviewingClient answers the SelfSubjectAccessReviews with the configured result.
+type accessReviewingClient struct {
+	client.Client
+	allowed bool
+}
+
+func (c *accessReviewingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if review, ok := obj.(*authzv1.SelfSubjectAccessReview); ok {
+		review.Status.Allowed = c.allowed
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// newFakeDeviceFlowProvider returns a server implementing the device authorization and the token endpoints. The token
+// endpoint responds with authorization_pending to the first poll.
+func newFakeDeviceFlowProvider(t *testing.T) *httptest.Server {
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device/code", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "repo", r.FormValue("scope"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"device_code":"device","user_code":"ABCD-1234","verification_uri":"https://sp/device","expires_in":60,"interval":1}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "device", r.FormValue("device_code"))
+		w.Header().Set("Content-Type", "application/json")
+		polls++
+		if polls == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"sp-token","token_type":"bearer"}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDeviceAuthorization(t *testing.T) {
+	provider := newFakeDeviceFlowProvider(t)
+	codec := oauthstate.NewCodec(config.OAuthStateConfiguration{})
+
+	oauthUrlOf := func(name string) string {
+		state, err := codec.Encode(&oauthstate.OAuthInfo{
+			TokenName:           name,
+			TokenNamespace:      "ns",
+			Scopes:              []string{"repo"},
+			ServiceProviderName: config.ServiceProviderTypeGitHub.Name,
+			ServiceProviderUrl:  "https://github.com",
+		})
+		assert.NoError(t, err)
+		return "https://spi/github/authenticate?state=" + url.QueryEscape(state)
+	}
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(api.AddToScheme(scheme))
+	utilruntime.Must(corev1.AddToScheme(scheme))
+
+	newRouter := func(ctx context.Context, allowed bool, deviceAuthUrl string, stored chan<- *api.Token) *mux.Router {
+		cl := &accessReviewingClient{
+			allowed: allowed,
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&api.SPIAccessToken{
+					ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "ns"},
+					Status:     api.SPIAccessTokenStatus{OAuthUrl: oauthUrlOf("token")},
+				},
+				&api.SPIAccessToken{
+					ObjectMeta: metav1.ObjectMeta{Name: "ready-token", Namespace: "ns"},
+				},
+				&api.SPIAccessToken{
+					ObjectMeta: metav1.ObjectMeta{Name: "stolen-token", Namespace: "ns"},
+					Status:     api.SPIAccessTokenStatus{OAuthUrl: oauthUrlOf("token")},
+				},
+			).Build(),
+		}
+		clientFactory := kubernetesclient.SingleInstanceClientFactory{Client: cl}
+
+		controller := &commonController{
+			OAuthServiceConfiguration: OAuthServiceConfiguration{SharedConfiguration: config.SharedConfiguration{
+				BaseUrl: "https://spi",
+				ServiceProviders: []config.ServiceProviderConfiguration{{
+					ServiceProviderType:    config.ServiceProviderTypeGitHub,
+					ServiceProviderBaseUrl: "https://github.com",
+					OAuth2Config: &oauth2.Config{
+						ClientID:     "client",
+						ClientSecret: "secret",
+						Endpoint: oauth2.Endpoint{
+							TokenURL:      provider.URL + "/token",
+							DeviceAuthURL: deviceAuthUrl,
+						},
+					},
+				}},
+			}},
+			ClientFactory:      clientFactory,
+			InClusterK8sClient: cl,
+			TokenStorage: tokenstorage.TestTokenStorage{
+				StoreImpl: func(_ context.Context, owner *api.SPIAccessToken, token *api.Token) error {
+					assert.Equal(t, "token", owner.Name)
+					stored <- token
+					return nil
+				},
+			},
+			ServiceProviderType: config.ServiceProviderTypeGitHub,
+			devicePolls:         newDevicePolls(ctx),
+		}
+
+		router := &Router{
+			controllers:   map[config.ServiceProviderName]Controller{config.ServiceProviderTypeGitHub.Name: controller},
+			stateCodec:    codec,
+			clientFactory: clientFactory,
+		}
+
+		muxRouter := mux.NewRouter()
+		muxRouter.NewRoute().Path("/device/{namespace}/{name}").Handler(router.DeviceAuthorization()).Methods("POST")
+		return muxRouter
+	}
+
+	request := func(router http.Handler, name string, authorized bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/device/ns/"+name, nil)
+		if authorized {
+			req.Header.Set("Authorization", "Bearer k8s-token")
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("token is obtained in the background", func(t *testing.T) {
+		stored := make(chan *api.Token, 1)
+		res := request(newRouter(context.TODO(), true, provider.URL+"/device/code", stored), "token", true)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		response := DeviceAuthorizationResponse{}
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, "ABCD-1234", response.UserCode)
+		assert.Equal(t, "https://sp/device", response.VerificationUri)
+		assert.Greater(t, response.ExpiresIn, int64(0))
+
+		select {
+		case token := <-stored:
+			assert.Equal(t, "sp-token", token.AccessToken)
+		case <-time.After(10 * time.Second):
+			assert.Fail(t, "the token has not been stored")
+		}
+	})
+
+	t.Run("second authorization of the same token is rejected", func(t *testing.T) {
+		stored := make(chan *api.Token, 1)
+		router := newRouter(context.TODO(), true, provider.URL+"/device/code", stored)
+
+		assert.Equal(t, http.StatusOK, request(router, "token", true).Code)
+		assert.Equal(t, http.StatusConflict, request(router, "token", true).Code)
+
+		select {
+		case token := <-stored:
+			assert.Equal(t, "sp-token", token.AccessToken)
+		case <-time.After(10 * time.Second):
+			assert.Fail(t, "the token has not been stored")
+		}
+	})
+
+	t.Run("polling stops when the service shuts down", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		stored := make(chan *api.Token, 1)
+		router := newRouter(ctx, true, newFakeDeviceFlowProvider(t).URL+"/device/code", stored)
+
+		assert.Equal(t, http.StatusOK, request(router, "token", true).Code)
+		cancel()
+
+		select {
+		case <-stored:
+			assert.Fail(t, "the token must not be stored after the shutdown")
+		case <-time.After(2 * time.Second):
+		}
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		res := request(newRouter(context.TODO(), true, provider.URL+"/device/code", nil), "token", false)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		res := request(newRouter(context.TODO(), true, provider.URL+"/device/code", nil), "unknown", true)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("token without OAuth URL", func(t *testing.T) {
+		res := request(newRouter(context.TODO(), true, provider.URL+"/device/code", nil), "ready-token", true)
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+
+	t.Run("OAuth URL of another token", func(t *testing.T) {
+		res := request(newRouter(context.TODO(), true, provider.URL+"/device/code", nil), "stolen-token", true)
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+
+	t.Run("not allowed to update the token data", func(t *testing.T) {
+		res := request(newRouter(context.TODO(), false, provider.URL+"/device/code", nil), "token", true)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("service provider without device authorization", func(t *testing.T) {
+		res := request(newRouter(context.TODO(), true, "", nil), "token", true)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
@@ -14,7 +14,6 @@
 package oauth
 
 import (
-	"html/template"
 	"net/http"
 	"strings"
 
@@ -35,7 +34,6 @@ import (
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapio"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // OkHandler is a Handler implementation that responds only with http.StatusOK.
@@ -45,43 +43,33 @@ func OkHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 // CallbackSuccessHandler is a Handler implementation that responds with HTML page
-// This page is a landing page after successfully completing the OAuth flow.
-// Resource file location is prefixed with `../` to be compatible with tests running locally.
-func CallbackSuccessHandler() http.Handler {
+// This page is a landing page after successfully completing the OAuth flow. The service provider and the token
+// are taken from the query parameters added by the callback.
+func CallbackSuccessHandler(pages *Pages) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "../static/callback_success.html")
+		q := r.URL.Query()
+		pages.Render(w, r, callbackSuccessPage, PageData{
+			Provider:       q.Get("provider"),
+			TokenName:      q.Get("tokenName"),
+			TokenNamespace: q.Get("tokenNamespace"),
+			Outcome:        FlowOutcomeSuccess,
+		})
 	})
 }
 
-// viewData structure is used to pass parameters during callback_error.html template processing.
-type viewData struct {
-	Title   string
-	Message string
-}
-
 // CallbackErrorHandler is a Handler implementation that responds with HTML page
 // This page is a landing page after unsuccessfully completing the OAuth flow.
-// Resource file location is prefixed with `../` to be compatible with tests running locally.
-func CallbackErrorHandler() http.Handler {
+func CallbackErrorHandler(pages *Pages) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
 		errorMsg := q.Get("error")
 		errorDescription := q.Get("error_description")
-		data := viewData{
+		logs.AuditLog(r.Context()).Info("OAuth authentication flow failed.", "message", errorMsg, "description", errorDescription)
+		pages.Render(w, r, callbackErrorPage, PageData{
 			Title:   errorMsg,
 			Message: errorDescription,
-		}
-		logs.AuditLog(r.Context()).Info("OAuth authentication flow failed.", "message", errorMsg, "description", errorDescription)
-		tmpl, _ := template.ParseFiles("../static/callback_error.html")
-
-		err := tmpl.Execute(w, data)
-		if err == nil {
-			w.WriteHeader(http.StatusOK)
-		} else {
-			log.FromContext(r.Context()).Error(err, "failed to process template")
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = w.Write([]byte("Error processing the OAuth response template. This may be caused by malformed response parameters."))
-		}
+			Outcome: FlowOutcomeError,
+		})
 	})
 }
 
@@ -174,7 +162,8 @@ func BypassHandler(mainHandler http.Handler, bypassPathPrefixes []string, bypass
 // - Service metrics
 // - Request logging
 // - CORS processing
-func MiddlewareHandler(reg prometheus.Registerer, allowedOrigins []string, h http.Handler) http.Handler {
+// - Rate limiting, if the limiter is not nil
+func MiddlewareHandler(reg prometheus.Registerer, allowedOrigins []string, limiter *RateLimiter, h http.Handler) http.Handler {
 
 	middlewareHandler := HttpServiceInstrumentMetricHandler(reg,
 		handlers.LoggingHandler(&zapio.Writer{Log: zap.L(), Level: zap.DebugLevel},
@@ -185,7 +174,7 @@ func MiddlewareHandler(reg prometheus.Registerer, allowedOrigins []string, h htt
 					"Accept-Language",
 					"Content-Language",
 					"Origin",
-					"Authorization"}))(h)))
+					"Authorization"}))(RateLimitHandler(reg, limiter, h))))
 
 	return BypassHandler(middlewareHandler, []string{"/health", "/ready"}, h)
 }
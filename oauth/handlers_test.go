@@ -125,7 +125,11 @@ func TestCallbackSuccessHandler(t *testing.T) {
 
 	// We create a ResponseRecorder (which satisfies http.ResponseWriter) to record the response.
 	rr := httptest.NewRecorder()
-	handler := CallbackSuccessHandler()
+	pages, err := LoadPagesFromDir("../static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := CallbackSuccessHandler(pages)
 
 	// Our handlers satisfy http.Handler, so we can call their ServeHTTP method
 	// directly and pass in our Request and ResponseRecorder.
@@ -148,7 +152,11 @@ func TestCallbackErrorHandler(t *testing.T) {
 
 	// We create a ResponseRecorder (which satisfies http.ResponseWriter) to record the response.
 	rr := httptest.NewRecorder()
-	handler := CallbackErrorHandler()
+	pages, err := LoadPagesFromDir("../static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := CallbackErrorHandler(pages)
 
 	// Our handlers satisfy http.Handler, so we can call their ServeHTTP method
 	// directly and pass in our Request and ResponseRecorder.
@@ -525,7 +533,7 @@ func TestMiddlewareHandlerCorsPart(t *testing.T) {
 
 	// We create a ResponseRecorder (which satisfies http.ResponseWriter) to record the response.
 	rr := httptest.NewRecorder()
-	handler := MiddlewareHandler(prometheus.NewRegistry(), []string{"https://console.dev.redhat.com", "https://prod.foo.redhat.com"}, http.HandlerFunc(OkHandler))
+	handler := MiddlewareHandler(prometheus.NewRegistry(), []string{"https://console.dev.redhat.com", "https://prod.foo.redhat.com"}, nil, http.HandlerFunc(OkHandler))
 
 	// Our handlers satisfy http.Handler, so we can call their ServeHTTP method
 	// directly and pass in our Request and ResponseRecorder.
@@ -568,7 +576,7 @@ func TestMiddlewareHandlerCors(t *testing.T) {
 
 	// We create a ResponseRecorder (which satisfies http.ResponseWriter) to record the response.
 	rr := httptest.NewRecorder()
-	handler := MiddlewareHandler(prometheus.NewRegistry(), []string{"https://file-retriever-server-service-spi-system.apps.cluster-flmv6.flmv6.sandbox1324.opentlc.com", "http:://acme.com"}, http.HandlerFunc(OkHandler))
+	handler := MiddlewareHandler(prometheus.NewRegistry(), []string{"https://file-retriever-server-service-spi-system.apps.cluster-flmv6.flmv6.sandbox1324.opentlc.com", "http:://acme.com"}, nil, http.HandlerFunc(OkHandler))
 
 	// Our handlers satisfy http.Handler, so we can call their ServeHTTP method
 	// directly and pass in our Request and ResponseRecorder.
@@ -588,6 +596,27 @@ func TestMiddlewareHandlerCors(t *testing.T) {
 
 }
 
+func TestMiddlewareHandlerRateLimit(t *testing.T) {
+	limits, err := ParseRateLimits("authenticate.ip=1/1m")
+	assert.NoError(t, err)
+	handler := MiddlewareHandler(prometheus.NewRegistry(), []string{"https://prod.foo.redhat.com"}, NewRateLimiter(limits, nil, nil), http.HandlerFunc(OkHandler))
+
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", oauth.AuthenticateRoutePath, nil)
+		req.Header.Set("Origin", "https://prod.foo.redhat.com")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, request().Code)
+	rr := request()
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	// the browser must be able to read the throttled response
+	assert.Equal(t, "https://prod.foo.redhat.com", rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
 // Simple counter server
 type Counter struct {
 	mu sync.Mutex // protects n
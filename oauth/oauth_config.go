@@ -34,6 +34,17 @@ var (
 // Currently, this can be configured with labeled secret living in namespace together with SPIAccessToken.
 // If no such secret is found, global configuration of oauth service is used.
 func (c *commonController) obtainOauthConfig(ctx context.Context, info *oauthstate.OAuthInfo) (*oauth2.Config, error) {
+	spConfig, err := c.obtainServiceProviderConfig(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	return spConfig.OAuth2Config, nil
+}
+
+// obtainServiceProviderConfig finds the configuration of the service provider the same way as obtainOauthConfig but
+// returns the whole configuration, not just the OAuth part of it. The returned configuration is guaranteed to have
+// the OAuth configuration with the redirect URL pointing to this service.
+func (c *commonController) obtainServiceProviderConfig(ctx context.Context, info *oauthstate.OAuthInfo) (*config.ServiceProviderConfiguration, error) {
 	spUrl, urlParseErr := url.Parse(info.ServiceProviderUrl)
 	if urlParseErr != nil {
 		return nil, fmt.Errorf("failed to parse serviceprovider url: %w", urlParseErr)
@@ -49,9 +60,8 @@ func (c *commonController) obtainOauthConfig(ctx context.Context, info *oauthsta
 		if spConfig.OAuth2Config == nil {
 			return nil, fmt.Errorf("user config error: %w", errConfigNoOAuth)
 		}
-		oauthConfig := spConfig.OAuth2Config
-		oauthConfig.RedirectURL = c.redirectUrl()
-		return oauthConfig, nil
+		spConfig.OAuth2Config.RedirectURL = c.redirectUrl()
+		return spConfig, nil
 	}
 
 	// if we don't have user's config, we
@@ -59,9 +69,8 @@ func (c *commonController) obtainOauthConfig(ctx context.Context, info *oauthsta
 		if globalSpConfig.OAuth2Config == nil {
 			return nil, fmt.Errorf("global config error: %w", errConfigNoOAuth)
 		}
-		oauthConfig := globalSpConfig.OAuth2Config
-		oauthConfig.RedirectURL = c.redirectUrl()
-		return oauthConfig, nil
+		globalSpConfig.OAuth2Config.RedirectURL = c.redirectUrl()
+		return globalSpConfig, nil
 	}
 
 	return nil, fmt.Errorf("%w '%s' url: '%s'", errNoOAuthConfiguration, info.ServiceProviderName, info.ServiceProviderUrl)
@@ -0,0 +1,251 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	"golang.org/x/oauth2"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// OIDCLoginRoutePath is the path of the endpoint initiating the OIDC login.
+	OIDCLoginRoutePath = "/login/oidc"
+	// OIDCCallbackRoutePath is the path of the endpoint the OIDC issuer redirects back to after the login.
+	OIDCCallbackRoutePath = "/login/oidc/callback"
+
+	sessionOIDCStateKey    = "oidc_state"
+	sessionOIDCNonceKey    = "oidc_nonce"
+	sessionOIDCVerifierKey = "oidc_verifier"
+	// sessionOIDCExpiryKey holds the expiry of the ID token in the session as Unix time. It is only present in the
+	// sessions established by the OIDC login.
+	sessionOIDCExpiryKey       = "oidc_expiry"
+	sessionOIDCRefreshTokenKey = "oidc_refresh_token"
+)
+
+var (
+	oidcStateMismatchError = errors.New("the state of the OIDC callback doesn't match the state of the session")
+	noIDTokenError         = errors.New("the token response of the OIDC issuer doesn't contain an ID token")
+	oidcNonceMismatchError = errors.New("the nonce of the ID token doesn't match the nonce of the session")
+	oidcExpiredError       = errors.New("the OIDC ID token of the session has expired and cannot be refreshed")
+)
+
+// OIDCLogin holds the configuration of the OIDC login mode of the Authenticator. The users are redirected to the OIDC
+// issuer and the ID token obtained from it is used as the bearer token in the requests to the Kubernetes API server.
+// Therefore, the API server needs to be configured to accept the ID tokens of the issuer.
+type OIDCLogin struct {
+	OAuth2Config *oauth2.Config
+	Verifier     *oidc.IDTokenVerifier
+	// SuccessUrl is the URL the users are redirected to after a successful login.
+	SuccessUrl string
+}
+
+// NewOIDCLogin discovers the configuration of the OIDC issuer and returns the OIDC login with the callback on the
+// provided base URL of the OAuth service.
+func NewOIDCLogin(ctx context.Context, issuerUrl, clientId, clientSecret string, scopes []string, baseUrl string) (*OIDCLogin, error) {
+	provider, err := oidc.NewProvider(ctx, issuerUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover the OIDC issuer %s: %w", issuerUrl, err)
+	}
+
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+
+	return &OIDCLogin{
+		OAuth2Config: &oauth2.Config{
+			ClientID:     clientId,
+			ClientSecret: clientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  baseUrl + OIDCCallbackRoutePath,
+			Scopes:       scopes,
+		},
+		Verifier:   provider.Verifier(&oidc.Config{ClientID: clientId}),
+		SuccessUrl: baseUrl + "/callback_success",
+	}, nil
+}
+
+// OIDCLogin redirects the user to the OIDC issuer. The state, nonce and PKCE verifier of the login are kept in
+// the session so that they can be checked in the callback.
+func (a Authenticator) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	lg := log.FromContext(r.Context())
+	defer logs.TimeTrack(lg, time.Now(), "/login/oidc")
+
+	if a.OIDC == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, err := randStringBytes(32)
+	if err != nil {
+		LogErrorAndWriteResponse(r.Context(), w, http.StatusInternalServerError, "failed to generate the OIDC state", err)
+		return
+	}
+	nonce, err := randStringBytes(32)
+	if err != nil {
+		LogErrorAndWriteResponse(r.Context(), w, http.StatusInternalServerError, "failed to generate the OIDC nonce", err)
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	a.SessionManager.Put(r.Context(), sessionOIDCStateKey, state)
+	a.SessionManager.Put(r.Context(), sessionOIDCNonceKey, nonce)
+	a.SessionManager.Put(r.Context(), sessionOIDCVerifierKey, verifier)
+
+	http.Redirect(w, r, a.OIDC.OAuth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), http.StatusFound)
+}
+
+// OIDCCallback finishes the OIDC code flow. The obtained ID token is reviewed in the same way as the tokens supplied
+// to Login and stored in the session in their place.
+func (a Authenticator) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	lg := log.FromContext(r.Context())
+	defer logs.TimeTrack(lg, time.Now(), "/login/oidc/callback")
+
+	if a.OIDC == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state := a.SessionManager.PopString(r.Context(), sessionOIDCStateKey)
+	nonce := a.SessionManager.PopString(r.Context(), sessionOIDCNonceKey)
+	verifier := a.SessionManager.PopString(r.Context(), sessionOIDCVerifierKey)
+
+	if state == "" || r.FormValue("state") != state {
+		LogDebugAndWriteResponse(r.Context(), w, http.StatusBadRequest, oidcStateMismatchError.Error())
+		return
+	}
+
+	if errorCode := r.FormValue("error"); errorCode != "" {
+		LogDebugAndWriteResponse(r.Context(), w, http.StatusUnauthorized, fmt.Sprintf("the OIDC login failed: %s: %s", errorCode, r.FormValue("error_description")))
+		logs.AuditLog(r.Context()).Info("unsuccessful OIDC login occurred", "reason", errorCode)
+		return
+	}
+
+	token, rawIDToken, idToken, err := a.OIDC.exchange(r.Context(), r.FormValue("code"), verifier, nonce)
+	if err != nil {
+		LogErrorAndWriteResponse(r.Context(), w, http.StatusUnauthorized, "failed to obtain the ID token from the OIDC issuer", err)
+		logs.AuditLog(r.Context()).Info("unsuccessful OIDC login occurred", "reason", err.Error())
+		return
+	}
+
+	username, err := a.TokenReviewer.Review(r.Context(), rawIDToken)
+	if err != nil {
+		if errors.Is(err, invalidK8sTokenError) {
+			LogDebugAndWriteResponse(r.Context(), w, http.StatusUnauthorized, "the ID token is not accepted by Kubernetes")
+			logs.AuditLog(r.Context()).Info("unsuccessful authentication with OIDC ID token occurred", "reason", err.Error())
+			return
+		}
+		LogErrorAndWriteResponse(r.Context(), w, http.StatusInternalServerError, "failed to determine if the authenticated user has access", err)
+		return
+	}
+
+	if err := a.SessionManager.RenewToken(r.Context()); err != nil {
+		LogErrorAndWriteResponse(r.Context(), w, http.StatusInternalServerError, "failed to renew the session", err)
+		return
+	}
+	a.SessionManager.Put(r.Context(), sessionK8sTokenKey, rawIDToken)
+	a.SessionManager.Put(r.Context(), sessionK8sUsernameKey, username)
+	a.SessionManager.Put(r.Context(), sessionOIDCExpiryKey, idToken.Expiry.Unix())
+	a.SessionManager.Put(r.Context(), sessionOIDCRefreshTokenKey, token.RefreshToken)
+	logs.AuditLog(r.Context()).Info("successful authentication with OIDC ID token", "action", "ADD", "username", username)
+
+	http.Redirect(w, r, a.OIDC.SuccessUrl, http.StatusFound)
+}
+
+// refreshOIDCToken returns the ID token stored in the session. If it has expired, it is refreshed using the refresh
+// token obtained at the login. If it cannot be refreshed, the identity is removed from the session so that the user
+// needs to log in again. The sessions that were not established by the OIDC login are returned as they are.
+func (a *Authenticator) refreshOIDCToken(ctx context.Context) (string, error) {
+	rawIDToken := a.SessionManager.GetString(ctx, sessionK8sTokenKey)
+	expiry := a.SessionManager.GetInt64(ctx, sessionOIDCExpiryKey)
+	if a.OIDC == nil || expiry == 0 || time.Now().Before(time.Unix(expiry, 0)) {
+		return rawIDToken, nil
+	}
+
+	username := a.SessionManager.GetString(ctx, sessionK8sUsernameKey)
+	refreshToken := a.SessionManager.GetString(ctx, sessionOIDCRefreshTokenKey)
+	if refreshToken == "" {
+		a.removeIdentity(ctx)
+		logs.AuditLog(ctx).Info("OIDC session expired", "action", "DELETE", "username", username)
+		return "", oidcExpiredError
+	}
+
+	token, rawIDToken, idToken, err := a.OIDC.refresh(ctx, refreshToken)
+	if err != nil {
+		a.removeIdentity(ctx)
+		logs.AuditLog(ctx).Info("OIDC session expired", "action", "DELETE", "username", username, "reason", err.Error())
+		return "", fmt.Errorf("%w: %s", oidcExpiredError, err.Error())
+	}
+
+	a.SessionManager.Put(ctx, sessionK8sTokenKey, rawIDToken)
+	a.SessionManager.Put(ctx, sessionOIDCExpiryKey, idToken.Expiry.Unix())
+	a.SessionManager.Put(ctx, sessionOIDCRefreshTokenKey, token.RefreshToken)
+	logs.AuditLog(ctx).Info("successful refresh of OIDC ID token", "action", "UPDATE", "username", username)
+
+	return rawIDToken, nil
+}
+
+// exchange exchanges the authorization code for the tokens and returns them along with the verified ID token.
+func (o *OIDCLogin) exchange(ctx context.Context, code, verifier, nonce string) (*oauth2.Token, string, *oidc.IDToken, error) {
+	token, err := o.OAuth2Config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to exchange the authorization code: %w", err)
+	}
+
+	rawIDToken, idToken, err := o.verifyIDToken(ctx, token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if idToken.Nonce != nonce {
+		return nil, "", nil, oidcNonceMismatchError
+	}
+
+	return token, rawIDToken, idToken, nil
+}
+
+// refresh obtains new tokens using the refresh token and returns them along with the verified ID token.
+func (o *OIDCLogin) refresh(ctx context.Context, refreshToken string) (*oauth2.Token, string, *oidc.IDToken, error) {
+	token, err := o.OAuth2Config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to refresh the tokens: %w", err)
+	}
+
+	rawIDToken, idToken, err := o.verifyIDToken(ctx, token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return token, rawIDToken, idToken, nil
+}
+
+// verifyIDToken extracts the ID token from the token response and verifies it.
+func (o *OIDCLogin) verifyIDToken(ctx context.Context, token *oauth2.Token) (string, *oidc.IDToken, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return "", nil, noIDTokenError
+	}
+
+	idToken, err := o.Verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to verify the ID token: %w", err)
+	}
+
+	return rawIDToken, idToken, nil
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/redhat-appstudio/remote-secret/pkg/kubernetesclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOIDCIssuer is a minimal OIDC issuer that issues ID tokens with the configured nonce for any authorization code.
+// The configured refresh token is issued along with them, if any.
+type fakeOIDCIssuer struct {
+	server       *httptest.Server
+	key          *rsa.PrivateKey
+	nonce        string
+	refreshToken string
+	codeVerifier string
+	grantType    string
+}
+
+func newFakeOIDCIssuer(t *testing.T) *fakeOIDCIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	issuer := &fakeOIDCIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.server.URL,
+			"authorization_endpoint":                issuer.server.URL + "/authorize",
+			"token_endpoint":                        issuer.server.URL + "/token",
+			"jwks_uri":                              issuer.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "key", Algorithm: "RS256", Use: "sig"}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		issuer.codeVerifier = r.FormValue("code_verifier")
+		issuer.grantType = r.FormValue("grant_type")
+		if issuer.grantType == "refresh_token" && r.FormValue("refresh_token") != issuer.refreshToken {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_grant"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		response := map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     issuer.idToken(t),
+		}
+		if issuer.refreshToken != "" {
+			response["refresh_token"] = issuer.refreshToken
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (i *fakeOIDCIssuer) idToken(t *testing.T) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: i.key}, (&jose.SignerOptions{}).WithHeader("kid", "key"))
+	assert.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(map[string]interface{}{
+		"iss":   i.server.URL,
+		"sub":   "alice",
+		"aud":   "spi",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": i.nonce,
+	}).CompactSerialize()
+	assert.NoError(t, err)
+	return token
+}
+
+// acceptingTokenReviewer authenticates all the tokens as the configured user, unless failWith is set.
+type acceptingTokenReviewer struct {
+	username string
+	failWith error
+}
+
+func (r acceptingTokenReviewer) Review(_ context.Context, _ string) (string, error) {
+	return r.username, r.failWith
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newFakeOIDCIssuer(t)
+	oidcLogin, err := NewOIDCLogin(context.TODO(), issuer.server.URL, "spi", "secret", []string{"openid"}, "https://spi.acme.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://spi.acme.com/login/oidc/callback", oidcLogin.OAuth2Config.RedirectURL)
+	assert.Equal(t, "https://spi.acme.com/callback_success", oidcLogin.SuccessUrl)
+
+	// login initiates the OIDC flow and returns the session cookie and the state and nonce sent to the issuer
+	login := func(t *testing.T, authenticator *Authenticator) (*http.Cookie, url.Values) {
+		res := httptest.NewRecorder()
+		authenticator.SessionManager.LoadAndSave(http.HandlerFunc(authenticator.OIDCLogin)).ServeHTTP(res, httptest.NewRequest("GET", "/login/oidc", nil))
+		assert.Equal(t, http.StatusFound, res.Code)
+		redirect, err := url.Parse(res.Header().Get("Location"))
+		assert.NoError(t, err)
+		assert.Equal(t, issuer.server.URL+"/authorize", redirect.Scheme+"://"+redirect.Host+redirect.Path)
+		assert.Equal(t, "S256", redirect.Query().Get("code_challenge_method"))
+		return res.Result().Cookies()[0], redirect.Query()
+	}
+
+	callback := func(authenticator *Authenticator, cookie *http.Cookie, state string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/login/oidc/callback?code=code&state="+url.QueryEscape(state), nil)
+		req.AddCookie(cookie)
+		res := httptest.NewRecorder()
+		authenticator.SessionManager.LoadAndSave(http.HandlerFunc(authenticator.OIDCCallback)).ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("successful login", func(t *testing.T) {
+		authenticator := NewAuthenticator(scs.New(), nil, acceptingTokenReviewer{username: "alice"})
+		authenticator.OIDC = oidcLogin
+
+		cookie, query := login(t, authenticator)
+		issuer.nonce = query.Get("nonce")
+		res := callback(authenticator, cookie, query.Get("state"))
+
+		assert.Equal(t, http.StatusFound, res.Code)
+		assert.Equal(t, "https://spi.acme.com/callback_success", res.Header().Get("Location"))
+		assert.NotEmpty(t, issuer.codeVerifier)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(res.Result().Cookies()[0])
+		authenticator.SessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "alice", authenticator.SessionManager.GetString(r.Context(), sessionK8sUsernameKey))
+			token, err := authenticator.GetToken(r.Context(), r)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, token)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+	})
+
+	// inSession runs the function in a request with the session of the cookie
+	inSession := func(authenticator *Authenticator, cookie *http.Cookie, fn func(r *http.Request)) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(cookie)
+		authenticator.SessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fn(r)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("expired ID token is refreshed", func(t *testing.T) {
+		authenticator := NewAuthenticator(scs.New(), nil, acceptingTokenReviewer{username: "alice"})
+		authenticator.OIDC = oidcLogin
+		issuer.refreshToken = "refresh"
+		defer func() { issuer.refreshToken = "" }()
+
+		cookie, query := login(t, authenticator)
+		issuer.nonce = query.Get("nonce")
+		res := callback(authenticator, cookie, query.Get("state"))
+		assert.Equal(t, http.StatusFound, res.Code)
+
+		inSession(authenticator, res.Result().Cookies()[0], func(r *http.Request) {
+			assert.True(t, authenticator.SessionManager.GetInt64(r.Context(), sessionOIDCExpiryKey) > time.Now().Unix())
+			authenticator.SessionManager.Put(r.Context(), sessionOIDCExpiryKey, time.Now().Add(-time.Minute).Unix())
+
+			token, err := authenticator.GetToken(r.Context(), r)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, token)
+			assert.Equal(t, "refresh_token", issuer.grantType)
+			assert.True(t, authenticator.SessionManager.GetInt64(r.Context(), sessionOIDCExpiryKey) > time.Now().Unix())
+			assert.Equal(t, "alice", authenticator.SessionManager.GetString(r.Context(), sessionK8sUsernameKey))
+		})
+	})
+
+	t.Run("expired ID token without refresh token ends the session", func(t *testing.T) {
+		authenticator := NewAuthenticator(scs.New(), nil, acceptingTokenReviewer{username: "alice"})
+		authenticator.OIDC = oidcLogin
+
+		cookie, query := login(t, authenticator)
+		issuer.nonce = query.Get("nonce")
+		res := callback(authenticator, cookie, query.Get("state"))
+		assert.Equal(t, http.StatusFound, res.Code)
+
+		inSession(authenticator, res.Result().Cookies()[0], func(r *http.Request) {
+			authenticator.SessionManager.Put(r.Context(), sessionOIDCExpiryKey, time.Now().Add(-time.Minute).Unix())
+
+			_, err := authenticator.GetToken(r.Context(), r)
+			assert.ErrorIs(t, err, oidcExpiredError)
+			assert.Empty(t, authenticator.SessionManager.GetString(r.Context(), sessionK8sTokenKey))
+			assert.Empty(t, authenticator.SessionManager.GetString(r.Context(), sessionK8sUsernameKey))
+		})
+	})
+
+	t.Run("state mismatch", func(t *testing.T) {
+		authenticator := NewAuthenticator(scs.New(), nil, acceptingTokenReviewer{username: "alice"})
+		authenticator.OIDC = oidcLogin
+
+		cookie, query := login(t, authenticator)
+		issuer.nonce = query.Get("nonce")
+		res := callback(authenticator, cookie, "forged")
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		authenticator := NewAuthenticator(scs.New(), nil, acceptingTokenReviewer{username: "alice"})
+		authenticator.OIDC = oidcLogin
+
+		cookie, query := login(t, authenticator)
+		issuer.nonce = "replayed"
+		res := callback(authenticator, cookie, query.Get("state"))
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("ID token not accepted by Kubernetes", func(t *testing.T) {
+		authenticator := NewAuthenticator(scs.New(), nil, acceptingTokenReviewer{failWith: invalidK8sTokenError})
+		authenticator.OIDC = oidcLogin
+
+		cookie, query := login(t, authenticator)
+		issuer.nonce = query.Get("nonce")
+		res := callback(authenticator, cookie, query.Get("state"))
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		authenticator := NewAuthenticator(scs.New(), kubernetesclient.SingleInstanceClientFactory{}, nil)
+
+		res := httptest.NewRecorder()
+		authenticator.OIDCLogin(res, httptest.NewRequest("GET", "/login/oidc", nil))
+		assert.Equal(t, http.StatusNotFound, res.Code)
+
+		res = httptest.NewRecorder()
+		authenticator.OIDCCallback(res, httptest.NewRequest("GET", "/login/oidc/callback", nil))
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
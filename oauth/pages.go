@@ -0,0 +1,239 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/language"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
+)
+
+const (
+	callbackSuccessPage = "callback_success.html"
+	callbackErrorPage   = "callback_error.html"
+)
+
+// FlowOutcome is the result of the OAuth flow passed to the pages and to the redirect_after URI.
+type FlowOutcome string
+
+const (
+	FlowOutcomeSuccess FlowOutcome = "success"
+	FlowOutcomeError   FlowOutcome = "error"
+)
+
+var (
+	errMissingPage             = errors.New("the page template is missing")
+	errRedirectAfterNotAllowed = errors.New("the redirect_after URI is not allowed")
+)
+
+// Pages holds the templates of the pages shown at the end of the OAuth flow. Each page can be localised by providing
+// additional templates named `<page>.<language tag>.html`, e.g. `callback_success.de.html`. The language is chosen
+// according to the Accept-Language header of the request, falling back to the template without the language tag.
+type Pages struct {
+	templates map[string]*template.Template
+}
+
+// PageData is the data passed to the page templates.
+type PageData struct {
+	// Title and Message describe the error, if any
+	Title   string
+	Message string
+	// Provider is the name of the service provider type
+	Provider       string
+	TokenName      string
+	TokenNamespace string
+	Outcome        FlowOutcome
+}
+
+// NewPages parses the page templates from the provided map of file names to their contents. Both the success and
+// the error pages must be present.
+func NewPages(files map[string]string) (*Pages, error) {
+	pages := &Pages{templates: map[string]*template.Template{}}
+	for name, content := range files {
+		if !strings.HasSuffix(name, ".html") {
+			continue
+		}
+		tmpl, err := template.New(name).Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the page template %s: %w", name, err)
+		}
+		pages.templates[name] = tmpl
+	}
+
+	for _, page := range []string{callbackSuccessPage, callbackErrorPage} {
+		if _, ok := pages.templates[page]; !ok {
+			return nil, fmt.Errorf("%w: %s", errMissingPage, page)
+		}
+	}
+
+	return pages, nil
+}
+
+// LoadPagesFromDir loads the page templates from the HTML files in the provided directory.
+func LoadPagesFromDir(dir string) (*Pages, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the page templates in %s: %w", dir, err)
+	}
+
+	files := map[string]string{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the page template %s: %w", path, err)
+		}
+		files[filepath.Base(path)] = string(content)
+	}
+
+	return NewPages(files)
+}
+
+// LoadPagesFromConfigMap loads the page templates from the data of the ConfigMap. The keys of the data are the file
+// names of the templates.
+func LoadPagesFromConfigMap(ctx context.Context, cl client.Client, namespace string, name string) (*Pages, error) {
+	cm := &corev1.ConfigMap{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, cm); err != nil {
+		return nil, fmt.Errorf("failed to get the ConfigMap %s/%s with the page templates: %w", namespace, name, err)
+	}
+
+	return NewPages(cm.Data)
+}
+
+// Render writes the page in the language preferred by the request.
+func (p *Pages) Render(w http.ResponseWriter, r *http.Request, page string, data PageData) {
+	tmpl := p.localised(page, r.Header.Get("Accept-Language"))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		log.FromContext(r.Context()).Error(err, "failed to process template", "page", page)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Error processing the OAuth response template. This may be caused by malformed response parameters."))
+	}
+}
+
+// localised finds the template of the page in the most preferred language that is available.
+func (p *Pages) localised(page string, acceptLanguage string) *template.Template {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	baseName := strings.TrimSuffix(page, ".html")
+	for _, tag := range tags {
+		if tmpl, ok := p.templates[baseName+"."+tag.String()+".html"]; ok {
+			return tmpl
+		}
+		if base, confidence := tag.Base(); confidence != language.No {
+			if tmpl, ok := p.templates[baseName+"."+base.String()+".html"]; ok {
+				return tmpl
+			}
+		}
+	}
+	return p.templates[page]
+}
+
+// flowResultUrl appends the outcome of the OAuth flow, the service provider and the token to the provided URL.
+// The description of the error is added if the flow failed.
+func flowResultUrl(target string, state *oauthstate.OAuthInfo, outcome FlowOutcome, flowError string) string {
+	targetUrl, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	query := targetUrl.Query()
+	query.Set("outcome", string(outcome))
+	query.Set("provider", string(state.ServiceProviderName))
+	query.Set("tokenName", state.TokenName)
+	query.Set("tokenNamespace", state.TokenNamespace)
+	if flowError != "" {
+		query.Set("error", flowError)
+	}
+	targetUrl.RawQuery = query.Encode()
+
+	return targetUrl.String()
+}
+
+// allowedRedirectAfter returns the redirect_after URI carried by the state if it is allowed. The URI is checked again
+// before every redirect, because the state is not necessarily signed and could have been crafted by the caller.
+func allowedRedirectAfter(ctx context.Context, allowedUrls []string, state *oauthstate.OAuthInfo) string {
+	if state.RedirectAfter == "" {
+		return ""
+	}
+	if err := checkRedirectAfter(allowedUrls, state.RedirectAfter); err != nil {
+		log.FromContext(ctx).Error(err, "ignoring the redirect_after URI of the state")
+		return ""
+	}
+	return state.RedirectAfter
+}
+
+// checkRedirectAfter verifies that the redirect_after URI matches one of the allowed URLs. The URI matches if it has
+// the same scheme and host as the allowed URL and its path is the same or below the path of the allowed URL.
+func checkRedirectAfter(allowedUrls []string, redirectAfter string) error {
+	target, err := url.Parse(redirectAfter)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errRedirectAfterNotAllowed, err.Error())
+	}
+	// the checks below are done on the decoded path, so it must be what the browser gets from the encoded one
+	if target.RawPath != "" {
+		if decoded, err := url.PathUnescape(target.RawPath); err != nil || decoded != target.Path {
+			return fmt.Errorf("%w: the path is not encoded properly: %s", errRedirectAfterNotAllowed, redirectAfter)
+		}
+	}
+	if !isCleanPath(target.Path) {
+		return fmt.Errorf("%w: the path is not in its canonical form: %s", errRedirectAfterNotAllowed, redirectAfter)
+	}
+
+	for _, allowed := range allowedUrls {
+		allowedUrl, err := url.Parse(allowed)
+		if err != nil {
+			continue
+		}
+		if target.Scheme != allowedUrl.Scheme || target.Host != allowedUrl.Host || target.User != nil {
+			continue
+		}
+		allowedPath := strings.TrimSuffix(allowedUrl.Path, "/")
+		if target.Path == allowedPath || strings.HasPrefix(target.Path, allowedPath+"/") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", errRedirectAfterNotAllowed, redirectAfter)
+}
+
+// isCleanPath checks that the browser doesn't resolve the decoded path to a different one. The browsers remove the dot
+// segments, also the percent-encoded ones, and treat the backslashes as slashes, so e.g. "/allowed/../admin" or
+// "/allowed/%2e%2e/admin" would escape the allowed path.
+func isCleanPath(p string) bool {
+	if p == "" {
+		return true
+	}
+	if strings.Contains(p, "\\") {
+		return false
+	}
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned == p
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPages(t *testing.T) {
+	pages, err := NewPages(map[string]string{
+		"callback_success.html":       "success {{ .Provider }} {{ .TokenName }} {{ .Outcome }}",
+		"callback_success.de.html":    "erfolg {{ .TokenName }}",
+		"callback_success.pt-BR.html": "sucesso {{ .TokenName }}",
+		"callback_error.html":         "error {{ .Title }}",
+		"README.md":                   "ignored",
+	})
+	assert.NoError(t, err)
+
+	render := func(acceptLanguage string) string {
+		req := httptest.NewRequest("GET", "/callback_success", nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		res := httptest.NewRecorder()
+		pages.Render(res, req, callbackSuccessPage, PageData{Provider: "GitHub", TokenName: "<token>", Outcome: FlowOutcomeSuccess})
+		assert.Equal(t, http.StatusOK, res.Code)
+		return res.Body.String()
+	}
+
+	t.Run("default language", func(t *testing.T) {
+		assert.Equal(t, "success GitHub &lt;token&gt; success", render(""))
+	})
+
+	t.Run("preferred language", func(t *testing.T) {
+		assert.Equal(t, "erfolg &lt;token&gt;", render("fr, de;q=0.8, en;q=0.5"))
+	})
+
+	t.Run("language with region", func(t *testing.T) {
+		assert.Equal(t, "sucesso &lt;token&gt;", render("pt-BR"))
+	})
+
+	t.Run("base language of region", func(t *testing.T) {
+		assert.Equal(t, "erfolg &lt;token&gt;", render("de-AT"))
+	})
+
+	t.Run("missing page", func(t *testing.T) {
+		_, err := NewPages(map[string]string{"callback_success.html": "success"})
+		assert.ErrorIs(t, err, errMissingPage)
+	})
+}
+
+func TestLoadPagesFromConfigMap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "pages", Namespace: "spi"},
+		Data: map[string]string{
+			"callback_success.html": "success",
+			"callback_error.html":   "error",
+		},
+	}).Build()
+
+	pages, err := LoadPagesFromConfigMap(context.TODO(), cl, "spi", "pages")
+	assert.NoError(t, err)
+	res := httptest.NewRecorder()
+	pages.Render(res, httptest.NewRequest("GET", "/", nil), callbackErrorPage, PageData{})
+	assert.Equal(t, "error", res.Body.String())
+
+	_, err = LoadPagesFromConfigMap(context.TODO(), cl, "spi", "unknown")
+	assert.Error(t, err)
+}
+
+func TestCheckRedirectAfter(t *testing.T) {
+	allowed := []string{"https://console.acme.com/spi/", "https://other.acme.com"}
+
+	assert.NoError(t, checkRedirectAfter(allowed, "https://console.acme.com/spi"))
+	assert.NoError(t, checkRedirectAfter(allowed, "https://console.acme.com/spi/tokens?x=y"))
+	assert.NoError(t, checkRedirectAfter(allowed, "https://other.acme.com/anything"))
+	assert.NoError(t, checkRedirectAfter(allowed, "https://console.acme.com/spi/tokens/"))
+	assert.NoError(t, checkRedirectAfter(allowed, "https://console.acme.com/spi/my%20tokens"))
+
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "https://console.acme.com/spiker"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "http://console.acme.com/spi"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "https://console.acme.com.evil.com/spi"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "https://user@other.acme.com"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "/spi"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "https://console.acme.com/spi/../admin"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "https://console.acme.com/spi/%2e%2e/admin"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "https://console.acme.com/spi/%2E%2E%2Fadmin"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "https://console.acme.com/spi/..\\admin"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(allowed, "https://console.acme.com/spi/./tokens"), errRedirectAfterNotAllowed)
+	assert.ErrorIs(t, checkRedirectAfter(nil, "https://console.acme.com/spi"), errRedirectAfterNotAllowed)
+}
+
+func TestAllowedRedirectAfter(t *testing.T) {
+	allowed := []string{"https://console.acme.com/spi"}
+
+	assert.Equal(t, "https://console.acme.com/spi/done", allowedRedirectAfter(context.TODO(), allowed, &oauthstate.OAuthInfo{RedirectAfter: "https://console.acme.com/spi/done"}))
+	assert.Empty(t, allowedRedirectAfter(context.TODO(), allowed, &oauthstate.OAuthInfo{RedirectAfter: "https://evil.com"}))
+	assert.Empty(t, allowedRedirectAfter(context.TODO(), allowed, &oauthstate.OAuthInfo{}))
+}
+
+func TestFlowResultUrl(t *testing.T) {
+	state := &oauthstate.OAuthInfo{TokenName: "token", TokenNamespace: "ns", ServiceProviderName: "GitHub"}
+
+	assert.Equal(t, "https://console.acme.com/spi?outcome=success&provider=GitHub&tab=tokens&tokenName=token&tokenNamespace=ns",
+		flowResultUrl("https://console.acme.com/spi?tab=tokens", state, FlowOutcomeSuccess, ""))
+	assert.Equal(t, "https://spi/callback_success?error=denied&outcome=error&provider=GitHub&tokenName=token&tokenNamespace=ns",
+		flowResultUrl("https://spi/callback_success", state, FlowOutcomeError, "denied"))
+}
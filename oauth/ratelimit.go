@@ -0,0 +1,348 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redhat-appstudio/service-provider-integration-operator/oauth/clientfactory"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider/oauth"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitRoute identifies the group of endpoints sharing the same rate limit budget.
+type RateLimitRoute string
+
+const (
+	RateLimitRouteLogin        RateLimitRoute = "login"
+	RateLimitRouteAuthenticate RateLimitRoute = "authenticate"
+	RateLimitRouteCallback     RateLimitRoute = "callback"
+	RateLimitRouteUpload       RateLimitRoute = "upload"
+	RateLimitRouteDevice       RateLimitRoute = "device"
+	RateLimitRouteApi          RateLimitRoute = "api"
+)
+
+// RateLimitKey is what the requests are counted by.
+type RateLimitKey string
+
+const (
+	// RateLimitKeyIp counts the requests by the IP address of the client.
+	RateLimitKeyIp RateLimitKey = "ip"
+	// RateLimitKeyIdentity counts the requests by the Kubernetes token of the caller. The requests without the token
+	// are only limited by the IP address.
+	RateLimitKeyIdentity RateLimitKey = "identity"
+)
+
+var (
+	// HttpServiceThrottledRequestCountMetric is the metric that collects the counts of the requests rejected by the rate limits.
+	HttpServiceThrottledRequestCountMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: config.MetricsNamespace,
+			Subsystem: config.MetricsSubsystem,
+			Name:      "oauth_service_throttled_requests_total",
+			Help:      "The counts of the requests to OAuth service rejected by the rate limits categorized by the route and the key of the exceeded limit.",
+		},
+		[]string{"route", "key"},
+	)
+
+	errInvalidRateLimit    = errors.New("invalid rate limit, expected '<route>.<ip|identity>=<requests>/<period>'")
+	errInvalidTrustedProxy = errors.New("invalid trusted proxy, expected an IP address or a CIDR")
+)
+
+// RateLimit is a token-bucket budget. The bucket holds at most Requests tokens and is refilled with Requests tokens
+// per Period.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// RateLimits configures the budgets of the individual routes. The routes without a budget are not limited.
+type RateLimits map[RateLimitRoute]map[RateLimitKey]RateLimit
+
+// ParseRateLimits parses the rate limits from the comma-separated list of `<route>.<key>=<requests>/<period>`, e.g.
+// `login.ip=20/1m,upload.identity=30/1m`.
+func ParseRateLimits(spec string) (RateLimits, error) {
+	limits := RateLimits{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		target, budget, found := strings.Cut(item, "=")
+		if !found {
+			return nil, fmt.Errorf("%w: %s", errInvalidRateLimit, item)
+		}
+		route, key, found := strings.Cut(target, ".")
+		if !found || (RateLimitKey(key) != RateLimitKeyIp && RateLimitKey(key) != RateLimitKeyIdentity) {
+			return nil, fmt.Errorf("%w: %s", errInvalidRateLimit, item)
+		}
+		requests, period, found := strings.Cut(budget, "/")
+		if !found {
+			return nil, fmt.Errorf("%w: %s", errInvalidRateLimit, item)
+		}
+		limit := RateLimit{}
+		var err error
+		if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests <= 0 {
+			return nil, fmt.Errorf("%w: %s", errInvalidRateLimit, item)
+		}
+		if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+			return nil, fmt.Errorf("%w: %s", errInvalidRateLimit, item)
+		}
+
+		if limits[RateLimitRoute(route)] == nil {
+			limits[RateLimitRoute(route)] = map[RateLimitKey]RateLimit{}
+		}
+		limits[RateLimitRoute(route)][RateLimitKey(key)] = limit
+	}
+	return limits, nil
+}
+
+// ParseTrustedProxies parses the comma-separated list of the IP addresses or CIDRs of the proxies trusted to set
+// the X-Forwarded-For header, e.g. `10.0.0.1,192.168.0.0/16`.
+func ParseTrustedProxies(spec string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %s", errInvalidTrustedProxy, item)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			item = fmt.Sprintf("%s/%d", item, bits)
+		}
+		_, cidr, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidTrustedProxy, item)
+		}
+		proxies = append(proxies, cidr)
+	}
+	return proxies, nil
+}
+
+// RateLimiter keeps the token buckets of the clients for all the configured rate limits.
+type RateLimiter struct {
+	limits RateLimits
+	// sessionManager is used to find the Kubernetes token of the requests authenticated by the session. It can be nil
+	// if the requests are not served within a session.
+	sessionManager *scs.SessionManager
+	// trustedProxies are the addresses of the proxies whose X-Forwarded-For header is used to determine the IP address
+	// of the client. The header of the requests coming from other addresses is ignored, because anyone can set it.
+	trustedProxies []*net.IPNet
+
+	lock      sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// now returns the current time. It can be overridden in the tests.
+	now func() time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	period   time.Duration
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a new rate limiter with the provided limits. The session manager and the trusted proxies are
+// optional.
+func NewRateLimiter(limits RateLimits, sessionManager *scs.SessionManager, trustedProxies []*net.IPNet) *RateLimiter {
+	return &RateLimiter{
+		limits:         limits,
+		sessionManager: sessionManager,
+		trustedProxies: trustedProxies,
+		buckets:        map[string]*bucket{},
+		now:            time.Now,
+	}
+}
+
+// RateLimitHandler is a Handler that rejects the requests exceeding the budgets of the rate limiter with
+// http.StatusTooManyRequests and the Retry-After header. The rejected requests are counted in the
+// HttpServiceThrottledRequestCountMetric registered in the prometheus.Registerer.
+func RateLimitHandler(reg prometheus.Registerer, limiter *RateLimiter, h http.Handler) http.Handler {
+	reg.MustRegister(HttpServiceThrottledRequestCountMetric)
+	if limiter == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, key, retryAfter := limiter.reserve(r)
+		if retryAfter > 0 {
+			HttpServiceThrottledRequestCountMetric.WithLabelValues(string(route), string(key)).Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			LogDebugAndWriteResponse(r.Context(), w, http.StatusTooManyRequests, "too many requests", "route", route, "key", key)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// reserve takes a token from each bucket of the request. If any of the buckets is empty, no token is taken and the
+// key of the exceeded limit is returned together with the time after which the request can be retried.
+func (l *RateLimiter) reserve(r *http.Request) (RateLimitRoute, RateLimitKey, time.Duration) {
+	route, ok := rateLimitRouteOf(r.URL.Path)
+	if !ok || len(l.limits[route]) == 0 {
+		return route, "", 0
+	}
+
+	clients := map[RateLimitKey]string{RateLimitKeyIp: l.clientIp(r)}
+	if token := l.requestToken(r); token != "" {
+		// only the hash of the token is kept in memory
+		hash := sha256.Sum256([]byte(token))
+		clients[RateLimitKeyIdentity] = hex.EncodeToString(hash[:])
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	var reservations []*rate.Reservation
+	for key, limit := range l.limits[route] {
+		client, ok := clients[key]
+		if !ok {
+			continue
+		}
+		reservation := l.bucketOf(string(route)+"/"+string(key)+"/"+client, limit, now).ReserveN(now, 1)
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			for _, other := range reservations {
+				other.CancelAt(now)
+			}
+			return route, key, delay
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return route, "", 0
+}
+
+func (l *RateLimiter) bucketOf(id string, limit RateLimit, now time.Time) *rate.Limiter {
+	b, ok := l.buckets[id]
+	if !ok {
+		b = &bucket{
+			limiter: rate.NewLimiter(rate.Every(limit.Period/time.Duration(limit.Requests)), limit.Requests),
+			period:  limit.Period,
+		}
+		l.buckets[id] = b
+	}
+	b.lastSeen = now
+	return b.limiter
+}
+
+// sweep removes the buckets that have not been used for longer than their period. Such buckets are full again, so
+// removing them doesn't change the behavior, it only keeps the memory bounded.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for id, b := range l.buckets {
+		if now.Sub(b.lastSeen) > b.period {
+			delete(l.buckets, id)
+		}
+	}
+}
+
+// clientIp returns the IP address of the client. If the request comes from a trusted proxy, the X-Forwarded-For header
+// is walked from the end, skipping the trusted proxies, and the first other address is the client. The addresses
+// before it could have been set by the client itself.
+func (l *RateLimiter) clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !l.isTrustedProxy(host) {
+		return host
+	}
+
+	addresses := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(addresses) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(addresses[i])
+		if address == "" {
+			break
+		}
+		host = address
+		if !l.isTrustedProxy(address) {
+			break
+		}
+	}
+	return host
+}
+
+func (l *RateLimiter) isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range l.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestToken returns the Kubernetes token the request is authenticated with, if any. Like Authenticator.GetToken,
+// the token is looked up in the session if the request doesn't provide it.
+func (l *RateLimiter) requestToken(r *http.Request) string {
+	if token := clientfactory.ExtractTokenFromAuthorizationHeader(r.Header.Get("Authorization")); token != "" {
+		return token
+	}
+	if token := r.URL.Query().Get("k8s_token"); token != "" {
+		return token
+	}
+	if l.sessionManager != nil {
+		return l.sessionManager.GetString(r.Context(), sessionK8sTokenKey)
+	}
+	return ""
+}
+
+func rateLimitRouteOf(path string) (RateLimitRoute, bool) {
+	switch {
+	case path == "/login" || strings.HasPrefix(path, "/login/"):
+		return RateLimitRouteLogin, true
+	case path == oauth.AuthenticateRoutePath || strings.HasSuffix(path, "/authenticate"):
+		return RateLimitRouteAuthenticate, true
+	case path == oauth.CallBackRoutePath || strings.HasSuffix(path, "/callback"):
+		return RateLimitRouteCallback, true
+	case strings.HasPrefix(path, "/token/"):
+		return RateLimitRouteUpload, true
+	case strings.HasPrefix(path, "/device/"):
+		return RateLimitRouteDevice, true
+	case strings.HasPrefix(path, "/api/"):
+		return RateLimitRouteApi, true
+	}
+	return "", false
+}
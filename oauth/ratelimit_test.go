@@ -0,0 +1,225 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexflint/go-arg"
+	"github.com/prometheus/client_golang/prometheus"
+	prometheusTest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redhat-appstudio/service-provider-integration-operator/cmd/oauth/oauthcli"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits("login.ip=20/1m, upload.identity=30/1h,upload.ip=5/10s,")
+	assert.NoError(t, err)
+	assert.Equal(t, RateLimits{
+		RateLimitRouteLogin:  {RateLimitKeyIp: {Requests: 20, Period: time.Minute}},
+		RateLimitRouteUpload: {RateLimitKeyIdentity: {Requests: 30, Period: time.Hour}, RateLimitKeyIp: {Requests: 5, Period: 10 * time.Second}},
+	}, limits)
+
+	for _, invalid := range []string{"login", "login=20/1m", "login.user=20/1m", "login.ip=20", "login.ip=0/1m", "login.ip=x/1m", "login.ip=20/x", "login.ip=20/-1m"} {
+		_, err := ParseRateLimits(invalid)
+		assert.ErrorIs(t, err, errInvalidRateLimit, invalid)
+	}
+}
+
+func TestDefaultRateLimitsCoverAllRoutes(t *testing.T) {
+	args := oauthcli.OAuthServiceCliArgs{}
+	p, err := arg.NewParser(arg.Config{}, &args)
+	assert.NoError(t, err)
+	assert.NoError(t, p.Parse([]string{}))
+
+	limits, err := ParseRateLimits(args.RateLimits)
+	assert.NoError(t, err)
+
+	for _, path := range []string{
+		"/login",
+		OIDCLoginRoutePath,
+		OIDCCallbackRoutePath,
+		oauth.AuthenticateRoutePath,
+		oauth.CallBackRoutePath,
+		"/token/default/token",
+		"/device/default/token",
+		"/api/v1/tokens/default/token",
+		"/api/v1/tokens/default/token/authorize",
+		"/api/v1/flows/state",
+	} {
+		route, ok := rateLimitRouteOf(path)
+		assert.True(t, ok, path)
+		assert.Contains(t, limits[route], RateLimitKeyIp, path)
+	}
+
+	for _, route := range []RateLimitRoute{RateLimitRouteAuthenticate, RateLimitRouteUpload, RateLimitRouteDevice, RateLimitRouteApi} {
+		assert.Contains(t, limits[route], RateLimitKeyIdentity, route)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.1, 192.168.0.0/16,fd00::1,")
+	assert.NoError(t, err)
+	assert.Len(t, proxies, 3)
+	assert.Equal(t, "10.0.0.1/32", proxies[0].String())
+	assert.Equal(t, "192.168.0.0/16", proxies[1].String())
+	assert.Equal(t, "fd00::1/128", proxies[2].String())
+
+	for _, invalid := range []string{"proxy", "10.0.0.1/33", "10.0.0"} {
+		_, err := ParseTrustedProxies(invalid)
+		assert.ErrorIs(t, err, errInvalidTrustedProxy, invalid)
+	}
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	newHandler := func(spec string, trustedProxies string) (http.Handler, *RateLimiter, prometheus.Gatherer) {
+		limits, err := ParseRateLimits(spec)
+		assert.NoError(t, err)
+		proxies, err := ParseTrustedProxies(trustedProxies)
+		assert.NoError(t, err)
+		limiter := NewRateLimiter(limits, nil, proxies)
+		now := time.Now()
+		limiter.now = func() time.Time { return now }
+
+		HttpServiceThrottledRequestCountMetric.Reset()
+		reg := prometheus.NewRegistry()
+		return RateLimitHandler(reg, limiter, http.HandlerFunc(OkHandler)), limiter, reg
+	}
+
+	request := func(h http.Handler, path string, ip string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":12345"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("per ip", func(t *testing.T) {
+		h, limiter, reg := newHandler("login.ip=2/1m", "")
+
+		assert.Equal(t, http.StatusOK, request(h, "/login", "10.0.0.1", "").Code)
+		assert.Equal(t, http.StatusOK, request(h, "/login/oidc", "10.0.0.1", "").Code)
+		res := request(h, "/login", "10.0.0.1", "")
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+		assert.Equal(t, "30", res.Header().Get("Retry-After"))
+
+		assert.Equal(t, http.StatusOK, request(h, "/login", "10.0.0.2", "").Code)
+		assert.Equal(t, http.StatusOK, request(h, "/oauth/callback", "10.0.0.1", "").Code)
+
+		now := limiter.now().Add(30 * time.Second)
+		limiter.now = func() time.Time { return now }
+		assert.Equal(t, http.StatusOK, request(h, "/login", "10.0.0.1", "").Code)
+
+		expected := `
+		# HELP redhat_appstudio_spi_oauth_service_throttled_requests_total The counts of the requests to OAuth service rejected by the rate limits categorized by the route and the key of the exceeded limit.
+		# TYPE redhat_appstudio_spi_oauth_service_throttled_requests_total counter
+		redhat_appstudio_spi_oauth_service_throttled_requests_total{key="ip",route="login"} 1
+`
+		assert.NoError(t, prometheusTest.GatherAndCompare(reg, strings.NewReader(expected), "redhat_appstudio_spi_oauth_service_throttled_requests_total"))
+	})
+
+	t.Run("per identity", func(t *testing.T) {
+		h, _, _ := newHandler("upload.identity=1/1m", "")
+
+		assert.Equal(t, http.StatusOK, request(h, "/token/ns/name", "10.0.0.1", "alice").Code)
+		assert.Equal(t, http.StatusTooManyRequests, request(h, "/token/ns/name", "10.0.0.2", "alice").Code)
+		assert.Equal(t, http.StatusOK, request(h, "/token/ns/name", "10.0.0.1", "bob").Code)
+		// the requests without the token are only limited by the ip
+		assert.Equal(t, http.StatusOK, request(h, "/token/ns/name", "10.0.0.1", "").Code)
+		assert.Equal(t, http.StatusOK, request(h, "/token/ns/name", "10.0.0.1", "").Code)
+	})
+
+	t.Run("rejected request doesn't consume the other budgets", func(t *testing.T) {
+		h, _, _ := newHandler("authenticate.ip=2/1m,authenticate.identity=1/1m", "")
+
+		assert.Equal(t, http.StatusOK, request(h, "/github/authenticate", "10.0.0.1", "alice").Code)
+		assert.Equal(t, http.StatusTooManyRequests, request(h, "/github/authenticate", "10.0.0.1", "alice").Code)
+		assert.Equal(t, http.StatusOK, request(h, "/github/authenticate", "10.0.0.1", "bob").Code)
+		assert.Equal(t, http.StatusTooManyRequests, request(h, "/github/authenticate", "10.0.0.1", "carol").Code)
+	})
+
+	t.Run("forwarded for", func(t *testing.T) {
+		h, _, _ := newHandler("login.ip=1/1m", "10.0.0.0/24,192.168.0.1")
+
+		forwarded := func(remoteIp string, forwardedFor string) int {
+			req := httptest.NewRequest("POST", "/login", nil)
+			req.RemoteAddr = remoteIp + ":12345"
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			return res.Code
+		}
+
+		assert.Equal(t, http.StatusOK, forwarded("10.0.0.1", "1.1.1.1"))
+		assert.Equal(t, http.StatusTooManyRequests, forwarded("10.0.0.2", "1.1.1.1"))
+		// the addresses set by the client before the last untrusted one are ignored
+		assert.Equal(t, http.StatusTooManyRequests, forwarded("10.0.0.1", "2.2.2.2, 1.1.1.1"))
+		// the chained trusted proxies are skipped
+		assert.Equal(t, http.StatusTooManyRequests, forwarded("10.0.0.1", "1.1.1.1, 192.168.0.1"))
+		assert.Equal(t, http.StatusOK, forwarded("10.0.0.1", "3.3.3.3, 192.168.0.1"))
+
+		// the header is ignored for the requests that don't come from a trusted proxy
+		assert.Equal(t, http.StatusOK, forwarded("172.16.0.1", "4.4.4.4"))
+		assert.Equal(t, http.StatusTooManyRequests, forwarded("172.16.0.1", "5.5.5.5"))
+	})
+
+	t.Run("unused buckets are removed", func(t *testing.T) {
+		h, limiter, _ := newHandler("login.ip=1/1m", "")
+
+		assert.Equal(t, http.StatusOK, request(h, "/login", "10.0.0.1", "").Code)
+		assert.Len(t, limiter.buckets, 1)
+
+		now := limiter.now().Add(2 * time.Minute)
+		limiter.now = func() time.Time { return now }
+		assert.Equal(t, http.StatusOK, request(h, "/login", "10.0.0.2", "").Code)
+		assert.Len(t, limiter.buckets, 1)
+	})
+
+	t.Run("identity from the session", func(t *testing.T) {
+		limits, err := ParseRateLimits("authenticate.identity=1/1m")
+		assert.NoError(t, err)
+		sessionManager := scs.New()
+		HttpServiceThrottledRequestCountMetric.Reset()
+		h := sessionManager.LoadAndSave(RateLimitHandler(prometheus.NewRegistry(), NewRateLimiter(limits, sessionManager, nil), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/login" {
+				sessionManager.Put(r.Context(), sessionK8sTokenKey, "alice")
+			}
+		})))
+
+		login := httptest.NewRecorder()
+		h.ServeHTTP(login, httptest.NewRequest("POST", "/login", nil))
+		cookie := login.Result().Cookies()[0]
+
+		authenticate := func(ip string) int {
+			req := httptest.NewRequest("GET", "/github/authenticate", nil)
+			req.RemoteAddr = ip + ":12345"
+			req.AddCookie(cookie)
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			return res.Code
+		}
+
+		assert.Equal(t, http.StatusOK, authenticate("10.0.0.1"))
+		assert.Equal(t, http.StatusTooManyRequests, authenticate("10.0.0.2"))
+	})
+}
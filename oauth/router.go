@@ -38,6 +38,16 @@ type Router struct {
 	controllers map[config.ServiceProviderName]Controller
 
 	stateStorage StateStorage
+
+	stateCodec *oauthstate.Codec
+
+	clientFactory kubernetesclient.K8sClientFactory
+
+	authenticator *Authenticator
+
+	pages *Pages
+
+	allowedRedirectAfterUrls []string
 }
 
 // CallbackRoute route for /oauth/callback requests
@@ -59,12 +69,19 @@ type RouterConfiguration struct {
 	InClusterK8sClient client.Client
 	TokenStorage       tokenstorage.TokenStorage
 	RedirectTemplate   *template.Template
+	Pages              *Pages
 }
 
 func NewRouter(ctx context.Context, cfg RouterConfiguration, spDefaults []config.ServiceProviderType) (*Router, error) {
 	router := &Router{
-		controllers:  map[config.ServiceProviderName]Controller{},
-		stateStorage: cfg.StateStorage,
+		controllers:   map[config.ServiceProviderName]Controller{},
+		stateStorage:  cfg.StateStorage,
+		stateCodec:    oauthstate.NewCodec(cfg.OAuthState),
+		clientFactory: cfg.ClientFactory,
+		authenticator: cfg.Authenticator,
+		pages:         cfg.Pages,
+
+		allowedRedirectAfterUrls: cfg.AllowedRedirectAfterUrls,
 	}
 
 	for _, sp := range spDefaults {
@@ -99,8 +116,9 @@ func (r *Router) findController(req *http.Request, veiled bool) (Controller, *oa
 		stateString = req.FormValue("state")
 	}
 
+	// the state is verified even when unveiled from the session, so that the flow cannot outlive the validity of its state
 	state := &oauthstate.OAuthInfo{}
-	err = oauthstate.ParseInto(stateString, state)
+	_, err = r.stateCodec.ParseInto(stateString, state)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to parse state string: %w", err)
 	}
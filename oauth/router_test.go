@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -238,6 +239,68 @@ func TestFindController(t *testing.T) {
 	})
 }
 
+func TestFindControllerSignedState(t *testing.T) {
+	oauthStateCfg := config.OAuthStateConfiguration{
+		Keys:     []config.OAuthStateKey{{Id: "new", Secret: []byte("the-new-key-which-is-long-enough-to-use")}, {Id: "old", Secret: []byte("the-old-key-which-is-long-enough-to-use")}},
+		Lifetime: time.Hour,
+	}
+	router, err := NewTestRouter(&SessionStateStorage{}, map[config.ServiceProviderName]Controller{
+		config.ServiceProviderTypeGitHub.Name: NopController{},
+	})
+	assert.NoError(t, err)
+	router.stateCodec = oauthstate.NewCodec(oauthStateCfg)
+
+	findWithState := func(stateString string) (*oauthstate.OAuthInfo, error) {
+		testReq, reqErr := http.NewRequest(http.MethodGet, "http://test", nil)
+		assert.NoError(t, reqErr)
+		testReq.Form = url.Values{"state": []string{stateString}}
+		_, state, err := router.findController(testReq, false)
+		return state, err
+	}
+
+	info := &oauthstate.OAuthInfo{TokenName: "token", TokenNamespace: "default", ServiceProviderName: config.ServiceProviderTypeGitHub.Name}
+
+	t.Run("accepts signed state", func(t *testing.T) {
+		stateString, err := oauthstate.NewCodec(oauthStateCfg).Encode(info)
+		assert.NoError(t, err)
+
+		state, err := findWithState(stateString)
+		assert.NoError(t, err)
+		assert.Equal(t, "token", state.TokenName)
+	})
+
+	t.Run("accepts state signed by a rotated key", func(t *testing.T) {
+		stateString, err := oauthstate.NewCodec(config.OAuthStateConfiguration{Keys: oauthStateCfg.Keys[1:]}).Encode(info)
+		assert.NoError(t, err)
+
+		_, err = findWithState(stateString)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects unsigned state", func(t *testing.T) {
+		stateString, err := oauthstate.Encode(info)
+		assert.NoError(t, err)
+
+		_, err = findWithState(stateString)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects tampered state", func(t *testing.T) {
+		stateString, err := oauthstate.NewCodec(oauthStateCfg).Encode(info)
+		assert.NoError(t, err)
+
+		tamperedInfo := *info
+		tamperedInfo.TokenNamespace = "other"
+		tampered, err := oauthstate.NewCodec(config.OAuthStateConfiguration{Keys: []config.OAuthStateKey{{Id: "new", Secret: []byte("an-attacker-key-which-is-long-enough-to-use")}}}).Encode(&tamperedInfo)
+		assert.NoError(t, err)
+		// keep the original signature
+		tampered = tampered[:strings.LastIndex(tampered, ".")] + stateString[strings.LastIndex(stateString, "."):]
+
+		_, err = findWithState(tampered)
+		assert.Error(t, err)
+	})
+}
+
 func TestCallbackRoute(t *testing.T) {
 
 	t.Run("OAuth flow metrics", func(t *testing.T) {
@@ -269,5 +332,6 @@ func NewTestRouter(stateStorage StateStorage, controllers map[config.ServiceProv
 	return &Router{
 		controllers:  controllers,
 		stateStorage: stateStorage,
+		stateCodec:   oauthstate.NewCodec(config.OAuthStateConfiguration{}),
 	}, nil
 }
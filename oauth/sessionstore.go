@@ -0,0 +1,259 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	corev1 "k8s.io/api/core/v1"
+	kuberrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// sessionSecretLabel marks the secrets holding the user sessions of the OAuth service.
+	sessionSecretLabel = "spi.appstudio.redhat.com/oauth-session"
+	// sessionExpiryAnnotation holds the unix time after which the session in the secret is expired.
+	sessionExpiryAnnotation = "spi.appstudio.redhat.com/oauth-session-expiry"
+	sessionSecretNamePrefix = "spi-oauth-session-"
+	sessionSecretDataKey    = "session"
+)
+
+var (
+	emptySessionKeyError      = errors.New("the key to encrypt the sessions must not be empty")
+	malformedSessionDataError = errors.New("the encrypted session data is malformed")
+)
+
+// KubernetesSessionStore is the session store that persists the sessions in short-lived Secrets in a single namespace.
+// Unlike the in-memory store, the sessions (and therefore the OAuth states veiled in them) are shared by all the
+// replicas of the OAuth service, so the OAuth callback can be handled by a different replica than the one that
+// initiated the OAuth flow. The sessions contain the Kubernetes tokens of the users, so they are encrypted before they
+// are stored in the Secrets.
+type KubernetesSessionStore struct {
+	Client    client.Client
+	Namespace string
+
+	aead cipher.AEAD
+}
+
+var (
+	_ scs.Store    = (*KubernetesSessionStore)(nil)
+	_ scs.CtxStore = (*KubernetesSessionStore)(nil)
+)
+
+// NewKubernetesSessionStore creates a new session store persisting the sessions in the given namespace. The sessions
+// are encrypted using AES-GCM with the key derived from the provided key material. If the cleanupInterval is greater
+// than zero, the expired sessions are deleted in that interval until the context is done.
+func NewKubernetesSessionStore(ctx context.Context, cl client.Client, namespace string, key []byte, cleanupInterval time.Duration) (*KubernetesSessionStore, error) {
+	if len(key) == 0 {
+		return nil, emptySessionKeyError
+	}
+
+	// the key material can be of any length, so the actual AES-256 key is derived from it
+	derivedKey := sha256.Sum256(key)
+	block, err := aes.NewCipher(derivedKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the session cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the session cipher: %w", err)
+	}
+
+	store := &KubernetesSessionStore{
+		Client:    cl,
+		Namespace: namespace,
+		aead:      aead,
+	}
+	if cleanupInterval > 0 {
+		go store.startCleanup(ctx, cleanupInterval)
+	}
+	return store, nil
+}
+
+func (s *KubernetesSessionStore) Find(token string) ([]byte, bool, error) {
+	return s.FindCtx(context.Background(), token)
+}
+
+func (s *KubernetesSessionStore) Commit(token string, b []byte, expiry time.Time) error {
+	return s.CommitCtx(context.Background(), token, b, expiry)
+}
+
+func (s *KubernetesSessionStore) Delete(token string) error {
+	return s.DeleteCtx(context.Background(), token)
+}
+
+// FindCtx implements scs.CtxStore. Expired sessions and the sessions that cannot be decrypted, e.g. because the key
+// has changed, are reported as not found.
+func (s *KubernetesSessionStore) FindCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	secretName := sessionSecretName(token)
+	secret := &corev1.Secret{}
+	if err := s.Client.Get(ctx, client.ObjectKey{Name: secretName, Namespace: s.Namespace}, secret); err != nil {
+		if kuberrors.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get the session secret: %w", err)
+	}
+
+	if sessionExpired(secret, time.Now()) {
+		return nil, false, nil
+	}
+
+	b, err := s.decrypt(secret.Data[sessionSecretDataKey], secretName)
+	if err != nil {
+		log.FromContext(ctx).V(logs.DebugLevel).Info("ignoring the session that cannot be decrypted", "secret", secretName, "error", err.Error())
+		return nil, false, nil
+	}
+
+	return b, true, nil
+}
+
+// CommitCtx implements scs.CtxStore.
+func (s *KubernetesSessionStore) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	secretName := sessionSecretName(token)
+	encrypted, err := s.encrypt(b, secretName)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{}
+	err = s.Client.Get(ctx, client.ObjectKey{Name: secretName, Namespace: s.Namespace}, secret)
+	if err != nil && !kuberrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get the session secret: %w", err)
+	}
+
+	if kuberrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: s.Namespace,
+				Labels: map[string]string{
+					sessionSecretLabel: "true",
+				},
+			},
+		}
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[sessionExpiryAnnotation] = strconv.FormatInt(expiry.Unix(), 10)
+	secret.Data = map[string][]byte{sessionSecretDataKey: encrypted}
+
+	if secret.ResourceVersion == "" {
+		err = s.Client.Create(ctx, secret)
+	} else {
+		err = s.Client.Update(ctx, secret)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to persist the session secret: %w", err)
+	}
+	return nil
+}
+
+// DeleteCtx implements scs.CtxStore.
+func (s *KubernetesSessionStore) DeleteCtx(ctx context.Context, token string) error {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: sessionSecretName(token), Namespace: s.Namespace}}
+	if err := s.Client.Delete(ctx, secret); err != nil && !kuberrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the session secret: %w", err)
+	}
+	return nil
+}
+
+// deleteExpired deletes all the session secrets that expired before the provided time.
+func (s *KubernetesSessionStore) deleteExpired(ctx context.Context, now time.Time) error {
+	secrets := &corev1.SecretList{}
+	if err := s.Client.List(ctx, secrets, client.InNamespace(s.Namespace), client.MatchingLabels{sessionSecretLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list the session secrets: %w", err)
+	}
+
+	for i := range secrets.Items {
+		if !sessionExpired(&secrets.Items[i], now) {
+			continue
+		}
+		if err := s.Client.Delete(ctx, &secrets.Items[i]); err != nil && !kuberrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete the expired session secret %s: %w", secrets.Items[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *KubernetesSessionStore) startCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.deleteExpired(ctx, time.Now()); err != nil {
+				log.FromContext(ctx).Error(err, "failed to clean up the expired sessions")
+			} else {
+				log.FromContext(ctx).V(logs.DebugLevel).Info("expired sessions cleaned up")
+			}
+		}
+	}
+}
+
+// encrypt encrypts the session data. The name of the secret is authenticated along with the data so that the encrypted
+// data of one session cannot be copied to the secret of another session.
+func (s *KubernetesSessionStore) encrypt(b []byte, secretName string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate the nonce to encrypt the session: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, b, []byte(secretName)), nil
+}
+
+// decrypt decrypts the session data encrypted by encrypt.
+func (s *KubernetesSessionStore) decrypt(b []byte, secretName string) ([]byte, error) {
+	if len(b) < s.aead.NonceSize() {
+		return nil, malformedSessionDataError
+	}
+	nonce, ciphertext := b[:s.aead.NonceSize()], b[s.aead.NonceSize():]
+	data, err := s.aead.Open(nil, nonce, ciphertext, []byte(secretName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the session: %w", err)
+	}
+	return data, nil
+}
+
+// sessionSecretName derives the name of the secret from the session token. The token is hashed so that it is not
+// exposed to anyone who can list the secrets and so that the name is always a valid object name.
+func sessionSecretName(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return sessionSecretNamePrefix + hex.EncodeToString(hash[:])
+}
+
+func sessionExpired(secret *corev1.Secret, now time.Time) bool {
+	expiry, err := strconv.ParseInt(secret.Annotations[sessionExpiryAnnotation], 10, 64)
+	if err != nil {
+		return true
+	}
+	return now.After(time.Unix(expiry, 0))
+}
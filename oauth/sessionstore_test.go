@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestKubernetesSessionStore() *KubernetesSessionStore {
+	sch := runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(sch))
+	store, err := NewKubernetesSessionStore(context.TODO(), fake.NewClientBuilder().WithScheme(sch).Build(), "spi-system", []byte("key"), 0)
+	utilruntime.Must(err)
+	return store
+}
+
+func TestKubernetesSessionStore(t *testing.T) {
+	store := newTestKubernetesSessionStore()
+
+	t.Run("missing session not found", func(t *testing.T) {
+		_, found, err := store.Find("token")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("commit and find", func(t *testing.T) {
+		assert.NoError(t, store.Commit("token", []byte("data"), time.Now().Add(time.Minute)))
+		data, found, err := store.Find("token")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []byte("data"), data)
+
+		assert.NoError(t, store.Commit("token", []byte("updated"), time.Now().Add(time.Minute)))
+		data, found, err = store.Find("token")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []byte("updated"), data)
+
+		secret := &corev1.Secret{}
+		assert.NoError(t, store.Client.Get(context.TODO(), client.ObjectKey{Name: sessionSecretName("token"), Namespace: "spi-system"}, secret))
+		assert.Equal(t, "true", secret.Labels[sessionSecretLabel])
+		assert.NotContains(t, secret.Name, "token")
+	})
+
+	t.Run("session is encrypted", func(t *testing.T) {
+		assert.NoError(t, store.Commit("encrypted", []byte("k8s-token"), time.Now().Add(time.Minute)))
+
+		secret := &corev1.Secret{}
+		assert.NoError(t, store.Client.Get(context.TODO(), client.ObjectKey{Name: sessionSecretName("encrypted"), Namespace: "spi-system"}, secret))
+		assert.NotContains(t, string(secret.Data[sessionSecretDataKey]), "k8s-token")
+	})
+
+	t.Run("session copied to another secret not found", func(t *testing.T) {
+		assert.NoError(t, store.Commit("original", []byte("data"), time.Now().Add(time.Minute)))
+		assert.NoError(t, store.Commit("copy", []byte("other"), time.Now().Add(time.Minute)))
+
+		original := &corev1.Secret{}
+		assert.NoError(t, store.Client.Get(context.TODO(), client.ObjectKey{Name: sessionSecretName("original"), Namespace: "spi-system"}, original))
+		copied := &corev1.Secret{}
+		assert.NoError(t, store.Client.Get(context.TODO(), client.ObjectKey{Name: sessionSecretName("copy"), Namespace: "spi-system"}, copied))
+		copied.Data = original.Data
+		assert.NoError(t, store.Client.Update(context.TODO(), copied))
+
+		_, found, err := store.Find("copy")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("session encrypted with another key not found", func(t *testing.T) {
+		assert.NoError(t, store.Commit("rotated", []byte("data"), time.Now().Add(time.Minute)))
+		rotated, err := NewKubernetesSessionStore(context.TODO(), store.Client, "spi-system", []byte("new-key"), 0)
+		assert.NoError(t, err)
+
+		_, found, err := rotated.Find("rotated")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("expired session not found", func(t *testing.T) {
+		assert.NoError(t, store.Commit("expired", []byte("data"), time.Now().Add(-time.Minute)))
+		_, found, err := store.Find("expired")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, store.Commit("deleted", []byte("data"), time.Now().Add(time.Minute)))
+		assert.NoError(t, store.Delete("deleted"))
+		_, found, err := store.Find("deleted")
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		assert.NoError(t, store.Delete("deleted"))
+	})
+}
+
+func TestKubernetesSessionStoreRequiresKey(t *testing.T) {
+	_, err := NewKubernetesSessionStore(context.TODO(), nil, "spi-system", nil, 0)
+	assert.ErrorIs(t, err, emptySessionKeyError)
+}
+
+func TestKubernetesSessionStoreDeleteExpired(t *testing.T) {
+	store := newTestKubernetesSessionStore()
+	assert.NoError(t, store.Commit("valid", []byte("data"), time.Now().Add(time.Minute)))
+	assert.NoError(t, store.Commit("expired", []byte("data"), time.Now().Add(-time.Minute)))
+	assert.NoError(t, store.Client.Create(context.TODO(), &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "spi-system"}}))
+
+	assert.NoError(t, store.deleteExpired(context.TODO(), time.Now()))
+
+	secrets := &corev1.SecretList{}
+	assert.NoError(t, store.Client.List(context.TODO(), secrets))
+	assert.Len(t, secrets.Items, 2)
+	names := []string{secrets.Items[0].Name, secrets.Items[1].Name}
+	assert.Contains(t, names, sessionSecretName("valid"))
+	assert.Contains(t, names, "unrelated")
+}
+
+func TestKubernetesSessionStoreSharedByReplicas(t *testing.T) {
+	store := newTestKubernetesSessionStore()
+	newReplica := func() (*scs.SessionManager, StateStorage) {
+		sessionManager := scs.New()
+		sessionManager.Store = store
+		return sessionManager, NewStateStorage(sessionManager)
+	}
+
+	// the state is veiled by one replica...
+	sessionManager1, storage1 := newReplica()
+	var veiledState string
+	res := httptest.NewRecorder()
+	sessionManager1.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		veiledState, err = storage1.VeilRealState(r)
+		assert.NoError(t, err)
+	})).ServeHTTP(res, httptest.NewRequest("GET", "/?state=spi-state", nil))
+
+	// ... and unveiled by another one
+	sessionManager2, storage2 := newReplica()
+	req := httptest.NewRequest("GET", fmt.Sprintf("/?state=%s", veiledState), nil)
+	req.AddCookie(res.Result().Cookies()[0])
+	sessionManager2.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, err := storage2.UnveilState(r.Context(), r)
+		assert.NoError(t, err)
+		assert.Equal(t, "spi-state", state)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+}
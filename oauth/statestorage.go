@@ -32,11 +32,34 @@ type StateStorage interface {
 	// VeilRealState returns the random string that can be used as OAuth state.
 	// Suppose to be reused to restore the original SPI's state on OAuth callback.
 	VeilRealState(req *http.Request) (string, error)
+	// VeilState is the same as VeilRealState but takes the SPI's state directly instead of from the request.
+	VeilState(ctx context.Context, state string) (string, error)
 	// UnveilState recover original SPI's state from OAuth callback request.
 	UnveilState(ctx context.Context, req *http.Request) (string, error)
 	// StateVeiledAt informs when the state was veiled.
 	StateVeiledAt(ctx context.Context, req *http.Request) (time.Time, error)
+	// StorePkceVerifier stores the PKCE code verifier of the OAuth flow next to the veiled state.
+	StorePkceVerifier(ctx context.Context, veiledState string, verifier string)
+	// PkceVerifier recovers the PKCE code verifier from OAuth callback request. The returned verifier is empty if
+	// the OAuth flow doesn't use PKCE.
+	PkceVerifier(ctx context.Context, req *http.Request) (string, error)
+	// StoreFlowStatus records the progress of the OAuth flow identified by the veiled state. Nothing is recorded
+	// if the veiled state is not known.
+	StoreFlowStatus(ctx context.Context, veiledState string, status FlowStatus)
+	// FlowStatus returns the progress of the OAuth flow identified by the veiled state. The returned status is empty
+	// if the veiled state is not known.
+	FlowStatus(ctx context.Context, veiledState string) FlowStatus
 }
+
+// FlowStatus describes the progress of an OAuth flow.
+type FlowStatus string
+
+const (
+	FlowStatusPending   FlowStatus = "Pending"
+	FlowStatusCompleted FlowStatus = "Completed"
+	FlowStatusFailed    FlowStatus = "Failed"
+)
+
 type SessionStateStorage struct {
 	sessionManager *scs.SessionManager
 }
@@ -53,8 +76,11 @@ const (
 )
 
 func (s *SessionStateStorage) VeilRealState(req *http.Request) (string, error) {
-	log := log.FromContext(req.Context())
-	state := req.URL.Query().Get("state")
+	return s.VeilState(req.Context(), req.URL.Query().Get("state"))
+}
+
+func (s *SessionStateStorage) VeilState(ctx context.Context, state string) (string, error) {
+	log := log.FromContext(ctx)
 	if state == "" {
 		log.Error(noStateError, "Request has no state parameter")
 		return "", noStateError
@@ -65,8 +91,9 @@ func (s *SessionStateStorage) VeilRealState(req *http.Request) (string, error) {
 		return "", err
 	}
 	log.V(logs.DebugLevel).Info("State veiled", "state", state, "veil", newState)
-	s.sessionManager.Put(req.Context(), newState, state)
-	s.sessionManager.Put(req.Context(), newState+"-createdAt", time.Now().Unix())
+	s.sessionManager.Put(ctx, newState, state)
+	s.sessionManager.Put(ctx, newState+"-createdAt", time.Now().Unix())
+	s.sessionManager.Put(ctx, newState+"-flowStatus", string(FlowStatusPending))
 	return newState, nil
 }
 
@@ -98,6 +125,34 @@ func (s *SessionStateStorage) StateVeiledAt(ctx context.Context, req *http.Reque
 	return time.Unix(createdAt, 0), nil
 }
 
+func (s *SessionStateStorage) StorePkceVerifier(ctx context.Context, veiledState string, verifier string) {
+	s.sessionManager.Put(ctx, veiledState+"-pkceVerifier", verifier)
+}
+
+func (s *SessionStateStorage) PkceVerifier(ctx context.Context, req *http.Request) (string, error) {
+	log := log.FromContext(ctx)
+	state := req.URL.Query().Get("state")
+	if state == "" {
+		log.Error(noStateError, "Request has no state parameter")
+		return "", noStateError
+	}
+	return s.sessionManager.GetString(ctx, state+"-pkceVerifier"), nil
+}
+
+func (s *SessionStateStorage) StoreFlowStatus(ctx context.Context, veiledState string, status FlowStatus) {
+	if veiledState == "" || !s.sessionManager.Exists(ctx, veiledState) {
+		return
+	}
+	s.sessionManager.Put(ctx, veiledState+"-flowStatus", string(status))
+}
+
+func (s *SessionStateStorage) FlowStatus(ctx context.Context, veiledState string) FlowStatus {
+	if veiledState == "" {
+		return ""
+	}
+	return FlowStatus(s.sessionManager.GetString(ctx, veiledState+"-flowStatus"))
+}
+
 func randStringBytes(n int) (string, error) {
 	b := make([]byte, n)
 	for i := range b {
@@ -123,10 +123,33 @@ func Test_FailToUnveilStateIfStateIsEmpty(t *testing.T) {
 
 }
 
+func Test_FlowStatusOfVeiledState(t *testing.T) {
+	//given
+	res := httptest.NewRecorder()
+	sessionManager := scs.New()
+	storage := NewStateStorage(sessionManager)
+
+	//when
+	sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		veiledState, err := storage.VeilState(r.Context(), "statestr")
+		assert.NoError(t, err)
+
+		//then
+		assert.Equal(t, FlowStatusPending, storage.FlowStatus(r.Context(), veiledState))
+
+		storage.StoreFlowStatus(r.Context(), veiledState, FlowStatusCompleted)
+		assert.Equal(t, FlowStatusCompleted, storage.FlowStatus(r.Context(), veiledState))
+
+		storage.StoreFlowStatus(r.Context(), "unknown", FlowStatusCompleted)
+		assert.Empty(t, storage.FlowStatus(r.Context(), "unknown"))
+	})).ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+}
+
 type SimpleStateStorage struct {
-	state     string
-	vailState string
-	vailAt    time.Time
+	state        string
+	vailState    string
+	vailAt       time.Time
+	pkceVerifier string
 }
 
 var _ StateStorage = (*SimpleStateStorage)(nil)
@@ -135,6 +158,10 @@ func (n SimpleStateStorage) VeilRealState(req *http.Request) (string, error) {
 	return n.vailState, nil
 }
 
+func (n SimpleStateStorage) VeilState(ctx context.Context, state string) (string, error) {
+	return n.vailState, nil
+}
+
 func (n SimpleStateStorage) UnveilState(ctx context.Context, req *http.Request) (string, error) {
 	return n.state, nil
 }
@@ -142,3 +169,17 @@ func (n SimpleStateStorage) UnveilState(ctx context.Context, req *http.Request)
 func (n SimpleStateStorage) StateVeiledAt(ctx context.Context, req *http.Request) (time.Time, error) {
 	return n.vailAt, nil
 }
+
+func (n SimpleStateStorage) StorePkceVerifier(ctx context.Context, veiledState string, verifier string) {
+}
+
+func (n SimpleStateStorage) PkceVerifier(ctx context.Context, req *http.Request) (string, error) {
+	return n.pkceVerifier, nil
+}
+
+func (n SimpleStateStorage) StoreFlowStatus(ctx context.Context, veiledState string, status FlowStatus) {
+}
+
+func (n SimpleStateStorage) FlowStatus(ctx context.Context, veiledState string) FlowStatus {
+	return ""
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redhat-appstudio/remote-secret/pkg/kubernetesclient"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	authv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var invalidK8sTokenError = errors.New("the kubernetes token is not valid")
+
+// TokenReviewer verifies the kubernetes tokens of the users.
+type TokenReviewer interface {
+	// Review returns the name of the user authenticated by the provided token. If the token is not valid, an error
+	// wrapping invalidK8sTokenError is returned.
+	Review(ctx context.Context, token string) (string, error)
+}
+
+// KubernetesTokenReviewer is the TokenReviewer using the TokenReview API of the cluster. The ClientFactory is expected
+// to create the clients authenticated as the service account of the OAuth service, so that the reviews don't depend
+// on the reviewed token itself. The successful reviews are cached for CacheTtl.
+type KubernetesTokenReviewer struct {
+	ClientFactory kubernetesclient.K8sClientFactory
+	CacheTtl      time.Duration
+
+	lock  sync.Mutex
+	cache map[string]cachedTokenReview
+}
+
+type cachedTokenReview struct {
+	username  string
+	expiresAt time.Time
+}
+
+var _ TokenReviewer = (*KubernetesTokenReviewer)(nil)
+
+func NewKubernetesTokenReviewer(clientFactory kubernetesclient.K8sClientFactory, cacheTtl time.Duration) *KubernetesTokenReviewer {
+	return &KubernetesTokenReviewer{
+		ClientFactory: clientFactory,
+		CacheTtl:      cacheTtl,
+		cache:         map[string]cachedTokenReview{},
+	}
+}
+
+func (r *KubernetesTokenReviewer) Review(ctx context.Context, token string) (string, error) {
+	lg := log.FromContext(ctx)
+	key := tokenCacheKey(token)
+
+	if username, ok := r.cached(key); ok {
+		lg.V(logs.DebugLevel).Info("using the cached token review", "username", username)
+		return username, nil
+	}
+
+	cl, err := r.ClientFactory.CreateClient(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the client for the token review: %w", err)
+	}
+
+	review := &authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{
+			Token: token,
+		},
+	}
+	if err = cl.Create(ctx, review); err != nil {
+		return "", fmt.Errorf("failed to create the token review: %w", err)
+	}
+
+	if !review.Status.Authenticated {
+		if review.Status.Error != "" {
+			return "", fmt.Errorf("%w: %s", invalidK8sTokenError, review.Status.Error)
+		}
+		return "", invalidK8sTokenError
+	}
+
+	username := review.Status.User.Username
+	lg.V(logs.DebugLevel).Info("token review successful", "username", username)
+	r.store(key, username)
+
+	return username, nil
+}
+
+func (r *KubernetesTokenReviewer) cached(key string) (string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	review, ok := r.cache[key]
+	if !ok || time.Now().After(review.expiresAt) {
+		return "", false
+	}
+	return review.username, true
+}
+
+func (r *KubernetesTokenReviewer) store(key string, username string) {
+	if r.CacheTtl <= 0 {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.cache == nil {
+		r.cache = map[string]cachedTokenReview{}
+	}
+
+	// evict the expired reviews so that the cache doesn't grow indefinitely
+	now := time.Now()
+	for k, v := range r.cache {
+		if now.After(v.expiresAt) {
+			delete(r.cache, k)
+		}
+	}
+
+	r.cache[key] = cachedTokenReview{username: username, expiresAt: now.Add(r.CacheTtl)}
+}
+
+// tokenCacheKey hashes the token so that the tokens themselves are not kept in the memory longer than necessary.
+func tokenCacheKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
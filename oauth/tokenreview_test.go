@@ -0,0 +1,175 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/redhat-appstudio/remote-secret/pkg/kubernetesclient"
+	"github.com/stretchr/testify/assert"
+	authv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// tokenReviewingClient is a client that authenticates the tokens from the validTokens map in the TokenReviews.
+type tokenReviewingClient struct {
+	client.Client
+	validTokens map[string]string
+	reviews     int
+}
+
+func (c *tokenReviewingClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	review, ok := obj.(*authv1.TokenReview)
+	if !ok {
+		return errors.New("unexpected object")
+	}
+	c.reviews++
+	if username, ok := c.validTokens[review.Spec.Token]; ok {
+		review.Status.Authenticated = true
+		review.Status.User.Username = username
+	} else {
+		review.Status.Error = "invalid bearer token"
+	}
+	return nil
+}
+
+func TestKubernetesTokenReviewer(t *testing.T) {
+	cl := &tokenReviewingClient{validTokens: map[string]string{"valid": "alice"}}
+	reviewer := NewKubernetesTokenReviewer(kubernetesclient.SingleInstanceClientFactory{Client: cl}, time.Minute)
+
+	t.Run("valid token", func(t *testing.T) {
+		username, err := reviewer.Review(context.TODO(), "valid")
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", username)
+	})
+
+	t.Run("positive review is cached", func(t *testing.T) {
+		reviews := cl.reviews
+		username, err := reviewer.Review(context.TODO(), "valid")
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", username)
+		assert.Equal(t, reviews, cl.reviews)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := reviewer.Review(context.TODO(), "invalid")
+		assert.ErrorIs(t, err, invalidK8sTokenError)
+		assert.ErrorContains(t, err, "invalid bearer token")
+
+		reviews := cl.reviews
+		_, err = reviewer.Review(context.TODO(), "invalid")
+		assert.ErrorIs(t, err, invalidK8sTokenError)
+		assert.Equal(t, reviews+1, cl.reviews)
+	})
+
+	t.Run("expired cache entries are reviewed again", func(t *testing.T) {
+		reviewer.cache[tokenCacheKey("valid")] = cachedTokenReview{username: "alice", expiresAt: time.Now().Add(-time.Second)}
+		reviews := cl.reviews
+		_, err := reviewer.Review(context.TODO(), "valid")
+		assert.NoError(t, err)
+		assert.Equal(t, reviews+1, cl.reviews)
+	})
+}
+
+func TestLoginReviewsToken(t *testing.T) {
+	cl := &tokenReviewingClient{validTokens: map[string]string{"valid": "alice"}}
+	sessionManager := scs.New()
+	authenticator := NewAuthenticator(sessionManager, nil, NewKubernetesTokenReviewer(kubernetesclient.SingleInstanceClientFactory{Client: cl}, time.Minute))
+
+	login := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/login", strings.NewReader("k8s_token="+token))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res := httptest.NewRecorder()
+		sessionManager.LoadAndSave(http.HandlerFunc(authenticator.Login)).ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("invalid token is rejected", func(t *testing.T) {
+		res := login("invalid")
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		assert.Empty(t, res.Result().Cookies())
+	})
+
+	t.Run("valid token is stored with the username", func(t *testing.T) {
+		res := login("valid")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Len(t, res.Result().Cookies(), 1)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(res.Result().Cookies()[0])
+		sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "valid", sessionManager.GetString(r.Context(), sessionK8sTokenKey))
+			assert.Equal(t, "alice", sessionManager.GetString(r.Context(), sessionK8sUsernameKey))
+		})).ServeHTTP(httptest.NewRecorder(), req)
+	})
+}
+
+func TestLoginFailedReviewIsServerError(t *testing.T) {
+	sessionManager := scs.New()
+	authenticator := NewAuthenticator(sessionManager, nil, acceptingTokenReviewer{failWith: errors.New("connection refused")})
+
+	req := httptest.NewRequest("POST", "/login", strings.NewReader("k8s_token=valid"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res := httptest.NewRecorder()
+	sessionManager.LoadAndSave(http.HandlerFunc(authenticator.Login)).ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+}
+
+func TestGetTokenReviewsQueryParameter(t *testing.T) {
+	cl := &tokenReviewingClient{validTokens: map[string]string{"valid": "alice"}}
+	sessionManager := scs.New()
+	authenticator := NewAuthenticator(sessionManager, nil, NewKubernetesTokenReviewer(kubernetesclient.SingleInstanceClientFactory{Client: cl}, time.Minute))
+
+	getToken := func(token string, check func(r *http.Request, token string, err error)) {
+		sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := authenticator.GetToken(r.Context(), r)
+			check(r, token, err)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?k8s_token="+token, nil))
+	}
+
+	t.Run("invalid token is not stored", func(t *testing.T) {
+		getToken("invalid", func(r *http.Request, token string, err error) {
+			assert.ErrorIs(t, err, invalidK8sTokenError)
+			assert.True(t, isUnauthenticated(err))
+			assert.Empty(t, token)
+			assert.Empty(t, sessionManager.GetString(r.Context(), sessionK8sTokenKey))
+		})
+	})
+
+	t.Run("valid token is stored with the username", func(t *testing.T) {
+		getToken("valid", func(r *http.Request, token string, err error) {
+			assert.NoError(t, err)
+			assert.Equal(t, "valid", token)
+			assert.Equal(t, "valid", sessionManager.GetString(r.Context(), sessionK8sTokenKey))
+			assert.Equal(t, "alice", sessionManager.GetString(r.Context(), sessionK8sUsernameKey))
+		})
+	})
+
+	t.Run("failed review is not an authentication failure", func(t *testing.T) {
+		failing := NewAuthenticator(sessionManager, nil, acceptingTokenReviewer{failWith: errors.New("connection refused")})
+		sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := failing.GetToken(r.Context(), r)
+			assert.Error(t, err)
+			assert.False(t, isUnauthenticated(err))
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?k8s_token=valid", nil))
+	})
+}
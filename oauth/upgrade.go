@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
+	"golang.org/x/oauth2"
+)
+
+var (
+	errUpgradeUserMismatch = errors.New("the upgraded token belongs to a different service provider user than the upgraded SPIAccessToken")
+	errUpgradeNotSupported = errors.New("the service provider doesn't support the verification of the user of the upgraded token")
+	errUserIdRequestFailed = errors.New("failed to determine the service provider user of the upgraded token")
+	errUserIdNotInResponse = errors.New("the service provider didn't return the id of the user")
+)
+
+// userEndpoint returns the URL of the API endpoint of the service provider that describes the user the token belongs to.
+// An empty string is returned for the service providers that don't expose the numeric id of the user.
+func userEndpoint(spName config.ServiceProviderName, spBaseUrl string) string {
+	spBaseUrl = strings.TrimSuffix(spBaseUrl, "/")
+	switch spName {
+	case config.ServiceProviderTypeGitHub.Name:
+		if spBaseUrl == config.ServiceProviderTypeGitHub.DefaultBaseUrl {
+			return "https://api.github.com/user"
+		}
+		return spBaseUrl + "/api/v3/user"
+	case config.ServiceProviderTypeGitLab.Name:
+		return spBaseUrl + "/api/v4/user"
+	}
+	return ""
+}
+
+// verifyUpgradedUser checks that the token obtained in the flow upgrading an existing token belongs to the service
+// provider user who authorized the upgraded token. This prevents the upgrade from changing the identity of the token
+// under the other bindings linked to it.
+func verifyUpgradedUser(ctx context.Context, oauthCfg *oauth2.Config, token *oauth2.Token, state *oauthstate.OAuthInfo) error {
+	endpoint := userEndpoint(state.ServiceProviderName, state.ServiceProviderUrl)
+	if endpoint == "" {
+		return fmt.Errorf("%w: %s", errUpgradeNotSupported, state.ServiceProviderName)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to construct the user request: %w", err)
+	}
+	resp, err := oauthCfg.Client(ctx, token).Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUserIdRequestFailed, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: unexpected status code %d", errUserIdRequestFailed, resp.StatusCode)
+	}
+
+	user := struct {
+		Id json.Number `json:"id"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return fmt.Errorf("failed to decode the user of the upgraded token: %w", err)
+	}
+	if user.Id == "" {
+		return errUserIdNotInResponse
+	}
+
+	if user.Id.String() != state.ExpectedUserId {
+		return fmt.Errorf("%w: expected user id %s, got %s", errUpgradeUserMismatch, state.ExpectedUserId, user.Id)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/oauthstate"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestUserEndpoint(t *testing.T) {
+	assert.Equal(t, "https://api.github.com/user", userEndpoint(config.ServiceProviderTypeGitHub.Name, "https://github.com"))
+	assert.Equal(t, "https://github.acme.com/api/v3/user", userEndpoint(config.ServiceProviderTypeGitHub.Name, "https://github.acme.com/"))
+	assert.Equal(t, "https://gitlab.com/api/v4/user", userEndpoint(config.ServiceProviderTypeGitLab.Name, "https://gitlab.com"))
+	assert.Empty(t, userEndpoint(config.ServiceProviderTypeQuay.Name, "https://quay.io"))
+}
+
+func TestVerifyUpgradedUser(t *testing.T) {
+	sp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/user", r.URL.Path)
+		switch r.Header.Get("Authorization") {
+		case "Bearer alice":
+			_, _ = w.Write([]byte(`{"id": 42, "username": "alice"}`))
+		case "Bearer bob":
+			_, _ = w.Write([]byte(`{"id": 43, "username": "bob"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer sp.Close()
+
+	state := &oauthstate.OAuthInfo{
+		ServiceProviderName: config.ServiceProviderTypeGitLab.Name,
+		ServiceProviderUrl:  sp.URL,
+		ExpectedUserId:      "42",
+	}
+
+	verify := func(accessToken string, state *oauthstate.OAuthInfo) error {
+		return verifyUpgradedUser(context.TODO(), &oauth2.Config{}, &oauth2.Token{AccessToken: accessToken, TokenType: "Bearer"}, state)
+	}
+
+	t.Run("same user", func(t *testing.T) {
+		assert.NoError(t, verify("alice", state))
+	})
+
+	t.Run("different user", func(t *testing.T) {
+		assert.ErrorIs(t, verify("bob", state), errUpgradeUserMismatch)
+	})
+
+	t.Run("failed request", func(t *testing.T) {
+		assert.ErrorIs(t, verify("invalid", state), errUserIdRequestFailed)
+	})
+
+	t.Run("unsupported service provider", func(t *testing.T) {
+		quayState := *state
+		quayState.ServiceProviderName = config.ServiceProviderTypeQuay.Name
+		assert.ErrorIs(t, verify("alice", &quayState), errUpgradeNotSupported)
+	})
+}
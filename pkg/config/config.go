@@ -17,6 +17,7 @@ package config
 import (
 	"time"
 
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
 )
 
@@ -49,12 +50,25 @@ type OperatorConfiguration struct {
 	// The policy to match the token against the binding
 	TokenMatchPolicy TokenPolicy
 
+	// The default strategy to choose the token for a binding if several tokens match it
+	TokenSelectionStrategy api.TokenSelectionStrategy
+
 	// The time before a token without data and with no bindings is automatically deleted.
 	DeletionGracePeriod time.Duration
 
 	// A maximum file size for file downloading from SCM capabilities supporting providers
 	MaxFileDownloadSize int
 
+	// A maximum file size for file downloading into the ConfigMap or Secret targets. If not positive, MaxFileDownloadSize
+	// is used for them, too.
+	MaxTargetFileDownloadSize int
+
+	// Determine the last modification time of the downloaded files where it costs additional API calls
+	LookupFileLastModified bool
+
 	// Enable Token Upload controller
 	EnableTokenUpload bool
+
+	// Enable the defaulting and validating webhooks of the SPI CRDs
+	EnableWebhooks bool
 }
@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprovider
+
+import (
+	"context"
+	"fmt"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// BulkAccessCheckCapability is an optional extension of the ServiceProvider for the providers that can check the access
+// to many repositories using fewer API calls than checking the repositories one by one.
+type BulkAccessCheckCapability interface {
+	// CheckRepositoriesAccess checks the access to the repositories of all the provided access checks. The returned
+	// slice contains the status of each of the access checks in the same order.
+	CheckRepositoriesAccess(ctx context.Context, cl client.Client, accessChecks []*api.SPIAccessCheck) ([]*api.SPIAccessCheckStatus, error)
+}
+
+// CredentialsGroup is a group of access checks that can be evaluated using the same credentials.
+type CredentialsGroup struct {
+	Credentials Credentials
+	// Indices are the indices of the access checks in the group.
+	Indices []int
+}
+
+// GroupByCredentials looks up the credentials for each of the access checks and groups the checks by the object
+// the credentials come from. The indices of the access checks for which no credentials were found or the lookup failed
+// are returned separately. These should be checked one by one so that they're checked anonymously or the lookup error
+// is reported in the same way as in a single access check.
+func GroupByCredentials(ctx context.Context, cl client.Client, lookup func(context.Context, client.Client, Matchable) (*Credentials, error), accessChecks []*api.SPIAccessCheck) ([]CredentialsGroup, []int) {
+	lg := log.FromContext(ctx)
+
+	groups := []CredentialsGroup{}
+	groupIndex := map[string]int{}
+	var ungrouped []int
+
+	for i, ac := range accessChecks {
+		credentials, err := lookup(ctx, cl, ac)
+		if err != nil {
+			lg.Error(err, "failed to look up the credentials for the repository in the bulk access check", "repository", ac.Spec.RepoUrl)
+		}
+		if err != nil || credentials == nil || credentials.Source == nil {
+			ungrouped = append(ungrouped, i)
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s", credentials.Source.Kind, credentials.Source.Name)
+		gi, ok := groupIndex[key]
+		if !ok {
+			gi = len(groups)
+			groupIndex[key] = gi
+			groups = append(groups, CredentialsGroup{Credentials: *credentials})
+		}
+		groups[gi].Indices = append(groups[gi].Indices, i)
+	}
+
+	return groups, ungrouped
+}
+
+// CheckRepositoriesAccessOneByOne checks the access to the repositories of the access checks on the provided indices
+// one by one and stores the results into the statuses.
+func CheckRepositoriesAccessOneByOne(ctx context.Context, cl client.Client, sp ServiceProvider, accessChecks []*api.SPIAccessCheck, indices []int, statuses []*api.SPIAccessCheckStatus) error {
+	for _, i := range indices {
+		status, err := sp.CheckRepositoryAccess(ctx, cl, accessChecks[i])
+		if err != nil {
+			return fmt.Errorf("failed to check the access to %s: %w", accessChecks[i].Spec.RepoUrl, err)
+		}
+		statuses[i] = status
+	}
+	return nil
+}
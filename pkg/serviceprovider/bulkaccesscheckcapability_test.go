@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprovider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGroupByCredentials(t *testing.T) {
+	accessChecks := []*api.SPIAccessCheck{
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/a"}},
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/b"}},
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/c"}},
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/d"}},
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/e"}},
+	}
+
+	lookup := func(_ context.Context, _ client.Client, m Matchable) (*Credentials, error) {
+		switch m.RepoUrl() {
+		case "https://test.sp/a", "https://test.sp/c":
+			return &Credentials{Token: "token", Source: &api.CredentialsSource{Kind: api.CredentialsSourceKindSPIAccessToken, Name: "token"}}, nil
+		case "https://test.sp/b":
+			return &Credentials{Token: "secret", Source: &api.CredentialsSource{Kind: api.CredentialsSourceKindRemoteSecret, Name: "token"}}, nil
+		case "https://test.sp/d":
+			return nil, errors.New("intentional failure")
+		default:
+			return nil, nil
+		}
+	}
+
+	groups, ungrouped := GroupByCredentials(context.TODO(), nil, lookup, accessChecks)
+
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "token", groups[0].Credentials.Token)
+	assert.Equal(t, []int{0, 2}, groups[0].Indices)
+	assert.Equal(t, "secret", groups[1].Credentials.Token)
+	assert.Equal(t, []int{1}, groups[1].Indices)
+	assert.Equal(t, []int{3, 4}, ungrouped)
+}
+
+func TestCheckRepositoriesAccessOneByOne(t *testing.T) {
+	accessChecks := []*api.SPIAccessCheck{
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/a"}},
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/b"}},
+		{Spec: api.SPIAccessCheckSpec{RepoUrl: "https://test.sp/c"}},
+	}
+
+	t.Run("only provided indices", func(t *testing.T) {
+		sp := TestServiceProvider{
+			CheckRepositoryAccessImpl: func(_ context.Context, _ client.Client, ac *api.SPIAccessCheck) (*api.SPIAccessCheckStatus, error) {
+				return &api.SPIAccessCheckStatus{Accessible: true, ErrorMessage: ac.Spec.RepoUrl}, nil
+			},
+		}
+		statuses := make([]*api.SPIAccessCheckStatus, len(accessChecks))
+
+		assert.NoError(t, CheckRepositoriesAccessOneByOne(context.TODO(), nil, sp, accessChecks, []int{0, 2}, statuses))
+		assert.Equal(t, "https://test.sp/a", statuses[0].ErrorMessage)
+		assert.Nil(t, statuses[1])
+		assert.Equal(t, "https://test.sp/c", statuses[2].ErrorMessage)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		sp := TestServiceProvider{
+			CheckRepositoryAccessImpl: func(_ context.Context, _ client.Client, _ *api.SPIAccessCheck) (*api.SPIAccessCheckStatus, error) {
+				return nil, errors.New("intentional failure")
+			},
+		}
+		statuses := make([]*api.SPIAccessCheckStatus, len(accessChecks))
+
+		err := CheckRepositoriesAccessOneByOne(context.TODO(), nil, sp, accessChecks, []int{1}, statuses)
+		assert.ErrorContains(t, err, "https://test.sp/b")
+	})
+}
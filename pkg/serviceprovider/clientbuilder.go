@@ -14,7 +14,11 @@
 
 package serviceprovider
 
-import "context"
+import (
+	"context"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+)
 
 type AuthenticatedClientBuilder[C any] interface {
 	CreateAuthenticatedClient(ctx context.Context, credentials Credentials) (*C, error)
@@ -23,4 +27,6 @@ type AuthenticatedClientBuilder[C any] interface {
 type Credentials struct {
 	Username string
 	Token    string
+	// Source is the object the credentials were looked up from, if known.
+	Source *api.CredentialsSource
 }
@@ -27,16 +27,23 @@ func (f FileDownloadNotSupportedError) Error() string {
 	return "provided repository URL does not supports file downloading"
 }
 
+// DownloadedFile is the content of the downloaded file together with the metadata describing which version of the file
+// it is.
+type DownloadedFile struct {
+	Content  string
+	Metadata api.SPIFileContentMetadata
+}
+
 // DownloadFileCapability indicates an ability of given SCM provider to download files from repository.
 type DownloadFileCapability interface {
-	DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxFileSizeLimit int) (string, error)
+	DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxFileSizeLimit int) (DownloadedFile, error)
 }
 
 // DownloadFileFunc converts a function into the implementation of the DownloadFileCapability interface
-type DownloadFileFunc func(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxFileSizeLimit int) (string, error)
+type DownloadFileFunc func(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxFileSizeLimit int) (DownloadedFile, error)
 
 var _ DownloadFileCapability = (DownloadFileFunc)(nil)
 
-func (d DownloadFileFunc) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxFileSizeLimit int) (string, error) {
+func (d DownloadFileFunc) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxFileSizeLimit int) (DownloadedFile, error) {
 	return d(ctx, request, credentials, maxFileSizeLimit)
 }
@@ -0,0 +1,99 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+)
+
+var (
+	NoFilesInTreeError            = errors.New("no files matching the request found in the directory")
+	TreeSizeLimitExceededError    = errors.New("failed to retrieve directory: aggregate size of the files too big")
+	InvalidTreeFilterPatternError = errors.New("invalid include or exclude pattern")
+)
+
+type TreeDownloadNotSupportedError struct {
+}
+
+func (f TreeDownloadNotSupportedError) Error() string {
+	return "provided repository URL does not supports directory downloading"
+}
+
+// DownloadTreeCapability indicates an ability of given SCM provider to download whole directories from repository.
+type DownloadTreeCapability interface {
+	// DownloadTree downloads the files in the directory specified by the file path of the request (recursively) that
+	// match the include and exclude patterns of the request. The keys of the returned map are the paths of the files
+	// relative to the directory. The aggregate size of the files must not exceed the maxTotalSizeLimit.
+	DownloadTree(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxTotalSizeLimit int) (map[string]string, error)
+}
+
+// DownloadTreeFunc converts a function into the implementation of the DownloadTreeCapability interface
+type DownloadTreeFunc func(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxTotalSizeLimit int) (map[string]string, error)
+
+var _ DownloadTreeCapability = (DownloadTreeFunc)(nil)
+
+func (d DownloadTreeFunc) DownloadTree(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxTotalSizeLimit int) (map[string]string, error) {
+	return d(ctx, request, credentials, maxTotalSizeLimit)
+}
+
+// TreeRelativePath returns the path of the file relative to the directory requested by the request and true, or
+// false if the file does not lie in the directory or is not selected by the include and exclude patterns of
+// the request.
+func TreeRelativePath(request *api.SPIFileContentRequestSpec, filePath string) (string, bool) {
+	dir := strings.Trim(request.FilePath, "/")
+	relPath := strings.Trim(filePath, "/")
+	if dir != "" {
+		if !strings.HasPrefix(relPath, dir+"/") {
+			return "", false
+		}
+		relPath = strings.TrimPrefix(relPath, dir+"/")
+	}
+
+	if len(request.Include) > 0 && !matchesAnyPattern(request.Include, relPath) {
+		return "", false
+	}
+	if matchesAnyPattern(request.Exclude, relPath) {
+		return "", false
+	}
+	return relPath, true
+}
+
+// ValidateTreeFilterPatterns checks that the include and exclude patterns of the request have the correct syntax.
+func ValidateTreeFilterPatterns(request *api.SPIFileContentRequestSpec) error {
+	for _, pattern := range append(append([]string{}, request.Include...), request.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: %s", InvalidTreeFilterPatternError, pattern)
+		}
+	}
+	return nil
+}
+
+func matchesAnyPattern(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if matches, err := path.Match(pattern, relPath); err == nil && matches {
+			return true
+		}
+		if matches, err := path.Match(pattern, path.Base(relPath)); err == nil && matches {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprovider
+
+import (
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreeRelativePath(t *testing.T) {
+	test := func(request api.SPIFileContentRequestSpec, filePath string, expectedPath string, expectedOk bool) {
+		t.Helper()
+		relPath, ok := TreeRelativePath(&request, filePath)
+		assert.Equal(t, expectedOk, ok, filePath)
+		assert.Equal(t, expectedPath, relPath, filePath)
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		request := api.SPIFileContentRequestSpec{FilePath: "/.tekton/"}
+		test(request, ".tekton/pipeline.yaml", "pipeline.yaml", true)
+		test(request, ".tekton/tasks/build.yaml", "tasks/build.yaml", true)
+		test(request, ".tekton-other/pipeline.yaml", "", false)
+		test(request, "README.md", "", false)
+	})
+
+	t.Run("root directory", func(t *testing.T) {
+		request := api.SPIFileContentRequestSpec{}
+		test(request, "README.md", "README.md", true)
+		test(request, "docs/USER.md", "docs/USER.md", true)
+	})
+
+	t.Run("include and exclude", func(t *testing.T) {
+		request := api.SPIFileContentRequestSpec{FilePath: "chart", Include: []string{"*.yaml", "templates/*.tpl"}, Exclude: []string{"values-*.yaml"}}
+		test(request, "chart/Chart.yaml", "Chart.yaml", true)
+		test(request, "chart/templates/deployment.yaml", "templates/deployment.yaml", true)
+		test(request, "chart/templates/_helpers.tpl", "templates/_helpers.tpl", true)
+		test(request, "chart/values-dev.yaml", "", false)
+		test(request, "chart/README.md", "", false)
+	})
+}
+
+func TestValidateTreeFilterPatterns(t *testing.T) {
+	assert.NoError(t, ValidateTreeFilterPatterns(&api.SPIFileContentRequestSpec{Include: []string{"*.yaml"}, Exclude: []string{"[a-z]*"}}))
+	assert.ErrorIs(t, ValidateTreeFilterPatterns(&api.SPIFileContentRequestSpec{Exclude: []string{"[a-"}}), InvalidTreeFilterPatternError)
+}
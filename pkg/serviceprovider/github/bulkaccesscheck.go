@@ -0,0 +1,195 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/redhat-appstudio/remote-secret/pkg/httptransport"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxRepositoriesPerQuery is the maximum number of repositories asked for in a single GraphQL query.
+const maxRepositoriesPerQuery = 50
+
+var bulkCheckMetricConfig = serviceprovider.CommonRequestMetricsConfig(config.ServiceProviderTypeGitHub, "bulk_check_repos")
+
+var _ serviceprovider.BulkAccessCheckCapability = (*Github)(nil)
+
+// viewerPermissionRoles maps the permissions of the viewer reported by the GraphQL API to the repository roles reported
+// by the REST API.
+var viewerPermissionRoles = map[ViewerPermission]string{
+	ViewerPermissionRead:     "pull",
+	ViewerPermissionTriage:   "triage",
+	ViewerPermissionWrite:    "push",
+	ViewerPermissionMaintain: "maintain",
+	ViewerPermissionAdmin:    "admin",
+}
+
+type repositoryQueryResult struct {
+	IsPrivate        bool             `json:"isPrivate"`
+	ViewerPermission ViewerPermission `json:"viewerPermission"`
+}
+
+type repositoriesQueryResponse struct {
+	// Data contains the results keyed by the aliases of the queried repositories. The result is nil if the repository
+	// was not found.
+	Data map[string]*repositoryQueryResult `json:"data"`
+}
+
+// CheckRepositoriesAccess implements serviceprovider.BulkAccessCheckCapability. The repositories are grouped by
+// the credentials found for them and each group is checked using GraphQL queries asking for many repositories at once.
+// The repositories that cannot be resolved this way are checked one by one.
+func (g *Github) CheckRepositoriesAccess(ctx context.Context, cl client.Client, accessChecks []*api.SPIAccessCheck) ([]*api.SPIAccessCheckStatus, error) {
+	statuses := make([]*api.SPIAccessCheckStatus, len(accessChecks))
+
+	groups, oneByOne := serviceprovider.GroupByCredentials(ctx, cl, g.lookup.LookupCredentials, accessChecks)
+	for _, group := range groups {
+		unresolved, err := g.checkRepositoriesWithCredentials(ctx, group, accessChecks, statuses)
+		if err != nil {
+			return nil, err
+		}
+		oneByOne = append(oneByOne, unresolved...)
+	}
+
+	if err := serviceprovider.CheckRepositoriesAccessOneByOne(ctx, cl, g, accessChecks, oneByOne, statuses); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// checkRepositoriesWithCredentials checks the repositories in the group and stores the results into the statuses. It
+// returns the indices of the access checks that could not be resolved, because the URL is not a GitHub repository URL
+// or the repository was not found using the credentials (it may still be public).
+func (g *Github) checkRepositoriesWithCredentials(ctx context.Context, group serviceprovider.CredentialsGroup, accessChecks []*api.SPIAccessCheck, statuses []*api.SPIAccessCheckStatus) ([]int, error) {
+	ctx = httptransport.ContextWithMetrics(ctx, bulkCheckMetricConfig)
+
+	githubClient, err := g.ghClientBuilder.CreateAuthenticatedClient(ctx, group.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the authenticated GitHub client: %w", err)
+	}
+
+	var unresolved []int
+	batch := make([]int, 0, maxRepositoriesPerQuery)
+	coordinates := make([][2]string, 0, maxRepositoriesPerQuery)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, scopes, err := queryRepositories(ctx, githubClient, coordinates)
+		if err != nil {
+			return err
+		}
+		for j, i := range batch {
+			if results[j] == nil {
+				unresolved = append(unresolved, i)
+				continue
+			}
+			statuses[i] = repositoryStatus(results[j], scopes, group.Credentials.Source, &accessChecks[i].Spec.Permissions)
+		}
+		batch = batch[:0]
+		coordinates = coordinates[:0]
+		return nil
+	}
+
+	for _, i := range group.Indices {
+		owner, repo, err := g.parseGithubRepoUrl(accessChecks[i].Spec.RepoUrl)
+		if err != nil {
+			unresolved = append(unresolved, i)
+			continue
+		}
+		batch = append(batch, i)
+		coordinates = append(coordinates, [2]string{owner, repo})
+		if len(batch) == maxRepositoriesPerQuery {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return unresolved, nil
+}
+
+// queryRepositories asks for the provided repositories (given as owner and name pairs) in a single GraphQL query. The
+// returned results are in the same order as the repositories and are nil for the repositories that were not found.
+// The scopes of the token are returned, too, if GitHub reports them.
+func queryRepositories(ctx context.Context, githubClient *github.Client, repositories [][2]string) ([]*repositoryQueryResult, []string, error) {
+	params := make([]string, 0, len(repositories))
+	fields := strings.Builder{}
+	variables := map[string]string{}
+	for j, r := range repositories {
+		params = append(params, fmt.Sprintf("$o%d: String!, $n%d: String!", j, j))
+		fmt.Fprintf(&fields, "r%d: repository(owner: $o%d, name: $n%d) { isPrivate viewerPermission }\n", j, j, j)
+		variables[fmt.Sprintf("o%d", j)] = r[0]
+		variables[fmt.Sprintf("n%d", j)] = r[1]
+	}
+	query := fmt.Sprintf("query(%s) {\n%s}", strings.Join(params, ", "), fields.String())
+
+	req, err := githubClient.NewRequest(http.MethodPost, "graphql", map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to construct the GraphQL request: %w", err)
+	}
+
+	response := &repositoriesQueryResponse{}
+	resp, err := githubClient.Do(ctx, req, response)
+	if err != nil {
+		checkRateLimitError(err)
+		if rlErr := rateLimitedError(err); rlErr != nil {
+			return nil, nil, rlErr
+		}
+		return nil, nil, fmt.Errorf("failed to query the repositories using GraphQL: %w", err)
+	}
+	log.FromContext(ctx).V(logs.DebugLevel).Info("queried repositories using GraphQL", "count", len(repositories), "found", len(response.Data))
+
+	results := make([]*repositoryQueryResult, len(repositories))
+	for j := range repositories {
+		results[j] = response.Data[fmt.Sprintf("r%d", j)]
+	}
+	return results, oauthScopes(resp), nil
+}
+
+// repositoryStatus converts the result of the GraphQL query to the status of the access check.
+func repositoryStatus(result *repositoryQueryResult, scopes []string, source *api.CredentialsSource, permissions *api.Permissions) *api.SPIAccessCheckStatus {
+	status := &api.SPIAccessCheckStatus{
+		Type:              api.SPIRepoTypeGit,
+		ServiceProvider:   api.ServiceProviderTypeGitHub,
+		Accessible:        true,
+		Accessibility:     api.SPIAccessCheckAccessibilityPublic,
+		CredentialsSource: source,
+		GrantedRole:       viewerPermissionRoles[result.ViewerPermission],
+		GrantedScopes:     scopes,
+	}
+	if result.IsPrivate {
+		status.Accessibility = api.SPIAccessCheckAccessibilityPrivate
+	}
+	status.Permissions = serviceprovider.CheckPermissions(func(p api.Permission) bool {
+		return permissionGranted(p, status.GrantedRole, status.GrantedScopes, result.IsPrivate)
+	}, permissions)
+	return status
+}
@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryRepositories(t *testing.T) {
+	var requestBody map[string]interface{}
+	githubClient := github.NewClient(&http.Client{
+		Transport: util.FakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/graphql", r.URL.Path)
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.NoError(t, json.Unmarshal(body, &requestBody))
+
+			header := http.Header{}
+			header.Set("X-OAuth-Scopes", "repo, read:org")
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"data": {"r0": {"isPrivate": true, "viewerPermission": "WRITE"}, "r1": null}}`)),
+				Request:    r,
+			}, nil
+		}),
+	})
+
+	results, scopes, err := queryRepositories(context.TODO(), githubClient, [][2]string{{"org", "private"}, {"org", "missing"}})
+	assert.NoError(t, err)
+
+	assert.Len(t, results, 2)
+	assert.True(t, results[0].IsPrivate)
+	assert.Equal(t, ViewerPermissionWrite, results[0].ViewerPermission)
+	assert.Nil(t, results[1])
+	assert.Equal(t, []string{"repo", "read:org"}, scopes)
+
+	assert.Contains(t, requestBody["query"], "r1: repository(owner: $o1, name: $n1)")
+	assert.Equal(t, map[string]interface{}{"o0": "org", "n0": "private", "o1": "org", "n1": "missing"}, requestBody["variables"])
+}
+
+func TestRepositoryStatus(t *testing.T) {
+	source := &api.CredentialsSource{Kind: api.CredentialsSourceKindSPIAccessToken, Name: "token"}
+	permissions := &api.Permissions{Required: []api.Permission{{Type: api.PermissionTypeReadWrite, Area: api.PermissionAreaRepository}}}
+
+	t.Run("private writable", func(t *testing.T) {
+		status := repositoryStatus(&repositoryQueryResult{IsPrivate: true, ViewerPermission: ViewerPermissionWrite}, []string{"repo"}, source, permissions)
+
+		assert.True(t, status.Accessible)
+		assert.Equal(t, api.SPIAccessCheckAccessibilityPrivate, status.Accessibility)
+		assert.Equal(t, api.ServiceProviderTypeGitHub, status.ServiceProvider)
+		assert.Equal(t, "push", status.GrantedRole)
+		assert.Equal(t, source, status.CredentialsSource)
+		assert.Len(t, status.Permissions, 1)
+		assert.True(t, status.Permissions[0].Granted)
+	})
+
+	t.Run("public readable", func(t *testing.T) {
+		status := repositoryStatus(&repositoryQueryResult{ViewerPermission: ViewerPermissionRead}, []string{"repo"}, source, permissions)
+
+		assert.Equal(t, api.SPIAccessCheckAccessibilityPublic, status.Accessibility)
+		assert.Equal(t, "pull", status.GrantedRole)
+		assert.Len(t, status.Permissions, 1)
+		assert.False(t, status.Permissions[0].Granted)
+	})
+}
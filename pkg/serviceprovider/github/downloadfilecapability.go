@@ -27,6 +27,7 @@ import (
 	"github.com/google/go-github/v45/github"
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -35,9 +36,13 @@ type downloadFileCapability struct {
 	ghClientBuilder githubClientBuilder
 	ghBaseUrl       string
 	ghRepoRegexp    *regexp.Regexp
+	// lookupLastModified enables the determination of the last modification time of the downloaded files. This costs
+	// an additional API call per download.
+	lookupLastModified bool
 }
 
 var _ serviceprovider.DownloadFileCapability = (*downloadFileCapability)(nil)
+var _ serviceprovider.RefResolvingCapability = (*downloadFileCapability)(nil)
 
 var (
 	unexpectedStatusCodeError  = errors.New("unexpected status code from GitHub API")
@@ -46,55 +51,153 @@ var (
 	unexpectedRepoUrlError     = errors.New("repoUrl has unexpected format")
 )
 
-func NewDownloadFileCapability(httpClient *http.Client, ghClientBuilder githubClientBuilder, ghBaseUrl string) (serviceprovider.DownloadFileCapability, error) {
+func NewDownloadFileCapability(httpClient *http.Client, ghClientBuilder githubClientBuilder, ghBaseUrl string, lookupLastModified bool) (serviceprovider.DownloadFileCapability, error) {
+	capability, err := newDownloadFileCapability(httpClient, ghClientBuilder, ghBaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	capability.lookupLastModified = lookupLastModified
+	return capability, nil
+}
+
+func newDownloadFileCapability(httpClient *http.Client, ghClientBuilder githubClientBuilder, ghBaseUrl string) (downloadFileCapability, error) {
 	ghRepoRegexp, err := regexp.Compile(`(?Um)^` + regexp.QuoteMeta(ghBaseUrl) + `/(?P<owner>[^/]+)/(?P<repo>[^/]+)(/|(.git)?)$`)
 	if err != nil {
-		return nil, fmt.Errorf("compiling repoUrl matching regex for GitHub with baseUrl %s failed with error: %w", ghBaseUrl, err)
+		return downloadFileCapability{}, fmt.Errorf("compiling repoUrl matching regex for GitHub with baseUrl %s failed with error: %w", ghBaseUrl, err)
 	}
 
 	return downloadFileCapability{
-		httpClient,
-		ghClientBuilder,
-		ghBaseUrl,
-		ghRepoRegexp,
+		httpClient:      httpClient,
+		ghClientBuilder: ghClientBuilder,
+		ghBaseUrl:       ghBaseUrl,
+		ghRepoRegexp:    ghRepoRegexp,
 	}, nil
 }
 
-func (d downloadFileCapability) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxFileSizeLimit int) (string, error) {
+func (d downloadFileCapability) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxFileSizeLimit int) (serviceprovider.DownloadedFile, error) {
 	owner, repo, err := d.parseOwnerAndRepoFromUrl(ctx, request.RepoUrl)
 	if err != nil {
-		return "", fmt.Errorf("could not parse repository name and owner from repoUrl: %w", err)
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("could not parse repository name and owner from repoUrl: %w", err)
 	}
 	lg := log.FromContext(ctx)
 	ghClient, err := d.ghClientBuilder.CreateAuthenticatedClient(ctx, credentials)
 	if err != nil {
-		return "", fmt.Errorf("failed to create authenticated GitHub client: %w", err)
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("failed to create authenticated GitHub client: %w", err)
 	}
-	file, dir, resp, err := ghClient.Repositories.GetContents(ctx, owner, repo, request.FilePath, &github.RepositoryContentGetOptions{Ref: request.Ref})
+
+	// we resolve the ref first so that the content and the metadata surely come from the same commit. The metadata are
+	// not essential, so we just fall back to the ref from the request if it cannot be resolved.
+	ref := request.Ref
+	commitSha, err := resolveCommitSha(ctx, ghClient, owner, repo, ref)
+	if err != nil {
+		lg.V(logs.DebugLevel).Info("failed to resolve the commit SHA of the ref", "ref", ref, "error", err.Error())
+	} else {
+		ref = commitSha
+	}
+
+	file, dir, resp, err := ghClient.Repositories.GetContents(ctx, owner, repo, request.FilePath, &github.RepositoryContentGetOptions{Ref: ref})
 	if err != nil {
 		checkRateLimitError(err)
+		if rlErr := rateLimitedError(err); rlErr != nil {
+			return serviceprovider.DownloadedFile{}, rlErr
+		}
 		if resp != nil {
 			defer resp.Body.Close()
 			bytes, _ := io.ReadAll(resp.Body)
-			return "", fmt.Errorf("%w: %d. Response: %s", unexpectedStatusCodeError, resp.StatusCode, string(bytes))
+			return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: %d. Response: %s", unexpectedStatusCodeError, resp.StatusCode, string(bytes))
 		}
 		lg.Error(err, "not able to get content from github")
-		return "", unexpectedStatusCodeError
+		return serviceprovider.DownloadedFile{}, unexpectedStatusCodeError
 
 	}
 	if file == nil && dir != nil {
-		return "", fmt.Errorf("%w: %s", pathIsADirectoryError, request.FilePath)
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: %s", pathIsADirectoryError, request.FilePath)
 	}
 	if file.GetSize() > maxFileSizeLimit {
 		lg.Error(err, "file size too big")
-		return "", fmt.Errorf("%w: (%d)", fileSizeLimitExceededError, file.Size)
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: (%d)", fileSizeLimitExceededError, file.Size)
 	}
 	content, err := file.GetContent()
 	if err != nil {
 		lg.Error(err, "file content reading error")
-		return "", fmt.Errorf("content reading error: %w", err)
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("content reading error: %w", err)
+	}
+
+	metadata := api.SPIFileContentMetadata{
+		CommitSha: commitSha,
+		BlobSha:   file.GetSHA(),
+		Size:      file.GetSize(),
+		FilePath:  file.GetPath(),
+	}
+	if d.lookupLastModified {
+		if lastModified, err := lastModifiedTime(ctx, ghClient, owner, repo, ref, file.GetPath()); err != nil {
+			lg.V(logs.DebugLevel).Info("failed to determine the last modification time of the file", "path", file.GetPath(), "error", err.Error())
+		} else {
+			metadata.LastModified = lastModified
+		}
+	}
+
+	return serviceprovider.DownloadedFile{Content: content, Metadata: metadata}, nil
+}
+
+// ResolveRef implements serviceprovider.RefResolvingCapability. It uses the last commit SHA as the ETag of the request,
+// so that the check does not count against the rate limit if the ref has not moved.
+func (d downloadFileCapability) ResolveRef(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, lastCommitSha string) (string, error) {
+	owner, repo, err := d.parseOwnerAndRepoFromUrl(ctx, request.RepoUrl)
+	if err != nil {
+		return "", fmt.Errorf("could not parse repository name and owner from repoUrl: %w", err)
+	}
+	ghClient, err := d.ghClientBuilder.CreateAuthenticatedClient(ctx, credentials)
+	if err != nil {
+		return "", fmt.Errorf("failed to create authenticated GitHub client: %w", err)
+	}
+
+	ref := request.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	sha, resp, err := ghClient.Repositories.GetCommitSHA1(ctx, owner, repo, ref, lastCommitSha)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotModified && lastCommitSha != "" {
+			return lastCommitSha, nil
+		}
+		checkRateLimitError(err)
+		if rlErr := rateLimitedError(err); rlErr != nil {
+			return "", rlErr
+		}
+		return "", fmt.Errorf("failed to resolve the ref %s: %w", ref, err)
+	}
+	return sha, nil
+}
+
+// resolveCommitSha returns the SHA of the commit the ref points to. An empty ref is resolved as HEAD.
+func resolveCommitSha(ctx context.Context, ghClient *github.Client, owner, repo, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	sha, _, err := ghClient.Repositories.GetCommitSHA1(ctx, owner, repo, ref, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the ref %s: %w", ref, err)
+	}
+	return sha, nil
+}
+
+// lastModifiedTime returns the commit time of the last commit reachable from the ref that modified the file, or nil if
+// there is no such commit.
+func lastModifiedTime(ctx context.Context, ghClient *github.Client, owner, repo, ref, filePath string) (*metav1.Time, error) {
+	commits, _, err := ghClient.Repositories.ListCommits(ctx, owner, repo, &github.CommitsListOptions{SHA: ref, Path: filePath, ListOptions: github.ListOptions{PerPage: 1}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the commits of %s: %w", filePath, err)
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+	date := commits[0].GetCommit().GetCommitter().GetDate()
+	if date.IsZero() {
+		return nil, nil
 	}
-	return content, nil
+	t := metav1.NewTime(date)
+	return &t, nil
 }
 
 func (d downloadFileCapability) parseOwnerAndRepoFromUrl(ctx context.Context, url string) (owner string, repo string, err error) {
@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
 
@@ -69,7 +70,7 @@ func TestGetFileHead(t *testing.T) {
 		tokenStorage: ts,
 	}
 
-	fileCapability, capabilityErr := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com")
+	fileCapability, capabilityErr := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com", false)
 	assert.NoError(t, capabilityErr)
 
 	content, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
@@ -82,7 +83,7 @@ func TestGetFileHead(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 	assert.True(t, githubReached)
-	assert.Equal(t, "abcdefg", content)
+	assert.Equal(t, "abcdefg", content.Content)
 }
 
 func TestGetFileHeadGitSuffix(t *testing.T) {
@@ -124,7 +125,7 @@ func TestGetFileHeadGitSuffix(t *testing.T) {
 		tokenStorage: ts,
 	}
 
-	fileCapability, capabilityErr := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com")
+	fileCapability, capabilityErr := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com", false)
 	assert.NoError(t, capabilityErr)
 
 	content, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
@@ -136,7 +137,7 @@ func TestGetFileHeadGitSuffix(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 	assert.True(t, githubReached)
-	assert.Equal(t, "abcdefg", content)
+	assert.Equal(t, "abcdefg", content.Content)
 }
 
 func TestGetFileOnBranch(t *testing.T) {
@@ -177,7 +178,7 @@ func TestGetFileOnBranch(t *testing.T) {
 		tokenStorage: ts,
 	}
 
-	fileCapability, capabilityErr := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com")
+	fileCapability, capabilityErr := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com", false)
 	assert.NoError(t, capabilityErr)
 
 	content, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
@@ -189,7 +190,7 @@ func TestGetFileOnBranch(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 	assert.True(t, githubReached)
-	assert.Equal(t, "abcdefg", content)
+	assert.Equal(t, "abcdefg", content.Content)
 }
 
 func TestGetFileOnCommitId(t *testing.T) {
@@ -232,7 +233,7 @@ func TestGetFileOnCommitId(t *testing.T) {
 		tokenStorage: ts,
 	}
 
-	fileCapability, capabilityErr := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com")
+	fileCapability, capabilityErr := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com", false)
 	assert.NoError(t, capabilityErr)
 
 	content, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
@@ -244,7 +245,7 @@ func TestGetFileOnCommitId(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 	assert.True(t, githubReached)
-	assert.Equal(t, "abcdefg", content)
+	assert.Equal(t, "abcdefg", content.Content)
 }
 
 func TestGetUnexistingFile(t *testing.T) {
@@ -276,7 +277,7 @@ func TestGetUnexistingFile(t *testing.T) {
 		tokenStorage: ts,
 	}
 
-	fileCapability, capabilityErr := NewDownloadFileCapability(&http.Client{}, githubClientBuilder, "https://github.com")
+	fileCapability, capabilityErr := NewDownloadFileCapability(&http.Client{}, githubClientBuilder, "https://github.com", false)
 	assert.NoError(t, capabilityErr)
 
 	_, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
@@ -293,7 +294,7 @@ func TestGetUnexistingFile(t *testing.T) {
 func TestInvalidRepoUrl(t *testing.T) {
 	test := func(t *testing.T, repoUrl string) {
 
-		fileCapability, err := NewDownloadFileCapability(&http.Client{}, githubClientBuilder{}, "https://github.com")
+		fileCapability, err := NewDownloadFileCapability(&http.Client{}, githubClientBuilder{}, "https://github.com", false)
 		assert.NoError(t, err)
 
 		c, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
@@ -315,7 +316,7 @@ func TestInvalidRepoUrl(t *testing.T) {
 
 func TestParseOwnerAndRepoFromUrl(t *testing.T) {
 	downloadCapability, err := NewDownloadFileCapability(&http.Client{}, githubClientBuilder{},
-		"https://github.com")
+		"https://github.com", false)
 	assert.NoError(t, err)
 	ghCapability := downloadCapability.(downloadFileCapability)
 
@@ -343,3 +344,116 @@ func TestParseOwnerAndRepoFromUrl(t *testing.T) {
 	testFail(t, "https://my.github.com/owner/repo")
 	testFail(t, "pink-soap")
 }
+
+func TestGetFileMetadata(t *testing.T) {
+	contentsResponse, _ := json.Marshal(map[string]interface{}{
+		"name":    "myfile",
+		"path":    "docs/myfile",
+		"sha":     "blobsha",
+		"size":    7,
+		"content": "abcdefg",
+	})
+	commitsResponse, _ := json.Marshal([]map[string]interface{}{
+		{"sha": "oldsha", "commit": map[string]interface{}{"committer": map[string]interface{}{"date": "2023-05-01T10:00:00Z"}}},
+	})
+
+	response := func(r *http.Request, body []byte) *http.Response {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewBuffer(body)),
+			Request:    r,
+		}
+	}
+
+	client := &http.Client{
+		Transport: fakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+			switch r.URL.String() {
+			case "https://api.github.com/repos/foo-user/foo-repo/commits/main":
+				return response(r, []byte("commitsha")), nil
+			case "https://api.github.com/repos/foo-user/foo-repo/contents/myfile?ref=commitsha":
+				return response(r, contentsResponse), nil
+			case "https://api.github.com/repos/foo-user/foo-repo/commits?path=docs%2Fmyfile&per_page=1&sha=commitsha":
+				return response(r, commitsResponse), nil
+			}
+
+			return nil, fmt.Errorf("unexpected request to: %s", r.URL.String())
+		}),
+	}
+
+	githubClientBuilder := githubClientBuilder{
+		httpClient: client,
+		tokenStorage: tokenstorage.TestTokenStorage{
+			GetImpl: func(ctx context.Context, token *api.SPIAccessToken) (*api.Token, error) {
+				return &api.Token{AccessToken: "access"}, nil
+			},
+		},
+	}
+
+	request := api.SPIFileContentRequestSpec{
+		FilePath: "myfile",
+		RepoUrl:  "https://github.com/foo-user/foo-repo",
+		Ref:      "main",
+	}
+
+	t.Run("with last modification time", func(t *testing.T) {
+		fileCapability, err := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com", true)
+		assert.NoError(t, err)
+
+		file, err := fileCapability.DownloadFile(context.TODO(), request, serviceprovider.Credentials{}, 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, "abcdefg", file.Content)
+		assert.Equal(t, "commitsha", file.Metadata.CommitSha)
+		assert.Equal(t, "blobsha", file.Metadata.BlobSha)
+		assert.Equal(t, 7, file.Metadata.Size)
+		assert.Equal(t, "docs/myfile", file.Metadata.FilePath)
+		assert.NotNil(t, file.Metadata.LastModified)
+		assert.Equal(t, time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), file.Metadata.LastModified.UTC())
+	})
+
+	t.Run("without last modification time", func(t *testing.T) {
+		fileCapability, err := NewDownloadFileCapability(client, githubClientBuilder, "https://github.com", false)
+		assert.NoError(t, err)
+
+		file, err := fileCapability.DownloadFile(context.TODO(), request, serviceprovider.Credentials{}, 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, "blobsha", file.Metadata.BlobSha)
+		assert.Nil(t, file.Metadata.LastModified)
+	})
+}
+
+func TestResolveRef(t *testing.T) {
+	client := &http.Client{
+		Transport: fakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+			if r.URL.String() == "https://api.github.com/repos/foo-user/foo-repo/commits/main" {
+				if r.Header.Get("If-None-Match") == `"commitsha"` {
+					return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewBuffer(nil)), Request: r}, nil
+				}
+				return &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewBufferString("newsha")), Request: r}, nil
+			}
+
+			return nil, fmt.Errorf("unexpected request to: %s", r.URL.String())
+		}),
+	}
+
+	githubClientBuilder := githubClientBuilder{
+		httpClient: client,
+		tokenStorage: tokenstorage.TestTokenStorage{
+			GetImpl: func(ctx context.Context, token *api.SPIAccessToken) (*api.Token, error) {
+				return &api.Token{AccessToken: "access"}, nil
+			},
+		},
+	}
+
+	fileCapability, err := newDownloadFileCapability(client, githubClientBuilder, "https://github.com")
+	assert.NoError(t, err)
+	request := api.SPIFileContentRequestSpec{FilePath: "myfile", RepoUrl: "https://github.com/foo-user/foo-repo", Ref: "main"}
+
+	sha, err := fileCapability.ResolveRef(context.TODO(), request, serviceprovider.Credentials{}, "commitsha")
+	assert.NoError(t, err)
+	assert.Equal(t, "commitsha", sha)
+
+	sha, err = fileCapability.ResolveRef(context.TODO(), request, serviceprovider.Credentials{}, "othersha")
+	assert.NoError(t, err)
+	assert.Equal(t, "newsha", sha)
+}
@@ -0,0 +1,117 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// downloadTreeCapability downloads directories using the git trees API of GitHub. It lists the whole tree of
+// the reference recursively and downloads the matching blobs one by one.
+type downloadTreeCapability struct {
+	downloadFileCapability
+}
+
+var _ serviceprovider.DownloadTreeCapability = (*downloadTreeCapability)(nil)
+
+var treeTruncatedError = errors.New("the repository tree is too large to be listed by GitHub API")
+
+func NewDownloadTreeCapability(httpClient *http.Client, ghClientBuilder githubClientBuilder, ghBaseUrl string) (serviceprovider.DownloadTreeCapability, error) {
+	fileCapability, err := newDownloadFileCapability(httpClient, ghClientBuilder, ghBaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	return downloadTreeCapability{fileCapability}, nil
+}
+
+func (d downloadTreeCapability) DownloadTree(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxTotalSizeLimit int) (map[string]string, error) {
+	owner, repo, err := d.parseOwnerAndRepoFromUrl(ctx, request.RepoUrl)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse repository name and owner from repoUrl: %w", err)
+	}
+	lg := log.FromContext(ctx)
+	ghClient, err := d.ghClientBuilder.CreateAuthenticatedClient(ctx, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticated GitHub client: %w", err)
+	}
+
+	ref := request.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	tree, resp, err := ghClient.Git.GetTree(ctx, owner, repo, ref, true)
+	if err != nil {
+		return nil, d.responseError(ctx, err, resp)
+	}
+	if tree.GetTruncated() {
+		return nil, fmt.Errorf("%w: %s/%s", treeTruncatedError, owner, repo)
+	}
+
+	entries := map[string]*github.TreeEntry{}
+	totalSize := 0
+	for _, entry := range tree.Entries {
+		if entry.GetType() != "blob" {
+			continue
+		}
+		relPath, ok := serviceprovider.TreeRelativePath(&request, entry.GetPath())
+		if !ok {
+			continue
+		}
+		entries[relPath] = entry
+		totalSize += entry.GetSize()
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", serviceprovider.NoFilesInTreeError, request.FilePath)
+	}
+	if totalSize > maxTotalSizeLimit {
+		return nil, fmt.Errorf("%w: (%d)", serviceprovider.TreeSizeLimitExceededError, totalSize)
+	}
+
+	lg.V(logs.DebugLevel).Info("downloading files of the directory from GitHub", "directory", request.FilePath, "files", len(entries), "size", totalSize)
+
+	files := make(map[string]string, len(entries))
+	for relPath, entry := range entries {
+		blob, resp, err := ghClient.Git.GetBlobRaw(ctx, owner, repo, entry.GetSHA())
+		if err != nil {
+			return nil, d.responseError(ctx, err, resp)
+		}
+		files[relPath] = string(blob)
+	}
+
+	return files, nil
+}
+
+func (d downloadTreeCapability) responseError(ctx context.Context, err error, resp *github.Response) error {
+	checkRateLimitError(err)
+	if resp != nil {
+		defer resp.Body.Close()
+		bytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %d. Response: %s", unexpectedStatusCodeError, resp.StatusCode, string(bytes))
+	}
+	log.FromContext(ctx).Error(err, "not able to get the repository tree from github")
+	return unexpectedStatusCodeError
+}
@@ -0,0 +1,107 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/tokenstorage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadTree(t *testing.T) {
+	treeResponse, _ := json.Marshal(map[string]interface{}{
+		"sha": "treesha",
+		"tree": []map[string]interface{}{
+			{"path": ".tekton", "type": "tree", "sha": "dirsha"},
+			{"path": ".tekton/pipeline.yaml", "type": "blob", "sha": "sha1", "size": 4},
+			{"path": ".tekton/tasks/build.yaml", "type": "blob", "sha": "sha2", "size": 5},
+			{"path": ".tekton/README.md", "type": "blob", "sha": "sha3", "size": 6},
+			{"path": "README.md", "type": "blob", "sha": "sha4", "size": 7},
+		},
+		"truncated": false,
+	})
+	blobs := map[string]string{"sha1": "pipe", "sha2": "build"}
+
+	response := func(r *http.Request, body []byte) *http.Response {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       io.NopCloser(bytes.NewBuffer(body)),
+			Request:    r,
+		}
+	}
+
+	client := &http.Client{
+		Transport: fakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+			if r.URL.String() == "https://api.github.com/repos/foo-user/foo-repo/git/trees/main?recursive=1" {
+				return response(r, treeResponse), nil
+			}
+			for sha, content := range blobs {
+				if r.URL.String() == "https://api.github.com/repos/foo-user/foo-repo/git/blobs/"+sha {
+					return response(r, []byte(content)), nil
+				}
+			}
+
+			return nil, fmt.Errorf("unexpected request to: %s", r.URL.String())
+		}),
+	}
+
+	githubClientBuilder := githubClientBuilder{
+		httpClient: client,
+		tokenStorage: tokenstorage.TestTokenStorage{
+			GetImpl: func(ctx context.Context, token *api.SPIAccessToken) (*api.Token, error) {
+				return &api.Token{AccessToken: "access"}, nil
+			},
+		},
+	}
+
+	treeCapability, err := NewDownloadTreeCapability(client, githubClientBuilder, "https://github.com")
+	assert.NoError(t, err)
+
+	request := api.SPIFileContentRequestSpec{
+		FilePath: ".tekton",
+		RepoUrl:  "https://github.com/foo-user/foo-repo",
+		Ref:      "main",
+		Mode:     api.SPIFileContentRequestModeDirectory,
+		Include:  []string{"*.yaml"},
+	}
+
+	t.Run("downloads matching files", func(t *testing.T) {
+		files, err := treeCapability.DownloadTree(context.TODO(), request, serviceprovider.Credentials{}, 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"pipeline.yaml": "pipe", "tasks/build.yaml": "build"}, files)
+	})
+
+	t.Run("aggregate size limit", func(t *testing.T) {
+		_, err := treeCapability.DownloadTree(context.TODO(), request, serviceprovider.Credentials{}, 8)
+		assert.ErrorIs(t, err, serviceprovider.TreeSizeLimitExceededError)
+	})
+
+	t.Run("no matching files", func(t *testing.T) {
+		noMatch := request
+		noMatch.Include = []string{"*.json"}
+		_, err := treeCapability.DownloadTree(context.TODO(), noMatch, serviceprovider.Credentials{}, 1024)
+		assert.ErrorIs(t, err, serviceprovider.NoFilesInTreeError)
+	})
+}
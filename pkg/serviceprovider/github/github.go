@@ -22,6 +22,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 	"github.com/prometheus/client_golang/prometheus"
@@ -31,6 +32,7 @@ import (
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
 
 	"k8s.io/utils/ptr"
+	"k8s.io/utils/strings/slices"
 
 	"k8s.io/client-go/rest"
 
@@ -79,6 +81,24 @@ func checkRateLimitError(err error) {
 	}
 }
 
+// rateLimitedError converts the GitHub rate limit errors to serviceprovider.RateLimitedError. It returns nil if the error
+// is not caused by exceeding the rate limit.
+func rateLimitedError(err error) error {
+	var rateLimitError *github.RateLimitError
+	if errors.As(err, &rateLimitError) {
+		return &serviceprovider.RateLimitedError{ResetTime: rateLimitError.Rate.Reset.Time, Cause: err}
+	}
+	var abuseRateLimitError *github.AbuseRateLimitError
+	if errors.As(err, &abuseRateLimitError) {
+		rlErr := &serviceprovider.RateLimitedError{Cause: err}
+		if abuseRateLimitError.RetryAfter != nil {
+			rlErr.ResetTime = time.Now().Add(*abuseRateLimitError.RetryAfter)
+		}
+		return rlErr
+	}
+	return nil
+}
+
 func init() {
 	metrics.Registry.MustRegister(unexpectedStatusCounter)
 	metrics.Registry.MustRegister(rateLimitErrorCounter)
@@ -91,6 +111,24 @@ var (
 	failedToParseRepoUrlError = errors.New("failed to parse repository URL")
 )
 
+const (
+	// anonymousRole is the role anyone has on a public repository
+	anonymousRole     = "pull"
+	oauthScopesHeader = "X-Oauth-Scopes"
+)
+
+var (
+	// repositoryRoles are the roles on a repository as reported by GitHub, ordered from the least to the most powerful.
+	repositoryRoles = []string{"pull", "triage", "push", "maintain", "admin"}
+
+	// impliedScopes lists the scopes implied by the OAuth scopes of the classic tokens.
+	impliedScopes = map[string][]string{
+		"admin:repo_hook": {"write:repo_hook", "read:repo_hook"},
+		"write:repo_hook": {"read:repo_hook"},
+		"user":            {"read:user", "user:email", "user:follow"},
+	}
+)
+
 type Github struct {
 	Configuration          *opconfig.OperatorConfiguration
 	lookup                 serviceprovider.GenericLookup
@@ -98,6 +136,7 @@ type Github struct {
 	tokenStorage           tokenstorage.TokenStorage
 	ghClientBuilder        serviceprovider.AuthenticatedClientBuilder[github.Client]
 	downloadFileCapability serviceprovider.DownloadFileCapability
+	downloadTreeCapability serviceprovider.DownloadTreeCapability
 	oauthCapability        serviceprovider.OAuthCapability
 	baseUrl                string
 }
@@ -134,7 +173,12 @@ func newGithub(factory *serviceprovider.Factory, spConfig *config.ServiceProvide
 		TokenStorage:  factory.TokenStorage,
 	}
 
-	downloadCapability, err := NewDownloadFileCapability(httpClient, ghClientBuilder, spConfig.ServiceProviderBaseUrl)
+	downloadCapability, err := NewDownloadFileCapability(httpClient, ghClientBuilder, spConfig.ServiceProviderBaseUrl, factory.Configuration.LookupFileLastModified)
+	if err != nil {
+		return nil, err
+	}
+
+	downloadTreeCapability, err := NewDownloadTreeCapability(httpClient, ghClientBuilder, spConfig.ServiceProviderBaseUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +190,7 @@ func newGithub(factory *serviceprovider.Factory, spConfig *config.ServiceProvide
 		httpClient:             factory.HttpClient,
 		ghClientBuilder:        ghClientBuilder,
 		downloadFileCapability: downloadCapability,
+		downloadTreeCapability: downloadTreeCapability,
 		oauthCapability:        newGithubOAuthCapability(factory, spConfig),
 		baseUrl:                spConfig.ServiceProviderBaseUrl,
 	}
@@ -174,6 +219,10 @@ func (g *Github) GetDownloadFileCapability() serviceprovider.DownloadFileCapabil
 	return g.downloadFileCapability
 }
 
+func (g *Github) GetDownloadTreeCapability() serviceprovider.DownloadTreeCapability {
+	return g.downloadTreeCapability
+}
+
 func (g *Github) GetRefreshTokenCapability() serviceprovider.RefreshTokenCapability {
 	return nil
 }
@@ -248,12 +297,33 @@ func (g *Github) CheckRepositoryAccess(ctx context.Context, cl client.Client, ac
 	status.Accessible = publicRepo
 	if publicRepo {
 		status.Accessibility = api.SPIAccessCheckAccessibilityPublic
-		return status, nil
+		status.Permissions = serviceprovider.CheckPermissions(func(p api.Permission) bool {
+			return permissionGranted(p, anonymousRole, nil, false)
+		}, &accessCheck.Spec.Permissions)
+		if serviceprovider.AllPermissionsGranted(status.Permissions) {
+			return status, nil
+		}
 	}
 
 	ctx = httptransport.ContextWithMetrics(ctx, fetchRepositoryMetricConfig)
 
-	return g.checkPrivateRepoAccess(ctx, cl, accessCheck)
+	privateStatus, err := g.checkPrivateRepoAccess(ctx, cl, accessCheck)
+	if !publicRepo {
+		return privateStatus, err
+	}
+	if err != nil || privateStatus.CredentialsSource == nil || !privateStatus.Accessible {
+		// The required permissions are not satisfied anonymously, and we either didn't find any better credentials
+		// or failed to use them. The repository is still public, only the permissions stay unverified, i.e. not
+		// granted in the public status.
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to check the permissions on a public repository using credentials", "repoUrl", accessCheck.Spec.RepoUrl)
+		} else if privateStatus.ErrorReason != "" {
+			log.FromContext(ctx).Info("failed to check the permissions on a public repository using credentials", "repoUrl", accessCheck.Spec.RepoUrl, "reason", privateStatus.ErrorReason, "error", privateStatus.ErrorMessage)
+		}
+		return status, nil
+	}
+	privateStatus.Accessibility = api.SPIAccessCheckAccessibilityPublic
+	return privateStatus, nil
 }
 
 // checkPrivateRepoAccess checks whether a repository is private and accessible.
@@ -281,6 +351,7 @@ func (g *Github) checkPrivateRepoAccess(ctx context.Context, cl client.Client, a
 	if credentials == nil {
 		return status, nil
 	}
+	status.CredentialsSource = credentials.Source
 
 	githubClient, err := g.ghClientBuilder.CreateAuthenticatedClient(ctx, *credentials)
 	if err != nil {
@@ -297,12 +368,99 @@ func (g *Github) checkPrivateRepoAccess(ctx context.Context, cl client.Client, a
 	}
 
 	status.Accessible = true
-	if ptr.Deref(ghRepository.Private, false) {
+	private := ptr.Deref(ghRepository.Private, false)
+	if private {
 		status.Accessibility = api.SPIAccessCheckAccessibilityPrivate
 	}
+
+	status.GrantedRole = repositoryRole(ghRepository.Permissions)
+	status.GrantedScopes = oauthScopes(resp)
+	status.Permissions = serviceprovider.CheckPermissions(func(p api.Permission) bool {
+		return permissionGranted(p, status.GrantedRole, status.GrantedScopes, private)
+	}, &accessCheck.Spec.Permissions)
+
 	return status, nil
 }
 
+// repositoryRole returns the highest role from the permissions GitHub reports for the repository.
+func repositoryRole(permissions map[string]bool) string {
+	for i := len(repositoryRoles) - 1; i >= 0; i-- {
+		if permissions[repositoryRoles[i]] {
+			return repositoryRoles[i]
+		}
+	}
+	return ""
+}
+
+// oauthScopes returns the scopes of the token as reported by GitHub. Only the classic tokens have scopes, nil is
+// returned for other kinds of tokens.
+func oauthScopes(resp *github.Response) []string {
+	if resp == nil || resp.Response == nil {
+		return nil
+	}
+	header, ok := resp.Header[oauthScopesHeader]
+	if !ok {
+		return nil
+	}
+	scopes := []string{}
+	for _, s := range strings.Split(strings.Join(header, ","), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// permissionGranted checks whether the permission is satisfied by the role on the repository and the scopes of
+// the token. If the scopes are nil, they're not known and only the role is considered.
+func permissionGranted(permission api.Permission, role string, scopes []string, privateRepo bool) bool {
+	requiredRole := ""
+	switch permission.Area {
+	case api.PermissionAreaRepository, api.PermissionAreaRepositoryMetadata:
+		requiredRole = "pull"
+		if permission.Type.IsWrite() {
+			requiredRole = "push"
+		} else if !privateRepo {
+			// reading public repositories doesn't require any scopes
+			return hasRole(role, requiredRole)
+		}
+	case api.PermissionAreaWebhooks:
+		requiredRole = "admin"
+	case api.PermissionAreaUser:
+		// not related to the repository
+	default:
+		return false
+	}
+
+	if requiredRole != "" && !hasRole(role, requiredRole) {
+		return false
+	}
+
+	if scopes == nil {
+		return permission.Area != api.PermissionAreaUser
+	}
+	for _, required := range translateToScopes(permission) {
+		if !scopeIncluded(required, scopes) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasRole(role string, required string) bool {
+	return slices.Index(repositoryRoles, role) >= slices.Index(repositoryRoles, required)
+}
+
+// scopeIncluded checks whether the scope is among the scopes either directly or implied by some other scope.
+func scopeIncluded(scope string, scopes []string) bool {
+	for _, s := range scopes {
+		if s == scope || slices.Contains(impliedScopes[s], scope) {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *Github) checkPublicRepoAccess(ctx context.Context, accessCheck *api.SPIAccessCheck) (bool, error) {
 	ctx = httptransport.ContextWithMetrics(ctx, publicRepoMetricConfig)
 	lg := log.FromContext(ctx)
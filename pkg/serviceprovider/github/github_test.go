@@ -249,6 +249,58 @@ func TestCheckAccessFailingLookupPublicRepo(t *testing.T) {
 	assert.Empty(t, status.ErrorMessage)
 }
 
+func TestCheckAccessFailingLookupPublicRepoWithPermissions(t *testing.T) {
+	cl := mockK8sClient(&api.SPIAccessToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "token",
+			Namespace: "ac-namespace",
+			Labels: map[string]string{
+				api.ServiceProviderTypeLabel: string(api.ServiceProviderTypeGitHub),
+				api.ServiceProviderHostLabel: config.ServiceProviderTypeGitHub.DefaultHost,
+			},
+		},
+		Spec: api.SPIAccessTokenSpec{
+			ServiceProviderUrl: config.ServiceProviderTypeGitHub.DefaultBaseUrl,
+		},
+		Status: api.SPIAccessTokenStatus{
+			Phase: api.SPIAccessTokenPhaseReady,
+			TokenMetadata: &api.TokenMetadata{
+				LastRefreshTime: time.Now().Add(time.Hour).Unix(),
+			},
+		},
+	})
+	gh := mockGithub(cl, http.StatusOK, nil, errors.New("intentional failure"))
+
+	ac := api.SPIAccessCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "access-check",
+			Namespace: "ac-namespace",
+		},
+		Spec: api.SPIAccessCheckSpec{
+			RepoUrl: testValidRepoUrl,
+			Permissions: api.Permissions{
+				Required: []api.Permission{
+					{Type: api.PermissionTypeRead, Area: api.PermissionAreaRepository},
+					{Type: api.PermissionTypeWrite, Area: api.PermissionAreaRepository},
+				},
+			},
+		},
+	}
+
+	status, err := gh.CheckRepositoryAccess(context.TODO(), cl, &ac)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, status)
+	assert.True(t, status.Accessible)
+	assert.Equal(t, api.SPIAccessCheckAccessibilityPublic, status.Accessibility)
+	assert.Nil(t, status.CredentialsSource)
+	assert.Empty(t, status.ErrorReason)
+	assert.Equal(t, []api.SPIAccessCheckPermissionResult{
+		{Permission: api.Permission{Type: api.PermissionTypeRead, Area: api.PermissionAreaRepository}, Granted: true},
+		{Permission: api.Permission{Type: api.PermissionTypeWrite, Area: api.PermissionAreaRepository}, Granted: false},
+	}, status.Permissions)
+}
+
 func TestCheckAccessFailingLookupNonPublicRepo(t *testing.T) {
 	cl := mockK8sClient(&api.SPIAccessToken{
 		ObjectMeta: metav1.ObjectMeta{
@@ -609,10 +661,64 @@ func TestCheckPrivateRepositoryAccessWithRemoteSecret(t *testing.T) {
 	assert.Equal(t, api.SPIRepoTypeGit, status.Type)
 	assert.Equal(t, api.ServiceProviderTypeGitHub, status.ServiceProvider)
 	assert.Equal(t, api.SPIAccessCheckAccessibilityPrivate, status.Accessibility)
+	assert.Equal(t, &api.CredentialsSource{Kind: api.CredentialsSourceKindRemoteSecret, Name: "rs"}, status.CredentialsSource)
 	assert.Empty(t, status.ErrorReason)
 	assert.Empty(t, status.ErrorMessage)
 }
 
+func TestRepositoryRole(t *testing.T) {
+	assert.Equal(t, "", repositoryRole(nil))
+	assert.Equal(t, "pull", repositoryRole(map[string]bool{"pull": true}))
+	assert.Equal(t, "maintain", repositoryRole(map[string]bool{"pull": true, "triage": true, "push": true, "maintain": true, "admin": false}))
+}
+
+func TestOauthScopes(t *testing.T) {
+	resp := func(header http.Header) *github.Response {
+		return &github.Response{Response: &http.Response{Header: header}}
+	}
+
+	assert.Nil(t, oauthScopes(nil))
+	assert.Nil(t, oauthScopes(resp(http.Header{})))
+	assert.Equal(t, []string{}, oauthScopes(resp(http.Header{oauthScopesHeader: []string{""}})))
+	assert.Equal(t, []string{"repo", "read:user"}, oauthScopes(resp(http.Header{oauthScopesHeader: []string{"repo, read:user"}})))
+}
+
+func TestPermissionGranted(t *testing.T) {
+	read := func(area api.PermissionArea) api.Permission {
+		return api.Permission{Type: api.PermissionTypeRead, Area: area}
+	}
+	write := func(area api.PermissionArea) api.Permission {
+		return api.Permission{Type: api.PermissionTypeWrite, Area: area}
+	}
+
+	t.Run("public repository", func(t *testing.T) {
+		assert.True(t, permissionGranted(read(api.PermissionAreaRepository), anonymousRole, nil, false))
+		assert.True(t, permissionGranted(read(api.PermissionAreaRepository), anonymousRole, []string{}, false))
+		assert.False(t, permissionGranted(write(api.PermissionAreaRepository), anonymousRole, nil, false))
+		assert.False(t, permissionGranted(read(api.PermissionAreaUser), anonymousRole, nil, false))
+	})
+
+	t.Run("private repository", func(t *testing.T) {
+		assert.True(t, permissionGranted(read(api.PermissionAreaRepository), "pull", []string{"repo"}, true))
+		assert.False(t, permissionGranted(read(api.PermissionAreaRepository), "pull", []string{"read:user"}, true))
+		assert.False(t, permissionGranted(read(api.PermissionAreaRepository), "", []string{"repo"}, true))
+		assert.True(t, permissionGranted(write(api.PermissionAreaRepository), "push", []string{"repo"}, true))
+		assert.False(t, permissionGranted(write(api.PermissionAreaRepository), "triage", []string{"repo"}, true))
+	})
+
+	t.Run("unknown scopes", func(t *testing.T) {
+		assert.True(t, permissionGranted(write(api.PermissionAreaRepository), "admin", nil, true))
+		assert.True(t, permissionGranted(write(api.PermissionAreaWebhooks), "admin", nil, true))
+		assert.False(t, permissionGranted(write(api.PermissionAreaWebhooks), "maintain", nil, true))
+		assert.False(t, permissionGranted(read(api.PermissionAreaUser), "admin", nil, true))
+	})
+
+	t.Run("implied scopes", func(t *testing.T) {
+		assert.True(t, permissionGranted(read(api.PermissionAreaUser), "pull", []string{"user"}, true))
+		assert.False(t, permissionGranted(read(api.PermissionAreaRegistry), "admin", nil, true))
+	})
+}
+
 func mockK8sClient(objects ...client.Object) client.WithWatch {
 	sch := runtime.NewScheme()
 	utilruntime.Must(corev1.AddToScheme(sch))
@@ -0,0 +1,71 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/redhat-appstudio/remote-secret/pkg/httptransport"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var listRepositoriesMetricConfig = serviceprovider.CommonRequestMetricsConfig(config.ServiceProviderTypeGitHub, "list_repos")
+
+var _ serviceprovider.RepositoryListingCapability = (*Github)(nil)
+
+// ListRepositories implements serviceprovider.RepositoryListingCapability. It lists all the repositories
+// the authenticated user has access to, including the ones accessible through the organization memberships.
+func (g *Github) ListRepositories(ctx context.Context, credentials serviceprovider.Credentials) ([]api.SPIRepositoryListItem, error) {
+	ctx = httptransport.ContextWithMetrics(ctx, listRepositoriesMetricConfig)
+
+	githubClient, err := g.ghClientBuilder.CreateAuthenticatedClient(ctx, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the authenticated GitHub client: %w", err)
+	}
+
+	repositories := []api.SPIRepositoryListItem{}
+	opt := &github.RepositoryListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		repos, resp, err := githubClient.Repositories.List(ctx, "", opt)
+		if err != nil {
+			checkRateLimitError(err)
+			if rlErr := rateLimitedError(err); rlErr != nil {
+				return nil, rlErr
+			}
+			return nil, fmt.Errorf("failed to list the GitHub repositories: %w", err)
+		}
+		for _, r := range repos {
+			repositories = append(repositories, api.SPIRepositoryListItem{
+				RepoUrl:  r.GetHTMLURL(),
+				FullName: r.GetFullName(),
+				Private:  r.GetPrivate(),
+				Role:     repositoryRole(r.Permissions),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.ListOptions.Page = resp.NextPage
+	}
+
+	log.FromContext(ctx).V(logs.DebugLevel).Info("listed the GitHub repositories", "count", len(repositories))
+	return repositories, nil
+}
@@ -0,0 +1,63 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListRepositories(t *testing.T) {
+	pages := map[string]string{
+		"":  `[{"full_name": "acme/app", "html_url": "https://github.com/acme/app", "private": true, "permissions": {"pull": true, "push": true}}]`,
+		"2": `[{"full_name": "acme/lib", "html_url": "https://github.com/acme/lib", "private": false, "permissions": {"pull": true}}]`,
+	}
+	gh := &Github{
+		ghClientBuilder: githubClientBuilder{
+			httpClient: &http.Client{
+				Transport: util.FakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+					assert.Equal(t, "/user/repos", r.URL.Path)
+					page := r.URL.Query().Get("page")
+					header := http.Header{}
+					if page == "" {
+						header.Set("Link", `<https://api.github.com/user/repos?page=2>; rel="next"`)
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     header,
+						Body:       io.NopCloser(bytes.NewBufferString(pages[page])),
+						Request:    r,
+					}, nil
+				}),
+			},
+		},
+	}
+
+	repositories, err := gh.ListRepositories(context.TODO(), serviceprovider.Credentials{Token: "token"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []api.SPIRepositoryListItem{
+		{RepoUrl: "https://github.com/acme/app", FullName: "acme/app", Private: true, Role: "push"},
+		{RepoUrl: "https://github.com/acme/lib", FullName: "acme/lib", Private: false, Role: "pull"},
+	}, repositories)
+}
@@ -0,0 +1,163 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redhat-appstudio/remote-secret/pkg/httptransport"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/xanzy/go-gitlab"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var bulkCheckMetricConfig = serviceprovider.CommonRequestMetricsConfig(config.ServiceProviderTypeGitLab, "bulk_check_repos")
+
+var _ serviceprovider.BulkAccessCheckCapability = (*Gitlab)(nil)
+
+// CheckRepositoriesAccess implements serviceprovider.BulkAccessCheckCapability. The repositories are grouped by
+// the credentials found for them and each group is checked against the listing of the projects the user is a member of.
+// The repositories that are not found in the listing are checked one by one.
+func (g *Gitlab) CheckRepositoriesAccess(ctx context.Context, cl client.Client, accessChecks []*api.SPIAccessCheck) ([]*api.SPIAccessCheckStatus, error) {
+	statuses := make([]*api.SPIAccessCheckStatus, len(accessChecks))
+
+	groups, oneByOne := serviceprovider.GroupByCredentials(ctx, cl, g.lookup.LookupCredentials, accessChecks)
+	for _, group := range groups {
+		unresolved, err := g.checkRepositoriesWithCredentials(ctx, group, accessChecks, statuses)
+		if err != nil {
+			return nil, err
+		}
+		oneByOne = append(oneByOne, unresolved...)
+	}
+
+	if err := serviceprovider.CheckRepositoriesAccessOneByOne(ctx, cl, g, accessChecks, oneByOne, statuses); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// checkRepositoriesWithCredentials checks the repositories in the group and stores the results into the statuses. It
+// returns the indices of the access checks that could not be resolved, because the URL cannot be parsed or
+// the project is not among the projects the user is a member of (it may still be public).
+func (g *Gitlab) checkRepositoriesWithCredentials(ctx context.Context, group serviceprovider.CredentialsGroup, accessChecks []*api.SPIAccessCheck, statuses []*api.SPIAccessCheckStatus) ([]int, error) {
+	ctx = httptransport.ContextWithMetrics(ctx, bulkCheckMetricConfig)
+
+	gitlabClient, err := g.glClientBuilder.CreateAuthenticatedClient(ctx, group.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the authenticated GitLab client: %w", err)
+	}
+
+	readable, err := memberProjects(ctx, gitlabClient, gitlab.ReporterPermissions)
+	if err != nil {
+		return nil, err
+	}
+	// the listing of the writable projects is only needed if the listing doesn't report the access levels
+	var writable map[string]*gitlab.Project
+
+	var scopes []string
+	if g.metadataProvider != nil {
+		// the scopes are not essential, so let's just log the failure
+		if scopes, err = g.metadataProvider.fetchScopes(ctx, gitlabClient); err != nil {
+			log.FromContext(ctx).Error(err, "failed to determine the scopes of the token used for the bulk access check")
+		}
+	}
+
+	var unresolved []int
+	for _, i := range group.Indices {
+		owner, name, err := g.repoUrlMatcher.parseOwnerAndProjectFromUrl(ctx, accessChecks[i].Spec.RepoUrl)
+		if err != nil {
+			unresolved = append(unresolved, i)
+			continue
+		}
+		path := strings.ToLower(owner + "/" + name)
+		project, ok := readable[path]
+		if !ok {
+			unresolved = append(unresolved, i)
+			continue
+		}
+
+		accessLevel := projectAccessLevel(project)
+		if accessLevel == gitlab.NoPermissions {
+			if writable == nil {
+				if writable, err = memberProjects(ctx, gitlabClient, gitlab.DeveloperPermissions); err != nil {
+					return nil, err
+				}
+			}
+			accessLevel = gitlab.ReporterPermissions
+			if _, ok := writable[path]; ok {
+				accessLevel = gitlab.DeveloperPermissions
+			}
+		}
+
+		statuses[i] = projectStatus(project, accessLevel, scopes, group.Credentials.Source, &accessChecks[i].Spec.Permissions)
+	}
+
+	return unresolved, nil
+}
+
+// memberProjects lists the projects the user is a member of with at least the provided access level. The projects are
+// keyed by their lower-cased path with namespace.
+func memberProjects(ctx context.Context, gitlabClient *gitlab.Client, minAccessLevel gitlab.AccessLevelValue) (map[string]*gitlab.Project, error) {
+	opts := &gitlab.ListProjectsOptions{
+		ListOptions:    gitlab.ListOptions{PerPage: 100},
+		Membership:     gitlab.Bool(true),
+		MinAccessLevel: gitlab.AccessLevel(minAccessLevel),
+	}
+
+	projects := map[string]*gitlab.Project{}
+	for {
+		page, response, err := gitlabClient.Projects.ListProjects(opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the GitLab projects of the user: %w", err)
+		}
+		for _, p := range page {
+			projects[strings.ToLower(p.PathWithNamespace)] = p
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+
+	log.FromContext(ctx).V(logs.DebugLevel).Info("listed the GitLab projects of the user", "minAccessLevel", minAccessLevel, "count", len(projects))
+	return projects, nil
+}
+
+// projectStatus converts the project found in the listing to the status of the access check.
+func projectStatus(project *gitlab.Project, accessLevel gitlab.AccessLevelValue, scopes []string, source *api.CredentialsSource, permissions *api.Permissions) *api.SPIAccessCheckStatus {
+	status := &api.SPIAccessCheckStatus{
+		Type:              api.SPIRepoTypeGit,
+		ServiceProvider:   api.ServiceProviderTypeGitLab,
+		Accessible:        true,
+		Accessibility:     api.SPIAccessCheckAccessibilityPublic,
+		CredentialsSource: source,
+		GrantedRole:       accessLevelNames[accessLevel],
+		GrantedScopes:     scopes,
+	}
+	if project.Visibility == gitlab.PrivateVisibility || project.Visibility == gitlab.InternalVisibility {
+		status.Accessibility = api.SPIAccessCheckAccessibilityPrivate
+	}
+	status.Permissions = serviceprovider.CheckPermissions(func(p api.Permission) bool {
+		return permissionGranted(p, accessLevel, scopes, project.Visibility == gitlab.PublicVisibility)
+	}, permissions)
+	return status
+}
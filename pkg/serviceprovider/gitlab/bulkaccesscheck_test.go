@@ -0,0 +1,87 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestMemberProjects(t *testing.T) {
+	pages := map[string]string{
+		"":  `[{"id": 1, "path_with_namespace": "Org/First"}]`,
+		"2": `[{"id": 2, "path_with_namespace": "org/second"}]`,
+	}
+	client, err := gitlab.NewClient("token", gitlab.WithHTTPClient(&http.Client{
+		Transport: util.FakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+			assert.Equal(t, "true", r.URL.Query().Get("membership"))
+			assert.Equal(t, "20", r.URL.Query().Get("min_access_level"))
+
+			page := r.URL.Query().Get("page")
+			header := http.Header{}
+			if page == "" {
+				header.Set("X-Next-Page", "2")
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       io.NopCloser(bytes.NewBufferString(pages[page])),
+				Request:    r,
+			}, nil
+		}),
+	}))
+	assert.NoError(t, err)
+
+	projects, err := memberProjects(context.TODO(), client, gitlab.ReporterPermissions)
+	assert.NoError(t, err)
+
+	assert.Len(t, projects, 2)
+	assert.Equal(t, 1, projects["org/first"].ID)
+	assert.Equal(t, 2, projects["org/second"].ID)
+}
+
+func TestProjectStatus(t *testing.T) {
+	source := &api.CredentialsSource{Kind: api.CredentialsSourceKindRemoteSecret, Name: "secret"}
+	permissions := &api.Permissions{Required: []api.Permission{{Type: api.PermissionTypeWrite, Area: api.PermissionAreaRepository}}}
+
+	t.Run("private writable", func(t *testing.T) {
+		status := projectStatus(&gitlab.Project{Visibility: gitlab.PrivateVisibility}, gitlab.DeveloperPermissions, []string{"api"}, source, permissions)
+
+		assert.True(t, status.Accessible)
+		assert.Equal(t, api.SPIAccessCheckAccessibilityPrivate, status.Accessibility)
+		assert.Equal(t, api.ServiceProviderTypeGitLab, status.ServiceProvider)
+		assert.Equal(t, "Developer", status.GrantedRole)
+		assert.Equal(t, source, status.CredentialsSource)
+		assert.Len(t, status.Permissions, 1)
+		assert.True(t, status.Permissions[0].Granted)
+	})
+
+	t.Run("public readable", func(t *testing.T) {
+		status := projectStatus(&gitlab.Project{Visibility: gitlab.PublicVisibility}, gitlab.ReporterPermissions, []string{"api"}, source, permissions)
+
+		assert.Equal(t, api.SPIAccessCheckAccessibilityPublic, status.Accessibility)
+		assert.Equal(t, "Reporter", status.GrantedRole)
+		assert.Len(t, status.Permissions, 1)
+		assert.False(t, status.Permissions[0].Granted)
+	})
+}
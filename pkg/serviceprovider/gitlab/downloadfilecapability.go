@@ -20,10 +20,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
 	"github.com/xanzy/go-gitlab"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -32,34 +36,39 @@ type downloadFileCapability struct {
 	glClientBuilder gitlabClientBuilder
 	baseUrl         string
 	repoMatcher     gitlabRepoUrlMatcher
+	// lookupLastModified enables the determination of the last modification time of the downloaded files. This costs
+	// an additional API call per download.
+	lookupLastModified bool
 }
 
-func NewDownloadFileCapability(httpClient *http.Client, glClientBuilder gitlabClientBuilder, baseUrl string, repoMatcher gitlabRepoUrlMatcher) downloadFileCapability {
+func NewDownloadFileCapability(httpClient *http.Client, glClientBuilder gitlabClientBuilder, baseUrl string, repoMatcher gitlabRepoUrlMatcher, lookupLastModified bool) downloadFileCapability {
 	return downloadFileCapability{
 		httpClient,
 		glClientBuilder,
 		baseUrl,
 		repoMatcher,
+		lookupLastModified,
 	}
 }
 
 var _ serviceprovider.DownloadFileCapability = (*downloadFileCapability)(nil)
+var _ serviceprovider.RefResolvingCapability = (*downloadFileCapability)(nil)
 
 var (
 	fileSizeLimitExceededError = errors.New("failed to retrieve file: size too big")
 	unexpectedRepoUrlError     = errors.New("repoUrl has unexpected format")
 )
 
-func (f downloadFileCapability) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxFileSizeLimit int) (string, error) {
+func (f downloadFileCapability) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxFileSizeLimit int) (serviceprovider.DownloadedFile, error) {
 	lg := log.FromContext(ctx)
 	owner, project, err := f.repoMatcher.parseOwnerAndProjectFromUrl(ctx, request.RepoUrl)
 	if err != nil {
-		return "", err
+		return serviceprovider.DownloadedFile{}, err
 	}
 
 	glClient, err := f.glClientBuilder.CreateAuthenticatedClient(ctx, credentials)
 	if err != nil {
-		return "", fmt.Errorf("failed to create authenticated GitLab client: %w", err)
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("failed to create authenticated GitLab client: %w", err)
 	}
 
 	var refOption gitlab.GetFileOptions
@@ -71,19 +80,81 @@ func (f downloadFileCapability) DownloadFile(ctx context.Context, request api.SP
 		refOption = gitlab.GetFileOptions{Ref: gitlab.String("HEAD")}
 	}
 
-	file, resp, err := glClient.RepositoryFiles.GetFile(owner+"/"+project, request.FilePath, &refOption)
+	pid := owner + "/" + project
+	file, resp, err := glClient.RepositoryFiles.GetFile(pid, request.FilePath, &refOption)
 	if err != nil {
+		if rlErr := rateLimitedError(resp, err); rlErr != nil {
+			return serviceprovider.DownloadedFile{}, rlErr
+		}
 		// unfortunately, GitLab library closes the response body, so it is cannot be read
-		return "", fmt.Errorf("%w: %d", unexpectedStatusCodeError, resp.StatusCode)
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: %d", unexpectedStatusCodeError, resp.StatusCode)
 	}
 
 	if file.Size > maxFileSizeLimit {
 		lg.Error(err, "file size too big")
-		return "", fmt.Errorf("%w: (%d)", fileSizeLimitExceededError, file.Size)
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: (%d)", fileSizeLimitExceededError, file.Size)
 	}
 	decoded, err := base64.StdEncoding.DecodeString(file.Content)
 	if err != nil {
-		return "", fmt.Errorf("unable to decode content: %w", err)
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("unable to decode content: %w", err)
+	}
+
+	metadata := api.SPIFileContentMetadata{
+		CommitSha: file.CommitID,
+		BlobSha:   file.BlobID,
+		Size:      file.Size,
+		FilePath:  file.FilePath,
+	}
+	if f.lookupLastModified && file.LastCommitID != "" {
+		// the modification time is not essential, so we don't fail the download if we cannot get it
+		commit, _, err := glClient.Commits.GetCommit(pid, file.LastCommitID)
+		if err != nil {
+			lg.V(logs.DebugLevel).Info("failed to determine the last modification time of the file", "path", file.FilePath, "error", err.Error())
+		} else if commit.CommittedDate != nil {
+			lastModified := metav1.NewTime(*commit.CommittedDate)
+			metadata.LastModified = &lastModified
+		}
+	}
+
+	return serviceprovider.DownloadedFile{Content: string(decoded), Metadata: metadata}, nil
+}
+
+// ResolveRef implements serviceprovider.RefResolvingCapability. GitLab does not support conditional requests for
+// commits, so the lastCommitSha is not used.
+func (f downloadFileCapability) ResolveRef(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, _ string) (string, error) {
+	owner, project, err := f.repoMatcher.parseOwnerAndProjectFromUrl(ctx, request.RepoUrl)
+	if err != nil {
+		return "", err
+	}
+
+	glClient, err := f.glClientBuilder.CreateAuthenticatedClient(ctx, credentials)
+	if err != nil {
+		return "", fmt.Errorf("failed to create authenticated GitLab client: %w", err)
+	}
+
+	ref := request.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	commit, resp, err := glClient.Commits.GetCommit(owner+"/"+project, ref)
+	if err != nil {
+		if rlErr := rateLimitedError(resp, err); rlErr != nil {
+			return "", rlErr
+		}
+		return "", fmt.Errorf("failed to resolve the ref %s: %w", ref, err)
+	}
+	return commit.ID, nil
+}
+
+// rateLimitedError returns serviceprovider.RateLimitedError if the response indicates that the rate limit was exceeded,
+// or nil otherwise. The reset time is read from the RateLimit-Reset header if GitLab sent it.
+func rateLimitedError(resp *gitlab.Response, err error) error {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	rlErr := &serviceprovider.RateLimitedError{Cause: err}
+	if reset, parseErr := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64); parseErr == nil {
+		rlErr.ResetTime = time.Unix(reset, 0)
 	}
-	return string(decoded), nil
+	return rlErr
 }
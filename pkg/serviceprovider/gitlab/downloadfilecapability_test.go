@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
 
@@ -75,7 +76,7 @@ func TestGetFileHead(t *testing.T) {
 	repoUrlMatcher, err := newRepoUrlMatcher("https://fake.github.com")
 	assert.NoError(t, err)
 
-	fileCapability := NewDownloadFileCapability(client, gitlabClientBuilder, "https://fake.github.com", repoUrlMatcher)
+	fileCapability := NewDownloadFileCapability(client, gitlabClientBuilder, "https://fake.github.com", repoUrlMatcher, false)
 	content, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
 		FilePath: "myfile",
 		RepoUrl:  "https://fake.github.com/foo-user/foo-repo",
@@ -85,7 +86,7 @@ func TestGetFileHead(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 	assert.True(t, gitlabReached)
-	assert.Equal(t, "abcdefg", content)
+	assert.Equal(t, "abcdefg", content.Content)
 }
 
 func TestGetFileHeadGitSuffix(t *testing.T) {
@@ -130,7 +131,7 @@ func TestGetFileHeadGitSuffix(t *testing.T) {
 	repoUrlMatcher, err := newRepoUrlMatcher("https://fake.github.com")
 	assert.NoError(t, err)
 
-	fileCapability := NewDownloadFileCapability(client, gitlabClientBuilder, "https://fake.github.com", repoUrlMatcher)
+	fileCapability := NewDownloadFileCapability(client, gitlabClientBuilder, "https://fake.github.com", repoUrlMatcher, false)
 	content, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
 		FilePath: "myfile",
 		RepoUrl:  "https://fake.github.com/foo-user/foo-repo.git",
@@ -140,7 +141,7 @@ func TestGetFileHeadGitSuffix(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 	assert.True(t, gitlabReached)
-	assert.Equal(t, "abcdefg", content)
+	assert.Equal(t, "abcdefg", content.Content)
 }
 
 func TestGetFileOnBranch(t *testing.T) {
@@ -185,7 +186,7 @@ func TestGetFileOnBranch(t *testing.T) {
 	repoUrlMatcher, err := newRepoUrlMatcher("https://fake.github.com")
 	assert.NoError(t, err)
 
-	fileCapability := NewDownloadFileCapability(client, gitlabClientBuilder, "https://fake.github.com", repoUrlMatcher)
+	fileCapability := NewDownloadFileCapability(client, gitlabClientBuilder, "https://fake.github.com", repoUrlMatcher, false)
 	content, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
 		FilePath: "myfile",
 		RepoUrl:  "https://fake.github.com/foo-user/foo-repo.git",
@@ -195,7 +196,7 @@ func TestGetFileOnBranch(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 	assert.True(t, githubReached)
-	assert.Equal(t, "abcdefg", content)
+	assert.Equal(t, "abcdefg", content.Content)
 }
 
 func TestGetUnexistingFile(t *testing.T) {
@@ -231,7 +232,7 @@ func TestGetUnexistingFile(t *testing.T) {
 	repoUrlMatcher, matcherErr := newRepoUrlMatcher("https://fake.github.com")
 	assert.NoError(t, matcherErr)
 
-	fileCapability := NewDownloadFileCapability(client, gitlabClientBuilder, "https://fake.github.com", repoUrlMatcher)
+	fileCapability := NewDownloadFileCapability(client, gitlabClientBuilder, "https://fake.github.com", repoUrlMatcher, false)
 	_, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
 		FilePath: "myfile",
 		RepoUrl:  "https://fake.github.com/foo-user/foo-repo",
@@ -242,3 +243,68 @@ func TestGetUnexistingFile(t *testing.T) {
 	}
 	assert.Equal(t, "unexpected status code from GitLab API: 404", err.Error())
 }
+
+func TestGetFileMetadata(t *testing.T) {
+	fileResponse, _ := json.Marshal(map[string]interface{}{
+		"file_path":      "myfile",
+		"size":           7,
+		"content":        base64.StdEncoding.EncodeToString([]byte("abcdefg")),
+		"blob_id":        "blobsha",
+		"commit_id":      "commitsha",
+		"last_commit_id": "lastsha",
+	})
+	commitResponse, _ := json.Marshal(map[string]interface{}{
+		"id":             "lastsha",
+		"committed_date": "2023-05-01T10:00:00Z",
+	})
+
+	response := func(r *http.Request, body []byte) *http.Response {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewBuffer(body)),
+			Request:    r,
+		}
+	}
+
+	client := &http.Client{
+		Transport: fakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+			switch r.URL.String() {
+			case "https://fake.gitlab.com/api/v4/projects/foo-user%2Ffoo-repo/repository/files/myfile?ref=main":
+				return response(r, fileResponse), nil
+			case "https://fake.gitlab.com/api/v4/projects/foo-user%2Ffoo-repo/repository/commits/lastsha":
+				return response(r, commitResponse), nil
+			}
+
+			return nil, fmt.Errorf("unexpected request to: %s", r.URL.String())
+		}),
+	}
+
+	gitlabClientBuilder := gitlabClientBuilder{
+		httpClient: client,
+		tokenStorage: tokenstorage.TestTokenStorage{
+			GetImpl: func(ctx context.Context, token *api.SPIAccessToken) (*api.Token, error) {
+				return &api.Token{AccessToken: "access"}, nil
+			},
+		},
+		gitlabBaseUrl: "https://fake.gitlab.com",
+	}
+
+	repoUrlMatcher, err := newRepoUrlMatcher("https://fake.gitlab.com")
+	assert.NoError(t, err)
+
+	fileCapability := NewDownloadFileCapability(client, gitlabClientBuilder, "https://fake.gitlab.com", repoUrlMatcher, true)
+	file, err := fileCapability.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
+		FilePath: "myfile",
+		RepoUrl:  "https://fake.gitlab.com/foo-user/foo-repo",
+		Ref:      "main",
+	}, serviceprovider.Credentials{}, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdefg", file.Content)
+	assert.Equal(t, "commitsha", file.Metadata.CommitSha)
+	assert.Equal(t, "blobsha", file.Metadata.BlobSha)
+	assert.Equal(t, 7, file.Metadata.Size)
+	assert.Equal(t, "myfile", file.Metadata.FilePath)
+	assert.NotNil(t, file.Metadata.LastModified)
+	assert.Equal(t, time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), file.Metadata.LastModified.UTC())
+}
@@ -0,0 +1,143 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/xanzy/go-gitlab"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// treeListPageSize is the number of the tree entries requested in one page from the GitLab API. 100 is the maximum
+// allowed by GitLab.
+const treeListPageSize = 100
+
+// downloadTreeCapability downloads directories using the repository tree API of GitLab and downloads the matching
+// files one by one.
+type downloadTreeCapability struct {
+	httpClient      *http.Client
+	glClientBuilder gitlabClientBuilder
+	baseUrl         string
+	repoMatcher     gitlabRepoUrlMatcher
+}
+
+func NewDownloadTreeCapability(httpClient *http.Client, glClientBuilder gitlabClientBuilder, baseUrl string, repoMatcher gitlabRepoUrlMatcher) downloadTreeCapability {
+	return downloadTreeCapability{
+		httpClient,
+		glClientBuilder,
+		baseUrl,
+		repoMatcher,
+	}
+}
+
+var _ serviceprovider.DownloadTreeCapability = (*downloadTreeCapability)(nil)
+
+func (f downloadTreeCapability) DownloadTree(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxTotalSizeLimit int) (map[string]string, error) {
+	owner, project, err := f.repoMatcher.parseOwnerAndProjectFromUrl(ctx, request.RepoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	glClient, err := f.glClientBuilder.CreateAuthenticatedClient(ctx, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticated GitLab client: %w", err)
+	}
+
+	ref := request.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	pid := owner + "/" + project
+
+	listOptions := gitlab.ListTreeOptions{
+		ListOptions: gitlab.ListOptions{PerPage: treeListPageSize},
+		Ref:         gitlab.String(ref),
+		Recursive:   gitlab.Bool(true),
+	}
+	if dir := strings.Trim(request.FilePath, "/"); dir != "" {
+		listOptions.Path = gitlab.String(dir)
+	}
+
+	filePaths := map[string]string{}
+	for {
+		nodes, resp, err := glClient.Repositories.ListTree(pid, &listOptions)
+		if err != nil {
+			return nil, responseError(resp, err)
+		}
+		for _, node := range nodes {
+			if node.Type != "blob" {
+				continue
+			}
+			if relPath, ok := serviceprovider.TreeRelativePath(&request, node.Path); ok {
+				filePaths[relPath] = node.Path
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+
+	if len(filePaths) == 0 {
+		return nil, fmt.Errorf("%w: %s", serviceprovider.NoFilesInTreeError, request.FilePath)
+	}
+
+	log.FromContext(ctx).V(logs.DebugLevel).Info("downloading files of the directory from GitLab", "directory", request.FilePath, "files", len(filePaths))
+
+	// the tree API doesn't report the sizes of the files, so we check the sizes using the (cheap) metadata requests
+	// before downloading any of the files
+	totalSize := 0
+	for _, filePath := range filePaths {
+		metadata, resp, err := glClient.RepositoryFiles.GetFileMetaData(pid, filePath, &gitlab.GetFileMetaDataOptions{Ref: gitlab.String(ref)})
+		if err != nil {
+			return nil, responseError(resp, err)
+		}
+		totalSize += metadata.Size
+		if totalSize > maxTotalSizeLimit {
+			return nil, fmt.Errorf("%w: (more than %d)", serviceprovider.TreeSizeLimitExceededError, maxTotalSizeLimit)
+		}
+	}
+
+	files := make(map[string]string, len(filePaths))
+	for relPath, filePath := range filePaths {
+		file, resp, err := glClient.RepositoryFiles.GetFile(pid, filePath, &gitlab.GetFileOptions{Ref: gitlab.String(ref)})
+		if err != nil {
+			return nil, responseError(resp, err)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode content of %s: %w", filePath, err)
+		}
+		files[relPath] = string(decoded)
+	}
+
+	return files, nil
+}
+
+func responseError(resp *gitlab.Response, err error) error {
+	// unfortunately, GitLab library closes the response body, so it is cannot be read
+	if resp == nil {
+		return fmt.Errorf("%w: %s", unexpectedStatusCodeError, err.Error())
+	}
+	return fmt.Errorf("%w: %d", unexpectedStatusCodeError, resp.StatusCode)
+}
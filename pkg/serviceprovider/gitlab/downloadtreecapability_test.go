@@ -0,0 +1,133 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/tokenstorage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadTree(t *testing.T) {
+	page1, _ := json.Marshal([]map[string]interface{}{
+		{"path": "chart/Chart.yaml", "type": "blob"},
+		{"path": "chart/templates", "type": "tree"},
+	})
+	page2, _ := json.Marshal([]map[string]interface{}{
+		{"path": "chart/templates/deployment.yaml", "type": "blob"},
+		{"path": "chart/values-dev.yaml", "type": "blob"},
+	})
+	fileResponse := func(content string) []byte {
+		b, _ := json.Marshal(map[string]interface{}{
+			"size":    len(content),
+			"content": base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+		return b
+	}
+
+	response := func(r *http.Request, body []byte, nextPage string) *http.Response {
+		header := http.Header{}
+		if nextPage != "" {
+			header.Set("X-Next-Page", nextPage)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     header,
+			Body:       io.NopCloser(bytes.NewBuffer(body)),
+			Request:    r,
+		}
+	}
+
+	metadataResponse := func(r *http.Request, content string) *http.Response {
+		resp := response(r, []byte{}, "")
+		resp.Header.Set("X-Gitlab-Size", fmt.Sprint(len(content)))
+		return resp
+	}
+
+	fileDownloads := 0
+	client := &http.Client{
+		Transport: fakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+			if r.Method == http.MethodHead {
+				switch r.URL.String() {
+				case "https://fake.gitlab.com/api/v4/projects/foo-user%2Ffoo-repo/repository/files/chart%2FChart%2Eyaml?ref=HEAD":
+					return metadataResponse(r, "chart"), nil
+				case "https://fake.gitlab.com/api/v4/projects/foo-user%2Ffoo-repo/repository/files/chart%2Ftemplates%2Fdeployment%2Eyaml?ref=HEAD":
+					return metadataResponse(r, "deployment"), nil
+				}
+				return nil, fmt.Errorf("unexpected HEAD request to: %s", r.URL.String())
+			}
+			if strings.Contains(r.URL.Path, "/repository/files/") {
+				fileDownloads++
+			}
+			switch r.URL.String() {
+			case "https://fake.gitlab.com/api/v4/projects/foo-user%2Ffoo-repo/repository/tree?path=chart&per_page=100&recursive=true&ref=HEAD":
+				return response(r, page1, "2"), nil
+			case "https://fake.gitlab.com/api/v4/projects/foo-user%2Ffoo-repo/repository/tree?page=2&path=chart&per_page=100&recursive=true&ref=HEAD":
+				return response(r, page2, ""), nil
+			case "https://fake.gitlab.com/api/v4/projects/foo-user%2Ffoo-repo/repository/files/chart%2FChart%2Eyaml?ref=HEAD":
+				return response(r, fileResponse("chart"), ""), nil
+			case "https://fake.gitlab.com/api/v4/projects/foo-user%2Ffoo-repo/repository/files/chart%2Ftemplates%2Fdeployment%2Eyaml?ref=HEAD":
+				return response(r, fileResponse("deployment"), ""), nil
+			}
+
+			return nil, fmt.Errorf("unexpected request to: %s", r.URL.String())
+		}),
+	}
+
+	gitlabClientBuilder := gitlabClientBuilder{
+		httpClient: client,
+		tokenStorage: tokenstorage.TestTokenStorage{
+			GetImpl: func(ctx context.Context, token *api.SPIAccessToken) (*api.Token, error) {
+				return &api.Token{AccessToken: "access"}, nil
+			},
+		},
+		gitlabBaseUrl: "https://fake.gitlab.com",
+	}
+
+	repoUrlMatcher, err := newRepoUrlMatcher("https://fake.gitlab.com")
+	assert.NoError(t, err)
+
+	treeCapability := NewDownloadTreeCapability(client, gitlabClientBuilder, "https://fake.gitlab.com", repoUrlMatcher)
+	request := api.SPIFileContentRequestSpec{
+		FilePath: "chart",
+		RepoUrl:  "https://fake.gitlab.com/foo-user/foo-repo",
+		Mode:     api.SPIFileContentRequestModeDirectory,
+		Exclude:  []string{"values-*.yaml"},
+	}
+
+	t.Run("downloads matching files", func(t *testing.T) {
+		files, err := treeCapability.DownloadTree(context.TODO(), request, serviceprovider.Credentials{}, 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"Chart.yaml": "chart", "templates/deployment.yaml": "deployment"}, files)
+	})
+
+	t.Run("aggregate size limit", func(t *testing.T) {
+		fileDownloads = 0
+		_, err := treeCapability.DownloadTree(context.TODO(), request, serviceprovider.Credentials{}, 10)
+		assert.ErrorIs(t, err, serviceprovider.TreeSizeLimitExceededError)
+		assert.Zero(t, fileDownloads)
+	})
+}
@@ -48,6 +48,17 @@ var fetchRepositoryMetricConfig = serviceprovider.CommonRequestMetricsConfig(con
 
 var _ serviceprovider.ServiceProvider = (*Gitlab)(nil)
 
+// accessLevelNames are the names of the roles corresponding to the access levels in GitLab.
+var accessLevelNames = map[gitlab.AccessLevelValue]string{
+	gitlab.MinimalAccessPermissions: "Minimal Access",
+	gitlab.GuestPermissions:         "Guest",
+	gitlab.ReporterPermissions:      "Reporter",
+	gitlab.DeveloperPermissions:     "Developer",
+	gitlab.MaintainerPermissions:    "Maintainer",
+	gitlab.OwnerPermissions:         "Owner",
+	gitlab.AdminPermissions:         "Admin",
+}
+
 type Gitlab struct {
 	Configuration          *opconfig.OperatorConfiguration
 	lookup                 serviceprovider.GenericLookup
@@ -57,6 +68,7 @@ type Gitlab struct {
 	glClientBuilder        serviceprovider.AuthenticatedClientBuilder[gitlab.Client]
 	baseUrl                string
 	downloadFileCapability downloadFileCapability
+	downloadTreeCapability downloadTreeCapability
 	refreshTokenCapability serviceprovider.RefreshTokenCapability
 	oauthCapability        serviceprovider.OAuthCapability
 	repoUrlMatcher         gitlabRepoUrlMatcher
@@ -124,7 +136,8 @@ func newGitlab(factory *serviceprovider.Factory, spConfig *config.ServiceProvide
 			oauthServiceBaseUrl: factory.Configuration.BaseUrl,
 		},
 		baseUrl:                spConfig.ServiceProviderBaseUrl,
-		downloadFileCapability: NewDownloadFileCapability(factory.HttpClient, glClientBuilder, spConfig.ServiceProviderBaseUrl, repoUrlMatcher),
+		downloadFileCapability: NewDownloadFileCapability(factory.HttpClient, glClientBuilder, spConfig.ServiceProviderBaseUrl, repoUrlMatcher, factory.Configuration.LookupFileLastModified),
+		downloadTreeCapability: NewDownloadTreeCapability(factory.HttpClient, glClientBuilder, spConfig.ServiceProviderBaseUrl, repoUrlMatcher),
 		oauthCapability:        oauthCapability,
 		repoUrlMatcher:         repoUrlMatcher,
 	}, nil
@@ -162,6 +175,10 @@ func (g *Gitlab) GetDownloadFileCapability() serviceprovider.DownloadFileCapabil
 	return g.downloadFileCapability
 }
 
+func (g *Gitlab) GetDownloadTreeCapability() serviceprovider.DownloadTreeCapability {
+	return g.downloadTreeCapability
+}
+
 func (g *Gitlab) GetRefreshTokenCapability() serviceprovider.RefreshTokenCapability {
 	return g.refreshTokenCapability
 }
@@ -217,11 +234,33 @@ func (g *Gitlab) CheckRepositoryAccess(ctx context.Context, cl client.Client, ac
 	status.Accessible = publicRepo
 	if publicRepo {
 		status.Accessibility = api.SPIAccessCheckAccessibilityPublic
-		return status, nil
+		status.Permissions = serviceprovider.CheckPermissions(func(p api.Permission) bool {
+			return permissionGranted(p, gitlab.NoPermissions, nil, true)
+		}, &accessCheck.Spec.Permissions)
+		if serviceprovider.AllPermissionsGranted(status.Permissions) {
+			return status, nil
+		}
 	}
 
 	ctx = httptransport.ContextWithMetrics(ctx, fetchRepositoryMetricConfig)
-	return g.checkPrivateRepoAccess(ctx, cl, accessCheck)
+
+	privateStatus, err := g.checkPrivateRepoAccess(ctx, cl, accessCheck)
+	if !publicRepo {
+		return privateStatus, err
+	}
+	if err != nil || privateStatus.CredentialsSource == nil || !privateStatus.Accessible {
+		// The required permissions are not satisfied anonymously, and we either didn't find any better credentials
+		// or failed to use them. The repository is still public, only the permissions stay unverified, i.e. not
+		// granted in the public status.
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to check the permissions on a public repository using credentials", "repoUrl", accessCheck.Spec.RepoUrl)
+		} else if privateStatus.ErrorReason != "" {
+			log.FromContext(ctx).Info("failed to check the permissions on a public repository using credentials", "repoUrl", accessCheck.Spec.RepoUrl, "reason", privateStatus.ErrorReason, "error", privateStatus.ErrorMessage)
+		}
+		return status, nil
+	}
+	privateStatus.Accessibility = api.SPIAccessCheckAccessibilityPublic
+	return privateStatus, nil
 }
 
 func (g *Gitlab) checkPrivateRepoAccess(ctx context.Context, cl client.Client, accessCheck *api.SPIAccessCheck) (*api.SPIAccessCheckStatus, error) {
@@ -248,6 +287,7 @@ func (g *Gitlab) checkPrivateRepoAccess(ctx context.Context, cl client.Client, a
 	if credentials == nil {
 		return status, nil
 	}
+	status.CredentialsSource = credentials.Source
 
 	gitlabClient, err := g.glClientBuilder.CreateAuthenticatedClient(ctx, *credentials)
 	if err != nil {
@@ -274,9 +314,80 @@ func (g *Gitlab) checkPrivateRepoAccess(ctx context.Context, cl client.Client, a
 	if project.Visibility == gitlab.PrivateVisibility || project.Visibility == gitlab.InternalVisibility {
 		status.Accessibility = api.SPIAccessCheckAccessibilityPrivate
 	}
+
+	accessLevel := projectAccessLevel(project)
+	status.GrantedRole = accessLevelNames[accessLevel]
+	if g.metadataProvider != nil {
+		// the scopes are not essential, so let's just log the failure
+		if status.GrantedScopes, err = g.metadataProvider.fetchScopes(ctx, gitlabClient); err != nil {
+			log.FromContext(ctx).Error(err, "failed to determine the scopes of the token used for the access check")
+		}
+	}
+	status.Permissions = serviceprovider.CheckPermissions(func(p api.Permission) bool {
+		return permissionGranted(p, accessLevel, status.GrantedScopes, project.Visibility == gitlab.PublicVisibility)
+	}, &accessCheck.Spec.Permissions)
+
 	return status, nil
 }
 
+// projectAccessLevel returns the effective access level of the user on the project, which is either given directly
+// by the membership in the project or inherited from the group.
+func projectAccessLevel(project *gitlab.Project) gitlab.AccessLevelValue {
+	level := gitlab.NoPermissions
+	if project.Permissions == nil {
+		return level
+	}
+	if project.Permissions.ProjectAccess != nil && project.Permissions.ProjectAccess.AccessLevel > level {
+		level = project.Permissions.ProjectAccess.AccessLevel
+	}
+	if project.Permissions.GroupAccess != nil && project.Permissions.GroupAccess.AccessLevel > level {
+		level = project.Permissions.GroupAccess.AccessLevel
+	}
+	return level
+}
+
+// permissionGranted checks whether the permission is satisfied by the access level on the project and the scopes of
+// the token. If the scopes are empty, they're not known and only the access level is considered.
+func permissionGranted(permission api.Permission, accessLevel gitlab.AccessLevelValue, scopes []string, publicProject bool) bool {
+	switch permission.Area {
+	case api.PermissionAreaRepository, api.PermissionAreaRepositoryMetadata, api.PermissionAreaRegistry:
+		if permission.Type.IsWrite() {
+			if accessLevel < gitlab.DeveloperPermissions {
+				return false
+			}
+		} else if publicProject {
+			// anyone can read public projects
+			return true
+		} else if accessLevel < gitlab.ReporterPermissions {
+			return false
+		}
+	case api.PermissionAreaUser:
+		// not related to the project
+	default:
+		return false
+	}
+
+	if len(scopes) == 0 {
+		return permission.Area != api.PermissionAreaUser
+	}
+	for _, required := range translateToGitlabScopes(permission) {
+		if !scopeIncluded(Scope(required), scopes) {
+			return false
+		}
+	}
+	return true
+}
+
+// scopeIncluded checks whether the scope is among the scopes either directly or implied by some other scope.
+func scopeIncluded(scope Scope, scopes []string) bool {
+	for _, s := range scopes {
+		if Scope(s).Implies(scope) {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *Gitlab) checkPublicRepoAccess(ctx context.Context, accessCheck *api.SPIAccessCheck) (bool, error) {
 	ctx = httptransport.ContextWithMetrics(ctx, publicRepoMetricConfig)
 	lg := log.FromContext(ctx)
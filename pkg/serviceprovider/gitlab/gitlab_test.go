@@ -33,6 +33,7 @@ import (
 
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
 	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/util"
+	"github.com/xanzy/go-gitlab"
 	"golang.org/x/oauth2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -313,10 +314,39 @@ func TestCheckRepositoryAccess_Uses_RemoteSecret(t *testing.T) {
 	assert.Equal(t, api.SPIRepoTypeGit, status.Type)
 	assert.Equal(t, api.ServiceProviderTypeGitLab, status.ServiceProvider)
 	assert.Equal(t, api.SPIAccessCheckAccessibilityPrivate, status.Accessibility)
+	assert.Equal(t, &api.CredentialsSource{Kind: api.CredentialsSourceKindRemoteSecret, Name: "rs"}, status.CredentialsSource)
 	assert.Empty(t, status.ErrorReason)
 	assert.Empty(t, status.ErrorMessage)
 }
 
+func TestProjectAccessLevel(t *testing.T) {
+	assert.Equal(t, gitlab.NoPermissions, projectAccessLevel(&gitlab.Project{}))
+	assert.Equal(t, gitlab.DeveloperPermissions, projectAccessLevel(&gitlab.Project{Permissions: &gitlab.Permissions{
+		ProjectAccess: &gitlab.ProjectAccess{AccessLevel: gitlab.DeveloperPermissions},
+		GroupAccess:   &gitlab.GroupAccess{AccessLevel: gitlab.ReporterPermissions},
+	}}))
+	assert.Equal(t, gitlab.MaintainerPermissions, projectAccessLevel(&gitlab.Project{Permissions: &gitlab.Permissions{
+		GroupAccess: &gitlab.GroupAccess{AccessLevel: gitlab.MaintainerPermissions},
+	}}))
+}
+
+func TestPermissionGranted(t *testing.T) {
+	read := api.Permission{Type: api.PermissionTypeRead, Area: api.PermissionAreaRepository}
+	write := api.Permission{Type: api.PermissionTypeWrite, Area: api.PermissionAreaRepository}
+	user := api.Permission{Type: api.PermissionTypeRead, Area: api.PermissionAreaUser}
+
+	assert.True(t, permissionGranted(read, gitlab.NoPermissions, nil, true))
+	assert.False(t, permissionGranted(read, gitlab.GuestPermissions, nil, false))
+	assert.True(t, permissionGranted(read, gitlab.ReporterPermissions, nil, false))
+	assert.False(t, permissionGranted(write, gitlab.ReporterPermissions, nil, true))
+	assert.True(t, permissionGranted(write, gitlab.DeveloperPermissions, nil, false))
+	assert.True(t, permissionGranted(write, gitlab.DeveloperPermissions, []string{string(ScopeWriteRepository)}, false))
+	assert.False(t, permissionGranted(write, gitlab.DeveloperPermissions, []string{string(ScopeReadRepository)}, false))
+	assert.False(t, permissionGranted(user, gitlab.OwnerPermissions, nil, false))
+	assert.True(t, permissionGranted(user, gitlab.NoPermissions, []string{string(ScopeReadUser)}, false))
+	assert.False(t, permissionGranted(api.Permission{Type: api.PermissionTypeWrite, Area: api.PermissionAreaWebhooks}, gitlab.OwnerPermissions, nil, false))
+}
+
 func mockGitlab(cl client.Client, returnCode, authClientReturnCode int, body string, responseError, lookupError error) *Gitlab {
 	metadataCache := serviceprovider.MetadataCache{
 		Client:                    cl,
@@ -112,19 +112,11 @@ func (p metadataProvider) doFetch(ctx context.Context, token *api.SPIAccessToken
 		return nil, err
 	}
 
-	scopes, err := p.fetchOAuthScopes(ctx, glClient)
+	scopes, err := p.fetchScopes(ctx, glClient)
 	if err != nil {
 		return nil, err
 	}
 
-	if scopes == nil {
-		lg.Info("could not obtain token scopes from OAuth API, proceeding to PAT API")
-		scopes, err = p.fetchPATScopes(ctx, glClient)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	// TODO: In the future we can figure out scopes by making request for different resources similarly to how we do it with Quay.
 	lg.V(logs.DebugLevel).Info("fetched user metadata from GitLab", "login", username, "userid", userId, "scopes", scopes)
 
@@ -168,6 +160,25 @@ func (p metadataProvider) fetchUser(ctx context.Context, gitlabClient *gitlab.Cl
 	return usr.Username, strconv.FormatInt(int64(usr.ID), 10), nil
 }
 
+// fetchScopes returns the scopes of the token the client is authenticated with. The token can be either an OAuth token
+// or a personal access token.
+func (p metadataProvider) fetchScopes(ctx context.Context, gitlabClient *gitlab.Client) ([]string, error) {
+	scopes, err := p.fetchOAuthScopes(ctx, gitlabClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if scopes == nil {
+		log.FromContext(ctx).Info("could not obtain token scopes from OAuth API, proceeding to PAT API")
+		scopes, err = p.fetchPATScopes(ctx, gitlabClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return scopes, nil
+}
+
 func (p metadataProvider) fetchOAuthScopes(ctx context.Context, gitlabClient *gitlab.Client) ([]string, error) {
 	lg := log.FromContext(ctx)
 	tokenInfoResponse := struct {
@@ -0,0 +1,57 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redhat-appstudio/remote-secret/pkg/httptransport"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/xanzy/go-gitlab"
+)
+
+var listRepositoriesMetricConfig = serviceprovider.CommonRequestMetricsConfig(config.ServiceProviderTypeGitLab, "list_repos")
+
+var _ serviceprovider.RepositoryListingCapability = (*Gitlab)(nil)
+
+// ListRepositories implements serviceprovider.RepositoryListingCapability. It lists all the projects the user is
+// a member of, either directly or through a group.
+func (g *Gitlab) ListRepositories(ctx context.Context, credentials serviceprovider.Credentials) ([]api.SPIRepositoryListItem, error) {
+	ctx = httptransport.ContextWithMetrics(ctx, listRepositoriesMetricConfig)
+
+	gitlabClient, err := g.glClientBuilder.CreateAuthenticatedClient(ctx, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the authenticated GitLab client: %w", err)
+	}
+
+	projects, err := memberProjects(ctx, gitlabClient, gitlab.GuestPermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := make([]api.SPIRepositoryListItem, 0, len(projects))
+	for _, p := range projects {
+		repositories = append(repositories, api.SPIRepositoryListItem{
+			RepoUrl:  p.WebURL,
+			FullName: p.PathWithNamespace,
+			Private:  p.Visibility != gitlab.PublicVisibility,
+			Role:     accessLevelNames[projectAccessLevel(p)],
+		})
+	}
+	return repositories, nil
+}
@@ -0,0 +1,55 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListRepositories(t *testing.T) {
+	g := &Gitlab{
+		glClientBuilder: gitlabClientBuilder{
+			gitlabBaseUrl: "https://gitlab.com",
+			httpClient: &http.Client{
+				Transport: util.FakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+					assert.Equal(t, "true", r.URL.Query().Get("membership"))
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{},
+						Body: io.NopCloser(bytes.NewBufferString(`[{"path_with_namespace": "acme/app", "web_url": "https://gitlab.com/acme/app", "visibility": "private",
+							"permissions": {"project_access": {"access_level": 30}}}]`)),
+						Request: r,
+					}, nil
+				}),
+			},
+		},
+	}
+
+	repositories, err := g.ListRepositories(context.TODO(), serviceprovider.Credentials{Token: "token"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []api.SPIRepositoryListItem{
+		{RepoUrl: "https://gitlab.com/acme/app", FullName: "acme/app", Private: true, Role: "Developer"},
+	}, repositories)
+}
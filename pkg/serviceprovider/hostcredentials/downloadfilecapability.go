@@ -0,0 +1,495 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostcredentials
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/sideband"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	uploadPackService             = "git-upload-pack"
+	uploadPackAdvertisementType   = "application/x-" + uploadPackService + "-advertisement"
+	uploadPackRequestContentType  = "application/x-" + uploadPackService + "-request"
+	uploadPackResultContentType   = "application/x-" + uploadPackService + "-result"
+	defaultRepositoryUrlScheme    = "https://"
+	refsPrefix                    = "refs/"
+	branchRefsPrefix              = "refs/heads/"
+	tagRefsPrefix                 = "refs/tags/"
+	gitRequestFailedMessage       = "request to the git server failed"
+	parsingUploadPackFailedFormat = "failed to parse the response of the git server: %w"
+	// defaultMaxPackSize is the maximum number of bytes read from the git server in a single fetch. This protects
+	// the operator from exhausting its memory on huge repositories, e.g. when the server doesn't support filtering.
+	defaultMaxPackSize = 64 * 1024 * 1024
+)
+
+var (
+	fileSizeLimitExceededError = errors.New("failed to retrieve file: size too big")
+	fileNotFoundError          = errors.New("the file does not exist in the repository")
+	refNotFoundError           = errors.New("the ref was not found in the repository")
+	unexpectedStatusCodeError  = errors.New("unexpected status code from the git server")
+	smartHttpNotSupportedError = errors.New("the git server does not support the smart HTTP protocol")
+	packSizeLimitExceededError = errors.New("the response of the git server exceeds the maximum size")
+
+	commitShaRegex = regexp.MustCompile("^[0-9a-f]{40}$")
+)
+
+// downloadFileCapability is a generic implementation of the serviceprovider.DownloadFileCapability for any git server
+// speaking the smart HTTP protocol. It does a shallow fetch of the requested ref and extracts the file from the received
+// packfile in memory. If the server supports filtering and fetching reachable objects by their hash, only the trees of
+// the commit are fetched first and then the single blob of the file. If the server only supports filtering, the blobs
+// bigger than the size limit are not even sent.
+type downloadFileCapability struct {
+	httpClient rest.HTTPClient
+	// maxPackSize is the maximum number of bytes read from the git server in a single fetch. If not positive,
+	// defaultMaxPackSize is used.
+	maxPackSize int64
+}
+
+// fetchRequest describes the objects requested from the git server.
+type fetchRequest struct {
+	// want is the hash of the requested object
+	want plumbing.Hash
+	// deepen requests only the wanted commit without its history
+	deepen bool
+	// filter is the object filter of the partial clone, if supported by the server
+	filter string
+}
+
+var _ serviceprovider.DownloadFileCapability = (*downloadFileCapability)(nil)
+var _ serviceprovider.RefResolvingCapability = (*downloadFileCapability)(nil)
+
+func (d downloadFileCapability) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxFileSizeLimit int) (serviceprovider.DownloadedFile, error) {
+	repoUrl := repositoryUrl(request.RepoUrl)
+
+	refs, err := d.advertisedRefs(ctx, repoUrl, credentials)
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, err
+	}
+
+	commitHash, err := resolveRef(refs, request.Ref)
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, err
+	}
+
+	caps := refs.Capabilities
+	filtering := caps.Supports(capability.Filter)
+	// with both capabilities, we can postpone the download of the file until we know which blob it is
+	blobSeparately := filtering && caps.Supports(capability.AllowReachableSHA1InWant)
+
+	commitFetch := fetchRequest{want: commitHash, deepen: caps.Supports(capability.Shallow)}
+	if blobSeparately {
+		commitFetch.filter = "blob:none"
+	} else if filtering {
+		commitFetch.filter = blobLimitFilter(maxFileSizeLimit)
+	}
+
+	storage, err := d.fetch(ctx, repoUrl, credentials, refs, commitFetch, maxFileSizeLimit)
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, err
+	}
+
+	commit, err := object.GetCommit(storage, commitHash)
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("the git server did not send the commit %s: %w", commitHash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("the git server did not send the tree of the commit %s: %w", commitHash, err)
+	}
+
+	filePath := strings.TrimPrefix(path.Clean("/"+request.FilePath), "/")
+	entry, err := tree.FindEntry(filePath)
+	if err != nil || !entry.Mode.IsFile() {
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: %s", fileNotFoundError, request.FilePath)
+	}
+
+	if blobSeparately {
+		storage, err = d.fetch(ctx, repoUrl, credentials, refs, fetchRequest{want: entry.Hash, filter: blobLimitFilter(maxFileSizeLimit)}, maxFileSizeLimit)
+		if err != nil {
+			return serviceprovider.DownloadedFile{}, err
+		}
+	}
+
+	blob, err := object.GetBlob(storage, entry.Hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		// the blob was either filtered out by the server or dropped while reading the packfile
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: (more than %d)", fileSizeLimitExceededError, maxFileSizeLimit)
+	} else if err != nil {
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("failed to read the file %s: %w", request.FilePath, err)
+	}
+	if blob.Size > int64(maxFileSizeLimit) {
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: (%d)", fileSizeLimitExceededError, blob.Size)
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("failed to read the file %s: %w", request.FilePath, err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(io.LimitReader(reader, int64(maxFileSizeLimit)))
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, fmt.Errorf("failed to read the file %s: %w", request.FilePath, err)
+	}
+
+	return serviceprovider.DownloadedFile{
+		Content: string(content),
+		Metadata: api.SPIFileContentMetadata{
+			CommitSha: commitHash.String(),
+			BlobSha:   entry.Hash.String(),
+			Size:      len(content),
+			FilePath:  filePath,
+		},
+	}, nil
+}
+
+// ResolveRef implements serviceprovider.RefResolvingCapability. The ref is resolved using the references advertised by
+// the git server, so no objects need to be transferred.
+func (d downloadFileCapability) ResolveRef(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, _ string) (string, error) {
+	refs, err := d.advertisedRefs(ctx, repositoryUrl(request.RepoUrl), credentials)
+	if err != nil {
+		return "", err
+	}
+
+	commitHash, err := resolveRef(refs, request.Ref)
+	if err != nil {
+		return "", err
+	}
+	return commitHash.String(), nil
+}
+
+// repositoryUrl returns the URL of the repository without the trailing slash. Repository URLs without a scheme are
+// assumed to use https.
+func repositoryUrl(repoUrl string) string {
+	if !strings.Contains(repoUrl, "://") {
+		repoUrl = defaultRepositoryUrlScheme + repoUrl
+	}
+	return strings.TrimSuffix(repoUrl, "/")
+}
+
+// resolveRef finds the commit the ref points to. The ref can be a full name of the reference, a branch, a tag or a full
+// commit SHA. An empty ref means HEAD.
+func resolveRef(refs *packp.AdvRefs, ref string) (plumbing.Hash, error) {
+	if ref == "" {
+		if refs.Head == nil {
+			return plumbing.ZeroHash, fmt.Errorf("%w: HEAD", refNotFoundError)
+		}
+		return *refs.Head, nil
+	}
+
+	candidates := []string{branchRefsPrefix + ref, tagRefsPrefix + ref}
+	if strings.HasPrefix(ref, refsPrefix) {
+		candidates = []string{ref}
+	}
+	for _, name := range candidates {
+		// annotated tags are advertised also with the commit they point to
+		if hash, ok := refs.Peeled[name]; ok {
+			return hash, nil
+		}
+		if hash, ok := refs.References[name]; ok {
+			return hash, nil
+		}
+	}
+
+	if commitShaRegex.MatchString(ref) {
+		return plumbing.NewHash(ref), nil
+	}
+
+	return plumbing.ZeroHash, fmt.Errorf("%w: %s", refNotFoundError, ref)
+}
+
+func (d downloadFileCapability) advertisedRefs(ctx context.Context, repoUrl string, credentials serviceprovider.Credentials) (*packp.AdvRefs, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, repoUrl+"/info/refs?service="+uploadPackService, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compose the git server request: %w", err)
+	}
+
+	resp, err := d.do(req, credentials)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), uploadPackAdvertisementType) {
+		return nil, fmt.Errorf("%w: %s", smartHttpNotSupportedError, repoUrl)
+	}
+
+	refs := packp.NewAdvRefs()
+	if err := refs.Decode(resp.Body); err != nil {
+		return nil, fmt.Errorf("failed to parse the references advertised by the git server: %w", err)
+	}
+	return refs, nil
+}
+
+// blobLimitFilter returns the object filter that makes the git server omit the blobs bigger than the size limit.
+func blobLimitFilter(maxFileSizeLimit int) string {
+	// blob:limit=n filters out the blobs of size n or more
+	return fmt.Sprintf("blob:limit=%d", maxFileSizeLimit+1)
+}
+
+// fetch fetches the requested objects and returns the storage with the received objects.
+func (d downloadFileCapability) fetch(ctx context.Context, repoUrl string, credentials serviceprovider.Credentials, refs *packp.AdvRefs, request fetchRequest, maxFileSizeLimit int) (*memory.Storage, error) {
+	caps := refs.Capabilities
+
+	requested := []string{}
+	for _, c := range []capability.Capability{capability.OFSDelta, capability.NoProgress, capability.Shallow, capability.Filter} {
+		if caps.Supports(c) {
+			requested = append(requested, c.String())
+		}
+	}
+	var sidebandType *sideband.Type
+	if caps.Supports(capability.Sideband64k) {
+		t := sideband.Sideband64k
+		sidebandType = &t
+		requested = append(requested, capability.Sideband64k.String())
+	} else if caps.Supports(capability.Sideband) {
+		t := sideband.Sideband
+		sidebandType = &t
+		requested = append(requested, capability.Sideband.String())
+	}
+
+	body := &bytes.Buffer{}
+	enc := pktline.NewEncoder(body)
+	if err := enc.Encodef("want %s %s\n", request.want, strings.Join(requested, " ")); err != nil {
+		return nil, fmt.Errorf("failed to compose the fetch request: %w", err)
+	}
+	if request.deepen {
+		if err := enc.Encodef("deepen 1\n"); err != nil {
+			return nil, fmt.Errorf("failed to compose the fetch request: %w", err)
+		}
+	}
+	if request.filter != "" && caps.Supports(capability.Filter) {
+		if err := enc.Encodef("filter %s\n", request.filter); err != nil {
+			return nil, fmt.Errorf("failed to compose the fetch request: %w", err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to compose the fetch request: %w", err)
+	}
+	if err := enc.EncodeString("done\n"); err != nil {
+		return nil, fmt.Errorf("failed to compose the fetch request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, repoUrl+"/"+uploadPackService, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compose the git server request: %w", err)
+	}
+	req.Header.Set("Content-Type", uploadPackRequestContentType)
+	req.Header.Set("Accept", uploadPackResultContentType)
+
+	log.FromContext(ctx).V(logs.DebugLevel).Info("fetching objects from the git server", "repoUrl", repoUrl, "want", request.want.String(), "filter", request.filter, "capabilities", requested)
+
+	resp, err := d.do(req, credentials)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	maxPackSize := d.maxPackSize
+	if maxPackSize <= 0 {
+		maxPackSize = defaultMaxPackSize
+	}
+	buf := bufio.NewReader(&limitedReader{reader: resp.Body, remaining: maxPackSize})
+	if request.deepen {
+		shallowUpdate := packp.ShallowUpdate{}
+		if err := shallowUpdate.Decode(buf); err != nil {
+			return nil, fmt.Errorf(parsingUploadPackFailedFormat, err)
+		}
+	}
+	serverResponse := packp.ServerResponse{}
+	if err := serverResponse.Decode(buf, false); err != nil {
+		return nil, fmt.Errorf(parsingUploadPackFailedFormat, err)
+	}
+
+	var pack io.Reader = buf
+	if sidebandType != nil {
+		pack = sideband.NewDemuxer(*sidebandType, buf)
+	}
+
+	return readPack(pack, maxFileSizeLimit)
+}
+
+// limitedReader fails with packSizeLimitExceededError once more than the given number of bytes is read. Unlike
+// io.LimitReader, it doesn't pretend that the data ended, so that the reason of the failure is clear.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, packSizeLimitExceededError
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	return n, err //nolint:wrapcheck // the errors of the underlying reader are passed through unchanged
+}
+
+// readPack reads the objects from the packfile into an in-memory storage. The blobs bigger than the size limit are
+// discarded while streaming the packfile, so that they never occupy the memory.
+func readPack(pack io.Reader, maxFileSizeLimit int) (*memory.Storage, error) {
+	storage := memory.NewStorage()
+	scanner := packfile.NewScanner(pack)
+
+	_, count, err := scanner.Header()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the packfile: %w", err)
+	}
+
+	// the hashes and types of the stored objects by their offset in the packfile, needed to resolve the deltas
+	hashes := map[int64]plumbing.Hash{}
+	types := map[int64]plumbing.ObjectType{}
+
+	for i := uint32(0); i < count; i++ {
+		header, err := scanner.NextObjectHeader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the packfile: %w", err)
+		}
+
+		var base plumbing.Hash
+		objectType := header.Type
+		switch header.Type {
+		case plumbing.OFSDeltaObject:
+			base = hashes[header.OffsetReference]
+			objectType = types[header.OffsetReference]
+		case plumbing.REFDeltaObject:
+			base = header.Reference
+			if baseObject, err := storage.EncodedObject(plumbing.AnyObject, base); err == nil {
+				objectType = baseObject.Type()
+			}
+		}
+
+		if (objectType == plumbing.BlobObject && header.Length > int64(maxFileSizeLimit)) || (header.Type.IsDelta() && base.IsZero()) {
+			// too big or a delta of an object that was discarded
+			if _, _, err := scanner.NextObject(io.Discard); err != nil {
+				return nil, fmt.Errorf("failed to read the packfile: %w", err)
+			}
+			continue
+		}
+
+		data := &bytes.Buffer{}
+		if _, _, err := scanner.NextObject(data); err != nil {
+			return nil, fmt.Errorf("failed to read the packfile: %w", err)
+		}
+
+		content := data.Bytes()
+		if header.Type.IsDelta() {
+			if content, err = patchDelta(storage, base, content); err != nil {
+				if errors.Is(err, plumbing.ErrObjectNotFound) {
+					continue
+				}
+				return nil, err
+			}
+			if objectType == plumbing.BlobObject && len(content) > maxFileSizeLimit {
+				continue
+			}
+		}
+
+		obj := storage.NewEncodedObject()
+		obj.SetType(objectType)
+		obj.SetSize(int64(len(content)))
+		w, err := obj.Writer()
+		if err != nil {
+			return nil, fmt.Errorf("failed to store the object: %w", err)
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, fmt.Errorf("failed to store the object: %w", err)
+		}
+		hash, err := storage.SetEncodedObject(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store the object: %w", err)
+		}
+
+		hashes[header.Offset] = hash
+		types[header.Offset] = objectType
+	}
+
+	return storage, nil
+}
+
+func patchDelta(storage *memory.Storage, base plumbing.Hash, delta []byte) ([]byte, error) {
+	baseObject, err := storage.EncodedObject(plumbing.AnyObject, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the base of the delta: %w", err)
+	}
+	reader, err := baseObject.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the base of the delta: %w", err)
+	}
+	defer reader.Close()
+	baseContent, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the base of the delta: %w", err)
+	}
+
+	content, err := packfile.PatchDelta(baseContent, delta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply the delta: %w", err)
+	}
+	return content, nil
+}
+
+// do performs the request with the basic auth credentials and checks the response was successful.
+func (d downloadFileCapability) do(req *http.Request, credentials serviceprovider.Credentials) (*http.Response, error) {
+	if credentials.Username != "" || credentials.Token != "" {
+		req.SetBasicAuth(credentials.Username, credentials.Token)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", gitRequestFailedMessage, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			rlErr := &serviceprovider.RateLimitedError{Cause: fmt.Errorf("%w: %d", unexpectedStatusCodeError, resp.StatusCode)}
+			if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				rlErr.ResetTime = time.Now().Add(time.Duration(retryAfter) * time.Second)
+			}
+			return nil, rlErr
+		}
+		return nil, fmt.Errorf("%s: %w: %d", gitRequestFailedMessage, unexpectedStatusCodeError, resp.StatusCode)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,318 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostcredentials
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/sideband"
+	"github.com/go-git/go-git/v5/storage/memory"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRepoUrl = "https://git.acme.com/org/repo.git"
+
+type testRepository struct {
+	storage *memory.Storage
+	commit  plumbing.Hash
+	bigBlob plumbing.Hash
+	objects []plumbing.Hash
+	// sent records the objects sent by the server in the packfiles
+	sent []plumbing.Hash
+}
+
+func newTestRepository(t *testing.T) *testRepository {
+	repo := &testRepository{storage: memory.NewStorage()}
+
+	blob := func(content string) plumbing.Hash {
+		obj := repo.storage.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+		return repo.store(t, obj)
+	}
+	encode := func(o interface {
+		Encode(plumbing.EncodedObject) error
+	}) plumbing.Hash {
+		obj := repo.storage.NewEncodedObject()
+		assert.NoError(t, o.Encode(obj))
+		return repo.store(t, obj)
+	}
+
+	readme := blob("# Repository\n\nThis is the readme of the repository used in the tests.\n")
+	guide := blob("# Repository\n\nThis is the guide of the repository used in the tests.\n")
+	repo.bigBlob = blob(strings.Repeat("big", 100))
+
+	docs := encode(&object.Tree{Entries: []object.TreeEntry{
+		{Name: "big.bin", Mode: filemode.Regular, Hash: repo.bigBlob},
+		{Name: "guide.md", Mode: filemode.Regular, Hash: guide},
+	}})
+	root := encode(&object.Tree{Entries: []object.TreeEntry{
+		{Name: "README.md", Mode: filemode.Regular, Hash: readme},
+		{Name: "docs", Mode: filemode.Dir, Hash: docs},
+	}})
+	signature := object.Signature{Name: "Alois", Email: "alois@acme.com", When: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+	repo.commit = encode(&object.Commit{Author: signature, Committer: signature, Message: "initial", TreeHash: root})
+
+	return repo
+}
+
+func (r *testRepository) store(t *testing.T, obj plumbing.EncodedObject) plumbing.Hash {
+	hash, err := r.storage.SetEncodedObject(obj)
+	assert.NoError(t, err)
+	r.objects = append(r.objects, hash)
+	return hash
+}
+
+// client returns an HTTP client acting as a smart HTTP git server with the provided capabilities.
+func (r *testRepository) client(t *testing.T, caps ...capability.Capability) *http.Client {
+	return &http.Client{
+		Transport: util.FakeRoundTrip(func(req *http.Request) (*http.Response, error) {
+			user, password, _ := req.BasicAuth()
+			if user != "alois" || password != "secret" {
+				return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}
+
+			body := &bytes.Buffer{}
+			header := http.Header{}
+
+			switch req.URL.Path {
+			case "/org/repo.git/info/refs":
+				header.Set("Content-Type", uploadPackAdvertisementType)
+				refs := packp.NewAdvRefs()
+				refs.Prefix = [][]byte{[]byte("# service=git-upload-pack"), pktline.Flush}
+				refs.Head = &r.commit
+				refs.References["refs/heads/main"] = r.commit
+				refs.References["refs/tags/v1"] = plumbing.NewHash("1111111111111111111111111111111111111111")
+				refs.Peeled["refs/tags/v1"] = r.commit
+				for _, c := range caps {
+					assert.NoError(t, refs.Capabilities.Add(c))
+				}
+				assert.NoError(t, refs.Encode(body))
+			case "/org/repo.git/git-upload-pack":
+				request, err := io.ReadAll(req.Body)
+				assert.NoError(t, err)
+				objects := r.wantedObjects(t, string(request))
+				r.sent = append(r.sent, objects...)
+
+				enc := pktline.NewEncoder(body)
+				if strings.Contains(string(request), "deepen 1") {
+					assert.NoError(t, enc.Encodef("shallow %s\n", r.commit))
+					assert.NoError(t, enc.Flush())
+				}
+				assert.NoError(t, enc.EncodeString("NAK\n"))
+
+				var pack io.Writer = body
+				if strings.Contains(string(request), capability.Sideband64k.String()) {
+					pack = sideband.NewMuxer(sideband.Sideband64k, body)
+				}
+				_, err = packfile.NewEncoder(pack, r.storage, false).Encode(objects, 10)
+				assert.NoError(t, err)
+				if pack != body {
+					assert.NoError(t, enc.Flush())
+				}
+			default:
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}
+
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(body)}, nil
+		}),
+	}
+}
+
+// wantedObjects returns the objects the server sends for the fetch request. Wanting the commit means the whole
+// repository, wanting any other object means just that object. The filters are applied on top of it.
+func (r *testRepository) wantedObjects(t *testing.T, request string) []plumbing.Hash {
+	idx := strings.Index(request, "want ")
+	if !assert.True(t, idx >= 0) {
+		return nil
+	}
+	want := plumbing.NewHash(request[idx+5 : idx+45])
+
+	candidates := []plumbing.Hash{want}
+	if want == r.commit {
+		candidates = r.objects
+	} else {
+		assert.Contains(t, r.objects, want)
+	}
+
+	objects := []plumbing.Hash{}
+	for _, o := range candidates {
+		obj, err := r.storage.EncodedObject(plumbing.AnyObject, o)
+		assert.NoError(t, err)
+		if obj.Type() == plumbing.BlobObject && strings.Contains(request, "filter blob:none") {
+			continue
+		}
+		if o == r.bigBlob && strings.Contains(request, "filter blob:limit=") {
+			// let's pretend the server filtered out the big blob
+			continue
+		}
+		objects = append(objects, o)
+	}
+	return objects
+}
+
+func TestDownloadFile(t *testing.T) {
+	repo := newTestRepository(t)
+	creds := serviceprovider.Credentials{Username: "alois", Token: "secret"}
+
+	download := func(cl *http.Client, ref, filePath string, limit int) (serviceprovider.DownloadedFile, error) {
+		return downloadFileCapability{httpClient: cl}.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
+			RepoUrl:  testRepoUrl,
+			Ref:      ref,
+			FilePath: filePath,
+		}, creds, limit)
+	}
+
+	fullCaps := []capability.Capability{capability.OFSDelta, capability.Shallow, capability.Filter, capability.Sideband64k, capability.NoProgress}
+
+	t.Run("file", func(t *testing.T) {
+		file, err := download(repo.client(t, fullCaps...), "", "docs/guide.md", 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, "# Repository\n\nThis is the guide of the repository used in the tests.\n", file.Content)
+		assert.Equal(t, repo.commit.String(), file.Metadata.CommitSha)
+		assert.NotEmpty(t, file.Metadata.BlobSha)
+		assert.Equal(t, len(file.Content), file.Metadata.Size)
+		assert.Equal(t, "docs/guide.md", file.Metadata.FilePath)
+	})
+
+	t.Run("file fetched separately", func(t *testing.T) {
+		repo.sent = nil
+		file, err := download(repo.client(t, append(fullCaps, capability.AllowReachableSHA1InWant)...), "", "docs/guide.md", 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, "# Repository\n\nThis is the guide of the repository used in the tests.\n", file.Content)
+		assert.Equal(t, repo.commit.String(), file.Metadata.CommitSha)
+
+		// only the commit, the trees and the blob of the file were sent
+		assert.Len(t, repo.sent, 4)
+		assert.Contains(t, repo.sent, repo.commit)
+		assert.Contains(t, repo.sent, plumbing.NewHash(file.Metadata.BlobSha))
+		assert.NotContains(t, repo.sent, repo.bigBlob)
+	})
+
+	t.Run("size limit fetched separately", func(t *testing.T) {
+		_, err := download(repo.client(t, append(fullCaps, capability.AllowReachableSHA1InWant)...), "", "docs/big.bin", 100)
+		assert.True(t, errors.Is(err, fileSizeLimitExceededError))
+	})
+
+	t.Run("pack size limit", func(t *testing.T) {
+		_, err := downloadFileCapability{httpClient: repo.client(t), maxPackSize: 100}.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
+			RepoUrl:  testRepoUrl,
+			FilePath: "README.md",
+		}, creds, 1024)
+		assert.True(t, errors.Is(err, packSizeLimitExceededError))
+	})
+
+	t.Run("server without optional capabilities", func(t *testing.T) {
+		file, err := download(repo.client(t), "main", "/README.md", 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, "# Repository\n\nThis is the readme of the repository used in the tests.\n", file.Content)
+	})
+
+	t.Run("annotated tag", func(t *testing.T) {
+		file, err := download(repo.client(t, fullCaps...), "v1", "README.md", 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, repo.commit.String(), file.Metadata.CommitSha)
+	})
+
+	t.Run("size limit with filtering", func(t *testing.T) {
+		_, err := download(repo.client(t, fullCaps...), "", "docs/big.bin", 100)
+		assert.True(t, errors.Is(err, fileSizeLimitExceededError))
+	})
+
+	t.Run("size limit without filtering", func(t *testing.T) {
+		_, err := download(repo.client(t), "", "docs/big.bin", 100)
+		assert.True(t, errors.Is(err, fileSizeLimitExceededError))
+
+		// the other files are still available
+		_, err = download(repo.client(t), "", "docs/guide.md", 100)
+		assert.NoError(t, err)
+	})
+
+	t.Run("file not found", func(t *testing.T) {
+		_, err := download(repo.client(t, fullCaps...), "", "docs/missing.md", 1024)
+		assert.True(t, errors.Is(err, fileNotFoundError))
+
+		_, err = download(repo.client(t, fullCaps...), "", "docs", 1024)
+		assert.True(t, errors.Is(err, fileNotFoundError))
+	})
+
+	t.Run("ref not found", func(t *testing.T) {
+		_, err := download(repo.client(t, fullCaps...), "feature", "README.md", 1024)
+		assert.True(t, errors.Is(err, refNotFoundError))
+	})
+
+	t.Run("bad credentials", func(t *testing.T) {
+		_, err := downloadFileCapability{httpClient: repo.client(t)}.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
+			RepoUrl:  testRepoUrl,
+			FilePath: "README.md",
+		}, serviceprovider.Credentials{Username: "alois", Token: "wrong"}, 1024)
+		assert.True(t, errors.Is(err, unexpectedStatusCodeError))
+	})
+
+	t.Run("dumb http", func(t *testing.T) {
+		cl := &http.Client{
+			Transport: util.FakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}),
+		}
+		_, err := download(cl, "", "README.md", 1024)
+		assert.True(t, errors.Is(err, smartHttpNotSupportedError))
+	})
+}
+
+func TestResolveRef(t *testing.T) {
+	repo := newTestRepository(t)
+	capability := downloadFileCapability{httpClient: repo.client(t)}
+	creds := serviceprovider.Credentials{Username: "alois", Token: "secret"}
+
+	test := func(ref string, expected string) {
+		t.Run(ref, func(t *testing.T) {
+			sha, err := capability.ResolveRef(context.TODO(), api.SPIFileContentRequestSpec{RepoUrl: testRepoUrl, Ref: ref}, creds, "")
+			assert.NoError(t, err)
+			assert.Equal(t, expected, sha)
+		})
+	}
+
+	test("", repo.commit.String())
+	test("main", repo.commit.String())
+	test("refs/heads/main", repo.commit.String())
+	test("v1", repo.commit.String())
+	test("2222222222222222222222222222222222222222", "2222222222222222222222222222222222222222")
+}
+
+func TestRepositoryUrl(t *testing.T) {
+	assert.Equal(t, "https://git.acme.com/repo", repositoryUrl("git.acme.com/repo/"))
+	assert.Equal(t, "http://git.acme.com/repo.git", repositoryUrl("http://git.acme.com/repo.git"))
+}
@@ -36,6 +36,8 @@ type HostCredentialsProvider struct {
 	lookup        serviceprovider.GenericLookup
 	httpClient    rest.HTTPClient
 	repoUrl       string
+
+	downloadFileCapability serviceprovider.DownloadFileCapability
 }
 
 // Note that given provider doesn't have any kind of probes, since it is used
@@ -62,6 +64,8 @@ func newHostCredentialsProvider(factory *serviceprovider.Factory, spConfig *conf
 		},
 		httpClient: factory.HttpClient,
 		repoUrl:    spConfig.ServiceProviderBaseUrl,
+
+		downloadFileCapability: downloadFileCapability{httpClient: factory.HttpClient},
 	}, nil
 }
 
@@ -104,6 +108,10 @@ func (g *HostCredentialsProvider) PersistMetadata(ctx context.Context, _ client.
 }
 
 func (g *HostCredentialsProvider) GetDownloadFileCapability() serviceprovider.DownloadFileCapability {
+	return g.downloadFileCapability
+}
+
+func (g *HostCredentialsProvider) GetDownloadTreeCapability() serviceprovider.DownloadTreeCapability {
 	return nil
 }
 
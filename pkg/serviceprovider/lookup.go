@@ -102,11 +102,35 @@ func (l GenericLookup) Lookup(ctx context.Context, cl client.Client, matchable M
 
 	lg.V(logs.DebugLevel).Info("lookup", "potential_matches", len(potentialMatches.Items))
 
+	result, err = l.filterMatchingTokens(ctx, matchable, potentialMatches.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		// only consider the tokens shared from other namespaces if there is no suitable token in the namespace itself
+		result, err = l.lookupSharedTokens(ctx, cl, matchable, repoUrl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lg.V(logs.DebugLevel).Info("lookup finished", "matching_tokens", len(result))
+
+	return result, nil
+}
+
+// filterMatchingTokens returns the tokens from the candidates that are ready and match the matchable according to
+// the TokenFilter. The metadata of the candidate tokens is refreshed if needed.
+func (l GenericLookup) filterMatchingTokens(ctx context.Context, matchable Matchable, candidates []api.SPIAccessToken) ([]api.SPIAccessToken, error) {
+	lg := log.FromContext(ctx)
+
+	var result = make([]api.SPIAccessToken, 0)
 	errs := make([]error, 0)
 
 	mutex := sync.Mutex{}
 	wg := sync.WaitGroup{}
-	for _, t := range potentialMatches.Items {
+	for _, t := range candidates {
 		if t.Status.Phase != api.SPIAccessTokenPhaseReady {
 			lg.V(logs.DebugLevel).Info("skipping lookup, token not ready", "token", t.Name)
 			continue
@@ -146,8 +170,6 @@ func (l GenericLookup) Lookup(ctx context.Context, cl client.Client, matchable M
 		return nil, fmt.Errorf("errors while examining the potential matches: %w", kubeerrors.NewAggregate(errs))
 	}
 
-	lg.V(logs.DebugLevel).Info("lookup finished", "matching_tokens", len(result))
-
 	return result, nil
 }
 
@@ -171,7 +193,11 @@ func (l GenericLookup) LookupCredentials(ctx context.Context, cl client.Client,
 		if tokenData == nil {
 			return nil, accessTokenNotFoundError
 		}
-		return &Credentials{Username: tokenData.Username, Token: tokenData.AccessToken}, nil
+		return &Credentials{
+			Username: tokenData.Username,
+			Token:    tokenData.AccessToken,
+			Source:   &api.CredentialsSource{Kind: api.CredentialsSourceKindSPIAccessToken, Name: tokens[0].Name},
+		}, nil
 	}
 
 	remoteSecrets, err := l.lookupRemoteSecrets(ctx, cl, matchable)
@@ -179,7 +205,7 @@ func (l GenericLookup) LookupCredentials(ctx context.Context, cl client.Client,
 		return nil, err
 	}
 
-	secret, err := l.lookupRemoteSecretSecret(ctx, cl, matchable, remoteSecrets)
+	secret, remoteSecret, err := l.lookupRemoteSecretSecret(ctx, cl, matchable, remoteSecrets)
 	if err != nil {
 		return nil, err
 	}
@@ -190,6 +216,7 @@ func (l GenericLookup) LookupCredentials(ctx context.Context, cl client.Client,
 	return &Credentials{
 		Username: string(secret.Data[v1.BasicAuthUsernameKey]),
 		Token:    string(secret.Data[v1.BasicAuthPasswordKey]),
+		Source:   &api.CredentialsSource{Kind: api.CredentialsSourceKindRemoteSecret, Name: remoteSecret.Name},
 	}, nil
 }
 
@@ -223,15 +250,15 @@ func (l GenericLookup) lookupRemoteSecrets(ctx context.Context, cl client.Client
 }
 
 // lookupRemoteSecretSecret finds a matching RemoteSecret based on the repoUrl of matchable. From this RemoteSecret it
-// finds and gets the target Secret from the same namespace as matchable.
-func (l GenericLookup) lookupRemoteSecretSecret(ctx context.Context, cl client.Client, matchable Matchable, remoteSecrets []v1beta1.RemoteSecret) (*v1.Secret, error) {
+// finds and gets the target Secret from the same namespace as matchable. The matching RemoteSecret is returned, too.
+func (l GenericLookup) lookupRemoteSecretSecret(ctx context.Context, cl client.Client, matchable Matchable, remoteSecrets []v1beta1.RemoteSecret) (*v1.Secret, *v1beta1.RemoteSecret, error) {
 	if len(remoteSecrets) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	repoUrl, err := l.RepoUrlParser(matchable.RepoUrl())
 	if err != nil {
-		return nil, fmt.Errorf("error parsing the repo URL %s: %w", matchable.RepoUrl(), err)
+		return nil, nil, fmt.Errorf("error parsing the repo URL %s: %w", matchable.RepoUrl(), err)
 	}
 
 	matchingRemoteSecret := remoteSecrets[0]
@@ -245,16 +272,16 @@ func (l GenericLookup) lookupRemoteSecretSecret(ctx context.Context, cl client.C
 
 	targetIndex := getLocalNamespaceTargetIndex(matchingRemoteSecret.Status.Targets, matchable.ObjNamespace())
 	if targetIndex < 0 || targetIndex >= len(matchingRemoteSecret.Status.Targets) {
-		return nil, missingTargetError // Should not happen, but avoids panicking just in case.
+		return nil, nil, missingTargetError // Should not happen, but avoids panicking just in case.
 	}
 
 	secret := &v1.Secret{}
 	err = cl.Get(ctx, client.ObjectKey{Namespace: matchable.ObjNamespace(), Name: matchingRemoteSecret.Status.Targets[targetIndex].SecretName}, secret)
 	if err != nil {
-		return nil, fmt.Errorf("unable to find Secret created by RemoteSecret: %w", err)
+		return nil, nil, fmt.Errorf("unable to find Secret created by RemoteSecret: %w", err)
 	}
 
-	return secret, nil
+	return secret, &matchingRemoteSecret, nil
 }
 
 // getLocalNamespaceTargetIndex is helper function which finds the index of a target in targets such that the target
@@ -102,6 +102,134 @@ func TestGenericLookup_Lookup(t *testing.T) {
 	assert.Equal(t, "matching", tkns[0].Name)
 }
 
+func TestGenericLookup_LookupSharedTokens(t *testing.T) {
+	sharedToken := &api.SPIAccessToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bot",
+			Namespace: "platform",
+			Labels: map[string]string{
+				api.ServiceProviderTypeLabel: "test",
+				api.ServiceProviderHostLabel: "fake.sp",
+			},
+		},
+		Status: api.SPIAccessTokenStatus{
+			Phase: api.SPIAccessTokenPhaseReady,
+		},
+	}
+	policy := &api.SPIAccessTokenSharingPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share-bot",
+		},
+		Spec: api.SPIAccessTokenSharingPolicySpec{
+			Token:               api.SharedTokenReference{Namespace: "platform", Name: "bot"},
+			TargetNamespaces:    []string{"tenant"},
+			AllowedRepositories: []string{"acme/*"},
+		},
+	}
+
+	lookup := func(cl client.Client, namespace string, repoUrl string) []api.SPIAccessToken {
+		cache := MetadataCache{
+			Client:                    cl,
+			ExpirationPolicy:          &TtlMetadataExpirationPolicy{Ttl: 1 * time.Hour},
+			CacheServiceProviderState: true,
+		}
+		gl := GenericLookup{
+			ServiceProviderType: "test",
+			TokenFilter: TokenFilterFunc(func(ctx context.Context, binding Matchable, token *api.SPIAccessToken) (bool, error) {
+				return true, nil
+			}),
+			MetadataProvider: MetadataProviderFunc(func(_ context.Context, _ *api.SPIAccessToken, _ bool) (*api.TokenMetadata, error) {
+				return &api.TokenMetadata{
+					UserId: "42",
+				}, nil
+			}),
+			MetadataCache: &cache,
+			RepoUrlParser: RepoUrlFromString,
+		}
+
+		tkns, err := gl.Lookup(context.TODO(), cl, &api.SPIAccessTokenBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+			},
+			Spec: api.SPIAccessTokenBindingSpec{
+				RepoUrl: repoUrl,
+			},
+		})
+		assert.NoError(t, err)
+		return tkns
+	}
+
+	t.Run("shared token used in target namespace", func(t *testing.T) {
+		tkns := lookup(mockK8sClient(sharedToken.DeepCopy(), policy.DeepCopy()), "tenant", "https://fake.sp/acme/repo")
+		assert.Len(t, tkns, 1)
+		assert.Equal(t, "platform", tkns[0].Namespace)
+		assert.Equal(t, "bot", tkns[0].Name)
+	})
+
+	t.Run("not shared with other namespaces", func(t *testing.T) {
+		assert.Empty(t, lookup(mockK8sClient(sharedToken.DeepCopy(), policy.DeepCopy()), "other", "https://fake.sp/acme/repo"))
+	})
+
+	t.Run("not shared for disallowed repositories", func(t *testing.T) {
+		assert.Empty(t, lookup(mockK8sClient(sharedToken.DeepCopy(), policy.DeepCopy()), "tenant", "https://fake.sp/other/repo"))
+	})
+
+	t.Run("local token preferred", func(t *testing.T) {
+		localToken := sharedToken.DeepCopy()
+		localToken.Namespace = "tenant"
+		localToken.Name = "local"
+		tkns := lookup(mockK8sClient(sharedToken.DeepCopy(), policy.DeepCopy(), localToken), "tenant", "https://fake.sp/acme/repo")
+		assert.Len(t, tkns, 1)
+		assert.Equal(t, "local", tkns[0].Name)
+	})
+}
+
+func TestIsTokenSharedWith(t *testing.T) {
+	token := &api.SPIAccessToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "bot", Namespace: "platform"},
+	}
+	policy := &api.SPIAccessTokenSharingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "share-bot"},
+		Spec: api.SPIAccessTokenSharingPolicySpec{
+			Token:               api.SharedTokenReference{Namespace: "platform", Name: "bot"},
+			TargetNamespaces:    []string{"tenant"},
+			AllowedRepositories: []string{"acme/*"},
+		},
+	}
+	binding := func(namespace, repoUrl string) *api.SPIAccessTokenBinding {
+		return &api.SPIAccessTokenBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			Spec:       api.SPIAccessTokenBindingSpec{RepoUrl: repoUrl},
+		}
+	}
+
+	t.Run("shared", func(t *testing.T) {
+		shared, err := IsTokenSharedWith(context.TODO(), mockK8sClient(policy.DeepCopy()), binding("tenant", "fake.sp/acme/repo"), token)
+		assert.NoError(t, err)
+		assert.True(t, shared)
+	})
+
+	t.Run("policy removed", func(t *testing.T) {
+		shared, err := IsTokenSharedWith(context.TODO(), mockK8sClient(), binding("tenant", "https://fake.sp/acme/repo"), token)
+		assert.NoError(t, err)
+		assert.False(t, shared)
+	})
+
+	t.Run("repository no longer allowed", func(t *testing.T) {
+		shared, err := IsTokenSharedWith(context.TODO(), mockK8sClient(policy.DeepCopy()), binding("tenant", "https://fake.sp/other/repo"), token)
+		assert.NoError(t, err)
+		assert.False(t, shared)
+	})
+
+	t.Run("policy of another token", func(t *testing.T) {
+		other := policy.DeepCopy()
+		other.Spec.Token.Name = "other"
+		shared, err := IsTokenSharedWith(context.TODO(), mockK8sClient(other), binding("tenant", "https://fake.sp/acme/repo"), token)
+		assert.NoError(t, err)
+		assert.False(t, shared)
+	})
+}
+
 func TestGenericLookup_PersistMetadata(t *testing.T) {
 	token := &api.SPIAccessToken{
 		ObjectMeta: metav1.ObjectMeta{
@@ -249,10 +377,11 @@ func TestGenericLookup_LookupRemoteSecretSecret(t *testing.T) {
 		RepoUrlParser: RepoUrlFromSchemalessString,
 	}
 
-	secret, err := gl.lookupRemoteSecretSecret(context.TODO(), cl, &check, remoteSecrets)
+	secret, remoteSecret, err := gl.lookupRemoteSecretSecret(context.TODO(), cl, &check, remoteSecrets)
 	assert.NoError(t, err)
 	assert.NotNil(t, secret)
 	assert.Equal(t, "rs-secret", secret.Name)
+	assert.NotNil(t, remoteSecret)
 }
 
 func TestGenericLookup_LookupCredentials(t *testing.T) {
@@ -339,6 +468,7 @@ func TestGenericLookup_LookupCredentials(t *testing.T) {
 		assert.NotNil(t, credentials)
 		assert.Equal(t, "spi-username", credentials.Username)
 		assert.Equal(t, "spi-password", credentials.Token)
+		assert.Equal(t, &api.CredentialsSource{Kind: api.CredentialsSourceKindSPIAccessToken, Name: "matching"}, credentials.Source)
 	})
 
 	t.Run("credentials from RemoteSecret", func(t *testing.T) {
@@ -350,6 +480,7 @@ func TestGenericLookup_LookupCredentials(t *testing.T) {
 		assert.NotNil(t, credentials)
 		assert.Equal(t, "rs-username", credentials.Username)
 		assert.Equal(t, "rs-password", credentials.Token)
+		assert.Equal(t, &api.CredentialsSource{Kind: api.CredentialsSourceKindRemoteSecret, Name: "matching"}, credentials.Source)
 	})
 
 	t.Run("no credentials", func(t *testing.T) {
@@ -0,0 +1,328 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quay
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	ociManifestMediaType         = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestMediaType      = "application/vnd.docker.distribution.manifest.v2+json"
+	ociTitleAnnotation           = "org.opencontainers.image.title"
+	ociCreatedAnnotation         = "org.opencontainers.image.created"
+	dockerContentDigestHeader    = "Docker-Content-Digest"
+	defaultArtifactReference     = "latest"
+	digestReferencePrefix        = "sha256:"
+	manifestAcceptHeaderMimeType = ociManifestMediaType + ", " + dockerManifestMediaType
+)
+
+var (
+	fileSizeLimitExceededError   = errors.New("failed to retrieve file: size too big")
+	registryLoginFailedError     = errors.New("failed to log in to the registry with the provided credentials")
+	unsupportedManifestError     = errors.New("unsupported manifest media type, only single image manifests are supported")
+	fileNotFoundInArtifactError  = errors.New("the file was found neither as a layer nor in the tar layers of the artifact")
+	registryRequestFailedMessage = "request to the registry failed"
+)
+
+// downloadFileCapability downloads files from the OCI artifacts (and container images) stored in Quay. The file path of
+// the request selects either a layer by its title annotation or digest, or a file inside one of the tar layers.
+type downloadFileCapability struct {
+	httpClient *http.Client
+}
+
+var _ serviceprovider.DownloadFileCapability = (*downloadFileCapability)(nil)
+var _ serviceprovider.RefResolvingCapability = (*downloadFileCapability)(nil)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	MediaType   string            `json:"mediaType"`
+	Layers      []ociDescriptor   `json:"layers"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+func (d downloadFileCapability) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, maxFileSizeLimit int) (serviceprovider.DownloadedFile, error) {
+	repository, reference, err := artifactReference(request)
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, err
+	}
+
+	token, err := d.login(ctx, repository, credentials)
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, err
+	}
+
+	manifest, manifestDigest, err := d.fetchManifest(ctx, repository, reference, token)
+	if err != nil {
+		return serviceprovider.DownloadedFile{}, err
+	}
+
+	metadata := api.SPIFileContentMetadata{CommitSha: manifestDigest, FilePath: request.FilePath}
+	if created, err := time.Parse(time.RFC3339, manifest.Annotations[ociCreatedAnnotation]); err == nil {
+		lastModified := metav1.NewTime(created)
+		metadata.LastModified = &lastModified
+	}
+
+	// the whole layer
+	for _, layer := range manifest.Layers {
+		if layer.Annotations[ociTitleAnnotation] != request.FilePath && layer.Digest != request.FilePath {
+			continue
+		}
+		if layer.Size > maxFileSizeLimit {
+			return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: (%d)", fileSizeLimitExceededError, layer.Size)
+		}
+		content, err := d.readLayer(ctx, repository, layer, token, maxFileSizeLimit)
+		if err != nil {
+			return serviceprovider.DownloadedFile{}, err
+		}
+		metadata.BlobSha = layer.Digest
+		metadata.Size = len(content)
+		return serviceprovider.DownloadedFile{Content: content, Metadata: metadata}, nil
+	}
+
+	// a file in the tar layers. The upper layers override the lower ones, so let's start from the top.
+	filePath := normalizeTarPath(request.FilePath)
+	for i := len(manifest.Layers) - 1; i >= 0; i-- {
+		layer := manifest.Layers[i]
+		if !isTarLayer(layer.MediaType) {
+			continue
+		}
+		content, found, err := d.extractFromLayer(ctx, repository, layer, token, filePath, maxFileSizeLimit)
+		if err != nil {
+			return serviceprovider.DownloadedFile{}, err
+		}
+		if found {
+			metadata.BlobSha = layer.Digest
+			metadata.Size = len(content)
+			return serviceprovider.DownloadedFile{Content: content, Metadata: metadata}, nil
+		}
+	}
+
+	return serviceprovider.DownloadedFile{}, fmt.Errorf("%w: %s", fileNotFoundInArtifactError, request.FilePath)
+}
+
+// ResolveRef implements serviceprovider.RefResolvingCapability. It returns the digest of the manifest the tag in the ref
+// points to.
+func (d downloadFileCapability) ResolveRef(ctx context.Context, request api.SPIFileContentRequestSpec, credentials serviceprovider.Credentials, _ string) (string, error) {
+	repository, reference, err := artifactReference(request)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(reference, digestReferencePrefix) {
+		return reference, nil
+	}
+
+	token, err := d.login(ctx, repository, credentials)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := d.registryRequest(ctx, http.MethodHead, "/v2/"+repository+"/manifests/"+reference, token, manifestAcceptHeaderMimeType)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	digest := resp.Header.Get(dockerContentDigestHeader)
+	if digest == "" {
+		return "", fmt.Errorf("%w: the registry did not report the digest of %s:%s", unexpectedStatusCodeError, repository, reference)
+	}
+	return digest, nil
+}
+
+// artifactReference returns the repository (in the form of org/name) and the tag or digest of the artifact. The ref of
+// the request takes precedence over the tag in the repository URL. If none is specified, the latest tag is used.
+func artifactReference(request api.SPIFileContentRequestSpec) (string, string, error) {
+	owner, repository, version := splitToOrganizationAndRepositoryAndVersion(request.RepoUrl)
+	if owner == "" || repository == "" {
+		return "", "", fmt.Errorf("%w: %s", failedToParseRepoUrlError, request.RepoUrl)
+	}
+
+	reference := request.Ref
+	if reference == "" {
+		reference = version
+	}
+	if reference == "" {
+		reference = defaultArtifactReference
+	}
+	return owner + "/" + repository, reference, nil
+}
+
+func (d downloadFileCapability) login(ctx context.Context, repository string, credentials serviceprovider.Credentials) (string, error) {
+	username, password := getUsernameAndPasswordFromCredentials(credentials)
+	token, err := DockerLogin(ctx, d.httpClient, repository, username, password)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to the registry: %w", err)
+	}
+	if token == "" {
+		return "", registryLoginFailedError
+	}
+	return token, nil
+}
+
+func (d downloadFileCapability) fetchManifest(ctx context.Context, repository, reference, token string) (*ociManifest, string, error) {
+	resp, err := d.registryRequest(ctx, http.MethodGet, "/v2/"+repository+"/manifests/"+reference, token, manifestAcceptHeaderMimeType)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the manifest of %s:%s: %w", repository, reference, err)
+	}
+
+	manifest := &ociManifest{}
+	if err := json.Unmarshal(body, manifest); err != nil {
+		return nil, "", fmt.Errorf("failed to parse the manifest of %s:%s: %w", repository, reference, err)
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType = resp.Header.Get("Content-Type")
+	}
+	if manifest.MediaType != ociManifestMediaType && manifest.MediaType != dockerManifestMediaType {
+		return nil, "", fmt.Errorf("%w: %s", unsupportedManifestError, manifest.MediaType)
+	}
+
+	digest := resp.Header.Get(dockerContentDigestHeader)
+	if digest == "" {
+		digest = fmt.Sprintf("%s%x", digestReferencePrefix, sha256.Sum256(body))
+	}
+
+	return manifest, digest, nil
+}
+
+// readLayer reads the whole content of the layer. At most maxSize bytes are read.
+func (d downloadFileCapability) readLayer(ctx context.Context, repository string, layer ociDescriptor, token string, maxSize int) (string, error) {
+	resp, err := d.registryRequest(ctx, http.MethodGet, "/v2/"+repository+"/blobs/"+layer.Digest, token, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the layer %s: %w", layer.Digest, err)
+	}
+	if len(content) > maxSize {
+		return "", fmt.Errorf("%w: (more than %d)", fileSizeLimitExceededError, maxSize)
+	}
+	return string(content), nil
+}
+
+// extractFromLayer looks for the file in the tar layer and returns its content if found.
+func (d downloadFileCapability) extractFromLayer(ctx context.Context, repository string, layer ociDescriptor, token string, filePath string, maxSize int) (string, bool, error) {
+	resp, err := d.registryRequest(ctx, http.MethodGet, "/v2/"+repository+"/blobs/"+layer.Digest, token, "")
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	var layerReader io.Reader = resp.Body
+	if strings.HasSuffix(layer.MediaType, "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decompress the layer %s: %w", layer.Digest, err)
+		}
+		defer gz.Close()
+		layerReader = gz
+	}
+
+	tr := tar.NewReader(layerReader)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read the layer %s: %w", layer.Digest, err)
+		}
+		if hdr.Typeflag != tar.TypeReg || normalizeTarPath(hdr.Name) != filePath {
+			continue
+		}
+		if hdr.Size > int64(maxSize) {
+			return "", false, fmt.Errorf("%w: (%d)", fileSizeLimitExceededError, hdr.Size)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s from the layer %s: %w", filePath, layer.Digest, err)
+		}
+		log.FromContext(ctx).V(logs.DebugLevel).Info("file found in the artifact layer", "file", filePath, "layer", layer.Digest)
+		return string(content), true, nil
+	}
+}
+
+// registryRequest performs a request to the docker registry API of Quay and checks that it was successful.
+func (d downloadFileCapability) registryRequest(ctx context.Context, method string, urlPath string, token string, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, config.ServiceProviderTypeQuay.DefaultBaseUrl+urlPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compose the registry request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", registryRequestFailedMessage, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			rlErr := &serviceprovider.RateLimitedError{Cause: fmt.Errorf("%w: %d", unexpectedStatusCodeError, resp.StatusCode)}
+			if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				rlErr.ResetTime = time.Now().Add(time.Duration(retryAfter) * time.Second)
+			}
+			return nil, rlErr
+		}
+		return nil, fmt.Errorf("%s: %w: %d", registryRequestFailedMessage, unexpectedStatusCodeError, resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+func isTarLayer(mediaType string) bool {
+	return strings.Contains(mediaType, "tar") && !strings.HasSuffix(mediaType, "zstd")
+}
+
+func normalizeTarPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
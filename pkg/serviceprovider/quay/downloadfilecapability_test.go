@@ -0,0 +1,208 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quay
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifestDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+const testManifest = `{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.oci.image.manifest.v1+json",
+	"layers": [
+		{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "sha256:base", "size": 100},
+		{"mediaType": "application/vnd.oci.image.layer.v1.tar", "digest": "sha256:top", "size": 100},
+		{"mediaType": "application/yaml", "digest": "sha256:artifact", "size": 11, "annotations": {"org.opencontainers.image.title": "values.yaml"}}
+	],
+	"annotations": {"org.opencontainers.image.created": "2023-01-02T03:04:05Z"}
+}`
+
+func tarLayer(t *testing.T, gzipped bool, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	var w io.Writer = buf
+	var gz *gzip.Writer
+	if gzipped {
+		gz = gzip.NewWriter(buf)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	if gz != nil {
+		assert.NoError(t, gz.Close())
+	}
+	return buf.Bytes()
+}
+
+func registryClient(t *testing.T, manifest string, manifestStatus int) *http.Client {
+	blobs := map[string][]byte{
+		"sha256:base":     tarLayer(t, true, map[string]string{"etc/config": "base", "./etc/base-only": "base-only"}),
+		"sha256:top":      tarLayer(t, false, map[string]string{"etc/config": "top"}),
+		"sha256:artifact": []byte("key: value\n"),
+	}
+
+	return &http.Client{
+		Transport: util.FakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/v2/auth" {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"token": "registry-token"}`))}, nil
+			}
+
+			assert.Equal(t, "Bearer registry-token", r.Header.Get("Authorization"))
+
+			if strings.HasPrefix(r.URL.Path, "/v2/org/repo/manifests/") {
+				header := http.Header{}
+				header.Set(dockerContentDigestHeader, testManifestDigest)
+				return &http.Response{StatusCode: manifestStatus, Header: header, Body: io.NopCloser(strings.NewReader(manifest))}, nil
+			}
+
+			if blob, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/org/repo/blobs/")]; ok {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(blob))}, nil
+			}
+
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	creds := serviceprovider.Credentials{Token: "token"}
+
+	download := func(t *testing.T, cl *http.Client, filePath string, limit int) (serviceprovider.DownloadedFile, error) {
+		return downloadFileCapability{httpClient: cl}.DownloadFile(context.TODO(), api.SPIFileContentRequestSpec{
+			RepoUrl:  "quay.io/org/repo:v1",
+			FilePath: filePath,
+		}, creds, limit)
+	}
+
+	t.Run("layer by title", func(t *testing.T) {
+		file, err := download(t, registryClient(t, testManifest, http.StatusOK), "values.yaml", 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, "key: value\n", file.Content)
+		assert.Equal(t, testManifestDigest, file.Metadata.CommitSha)
+		assert.Equal(t, "sha256:artifact", file.Metadata.BlobSha)
+		assert.Equal(t, 11, file.Metadata.Size)
+		assert.Equal(t, "values.yaml", file.Metadata.FilePath)
+		assert.NotNil(t, file.Metadata.LastModified)
+		assert.Equal(t, 2023, file.Metadata.LastModified.Year())
+	})
+
+	t.Run("layer by digest", func(t *testing.T) {
+		file, err := download(t, registryClient(t, testManifest, http.StatusOK), "sha256:artifact", 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, "key: value\n", file.Content)
+	})
+
+	t.Run("file in the top layer wins", func(t *testing.T) {
+		file, err := download(t, registryClient(t, testManifest, http.StatusOK), "/etc/config", 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, "top", file.Content)
+		assert.Equal(t, "sha256:top", file.Metadata.BlobSha)
+	})
+
+	t.Run("file in the gzipped layer", func(t *testing.T) {
+		file, err := download(t, registryClient(t, testManifest, http.StatusOK), "etc/base-only", 1024)
+		assert.NoError(t, err)
+		assert.Equal(t, "base-only", file.Content)
+		assert.Equal(t, "sha256:base", file.Metadata.BlobSha)
+	})
+
+	t.Run("file not found", func(t *testing.T) {
+		_, err := download(t, registryClient(t, testManifest, http.StatusOK), "etc/missing", 1024)
+		assert.True(t, errors.Is(err, fileNotFoundInArtifactError))
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		_, err := download(t, registryClient(t, testManifest, http.StatusOK), "values.yaml", 5)
+		assert.True(t, errors.Is(err, fileSizeLimitExceededError))
+
+		_, err = download(t, registryClient(t, testManifest, http.StatusOK), "etc/base-only", 5)
+		assert.True(t, errors.Is(err, fileSizeLimitExceededError))
+	})
+
+	t.Run("index not supported", func(t *testing.T) {
+		_, err := download(t, registryClient(t, `{"mediaType": "application/vnd.oci.image.index.v1+json", "manifests": []}`, http.StatusOK), "values.yaml", 1024)
+		assert.True(t, errors.Is(err, unsupportedManifestError))
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		_, err := download(t, registryClient(t, "", http.StatusTooManyRequests), "values.yaml", 1024)
+		rlErr := &serviceprovider.RateLimitedError{}
+		assert.True(t, errors.As(err, &rlErr))
+	})
+
+	t.Run("login failure", func(t *testing.T) {
+		cl := &http.Client{
+			Transport: util.FakeRoundTrip(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}),
+		}
+		_, err := download(t, cl, "values.yaml", 1024)
+		assert.True(t, errors.Is(err, registryLoginFailedError))
+	})
+}
+
+func TestResolveRef(t *testing.T) {
+	capability := downloadFileCapability{httpClient: registryClient(t, testManifest, http.StatusOK)}
+	creds := serviceprovider.Credentials{Token: "token"}
+
+	t.Run("tag", func(t *testing.T) {
+		digest, err := capability.ResolveRef(context.TODO(), api.SPIFileContentRequestSpec{RepoUrl: "quay.io/org/repo", Ref: "v1"}, creds, "")
+		assert.NoError(t, err)
+		assert.Equal(t, testManifestDigest, digest)
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		digest, err := capability.ResolveRef(context.TODO(), api.SPIFileContentRequestSpec{RepoUrl: "quay.io/org/repo", Ref: "sha256:abcd"}, creds, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "sha256:abcd", digest)
+	})
+}
+
+func TestArtifactReference(t *testing.T) {
+	test := func(repoUrl, ref, expectedRepo, expectedRef string) {
+		t.Run(repoUrl+"@"+ref, func(t *testing.T) {
+			repo, reference, err := artifactReference(api.SPIFileContentRequestSpec{RepoUrl: repoUrl, Ref: ref})
+			assert.NoError(t, err)
+			assert.Equal(t, expectedRepo, repo)
+			assert.Equal(t, expectedRef, reference)
+		})
+	}
+
+	test("https://quay.io/org/repo", "", "org/repo", "latest")
+	test("quay.io/org/repo:v1", "", "org/repo", "v1")
+	test("quay.io/org/repo:v1", "v2", "org/repo", "v2")
+
+	_, _, err := artifactReference(api.SPIFileContentRequestSpec{RepoUrl: "https://github.com/org/repo"})
+	assert.True(t, errors.Is(err, failedToParseRepoUrlError))
+}
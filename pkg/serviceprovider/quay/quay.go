@@ -39,6 +39,14 @@ import (
 
 var _ serviceprovider.ServiceProvider = (*Quay)(nil)
 
+// The roles on a repository. Quay doesn't name them explicitly in the API, so we name them after the permissions that
+// can be granted on a repository.
+const (
+	repositoryRoleRead  = "read"
+	repositoryRoleWrite = "write"
+	repositoryRoleAdmin = "admin"
+)
+
 var (
 	unsupportedAreaError      = errors.New("unsupported permission area for Quay")
 	unsupportedScopeError     = errors.New("unsupported scope")
@@ -58,6 +66,8 @@ type Quay struct {
 	tokenStorage     tokenstorage.TokenStorage
 	BaseUrl          string
 	OAuthCapability  serviceprovider.OAuthCapability
+
+	downloadFileCapability serviceprovider.DownloadFileCapability
 }
 type quayOAuthCapability struct {
 	serviceprovider.DefaultOAuthCapability
@@ -103,6 +113,8 @@ func newQuay(factory *serviceprovider.Factory, spConfig *config.ServiceProviderC
 		tokenStorage:     factory.TokenStorage,
 		metadataProvider: mp,
 		OAuthCapability:  oauthCapability,
+
+		downloadFileCapability: downloadFileCapability{httpClient: factory.HttpClient},
 	}, nil
 }
 
@@ -117,6 +129,10 @@ func (q *Quay) GetType() config.ServiceProviderType {
 }
 
 func (q *Quay) GetDownloadFileCapability() serviceprovider.DownloadFileCapability {
+	return q.downloadFileCapability
+}
+
+func (q *Quay) GetDownloadTreeCapability() serviceprovider.DownloadTreeCapability {
 	return nil
 }
 
@@ -225,8 +241,12 @@ func (q *Quay) CheckRepositoryAccess(ctx context.Context, cl client.Client, acce
 		status.ErrorMessage = err.Error()
 	} else if credentials != nil {
 		username, token = getUsernameAndPasswordFromCredentials(*credentials)
+		status.CredentialsSource = credentials.Source
 	}
 
+	// the role on the repository, if known
+	role := ""
+
 	if responseCode, repoInfo, err := q.requestRepoInfo(ctx, owner, repository, token); err != nil {
 		status.ErrorReason = api.SPIAccessCheckErrorUnknownError
 		status.ErrorMessage = "failed request to Quay API"
@@ -242,6 +262,9 @@ func (q *Quay) CheckRepositoryAccess(ctx context.Context, cl client.Client, acce
 			} else {
 				status.Accessibility = api.SPIAccessCheckAccessibilityPrivate
 			}
+			if token != "" {
+				role = repositoryRole(repoInfo)
+			}
 		case http.StatusUnauthorized, http.StatusForbidden:
 			// if we have no token, we cannot distinguish between non-existent and private repository, so in that case
 			// we can assign no new status here...
@@ -273,9 +296,37 @@ func (q *Quay) CheckRepositoryAccess(ctx context.Context, cl client.Client, acce
 		}
 	}
 
+	status.GrantedRole = role
+	if status.Accessible {
+		status.Permissions = serviceprovider.CheckPermissions(func(p api.Permission) bool {
+			return permissionGranted(p, role)
+		}, &accessCheck.Spec.Permissions)
+	}
+
 	return status, nil
 }
 
+// repositoryRole returns the role of the user on the repository based on the repository info returned by Quay API.
+func repositoryRole(repoInfo map[string]interface{}) string {
+	if canAdmin, _ := repoInfo["can_admin"].(bool); canAdmin {
+		return repositoryRoleAdmin
+	}
+	if canWrite, _ := repoInfo["can_write"].(bool); canWrite {
+		return repositoryRoleWrite
+	}
+	return repositoryRoleRead
+}
+
+// permissionGranted checks whether the permission is satisfied on an accessible repository with the provided role.
+// Reading is always possible on an accessible repository.
+func permissionGranted(permission api.Permission, role string) bool {
+	switch permission.Area {
+	case api.PermissionAreaRegistry, api.PermissionAreaRegistryMetadata:
+		return !permission.Type.IsWrite() || role == repositoryRoleWrite || role == repositoryRoleAdmin
+	}
+	return false
+}
+
 func (q *Quay) requestRepoInfo(ctx context.Context, owner, repository, token string) (int, map[string]interface{}, error) {
 	requestUrl := fmt.Sprintf("%s/repository/%s/%s?includeTags=false", quayApiBaseUrl, owner, repository)
 	lg := log.FromContext(ctx, "repository", repository, "url", requestUrl)
@@ -345,6 +396,12 @@ func (q *Quay) MapToken(ctx context.Context, binding *api.SPIAccessTokenBinding,
 func (q *Quay) Validate(ctx context.Context, validated serviceprovider.Validated) (serviceprovider.ValidationResult, error) {
 	ret := serviceprovider.ValidationResult{}
 
+	if _, ok := validated.(*api.SPIFileContentRequest); ok {
+		// the file content requests always require reading the repository which, in case of Quay, means pulling
+		// the artifact from the registry.
+		return ret, nil
+	}
+
 	for _, p := range validated.Permissions().Required {
 		switch p.Area {
 		case api.PermissionAreaRegistry,
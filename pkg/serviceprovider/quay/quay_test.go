@@ -414,6 +414,8 @@ func TestCheckRepositoryAccess(t *testing.T) {
 		assert.Equal(t, api.SPIRepoTypeContainerRegistry, status.Type)
 		assert.Equal(t, api.ServiceProviderTypeQuay, status.ServiceProvider)
 		assert.Equal(t, api.SPIAccessCheckAccessibilityPrivate, status.Accessibility)
+		assert.Equal(t, &api.CredentialsSource{Kind: api.CredentialsSourceKindSPIAccessToken, Name: "token"}, status.CredentialsSource)
+		assert.Equal(t, repositoryRoleRead, status.GrantedRole)
 	})
 
 	t.Run("non existing or private without access", func(t *testing.T) {
@@ -754,3 +756,21 @@ type tokenFilterMock struct {
 func (t tokenFilterMock) Matches(ctx context.Context, matchable serviceprovider.Matchable, token *api.SPIAccessToken) (bool, error) {
 	return t.matchesFunc(ctx, matchable, token)
 }
+
+func TestRepositoryRole(t *testing.T) {
+	assert.Equal(t, repositoryRoleRead, repositoryRole(map[string]interface{}{}))
+	assert.Equal(t, repositoryRoleWrite, repositoryRole(map[string]interface{}{"can_write": true, "can_admin": false}))
+	assert.Equal(t, repositoryRoleAdmin, repositoryRole(map[string]interface{}{"can_write": true, "can_admin": true}))
+}
+
+func TestPermissionGranted(t *testing.T) {
+	read := api.Permission{Type: api.PermissionTypeRead, Area: api.PermissionAreaRegistry}
+	write := api.Permission{Type: api.PermissionTypeReadWrite, Area: api.PermissionAreaRegistryMetadata}
+
+	assert.True(t, permissionGranted(read, ""))
+	assert.True(t, permissionGranted(read, repositoryRoleRead))
+	assert.False(t, permissionGranted(write, repositoryRoleRead))
+	assert.True(t, permissionGranted(write, repositoryRoleWrite))
+	assert.True(t, permissionGranted(write, repositoryRoleAdmin))
+	assert.False(t, permissionGranted(api.Permission{Type: api.PermissionTypeRead, Area: api.PermissionAreaRepository}, repositoryRoleAdmin))
+}
@@ -0,0 +1,133 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/redhat-appstudio/remote-secret/pkg/httptransport"
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var listRepositoriesMetricConfig = serviceprovider.CommonRequestMetricsConfig(config.ServiceProviderTypeQuay, "list_repos")
+
+var _ serviceprovider.RepositoryListingCapability = (*Quay)(nil)
+
+type quayUserInfo struct {
+	Username      string `json:"username"`
+	Organizations []struct {
+		Name       string `json:"name"`
+		IsOrgAdmin bool   `json:"is_org_admin"`
+	} `json:"organizations"`
+}
+
+type quayRepositoryPage struct {
+	Repositories []struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+		IsPublic  bool   `json:"is_public"`
+	} `json:"repositories"`
+	NextPage string `json:"next_page"`
+}
+
+// ListRepositories implements serviceprovider.RepositoryListingCapability. It lists the repositories in the namespace
+// of the user and in all the organizations the user is a member of. Quay doesn't report the role of the user on
+// the individual repositories in the listing, so the role is only filled in as "admin" for the repositories in
+// the namespace of the user and in the organizations the user administers.
+func (q *Quay) ListRepositories(ctx context.Context, credentials serviceprovider.Credentials) ([]api.SPIRepositoryListItem, error) {
+	username, token := getUsernameAndPasswordFromCredentials(credentials)
+	if username != OAuthTokenUserName {
+		return nil, fmt.Errorf("%w: an OAuth token is required, robot account credentials cannot be used", serviceprovider.RepositoryListingNotSupportedError)
+	}
+
+	ctx = httptransport.ContextWithMetrics(ctx, listRepositoriesMetricConfig)
+
+	user := &quayUserInfo{}
+	if err := q.getQuayJson(ctx, quayApiBaseUrl+"/user/", token, user); err != nil {
+		return nil, fmt.Errorf("failed to get the details of the Quay user: %w", err)
+	}
+
+	// namespaces maps the namespaces to the role of the user in all the repositories in the namespace, if known
+	namespaces := map[string]string{user.Username: repositoryRoleAdmin}
+	for _, org := range user.Organizations {
+		namespaces[org.Name] = ""
+		if org.IsOrgAdmin {
+			namespaces[org.Name] = repositoryRoleAdmin
+		}
+	}
+
+	repositories := []api.SPIRepositoryListItem{}
+	for namespace, role := range namespaces {
+		nextPage := ""
+		for {
+			requestUrl := fmt.Sprintf("%s/repository?namespace=%s", quayApiBaseUrl, url.QueryEscape(namespace))
+			if nextPage != "" {
+				requestUrl += "&next_page=" + url.QueryEscape(nextPage)
+			}
+			page := &quayRepositoryPage{}
+			if err := q.getQuayJson(ctx, requestUrl, token, page); err != nil {
+				return nil, fmt.Errorf("failed to list the Quay repositories in %s: %w", namespace, err)
+			}
+			for _, r := range page.Repositories {
+				fullName := r.Namespace + "/" + r.Name
+				repositories = append(repositories, api.SPIRepositoryListItem{
+					RepoUrl:  config.ServiceProviderTypeQuay.DefaultHost + "/" + fullName,
+					FullName: fullName,
+					Private:  !r.IsPublic,
+					Role:     role,
+				})
+			}
+			if page.NextPage == "" {
+				break
+			}
+			nextPage = page.NextPage
+		}
+	}
+
+	log.FromContext(ctx).V(logs.DebugLevel).Info("listed the Quay repositories", "namespaces", len(namespaces), "count", len(repositories))
+	return repositories, nil
+}
+
+// getQuayJson performs a GET request to the Quay API and decodes the JSON response into the provided object.
+func (q *Quay) getQuayJson(ctx context.Context, requestUrl string, token string, into interface{}) error {
+	resp, err := doQuayRequest(ctx, q.httpClient, requestUrl, token, http.MethodGet, nil, "")
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return fmt.Errorf("%w for request '%s'", noResponseError, requestUrl)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.FromContext(ctx).Error(err, "failed to close response body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w '%d' for request '%s'", unexpectedStatusCodeError, resp.StatusCode, requestUrl)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		return fmt.Errorf("failed to decode the response of '%s': %w", requestUrl, err)
+	}
+	return nil
+}
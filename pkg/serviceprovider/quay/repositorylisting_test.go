@@ -0,0 +1,66 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quay
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/serviceprovider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListRepositories(t *testing.T) {
+	responses := map[string]string{
+		"/api/v1/user/":                                   `{"username": "alois", "organizations": [{"name": "acme", "is_org_admin": false}]}`,
+		"/api/v1/repository?namespace=alois":              `{"repositories": [{"namespace": "alois", "name": "own", "is_public": true}]}`,
+		"/api/v1/repository?namespace=acme":               `{"repositories": [{"namespace": "acme", "name": "first", "is_public": false}], "next_page": "abc"}`,
+		"/api/v1/repository?namespace=acme&next_page=abc": `{"repositories": [{"namespace": "acme", "name": "second", "is_public": false}]}`,
+	}
+
+	quay := &Quay{
+		httpClient: httpClientMock{doFunc: func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+			path := req.URL.Path
+			if req.URL.RawQuery != "" {
+				path += "?" + req.URL.RawQuery
+			}
+			body, ok := responses[path]
+			if !ok {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+		}},
+	}
+
+	t.Run("oauth token", func(t *testing.T) {
+		repositories, err := quay.ListRepositories(context.TODO(), serviceprovider.Credentials{Token: "token"})
+		assert.NoError(t, err)
+
+		assert.Len(t, repositories, 3)
+		assert.Contains(t, repositories, api.SPIRepositoryListItem{RepoUrl: "quay.io/alois/own", FullName: "alois/own", Role: repositoryRoleAdmin})
+		assert.Contains(t, repositories, api.SPIRepositoryListItem{RepoUrl: "quay.io/acme/first", FullName: "acme/first", Private: true})
+		assert.Contains(t, repositories, api.SPIRepositoryListItem{RepoUrl: "quay.io/acme/second", FullName: "acme/second", Private: true})
+	})
+
+	t.Run("robot account", func(t *testing.T) {
+		_, err := quay.ListRepositories(context.TODO(), serviceprovider.Credentials{Username: "acme+robot", Token: "token"})
+		assert.ErrorIs(t, err, serviceprovider.RepositoryListingNotSupportedError)
+	})
+}
@@ -0,0 +1,51 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprovider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+)
+
+// RefResolvingCapability is an optional extension of the DownloadFileCapability for the providers that can cheaply
+// determine the commit the ref of a file content request points to. It is used to avoid downloading the file again
+// if the ref has not moved since the last download.
+type RefResolvingCapability interface {
+	// ResolveRef returns the SHA of the commit the ref of the request points to. The lastCommitSha is the result of
+	// the previous resolution (can be empty) which the implementations can use to make a conditional request.
+	ResolveRef(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, lastCommitSha string) (string, error)
+}
+
+// RateLimitedError is returned by the capabilities if the service provider refused the request because the rate limit
+// was exceeded.
+type RateLimitedError struct {
+	// ResetTime is the time when the rate limit is reset. It is zero if the service provider did not report it.
+	ResetTime time.Time
+	Cause     error
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.ResetTime.IsZero() {
+		return fmt.Sprintf("rate limit of the service provider exceeded: %s", e.Cause)
+	}
+	return fmt.Sprintf("rate limit of the service provider exceeded until %s: %s", e.ResetTime.Format(time.RFC3339), e.Cause)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,71 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprovider
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+)
+
+// RepositoryListingNotSupportedError is returned when the repositories cannot be listed at all, either because
+// the service provider doesn't support it or because of the kind of the credentials.
+var RepositoryListingNotSupportedError = errors.New("listing the repositories is not supported")
+
+// RepositoryListingCapability is an optional extension of the ServiceProvider for the providers that can list all
+// the repositories accessible using some credentials.
+type RepositoryListingCapability interface {
+	// ListRepositories lists all the repositories accessible using the provided credentials. The order of
+	// the repositories is not specified.
+	ListRepositories(ctx context.Context, credentials Credentials) ([]api.SPIRepositoryListItem, error)
+}
+
+// FilterAndPaginateRepositories sorts the repositories by their full names, leaves out the ones not starting with
+// the (case-insensitive) name prefix and returns the requested 1-based page of the result together with the total
+// number of the matching repositories and the number of the next page (0 if there's no next page).
+func FilterAndPaginateRepositories(repositories []api.SPIRepositoryListItem, namePrefix string, page int, pageSize int) ([]api.SPIRepositoryListItem, int, int) {
+	prefix := strings.ToLower(namePrefix)
+	matching := make([]api.SPIRepositoryListItem, 0, len(repositories))
+	for _, r := range repositories {
+		if strings.HasPrefix(strings.ToLower(r.FullName), prefix) {
+			matching = append(matching, r)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return strings.ToLower(matching[i].FullName) < strings.ToLower(matching[j].FullName)
+	})
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		return []api.SPIRepositoryListItem{}, len(matching), 0
+	}
+
+	start := (page - 1) * pageSize
+	if start >= len(matching) {
+		return []api.SPIRepositoryListItem{}, len(matching), 0
+	}
+	end := start + pageSize
+	nextPage := page + 1
+	if end >= len(matching) {
+		end = len(matching)
+		nextPage = 0
+	}
+	return matching[start:end], len(matching), nextPage
+}
@@ -0,0 +1,65 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprovider
+
+import (
+	"testing"
+
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterAndPaginateRepositories(t *testing.T) {
+	repositories := []api.SPIRepositoryListItem{
+		{FullName: "acme/c"},
+		{FullName: "other/a"},
+		{FullName: "Acme/a"},
+		{FullName: "acme/b"},
+	}
+	names := func(items []api.SPIRepositoryListItem) []string {
+		ret := []string{}
+		for _, i := range items {
+			ret = append(ret, i.FullName)
+		}
+		return ret
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		page, total, next := FilterAndPaginateRepositories(repositories, "acme/", 1, 2)
+		assert.Equal(t, []string{"Acme/a", "acme/b"}, names(page))
+		assert.Equal(t, 3, total)
+		assert.Equal(t, 2, next)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		page, total, next := FilterAndPaginateRepositories(repositories, "acme/", 2, 2)
+		assert.Equal(t, []string{"acme/c"}, names(page))
+		assert.Equal(t, 3, total)
+		assert.Equal(t, 0, next)
+	})
+
+	t.Run("page out of range", func(t *testing.T) {
+		page, total, next := FilterAndPaginateRepositories(repositories, "", 3, 2)
+		assert.Empty(t, page)
+		assert.Equal(t, 4, total)
+		assert.Equal(t, 0, next)
+	})
+
+	t.Run("no prefix", func(t *testing.T) {
+		page, _, next := FilterAndPaginateRepositories(repositories, "", 1, 10)
+		assert.Equal(t, []string{"Acme/a", "acme/b", "acme/c", "other/a"}, names(page))
+		assert.Equal(t, 0, next)
+	})
+}
@@ -65,6 +65,10 @@ type ServiceProvider interface {
 	// or nil for those which are not
 	GetDownloadFileCapability() DownloadFileCapability
 
+	// GetDownloadTreeCapability returns capability object for the providers which are able to download whole directories
+	// from the repository or nil for those which are not
+	GetDownloadTreeCapability() DownloadTreeCapability
+
 	// GetRefreshTokenCapability returns capability object for the providers which are able to refresh OAuth access tokens.
 	// or nil
 	GetRefreshTokenCapability() RefreshTokenCapability
@@ -40,6 +40,7 @@ type TestServiceProvider struct {
 	ValidateImpl              func(context.Context, Validated) (ValidationResult, error)
 	CustomizeReset            func(provider *TestServiceProvider)
 	DownloadFileCapability    func() DownloadFileCapability
+	DownloadTreeCapability    func() DownloadTreeCapability
 	RefreshTokenCapability    func() RefreshTokenCapability
 	OAuthCapability           func() OAuthCapability
 }
@@ -96,6 +97,13 @@ func (t TestServiceProvider) GetDownloadFileCapability() DownloadFileCapability
 	return t.DownloadFileCapability()
 }
 
+func (t TestServiceProvider) GetDownloadTreeCapability() DownloadTreeCapability {
+	if t.DownloadTreeCapability == nil {
+		return nil
+	}
+	return t.DownloadTreeCapability()
+}
+
 func (t TestServiceProvider) GetRefreshTokenCapability() RefreshTokenCapability {
 	if t.RefreshTokenCapability == nil {
 		return nil
@@ -135,6 +143,7 @@ func (t *TestServiceProvider) Reset() {
 	t.MapTokenImpl = nil
 	t.ValidateImpl = nil
 	t.DownloadFileCapability = nil
+	t.DownloadTreeCapability = nil
 	t.RefreshTokenCapability = nil
 	t.OAuthCapability = nil
 	if t.CustomizeReset != nil {
@@ -143,24 +152,34 @@ func (t *TestServiceProvider) Reset() {
 }
 
 // TestCapabilities is a test implementation for capabilities that Service Provider can have.
-// Currently, it aggregates DownloadFileCapability and OAuthCapability. All of these have valid results (i.e. do not result in any errors).
+// Currently, it aggregates DownloadFileCapability, DownloadTreeCapability and OAuthCapability. All of these have valid results (i.e. do not result in any errors).
 type TestCapabilities struct {
-	DownloadFileImpl     func(context.Context, api.SPIFileContentRequestSpec, Credentials, int) (string, error)
+	DownloadFileImpl     func(context.Context, api.SPIFileContentRequestSpec, Credentials, int) (DownloadedFile, error)
+	DownloadTreeImpl     func(context.Context, api.SPIFileContentRequestSpec, Credentials, int) (map[string]string, error)
 	GetOAuthEndpointImpl func() string
 	OAuthScopesForImpl   func(permission *api.Permissions) []string
 	RefreshTokenImpl     func(ctx context.Context, token *api.Token, config *oauth2.Config) (*api.Token, error)
 }
 
 var _ DownloadFileCapability = (*TestCapabilities)(nil)
+var _ DownloadTreeCapability = (*TestCapabilities)(nil)
 var _ OAuthCapability = (*TestCapabilities)(nil)
 var _ RefreshTokenCapability = (*TestCapabilities)(nil)
 
-func (t *TestCapabilities) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxFileSizeLimit int) (string, error) {
+func (t *TestCapabilities) DownloadFile(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxFileSizeLimit int) (DownloadedFile, error) {
 	if t.DownloadFileImpl != nil {
 		return t.DownloadFileImpl(ctx, request, credentials, maxFileSizeLimit)
 	}
 
-	return "", nil
+	return DownloadedFile{}, nil
+}
+
+func (t *TestCapabilities) DownloadTree(ctx context.Context, request api.SPIFileContentRequestSpec, credentials Credentials, maxTotalSizeLimit int) (map[string]string, error) {
+	if t.DownloadTreeImpl != nil {
+		return t.DownloadTreeImpl(ctx, request, credentials, maxTotalSizeLimit)
+	}
+
+	return map[string]string{}, nil
 }
 
 func (t *TestCapabilities) GetOAuthEndpoint() string {
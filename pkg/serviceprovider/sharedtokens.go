@@ -0,0 +1,123 @@
+//
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprovider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/redhat-appstudio/remote-secret/pkg/logs"
+	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=spiaccesstokensharingpolicies,verbs=get;list;watch
+
+// lookupSharedTokens finds the tokens shared with the namespace of the matchable using SPIAccessTokenSharingPolicy
+// objects. Only the tokens of the service provider of this lookup that are allowed by the policies to be used for
+// the repository and permissions of the matchable are considered. Each use of a shared token is recorded in the audit
+// log.
+func (l GenericLookup) lookupSharedTokens(ctx context.Context, cl client.Client, matchable Matchable, repoUrl *url.URL) ([]api.SPIAccessToken, error) {
+	lg := log.FromContext(ctx)
+
+	policies := &api.SPIAccessTokenSharingPolicyList{}
+	if err := cl.List(ctx, policies); err != nil {
+		return nil, fmt.Errorf("failed to list the token sharing policies: %w", err)
+	}
+
+	candidates := make([]api.SPIAccessToken, 0)
+	policyNames := map[client.ObjectKey]string{}
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		if !policySharesWith(policy, matchable, repoUrl) {
+			continue
+		}
+
+		key := client.ObjectKey{Namespace: policy.Spec.Token.Namespace, Name: policy.Spec.Token.Name}
+		if _, ok := policyNames[key]; ok {
+			// the token is shared by more than one policy, no need to consider it again
+			continue
+		}
+
+		token := api.SPIAccessToken{}
+		if err := cl.Get(ctx, key, &token); err != nil {
+			if errors.IsNotFound(err) {
+				lg.V(logs.DebugLevel).Info("token referenced by the sharing policy not found", "policy", policy.Name, "token", key.String())
+				continue
+			}
+			return nil, fmt.Errorf("failed to get the token %s shared by the policy %s: %w", key, policy.Name, err)
+		}
+
+		if token.Labels[api.ServiceProviderTypeLabel] != string(l.ServiceProviderType) || token.Labels[api.ServiceProviderHostLabel] != repoUrl.Host {
+			continue
+		}
+
+		policyNames[key] = policy.Name
+		candidates = append(candidates, token)
+	}
+
+	lg.V(logs.DebugLevel).Info("shared token lookup", "potential_matches", len(candidates))
+
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	result, err := l.filterMatchingTokens(ctx, matchable, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range result {
+		auditLog := log.FromContext(ctx, "audit", "true", "namespace", matchable.ObjNamespace(), "token", t.Namespace+"/"+t.Name,
+			"policy", policyNames[client.ObjectKeyFromObject(&t)], "repository", matchable.RepoUrl())
+		auditLog.Info("token shared from another namespace matched", "action", "READ")
+	}
+
+	return result, nil
+}
+
+// IsTokenSharedWith checks whether the token from another namespace is still shared with the namespace of the matchable
+// by some SPIAccessTokenSharingPolicy for the repository and permissions of the matchable. This is used to find out
+// that the sharing of an already linked token has been revoked.
+func IsTokenSharedWith(ctx context.Context, cl client.Client, matchable Matchable, token *api.SPIAccessToken) (bool, error) {
+	repoUrl, err := RepoUrlFromSchemalessString(matchable.RepoUrl())
+	if err != nil {
+		return false, fmt.Errorf("error parsing the repo URL %s: %w", matchable.RepoUrl(), err)
+	}
+
+	policies := &api.SPIAccessTokenSharingPolicyList{}
+	if err := cl.List(ctx, policies); err != nil {
+		return false, fmt.Errorf("failed to list the token sharing policies: %w", err)
+	}
+
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		if policy.Spec.Token.Namespace == token.Namespace && policy.Spec.Token.Name == token.Name && policySharesWith(policy, matchable, repoUrl) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// policySharesWith returns true if the policy allows the matchable to use the token shared by it.
+func policySharesWith(policy *api.SPIAccessTokenSharingPolicy, matchable Matchable, repoUrl *url.URL) bool {
+	return policy.AppliesToNamespace(matchable.ObjNamespace()) &&
+		policy.AllowsRepository(repoUrl.Path) &&
+		policy.AllowsPermissions(matchable.Permissions())
+}
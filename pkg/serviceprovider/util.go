@@ -39,3 +39,27 @@ func GetAllScopes(convertToScopes func(permission api.Permission) []string, perm
 	}
 	return allScopes
 }
+
+// CheckPermissions is a helper method to evaluate each of the required permissions using the provided function. The
+// function is given each permission and returns whether the permission is granted.
+func CheckPermissions(isGranted func(permission api.Permission) bool, perms *api.Permissions) []api.SPIAccessCheckPermissionResult {
+	if len(perms.Required) == 0 {
+		return nil
+	}
+
+	results := make([]api.SPIAccessCheckPermissionResult, 0, len(perms.Required))
+	for _, p := range perms.Required {
+		results = append(results, api.SPIAccessCheckPermissionResult{Permission: p, Granted: isGranted(p)})
+	}
+	return results
+}
+
+// AllPermissionsGranted returns true if all the permissions in the results are granted.
+func AllPermissionsGranted(results []api.SPIAccessCheckPermissionResult) bool {
+	for _, r := range results {
+		if !r.Granted {
+			return false
+		}
+	}
+	return true
+}
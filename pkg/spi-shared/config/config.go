@@ -31,6 +31,7 @@ type ServiceProviderType struct {
 	DefaultOAuthEndpoint oauth2.Endpoint // default oauth endpoint of the service provider
 	DefaultHost          string          // default host of the service provider. ex.: `github.com`
 	DefaultBaseUrl       string          // default base url of the service provider, typically scheme+host. ex: `https://github.com`
+	DefaultPkceEnabled   bool            // whether the OAuth flow uses PKCE unless configured otherwise
 }
 
 // all service provider types we support, including default values
@@ -60,6 +61,9 @@ const (
 type persistedConfiguration struct {
 	// ServiceProviders is the list of configuration options for the individual service providers
 	ServiceProviders []persistedServiceProviderConfiguration `yaml:"serviceProviders"  validate:"omitempty,dive"`
+
+	// OAuthState configures the signing and encryption of the OAuth state
+	OAuthState persistedOAuthStateConfiguration `yaml:"oauthState,omitempty"`
 }
 
 // ServiceProviderConfiguration contains configuration for a single service provider configured with the SPI. This
@@ -80,6 +84,9 @@ type persistedServiceProviderConfiguration struct {
 
 	// Extra is the extra configuration required for some service providers to be able to uniquely identify them.
 	Extra map[string]string `yaml:"extra,omitempty"`
+
+	// Pkce enables or disables PKCE in the OAuth flow. If not specified, the default of the service provider type is used.
+	Pkce *bool `yaml:"pkce,omitempty"`
 }
 
 // SharedConfiguration contains the specification of the known service providers as well as other configuration data shared
@@ -91,6 +98,9 @@ type SharedConfiguration struct {
 	// BaseUrl is the URL on which the OAuth service is deployed. It is used to compose the redirect URLs for the
 	// service providers in the form of `${BASE_URL}/oauth/callback` (e.g. my-host/oauth/callback).
 	BaseUrl string `validate:"required,https_only"`
+
+	// OAuthState configures the signing and encryption of the OAuth state
+	OAuthState OAuthStateConfiguration
 }
 
 // ServiceProviderConfiguration contains configuration for a single service provider configured with the SPI. This
@@ -109,6 +119,9 @@ type ServiceProviderConfiguration struct {
 	// OAuth2Config holds oauth2 configuration of the service provider.
 	// It can be nil in case provider does not support OAuth or we don't have it configured.
 	OAuth2Config *oauth2.Config
+
+	// Pkce is true if the OAuth flow should use PKCE (RFC 7636).
+	Pkce bool
 }
 
 // convert converts persisted configuration into the SharedConfiguration instance.
@@ -117,6 +130,12 @@ func (persistedConfig persistedConfiguration) convert() (*SharedConfiguration, e
 		ServiceProviders: []ServiceProviderConfiguration{},
 	}
 
+	oauthStateConf, err := persistedConfig.OAuthState.convert()
+	if err != nil {
+		return nil, err
+	}
+	conf.OAuthState = oauthStateConf
+
 	for _, sp := range persistedConfig.ServiceProviders {
 		spType, err := GetServiceProviderTypeByName(sp.ServiceProviderName)
 		if err != nil {
@@ -127,6 +146,11 @@ func (persistedConfig persistedConfiguration) convert() (*SharedConfiguration, e
 			ServiceProviderType:    spType,
 			ServiceProviderBaseUrl: sp.ServiceProviderBaseUrl,
 			Extra:                  sp.Extra,
+			Pkce:                   spType.DefaultPkceEnabled,
+		}
+
+		if sp.Pkce != nil {
+			newSp.Pkce = *sp.Pkce
 		}
 
 		if sp.ClientId != "" && sp.ClientSecret != "" {
@@ -159,6 +183,7 @@ func (persistedConfig persistedConfiguration) convert() (*SharedConfiguration, e
 			conf.ServiceProviders = append(conf.ServiceProviders, ServiceProviderConfiguration{
 				ServiceProviderType:    spDefault,
 				ServiceProviderBaseUrl: spDefault.DefaultBaseUrl,
+				Pkce:                   spDefault.DefaultPkceEnabled,
 			})
 		}
 	}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/redhat-appstudio/remote-secret/pkg/config"
@@ -62,6 +63,85 @@ serviceProviders:
 		assert.Len(t, cfg.ServiceProviders, len(SupportedServiceProviderTypes))
 	})
 
+	t.Run("pkce defaults to the service provider type and can be overridden", func(t *testing.T) {
+		configFileContent := `
+serviceProviders:
+- type: GitHub
+  clientId: "123"
+  clientSecret: "42"
+  pkce: true
+- type: GitLab
+  clientId: "456"
+  clientSecret: "54"
+  pkce: false
+- type: GitLab
+  clientId: "789"
+  clientSecret: "98"
+  serviceProviderBaseUrl: https://gitlab.acme
+`
+		cfgFilePath := createFile(t, "config", configFileContent)
+		defer os.Remove(cfgFilePath)
+
+		cfg, err := LoadFrom(cfgFilePath, "blabol")
+		assert.NoError(t, err)
+
+		assert.True(t, cfg.ServiceProviders[0].Pkce)
+		assert.False(t, cfg.ServiceProviders[1].Pkce)
+		assert.True(t, cfg.ServiceProviders[2].Pkce)
+	})
+
+	t.Run("oauth state configuration", func(t *testing.T) {
+		configFileContent := `
+oauthState:
+  keys:
+  - id: new
+    secret: 0123456789abcdef0123456789abcdef
+  - id: old
+    secret: fedcba9876543210fedcba9876543210
+  encrypt: true
+  lifetime: 30m
+`
+		cfgFilePath := createFile(t, "config", configFileContent)
+		defer os.Remove(cfgFilePath)
+
+		cfg, err := LoadFrom(cfgFilePath, "blabol")
+		assert.NoError(t, err)
+
+		assert.Len(t, cfg.OAuthState.Keys, 2)
+		assert.True(t, cfg.OAuthState.Signed())
+		assert.Equal(t, "new", cfg.OAuthState.Keys[0].Id)
+		assert.Equal(t, []byte("0123456789abcdef0123456789abcdef"), cfg.OAuthState.Keys[0].Secret)
+		assert.True(t, cfg.OAuthState.Encrypt)
+		assert.Equal(t, 30*time.Minute, cfg.OAuthState.Lifetime)
+	})
+
+	t.Run("oauth state defaults", func(t *testing.T) {
+		cfgFilePath := createFile(t, "config", "")
+		defer os.Remove(cfgFilePath)
+
+		cfg, err := LoadFrom(cfgFilePath, "blabol")
+		assert.NoError(t, err)
+
+		assert.Empty(t, cfg.OAuthState.Keys)
+		assert.False(t, cfg.OAuthState.Signed())
+		assert.False(t, cfg.OAuthState.Encrypt)
+		assert.Equal(t, DefaultOAuthStateLifetime, cfg.OAuthState.Lifetime)
+	})
+
+	t.Run("invalid oauth state keys result in error", func(t *testing.T) {
+		for _, configFileContent := range []string{
+			"oauthState:\n  keys:\n  - id: short\n    secret: tooshort\n",
+			"oauthState:\n  keys:\n  - id: a.b\n    secret: 0123456789abcdef0123456789abcdef\n",
+			"oauthState:\n  keys:\n  - id: a\n    secret: 0123456789abcdef0123456789abcdef\n  - id: a\n    secret: fedcba9876543210fedcba9876543210\n",
+			"oauthState:\n  encrypt: true\n",
+		} {
+			cfgFilePath := createFile(t, "config", configFileContent)
+			_, err := LoadFrom(cfgFilePath, "blabol")
+			assert.ErrorIs(t, err, invalidOAuthStateKeyError)
+			os.Remove(cfgFilePath)
+		}
+	})
+
 	t.Run("unknown service provider result in error", func(t *testing.T) {
 		configFileContent := `
 serviceProviders:
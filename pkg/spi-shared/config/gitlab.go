@@ -25,9 +25,11 @@ const (
 var ServiceProviderTypeGitLab ServiceProviderType = ServiceProviderType{
 	Name: "GitLab",
 	DefaultOAuthEndpoint: oauth2.Endpoint{
-		AuthURL:  gitlabUrl + "/oauth/authorize",
-		TokenURL: gitlabUrl + "/oauth/token",
+		AuthURL:       gitlabUrl + "/oauth/authorize",
+		TokenURL:      gitlabUrl + "/oauth/token",
+		DeviceAuthURL: gitlabUrl + "/oauth/authorize_device",
 	},
-	DefaultHost:    gitlabHost,
-	DefaultBaseUrl: gitlabUrl,
+	DefaultHost:        gitlabHost,
+	DefaultBaseUrl:     gitlabUrl,
+	DefaultPkceEnabled: true,
 }
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultOAuthStateLifetime is the time for which the signed OAuth state is valid if not configured otherwise.
+	DefaultOAuthStateLifetime = time.Hour
+
+	minimalOAuthStateKeyLength = 32
+)
+
+var invalidOAuthStateKeyError = errors.New("invalid OAuth state key")
+
+// persistedOAuthStateConfiguration is the persisted form of the OAuthStateConfiguration.
+type persistedOAuthStateConfiguration struct {
+	// Keys are the keys used to sign the OAuth state. The first key is used for signing, all of them are accepted when
+	// verifying the state.
+	Keys []persistedOAuthStateKey `yaml:"keys,omitempty"`
+
+	// Encrypt makes the OAuth state encrypted in addition to being signed.
+	Encrypt bool `yaml:"encrypt,omitempty"`
+
+	// Lifetime is the time for which the signed OAuth state is valid.
+	Lifetime time.Duration `yaml:"lifetime,omitempty"`
+}
+
+type persistedOAuthStateKey struct {
+	// Id identifies the key in the signed state, so that the right key can be chosen for the verification.
+	Id string `yaml:"id"`
+
+	// Secret is the secret key material. It must be at least 32 bytes long.
+	Secret string `yaml:"secret"`
+}
+
+// OAuthStateConfiguration configures the signing and the encryption of the OAuth state passed between the operator,
+// the OAuth service and the service providers. If there are no keys, the state is neither signed nor encrypted. Such
+// configuration is only supported for the backward compatibility, see Signed.
+type OAuthStateConfiguration struct {
+	// Keys are the keys that are accepted when verifying the state. The first one is used to sign the new states, so
+	// that the keys can be rotated by prepending a new key and removing the old one once the states signed by it
+	// expire.
+	Keys []OAuthStateKey
+
+	// Encrypt is true if the state should also be encrypted.
+	Encrypt bool
+
+	// Lifetime is the time for which the signed state is valid.
+	Lifetime time.Duration
+}
+
+// Signed returns true if the configuration contains any keys to sign the OAuth state. The deployments without the keys
+// accept any well-formed state, so the operator and the OAuth service warn about them at startup.
+func (c OAuthStateConfiguration) Signed() bool {
+	return len(c.Keys) > 0
+}
+
+// OAuthStateKey is a single key used to sign and encrypt the OAuth state.
+type OAuthStateKey struct {
+	Id     string
+	Secret []byte
+}
+
+// convert converts the persisted OAuth state configuration into the OAuthStateConfiguration while validating the keys.
+func (persistedConfig persistedOAuthStateConfiguration) convert() (OAuthStateConfiguration, error) {
+	conf := OAuthStateConfiguration{
+		Encrypt:  persistedConfig.Encrypt,
+		Lifetime: persistedConfig.Lifetime,
+	}
+
+	if conf.Lifetime <= 0 {
+		conf.Lifetime = DefaultOAuthStateLifetime
+	}
+
+	ids := map[string]bool{}
+	for _, k := range persistedConfig.Keys {
+		if k.Id == "" || strings.Contains(k.Id, ".") {
+			return OAuthStateConfiguration{}, fmt.Errorf("%w: the id '%s' must be non-empty and must not contain '.'", invalidOAuthStateKeyError, k.Id)
+		}
+		if ids[k.Id] {
+			return OAuthStateConfiguration{}, fmt.Errorf("%w: duplicate id '%s'", invalidOAuthStateKeyError, k.Id)
+		}
+		if len(k.Secret) < minimalOAuthStateKeyLength {
+			return OAuthStateConfiguration{}, fmt.Errorf("%w: the secret of the key '%s' must be at least %d bytes long", invalidOAuthStateKeyError, k.Id, minimalOAuthStateKeyLength)
+		}
+		ids[k.Id] = true
+		conf.Keys = append(conf.Keys, OAuthStateKey{Id: k.Id, Secret: []byte(k.Secret)})
+	}
+
+	if conf.Encrypt && len(conf.Keys) == 0 {
+		return OAuthStateConfiguration{}, fmt.Errorf("%w: encryption of the OAuth state requires at least one key", invalidOAuthStateKeyError)
+	}
+
+	return conf, nil
+}
@@ -52,6 +52,7 @@ func SpConfigFromGlobalConfig(globalConfiguration *SharedConfiguration, spType S
 		return &ServiceProviderConfiguration{
 			ServiceProviderType:    spType,
 			ServiceProviderBaseUrl: spType.DefaultBaseUrl,
+			Pkce:                   spType.DefaultPkceEnabled,
 		}
 	}
 
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/redhat-appstudio/remote-secret/pkg/logs"
 	api "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
@@ -29,10 +30,12 @@ import (
 )
 
 const (
-	oauthCfgSecretFieldClientId     = "clientId"
-	oauthCfgSecretFieldClientSecret = "clientSecret"
-	oauthCfgSecretFieldAuthUrl      = "authUrl"
-	oauthCfgSecretFieldTokenUrl     = "tokenUrl"
+	oauthCfgSecretFieldClientId      = "clientId"
+	oauthCfgSecretFieldClientSecret  = "clientSecret"
+	oauthCfgSecretFieldAuthUrl       = "authUrl"
+	oauthCfgSecretFieldTokenUrl      = "tokenUrl"
+	oauthCfgSecretFieldDeviceAuthUrl = "deviceAuthUrl"
+	oauthCfgSecretFieldPkce          = "pkce"
 )
 
 var (
@@ -117,12 +120,25 @@ func createServiceProviderConfigurationFromSecret(configSecret *corev1.Secret, b
 		ServiceProviderBaseUrl: baseUrl,
 		Extra:                  map[string]string{},
 		OAuth2Config:           initializeOAuthConfigFromSecret(configSecret, spType),
+		Pkce:                   pkceFromSecret(configSecret, spType),
 	}
 }
 
+// pkceFromSecret returns whether PKCE is enabled by the `pkce` key of the secret. If the key is missing or its value is
+// not a valid boolean, the default of the service provider type is returned.
+func pkceFromSecret(secret *corev1.Secret, spType ServiceProviderType) bool {
+	if value, has := secret.Data[oauthCfgSecretFieldPkce]; has {
+		if pkce, err := strconv.ParseBool(string(value)); err == nil {
+			return pkce
+		}
+	}
+	return spType.DefaultPkceEnabled
+}
+
 // initializeOAuthConfigFromSecret creates `oauth2.Config` from given `Secret`.
 // In case Secret doesn't have both `clientId` and `clientSecret` keys set, we just return nil.
-// Endpoint is initially set from given `ServiceProviderType` defaults, but can be overwritten with `authUrl` and `tokenUrl` Secret keys.
+// Endpoint is initially set from given `ServiceProviderType` defaults, but can be overwritten with `authUrl`, `tokenUrl`
+// and `deviceAuthUrl` Secret keys.
 func initializeOAuthConfigFromSecret(secret *corev1.Secret, spType ServiceProviderType) *oauth2.Config {
 	oauthCfg := &oauth2.Config{
 		Endpoint: spType.DefaultOAuthEndpoint,
@@ -149,5 +165,9 @@ func initializeOAuthConfigFromSecret(secret *corev1.Secret, spType ServiceProvid
 		oauthCfg.Endpoint.TokenURL = string(tokenUrl)
 	}
 
+	if deviceAuthUrl, has := secret.Data[oauthCfgSecretFieldDeviceAuthUrl]; has && len(deviceAuthUrl) > 0 {
+		oauthCfg.Endpoint.DeviceAuthURL = string(deviceAuthUrl)
+	}
+
 	return oauthCfg
 }
@@ -32,10 +32,11 @@ import (
 )
 
 const (
-	testClientId     = "test_client_id_123"
-	testClientSecret = "test_client_secret_123"
-	testAuthUrl      = "test_auth_url_123"
-	testTokenUrl     = "test_token_url_123"
+	testClientId      = "test_client_id_123"
+	testClientSecret  = "test_client_secret_123"
+	testAuthUrl       = "test_auth_url_123"
+	testTokenUrl      = "test_token_url_123"
+	testDeviceAuthUrl = "test_device_auth_url_123"
 )
 
 func TestCreateServiceProviderConfigurationFromSecret(t *testing.T) {
@@ -69,14 +70,23 @@ func TestCreateServiceProviderConfigurationFromSecret(t *testing.T) {
 	})
 }
 
+func TestPkceFromSecret(t *testing.T) {
+	assert.False(t, pkceFromSecret(&v1.Secret{}, ServiceProviderTypeGitHub))
+	assert.True(t, pkceFromSecret(&v1.Secret{}, ServiceProviderTypeGitLab))
+	assert.True(t, pkceFromSecret(&v1.Secret{Data: map[string][]byte{oauthCfgSecretFieldPkce: []byte("true")}}, ServiceProviderTypeGitHub))
+	assert.False(t, pkceFromSecret(&v1.Secret{Data: map[string][]byte{oauthCfgSecretFieldPkce: []byte("false")}}, ServiceProviderTypeGitLab))
+	assert.True(t, pkceFromSecret(&v1.Secret{Data: map[string][]byte{oauthCfgSecretFieldPkce: []byte("blabol")}}, ServiceProviderTypeGitLab))
+}
+
 func TestCreateOauthConfigFromSecret(t *testing.T) {
 	t.Run("all fields set ok", func(t *testing.T) {
 		secret := &v1.Secret{
 			Data: map[string][]byte{
-				oauthCfgSecretFieldClientId:     []byte(testClientId),
-				oauthCfgSecretFieldClientSecret: []byte(testClientSecret),
-				oauthCfgSecretFieldAuthUrl:      []byte(testAuthUrl),
-				oauthCfgSecretFieldTokenUrl:     []byte(testTokenUrl),
+				oauthCfgSecretFieldClientId:      []byte(testClientId),
+				oauthCfgSecretFieldClientSecret:  []byte(testClientSecret),
+				oauthCfgSecretFieldAuthUrl:       []byte(testAuthUrl),
+				oauthCfgSecretFieldTokenUrl:      []byte(testTokenUrl),
+				oauthCfgSecretFieldDeviceAuthUrl: []byte(testDeviceAuthUrl),
 			},
 		}
 
@@ -86,6 +96,7 @@ func TestCreateOauthConfigFromSecret(t *testing.T) {
 		assert.Equal(t, testClientSecret, oauthCfg.ClientSecret)
 		assert.Equal(t, testAuthUrl, oauthCfg.Endpoint.AuthURL)
 		assert.Equal(t, testTokenUrl, oauthCfg.Endpoint.TokenURL)
+		assert.Equal(t, testDeviceAuthUrl, oauthCfg.Endpoint.DeviceAuthURL)
 	})
 
 	t.Run("error if missing client id", func(t *testing.T) {
@@ -130,6 +141,7 @@ func TestCreateOauthConfigFromSecret(t *testing.T) {
 		assert.Equal(t, testClientSecret, oauthCfg.ClientSecret)
 		assert.Equal(t, ServiceProviderTypeGitHub.DefaultOAuthEndpoint.AuthURL, oauthCfg.Endpoint.AuthURL)
 		assert.Equal(t, ServiceProviderTypeGitHub.DefaultOAuthEndpoint.TokenURL, oauthCfg.Endpoint.TokenURL)
+		assert.Equal(t, ServiceProviderTypeGitHub.DefaultOAuthEndpoint.DeviceAuthURL, oauthCfg.Endpoint.DeviceAuthURL)
 	})
 }
 
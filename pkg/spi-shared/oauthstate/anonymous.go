@@ -38,6 +38,14 @@ type OAuthInfo struct {
 
 	// ServiceProviderUrl the URL where the service provider is to be reached
 	ServiceProviderUrl string `json:"serviceProviderUrl"`
+
+	// ExpectedUserId is set when the OAuth flow upgrades the scopes of a token that already has data. It is the id of
+	// the service provider user that authorized the token. The new token data is only stored if it belongs to the same user.
+	ExpectedUserId string `json:"expectedUserId,omitempty"`
+
+	// RedirectAfter is the URI to which the user is redirected at the end of the OAuth flow instead of the default
+	// result page. It is requested by the caller when the flow starts and verified against the configured allow-list.
+	RedirectAfter string `json:"redirectAfter,omitempty"`
 }
 
 // ParseOAuthInfo parses the state from the URL query parameter and returns the anonymous state struct. It is just
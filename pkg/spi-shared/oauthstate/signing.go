@@ -0,0 +1,255 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauthstate
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+)
+
+var (
+	invalidStateSignatureError = errors.New("invalid signature of the OAuth state")
+	expiredStateError          = errors.New("the OAuth state has expired")
+	unsignedStateError         = errors.New("the OAuth state is not signed")
+	unknownStateKeyError       = errors.New("the OAuth state is signed by an unknown key")
+	malformedStateError        = errors.New("malformed signed OAuth state")
+)
+
+// Codec encodes and decodes the OAuth state according to the OAuth state configuration. If the configuration contains
+// any keys, the state is signed using HMAC-SHA256, carries its issue and expiry times, and is optionally encrypted
+// using AES-GCM. Such state has the form of `header.payload.signature`, all parts being base64-encoded. If there are
+// no keys, the state is encoded using the Encode function and is not verified at all. This unsigned mode only exists
+// for the backward compatibility with the deployments that don't configure any keys.
+type Codec struct {
+	Configuration config.OAuthStateConfiguration
+
+	// now returns the current time. It can be overridden in the tests.
+	now func() time.Time
+}
+
+type signedStateHeader struct {
+	KeyId     string `json:"kid"`
+	Encrypted bool   `json:"enc,omitempty"`
+}
+
+type signedStatePayload struct {
+	IssuedAt  int64           `json:"iat"`
+	ExpiresAt int64           `json:"exp"`
+	State     json.RawMessage `json:"state"`
+}
+
+// NewCodec creates a new codec using the provided configuration.
+func NewCodec(configuration config.OAuthStateConfiguration) *Codec {
+	return &Codec{Configuration: configuration, now: time.Now}
+}
+
+// Signed returns true if the states produced by this codec are signed.
+func (c *Codec) Signed() bool {
+	return c.Configuration.Signed()
+}
+
+// Encode encodes the provided state as a URL-safe string. The state is signed and possibly encrypted if the codec is
+// configured to do so.
+func (c *Codec) Encode(state interface{}) (string, error) {
+	if !c.Signed() {
+		return Encode(state)
+	}
+
+	stateData, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the state: %w", err)
+	}
+
+	now := c.currentTime()
+	payload, err := json.Marshal(signedStatePayload{
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(c.Lifetime()).Unix(),
+		State:     stateData,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the state payload: %w", err)
+	}
+
+	key := c.Configuration.Keys[0]
+	if c.Configuration.Encrypt {
+		if payload, err = encrypt(key, payload); err != nil {
+			return "", err
+		}
+	}
+
+	header, err := json.Marshal(signedStateHeader{KeyId: key.Id, Encrypted: c.Configuration.Encrypt})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the state header: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sign(key, signingInput)), nil
+}
+
+// ParseInto verifies the provided state and parses it into the dest object. It returns the time after which the state
+// is no longer valid or a zero time if the state is not signed.
+func (c *Codec) ParseInto(state string, dest interface{}) (time.Time, error) {
+	if !c.Signed() {
+		return time.Time{}, ParseInto(state, dest)
+	}
+
+	parts := strings.Split(state, ".")
+	if len(parts) != 3 {
+		return time.Time{}, unsignedStateError
+	}
+
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: failed to decode the header: %s", malformedStateError, err.Error())
+	}
+	header := signedStateHeader{}
+	if err = json.Unmarshal(headerData, &header); err != nil {
+		return time.Time{}, fmt.Errorf("%w: failed to unmarshal the header: %s", malformedStateError, err.Error())
+	}
+
+	key, found := c.findKey(header.KeyId)
+	if !found {
+		return time.Time{}, fmt.Errorf("%w: '%s'", unknownStateKeyError, header.KeyId)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, sign(key, parts[0]+"."+parts[1])) {
+		return time.Time{}, invalidStateSignatureError
+	}
+
+	payloadData, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: failed to decode the payload: %s", malformedStateError, err.Error())
+	}
+	if header.Encrypted {
+		if payloadData, err = decrypt(key, payloadData); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	payload := signedStatePayload{}
+	if err = json.Unmarshal(payloadData, &payload); err != nil {
+		return time.Time{}, fmt.Errorf("%w: failed to unmarshal the payload: %s", malformedStateError, err.Error())
+	}
+
+	expiresAt := time.Unix(payload.ExpiresAt, 0)
+	if c.currentTime().After(expiresAt) {
+		return time.Time{}, expiredStateError
+	}
+
+	if err = json.Unmarshal(payload.State, dest); err != nil {
+		return time.Time{}, fmt.Errorf("failed to unmarshal the state JSON: %w", err)
+	}
+
+	return expiresAt, nil
+}
+
+// ParseOAuthInfo is a typed variant of the ParseInto method.
+func (c *Codec) ParseOAuthInfo(state string) (OAuthInfo, time.Time, error) {
+	parsedState := OAuthInfo{}
+	expiresAt, err := c.ParseInto(state, &parsedState)
+	if err != nil {
+		return parsedState, time.Time{}, fmt.Errorf("error parsing OAuth state %w", err)
+	}
+
+	return parsedState, expiresAt, nil
+}
+
+// Lifetime returns the time for which the signed states are valid.
+func (c *Codec) Lifetime() time.Duration {
+	if c.Configuration.Lifetime <= 0 {
+		return config.DefaultOAuthStateLifetime
+	}
+	return c.Configuration.Lifetime
+}
+
+func (c *Codec) currentTime() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+func (c *Codec) findKey(id string) (config.OAuthStateKey, bool) {
+	for _, k := range c.Configuration.Keys {
+		if k.Id == id {
+			return k, true
+		}
+	}
+	return config.OAuthStateKey{}, false
+}
+
+func sign(key config.OAuthStateKey, data string) []byte {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// encryptionKey derives the AES key from the key secret, so that the same secret is not directly used both for
+// the signing and the encryption.
+func encryptionKey(key config.OAuthStateKey) []byte {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte("spi-oauth-state-encryption"))
+	return mac.Sum(nil)
+}
+
+func newAead(key config.OAuthStateKey) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(encryptionKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the state cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the state cipher: %w", err)
+	}
+	return aead, nil
+}
+
+func encrypt(key config.OAuthStateKey, data []byte) ([]byte, error) {
+	aead, err := newAead(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate the encryption nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func decrypt(key config.OAuthStateKey, data []byte) ([]byte, error) {
+	aead, err := newAead(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: the encrypted payload is too short", malformedStateError)
+	}
+	decrypted, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decrypt the payload: %s", malformedStateError, err.Error())
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package oauthstate
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redhat-appstudio/service-provider-integration-operator/pkg/spi-shared/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testKey1 = config.OAuthStateKey{Id: "key1", Secret: []byte("0123456789abcdef0123456789abcdef")}
+	testKey2 = config.OAuthStateKey{Id: "key2", Secret: []byte("fedcba9876543210fedcba9876543210")}
+
+	testInfo = &OAuthInfo{
+		TokenName:           "token-name",
+		TokenNamespace:      "default",
+		Scopes:              []string{"a", "b"},
+		ServiceProviderName: "sp type",
+		ServiceProviderUrl:  "https://sp",
+	}
+)
+
+func TestCodecUnsigned(t *testing.T) {
+	codec := NewCodec(config.OAuthStateConfiguration{})
+	assert.False(t, codec.Signed())
+
+	encoded, err := codec.Encode(testInfo)
+	assert.NoError(t, err)
+
+	plain, err := Encode(testInfo)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, encoded)
+
+	decoded, expiresAt, err := codec.ParseOAuthInfo(encoded)
+	assert.NoError(t, err)
+	assert.True(t, expiresAt.IsZero())
+	assert.Equal(t, *testInfo, decoded)
+}
+
+func TestCodecSigned(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	codec := NewCodec(config.OAuthStateConfiguration{Keys: []config.OAuthStateKey{testKey1}, Lifetime: time.Hour})
+	codec.now = func() time.Time { return now }
+
+	encoded, err := codec.Encode(testInfo)
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(encoded, "."), 3)
+
+	t.Run("valid state", func(t *testing.T) {
+		decoded, expiresAt, err := codec.ParseOAuthInfo(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, now.Add(time.Hour), expiresAt)
+		assert.Equal(t, *testInfo, decoded)
+	})
+
+	t.Run("payload readable without encryption", func(t *testing.T) {
+		payload, err := base64.RawURLEncoding.DecodeString(strings.Split(encoded, ".")[1])
+		assert.NoError(t, err)
+		assert.Contains(t, string(payload), "token-name")
+	})
+
+	t.Run("expired state", func(t *testing.T) {
+		later := NewCodec(codec.Configuration)
+		later.now = func() time.Time { return now.Add(2 * time.Hour) }
+		_, err := later.ParseInto(encoded, &OAuthInfo{})
+		assert.ErrorIs(t, err, expiredStateError)
+	})
+
+	t.Run("unsigned state", func(t *testing.T) {
+		plain, err := Encode(testInfo)
+		assert.NoError(t, err)
+		_, err = codec.ParseInto(plain, &OAuthInfo{})
+		assert.ErrorIs(t, err, unsignedStateError)
+	})
+
+	t.Run("tampered payload", func(t *testing.T) {
+		parts := strings.Split(encoded, ".")
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.NoError(t, err)
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(payload), "default", "kube-system", 1)))
+		_, err = codec.ParseInto(strings.Join(parts, "."), &OAuthInfo{})
+		assert.ErrorIs(t, err, invalidStateSignatureError)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		other := NewCodec(config.OAuthStateConfiguration{Keys: []config.OAuthStateKey{testKey2}})
+		_, err := other.ParseInto(encoded, &OAuthInfo{})
+		assert.ErrorIs(t, err, unknownStateKeyError)
+	})
+}
+
+func TestCodecKeyRotation(t *testing.T) {
+	oldCodec := NewCodec(config.OAuthStateConfiguration{Keys: []config.OAuthStateKey{testKey1}})
+	rotatedCodec := NewCodec(config.OAuthStateConfiguration{Keys: []config.OAuthStateKey{testKey2, testKey1}})
+
+	encodedByOld, err := oldCodec.Encode(testInfo)
+	assert.NoError(t, err)
+	_, _, err = rotatedCodec.ParseOAuthInfo(encodedByOld)
+	assert.NoError(t, err)
+
+	encodedByRotated, err := rotatedCodec.Encode(testInfo)
+	assert.NoError(t, err)
+	_, err = oldCodec.ParseInto(encodedByRotated, &OAuthInfo{})
+	assert.ErrorIs(t, err, unknownStateKeyError)
+}
+
+func TestCodecEncrypted(t *testing.T) {
+	codec := NewCodec(config.OAuthStateConfiguration{Keys: []config.OAuthStateKey{testKey1}, Encrypt: true})
+
+	encoded, err := codec.Encode(testInfo)
+	assert.NoError(t, err)
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(encoded, ".")[1])
+	assert.NoError(t, err)
+	assert.NotContains(t, string(payload), "token-name")
+
+	decoded, _, err := codec.ParseOAuthInfo(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, *testInfo, decoded)
+
+	// the decryption doesn't depend on the encryption being configured on the decoding side
+	decoded, _, err = NewCodec(config.OAuthStateConfiguration{Keys: []config.OAuthStateKey{testKey1}}).ParseOAuthInfo(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, *testInfo, decoded)
+}